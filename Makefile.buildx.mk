@@ -58,3 +58,14 @@ docker.buildx.nfs-server:
 .PHONY: buildx.push.nfs-server
 buildx.push.nfs-server:
 	BUILDX=true DIMAGE=${IMAGE_ORG}/nf-server-alpine ./buildscripts/push.sh
+
+.PHONY: docker.buildx.nfs-ganesha-server
+docker.buildx.nfs-ganesha-server:
+	@cd nfs-ganesha-container && \
+		docker buildx build --platform ${PLATFORMS} -t "$(NFS_GANESHA_SERVER_IMAGE_TAG)" . ${PUSH_ARG}
+	@echo "--> Build docker image: $(NFS_GANESHA_SERVER_IMAGE_TAG)"
+	@echo
+
+.PHONY: buildx.push.nfs-ganesha-server
+buildx.push.nfs-ganesha-server:
+	BUILDX=true DIMAGE=${IMAGE_ORG}/nfs-ganesha-server ./buildscripts/push.sh
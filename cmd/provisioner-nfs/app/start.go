@@ -28,6 +28,7 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/nfsmetrics"
 	"github.com/openebs/dynamic-nfs-provisioner/provisioner"
 	"github.com/openebs/maya/pkg/util"
 )
@@ -40,6 +41,14 @@ var (
 	defaultMetricsPath = "/metrics"
 	// defaultListenAddress defines the address where prometheus metrics are exposed
 	defaultListenAddress = ":9500"
+
+	// modeProvisioner runs the dynamic provisioner for NFS PVs
+	modeProvisioner = "provisioner"
+	// modeCSI runs the NFS CSI driver
+	modeCSI = "csi"
+	// modeExporter runs the metrics exporter of the NFS server,
+	// in the nfs-server pod
+	modeExporter = "exporter"
 )
 
 // StartProvisioner will start a new dynamic NFS provisioner
@@ -47,6 +56,18 @@ func StartProvisioner() (*cobra.Command, error) {
 	var (
 		metricsPath   string
 		listenAddress string
+		mode          string
+		csiEndpoint   string
+		csiRole       string
+		nodeID        string
+		webhookAddr   string
+		webhookCert   string
+		webhookKey    string
+		exportPath    string
+		nfsdPath      string
+		pvName        string
+		pvcName       string
+		pvcNamespace  string
 	)
 
 	// Create a new command.
@@ -63,6 +84,18 @@ func StartProvisioner() (*cobra.Command, error) {
 
 	cmd.Flags().StringVar(&metricsPath, "metrics-path", defaultMetricsPath, "path under which to expose metrics")
 	cmd.Flags().StringVar(&listenAddress, "listen-address", defaultListenAddress, "address on which to expose metrics")
+	cmd.Flags().StringVar(&mode, "mode", modeProvisioner, fmt.Sprintf("mode to run the provisioner in, supported modes are %s, %s and %s", modeProvisioner, modeCSI, modeExporter))
+	cmd.Flags().StringVar(&csiEndpoint, "csi-endpoint", provisioner.DefaultCSIEndpoint, "unix socket endpoint of the CSI driver, used in csi mode")
+	cmd.Flags().StringVar(&csiRole, "csi-role", provisioner.CSIRoleController, fmt.Sprintf("role of the CSI driver, supported roles are %s and %s, used in csi mode", provisioner.CSIRoleController, provisioner.CSIRoleNode))
+	cmd.Flags().StringVar(&nodeID, "node-id", "", "name of the node on which the CSI driver is running, required by the node role in csi mode")
+	cmd.Flags().StringVar(&webhookAddr, "webhook-listen-address", "", "address on which to serve the config validation webhook, webhook is disabled if empty")
+	cmd.Flags().StringVar(&webhookCert, "webhook-tls-cert-file", "", "path of the TLS certificate of the config validation webhook")
+	cmd.Flags().StringVar(&webhookKey, "webhook-tls-key-file", "", "path of the TLS key of the config validation webhook")
+	cmd.Flags().StringVar(&exportPath, "export-path", "/nfsshare", "path of the filesystem exported by the NFS server, used in exporter mode")
+	cmd.Flags().StringVar(&nfsdPath, "nfsd-path", "/proc/fs/nfsd", "mount path of the nfsd filesystem, used in exporter mode")
+	cmd.Flags().StringVar(&pvName, "pv-name", "", "name of the NFS PV, used as metrics label in exporter mode")
+	cmd.Flags().StringVar(&pvcName, "pvc-name", "", "name of the NFS PVC, used as metrics label in exporter mode")
+	cmd.Flags().StringVar(&pvcNamespace, "pvc-namespace", "", "namespace of the NFS PVC, used as metrics label in exporter mode")
 
 	// add the default command line flags as global flags to cobra command
 	// flagset
@@ -78,12 +111,45 @@ func StartProvisioner() (*cobra.Command, error) {
 func Start(cmd *cobra.Command) error {
 	metricPath := cmd.Flag("metrics-path").Value.String()
 	metricListenAddress := cmd.Flag("listen-address").Value.String()
+	mode := cmd.Flag("mode").Value.String()
+
+	if mode != modeProvisioner && mode != modeCSI && mode != modeExporter {
+		return fmt.Errorf("invalid mode %q, supported modes are %s, %s and %s", mode, modeProvisioner, modeCSI, modeExporter)
+	}
+
+	if mode == modeExporter {
+		return startExporter(cmd, metricPath, metricListenAddress)
+	}
+
+	webhookAddr := cmd.Flag("webhook-listen-address").Value.String()
+	if len(webhookAddr) != 0 {
+		if mode != modeProvisioner {
+			return fmt.Errorf("webhook is supported only in %s mode", modeProvisioner)
+		}
+		if len(cmd.Flag("webhook-tls-cert-file").Value.String()) == 0 ||
+			len(cmd.Flag("webhook-tls-key-file").Value.String()) == 0 {
+			return fmt.Errorf("webhook-tls-cert-file and webhook-tls-key-file are required by the webhook")
+		}
+	}
 
 	prometheus.MustRegister([]prometheus.Collector{
 		metrics.PersistentVolumeDeleteTotal,
 		metrics.PersistentVolumeDeleteFailedTotal,
 		metrics.PersistentVolumeCreateTotal,
 		metrics.PersistentVolumeCreateFailedTotal,
+		metrics.NFSServerResourceRepairTotal,
+		metrics.NFSServerResourceRepairFailedTotal,
+		metrics.NFSServerAddressMismatch,
+		metrics.NFSServerSpecDrift,
+		metrics.NFSServerUpgradeTotal,
+		metrics.NFSServerUpgradeFailedTotal,
+		metrics.PersistentVolumeProvisionDuration,
+		metrics.PersistentVolumeCount,
+		metrics.NFSServerProvisionStageDuration,
+		metrics.HookExecutionTotal,
+		metrics.HookExecutionFailedTotal,
+		metrics.GarbageCollectorRunTotal,
+		metrics.GarbageCollectorDeleteTotal,
 	}...)
 
 	go func() {
@@ -101,5 +167,33 @@ func Start(cmd *cobra.Command) error {
 	// So not required to do same action here
 	provisioner.RegisterShutdownChannel(cancelFn)
 
-	return provisioner.Start(ctx)
+	if mode == modeCSI {
+		return provisioner.StartCSIDriver(ctx, cmd.Flag("csi-endpoint").Value.String(), cmd.Flag("csi-role").Value.String(), cmd.Flag("node-id").Value.String())
+	}
+	return provisioner.Start(ctx, provisioner.WebhookConfig{
+		ListenAddress: cmd.Flag("webhook-listen-address").Value.String(),
+		CertFile:      cmd.Flag("webhook-tls-cert-file").Value.String(),
+		KeyFile:       cmd.Flag("webhook-tls-key-file").Value.String(),
+	})
+}
+
+// startExporter runs the metrics exporter of the NFS server
+func startExporter(cmd *cobra.Command, metricPath, metricListenAddress string) error {
+	nfsdPath := cmd.Flag("nfsd-path").Value.String()
+	if err := nfsmetrics.MountNFSD(nfsdPath); err != nil {
+		// NFS server metrics are still exported, except the clients
+		fmt.Printf("Failed to mount nfsd filesystem: %v\n", err)
+	}
+
+	prometheus.MustRegister(nfsmetrics.NewCollector(nfsmetrics.Config{
+		NFSDPath:     nfsdPath,
+		ExportPath:   cmd.Flag("export-path").Value.String(),
+		PVName:       cmd.Flag("pv-name").Value.String(),
+		PVCName:      cmd.Flag("pvc-name").Value.String(),
+		PVCNamespace: cmd.Flag("pvc-namespace").Value.String(),
+	}))
+
+	http.Handle(metricPath, promhttp.Handler())
+	fmt.Printf("Starting NFS server metrics exporter at address [%s]\n", metricListenAddress)
+	return http.ListenAndServe(metricListenAddress, nil)
 }
@@ -0,0 +1,140 @@
+#!/bin/bash
+
+# Copyright 2021 The OpenEBS Authors.
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#     http://www.apache.org/licenses/LICENSE-2.0
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# This script configures and launches the userspace NFS-Ganesha server.
+# It understands the same environment variables as nfsd.sh of the kernel
+# nfs-server image, so that both server types can be configured alike.
+
+GANESHA_CONFIG=/etc/ganesha/ganesha.conf
+
+# Make sure we react to these signals by running stop() when we see them - for clean shutdown
+# And then exiting
+trap "stop; exit 0;" SIGTERM SIGINT
+
+stop()
+{
+  echo "SIGTERM caught, terminating NFS-Ganesha process(es)..."
+  pid1=`pidof ganesha.nfsd`
+  pid2=`pidof rpcbind`
+  pid3=`pidof dbus-daemon`
+  kill -TERM $pid1 $pid2 $pid3 > /dev/null 2>&1
+  echo "Terminated."
+  exit
+}
+
+# Check if the SHARED_DIRECTORY variable is empty
+if [ -z "${SHARED_DIRECTORY}" ]; then
+  echo "The SHARED_DIRECTORY environment variable is unset or null, exiting..."
+  exit 1
+fi
+
+NFS_LEASE_TIME=${NFS_LEASE_TIME:-90}
+NFS_GRACE_TIME=${NFS_GRACE_TIME:-90}
+
+# Writes the NFS-Ganesha configuration. The backend volume is exported
+# as the NFSv4 pseudo root, so that clients can mount "/" similar to the
+# fsid=0 export of the kernel NFS server
+write_config() {
+  cat > ${GANESHA_CONFIG} <<CONFIG
+NFS_CORE_PARAM {
+  Protocols = 4;
+  NFS_Port = 2049;
+  Enable_NLM = false;
+  Enable_RQUOTA = false;
+}
+
+NFSV4 {
+  Lease_Lifetime = ${NFS_LEASE_TIME};
+  Grace_Period = ${NFS_GRACE_TIME};
+  RecoveryBackend = fs;
+}
+
+CONFIG
+
+  # Check if the CUSTOM_EXPORTS_CONFIG variable is set, and if it is, use
+  # it as export configuration in place of the default one
+  if [ ! -z "${CUSTOM_EXPORTS_CONFIG}" ]; then
+    echo "CUSTOM_EXPORTS_CONFIG variable is set, using it as export configuration..."
+    echo "${CUSTOM_EXPORTS_CONFIG}" >> ${GANESHA_CONFIG}
+    return
+  fi
+
+  cat >> ${GANESHA_CONFIG} <<CONFIG
+EXPORT {
+  Export_Id = 1;
+  Path = ${SHARED_DIRECTORY};
+  Pseudo = /;
+  Access_Type = RW;
+  Squash = No_Root_Squash;
+  SecType = sys;
+  Protocols = 4;
+  Transports = TCP;
+  FSAL {
+    Name = VFS;
+  }
+}
+CONFIG
+}
+
+write_config
+
+# Partially set 'unofficial Bash Strict Mode' as described here: http://redsymbol.net/articles/unofficial-bash-strict-mode/
+# We don't set -e because the pidof command returns an exit code of 1 when the specified process is not found
+set -uo pipefail
+IFS=$'\n\t'
+
+# Modify the shared directory (${SHARED_DIRECTORY}) file user owner
+if [ -n "${FILEPERMISSIONS_UID:-}" ]; then
+  targetUID=$(printf %d ${FILEPERMISSIONS_UID}) || { echo "user change error: Invalid UID ${FILEPERMISSIONS_UID}"; exit 1; }
+  if [ "$(stat ${SHARED_DIRECTORY} --printf=%u)" -ne "$targetUID" ]; then
+    chown -R $targetUID ${SHARED_DIRECTORY} || { echo "user change error: Failed to change user owner of ${SHARED_DIRECTORY}"; exit 1; }
+    echo "chown user command succeeded"
+  fi
+fi
+
+# Modify the shared directory (${SHARED_DIRECTORY}) file group owner
+if [ -n "${FILEPERMISSIONS_GID:-}" ]; then
+  targetGID=$(printf %d ${FILEPERMISSIONS_GID}) || { echo "group change error: Invalid GID ${FILEPERMISSIONS_GID}"; exit 1; }
+  if [ "$(stat ${SHARED_DIRECTORY} --printf=%g)" -ne "$targetGID" ]; then
+    chown -R :${targetGID} ${SHARED_DIRECTORY} || { echo "group change error: Failed to change group owner of ${SHARED_DIRECTORY}"; exit 1; }
+    echo "chown group command succeeded"
+  fi
+fi
+
+# Modify the shared directory (${SHARED_DIRECTORY}) file permissions
+if [ -n "${FILEPERMISSIONS_MODE:-}" ]; then
+  TEST_CHMOD_OUT=$(chmod ${FILEPERMISSIONS_MODE} ${SHARED_DIRECTORY} -c) || { echo "mode change error: 'mode' value ${FILEPERMISSIONS_MODE} might be invalid"; exit 1; }
+  if [ -n "${TEST_CHMOD_OUT}" ]; then
+    chmod -R ${FILEPERMISSIONS_MODE} ${SHARED_DIRECTORY} || { echo "mode change error: Failed to change file mode of ${SHARED_DIRECTORY}"; exit 1; }
+    echo "chmod command succeeded"
+  fi
+fi
+
+echo "Displaying ${GANESHA_CONFIG} contents:"
+cat ${GANESHA_CONFIG}
+echo ""
+
+echo "Starting rpcbind..."
+/sbin/rpcbind -w || /usr/sbin/rpcbind -w
+
+echo "Starting dbus..."
+rm -f /var/run/dbus/pid
+dbus-daemon --system --nopidfile
+
+echo "Starting NFS-Ganesha in the foreground..."
+# Running in foreground, so that container exits if NFS-Ganesha fails
+/usr/bin/ganesha.nfsd -F -L /dev/stdout -f ${GANESHA_CONFIG} &
+wait $!
+
+echo "NFS-Ganesha has failed, exiting, so Docker can restart the container..."
+exit 1
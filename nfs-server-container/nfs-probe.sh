@@ -0,0 +1,75 @@
+#!/bin/bash
+
+# Copyright 2021 The OpenEBS Authors.
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#     http://www.apache.org/licenses/LICENSE-2.0
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# This script checks the health of the NFS server, and is used as the
+# liveness and readiness probe of the nfs-server container.
+#
+# - liveness: rpcbind is responding, nfsd is registered with rpcbind and
+#   responding to the NFS NULL call, and nfsd threads are running. Failures
+#   are ignored during the NFSv4 grace period, while the NFS server recovers
+#   the client state.
+# - readiness: liveness checks pass, and the file systems are exported.
+#
+# Usage:
+#   nfs-probe.sh liveness
+#   nfs-probe.sh readiness
+
+set -uo pipefail
+
+NFSD_FS=/proc/fs/nfsd
+ETAB_FILE=/var/lib/nfs/etab
+
+fail() {
+  echo "$@" >&2
+  exit 1
+}
+
+# in_grace returns success if the NFSv4 grace period has not ended
+in_grace() {
+  [ -r ${NFSD_FS}/v4_end_grace ] && [ "$(cat ${NFSD_FS}/v4_end_grace)" = "N" ]
+}
+
+check_nfsd() {
+  /sbin/rpcinfo -p 127.0.0.1 > /dev/null 2>&1 || fail "rpcbind is not responding"
+
+  # NFSv4 is probed if it is enabled, otherwise NFSv3
+  version=4
+  if [[ ",${NFS_VERSIONS:-4}," != *",4,"* ]]; then
+    version=3
+  fi
+  /sbin/rpcinfo -t 127.0.0.1 nfs ${version} > /dev/null 2>&1 || \
+    fail "nfsd is not registered with rpcbind or not responding"
+
+  threads=$(cat ${NFSD_FS}/threads 2>/dev/null)
+  [ "${threads:-0}" -gt 0 ] || fail "nfsd threads are not running"
+}
+
+check_exports() {
+  [ -s ${ETAB_FILE} ] || fail "file systems are not exported"
+}
+
+case "${1:-}" in
+  liveness)
+    if in_grace; then
+      exit 0
+    fi
+    check_nfsd
+    ;;
+  readiness)
+    check_nfsd
+    check_exports
+    ;;
+  *)
+    fail "Usage: $0 liveness|readiness"
+    ;;
+esac
@@ -0,0 +1,151 @@
+#!/bin/bash
+
+# Copyright 2021 The OpenEBS Authors.
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#     http://www.apache.org/licenses/LICENSE-2.0
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# This script manages the volumes of a shared NFS server. Each volume is a
+# subdirectory of ${SHARED_DIRECTORY}, whose size is limited using filesystem
+# project quota. Project IDs of the volumes are recorded in the projects file
+# on the shared volume, so that the quota can be cleared on volume removal.
+#
+# Usage:
+#   nfs-volume.sh add <name> <size-in-bytes> [uid] [gid] [mode]
+#   nfs-volume.sh remove <name>
+#   nfs-volume.sh list
+
+set -uo pipefail
+
+SHARED_DIRECTORY=${SHARED_DIRECTORY:-/nfsshare}
+PROJECTS_FILE=${SHARED_DIRECTORY}/.nfs-projects
+LOCK_FILE=${SHARED_DIRECTORY}/.nfs-projects.lock
+
+fail() {
+  echo "$@" >&2
+  exit 1
+}
+
+# get_project_id prints the project ID recorded for the given volume
+get_project_id() {
+  awk -F: -v name="$1" '$2 == name { print $1 }' ${PROJECTS_FILE} 2>/dev/null
+}
+
+# next_project_id prints the next unused project ID
+next_project_id() {
+  awk -F: 'BEGIN { max = 0 } $1 > max { max = $1 } END { print max + 1 }' ${PROJECTS_FILE} 2>/dev/null || echo 1
+}
+
+# set_quota sets the project quota for the given directory. Only xfs (mounted
+# with prjquota) and ext4 (with project quota feature) filesystems are supported.
+set_quota() {
+  local dir=$1 id=$2 size=$3
+  local fstype
+  fstype=$(stat -f -c %T ${SHARED_DIRECTORY})
+
+  case ${fstype} in
+    xfs)
+      xfs_quota -x -c "project -s -p ${dir} ${id}" ${SHARED_DIRECTORY} > /dev/null || return 1
+      xfs_quota -x -c "limit -p bhard=${size} ${id}" ${SHARED_DIRECTORY} || return 1
+      ;;
+    ext2/ext3|ext4)
+      chattr -R +P -p ${id} ${dir} || return 1
+      setquota -P ${id} 0 $(( (size + 1023) / 1024 )) 0 0 ${SHARED_DIRECTORY} || return 1
+      ;;
+    *)
+      echo "project quota is not supported on filesystem ${fstype}" >&2
+      return 1
+      ;;
+  esac
+}
+
+# clear_quota removes the project quota limits of the given project ID
+clear_quota() {
+  local id=$1
+  local fstype
+  fstype=$(stat -f -c %T ${SHARED_DIRECTORY})
+
+  case ${fstype} in
+    xfs)
+      xfs_quota -x -c "limit -p bhard=0 ${id}" ${SHARED_DIRECTORY}
+      ;;
+    ext2/ext3|ext4)
+      setquota -P ${id} 0 0 0 0 ${SHARED_DIRECTORY}
+      ;;
+  esac
+}
+
+add_volume() {
+  local name=$1 size=$2 uid=${3:-} gid=${4:-} mode=${5:-}
+  local dir=${SHARED_DIRECTORY}/${name}
+  local id
+
+  id=$(get_project_id ${name})
+  if [ -z "${id}" ]; then
+    id=$(next_project_id)
+    echo "${id}:${name}" >> ${PROJECTS_FILE}
+  fi
+
+  mkdir -p ${dir} || fail "failed to create directory ${dir}"
+  set_quota ${dir} ${id} ${size} || fail "failed to set quota of ${size} bytes on ${dir}"
+
+  if [ -n "${uid}" ]; then
+    chown ${uid} ${dir} || fail "failed to change user owner of ${dir}"
+  fi
+  if [ -n "${gid}" ]; then
+    chown :${gid} ${dir} || fail "failed to change group owner of ${dir}"
+  fi
+  if [ -n "${mode}" ]; then
+    chmod ${mode} ${dir} || fail "failed to change file mode of ${dir}"
+  fi
+
+  echo "volume ${name} added with project ID ${id}"
+}
+
+remove_volume() {
+  local name=$1
+  local dir=${SHARED_DIRECTORY}/${name}
+  local id
+
+  rm -rf ${dir} || fail "failed to remove directory ${dir}"
+
+  id=$(get_project_id ${name})
+  if [ -n "${id}" ]; then
+    clear_quota ${id}
+    sed -i "/^${id}:${name}\$/d" ${PROJECTS_FILE}
+  fi
+
+  echo "volume ${name} removed"
+}
+
+list_volumes() {
+  find ${SHARED_DIRECTORY} -mindepth 1 -maxdepth 1 -type d ! -name 'lost+found' ! -name '.*' -exec basename {} \;
+}
+
+# All the operations are serialized, since the projects file
+# is shared across the volumes
+exec 9> ${LOCK_FILE}
+flock 9 || fail "failed to acquire lock ${LOCK_FILE}"
+
+case "${1:-}" in
+  add)
+    [ $# -ge 3 ] || fail "usage: $0 add <name> <size-in-bytes> [uid] [gid] [mode]"
+    add_volume "${@:2}"
+    ;;
+  remove)
+    [ $# -eq 2 ] || fail "usage: $0 remove <name>"
+    remove_volume $2
+    ;;
+  list)
+    list_volumes
+    ;;
+  *)
+    fail "usage: $0 {add|remove|list}"
+    ;;
+esac
@@ -26,15 +26,31 @@ stop()
   pid2=`pidof rpc.mountd`
   # For IPv6 bug:
   pid3=`pidof rpcbind`
-  kill -TERM $pid1 $pid2 $pid3 > /dev/null 2>&1
+  pid4=`pidof nfsdcld`
+  pid5=`pidof rpc.statd`
+  kill -TERM $pid1 $pid2 $pid3 $pid4 $pid5 > /dev/null 2>&1
   echo "Terminated."
   exit
 }
 
+# nfs_version_enabled returns success if the given NFS version is in the
+# comma separated NFS_VERSIONS. Only NFSv4 is enabled if NFS_VERSIONS is unset.
+nfs_version_enabled() {
+  [[ ",${NFS_VERSIONS:-4}," == *",$1,"* ]]
+}
+
 get_nfs_args() {
   declare -n args=$1
 
-  args=(--debug 8 --no-udp --no-nfs-version 2 --no-nfs-version 3)
+  args=(--debug 8 --no-udp --no-nfs-version 2)
+
+  if ! nfs_version_enabled 3; then
+    args+=( --no-nfs-version 3)
+  fi
+
+  if ! nfs_version_enabled 4; then
+    args+=( --no-nfs-version 4)
+  fi
 
   # here we are checking if variable exist and its value is not null
   if [ ! -z ${NFS_GRACE_TIME:+x} ]; then
@@ -46,6 +62,73 @@ get_nfs_args() {
   fi
 }
 
+get_mountd_args() {
+  declare -n args=$1
+
+  args=(--debug all --no-udp --no-nfs-version 2)
+
+  if nfs_version_enabled 3; then
+    if [ ! -z ${MOUNTD_PORT:+x} ]; then
+      args+=( --port ${MOUNTD_PORT})
+    fi
+  else
+    args+=( --no-nfs-version 3)
+  fi
+}
+
+# start_nfs_locking starts the NFSv3 locking daemons. The kernel lock
+# manager(lockd) and rpc.statd listen on the fixed ports, so that the
+# clients can reach them through the NFS service.
+start_nfs_locking() {
+  if ! nfs_version_enabled 3; then
+    return
+  fi
+
+  if [ ! -z ${LOCKD_PORT:+x} ]; then
+    for param in nlm_tcpport nlm_udpport; do
+      if [ -w /proc/sys/fs/nfs/${param} ]; then
+        echo ${LOCKD_PORT} > /proc/sys/fs/nfs/${param}
+      else
+        echo "Unable to set ${param}, lockd uses a random port"
+      fi
+    done
+  fi
+
+  if [ -z "`pidof rpc.statd`" ]; then
+    statd_args=()
+    if [ ! -z ${STATD_PORT:+x} ]; then
+      statd_args+=( --port ${STATD_PORT})
+    fi
+    if [ ! -z ${STATD_OUTGOING_PORT:+x} ]; then
+      statd_args+=( --outgoing-port ${STATD_OUTGOING_PORT})
+    fi
+    echo "Starting rpc.statd..."
+    /sbin/rpc.statd ${statd_args[@]}
+  fi
+}
+
+# start_nfsdcld starts the NFSv4 client tracking daemon, which stores the
+# client records in NFS_RECOVERY_DIR. The kernel doesn't support the legacy
+# client tracking in a container network namespace, so the clients can
+# reclaim their state after the NFS server restarts only if nfsdcld is running.
+start_nfsdcld() {
+  local recovery_dir=${NFS_RECOVERY_DIR:-/var/lib/nfs/v4recovery}
+
+  if [ ! -x /usr/sbin/nfsdcld ]; then
+    echo "nfsdcld is not available, clients can't reclaim their state after restart"
+    return
+  fi
+
+  if ! mountpoint -q /var/lib/nfs/rpc_pipefs; then
+    mount -t rpc_pipefs rpc_pipefs /var/lib/nfs/rpc_pipefs || return
+  fi
+
+  if [ -z "`pidof nfsdcld`" ]; then
+    echo "Starting nfsdcld with storage directory ${recovery_dir}..."
+    /usr/sbin/nfsdcld --storagedir ${recovery_dir}
+  fi
+}
+
 # Check if the SHARED_DIRECTORY variable is empty
 if [ -z "${SHARED_DIRECTORY}" ]; then
   echo "The SHARED_DIRECTORY environment variable is unset or null, exiting..."
@@ -232,7 +315,9 @@ while true; do
     # Only required if v3 will be used
     # /usr/sbin/rpc.idmapd
     # /usr/sbin/rpc.gssd -v
-    # /usr/sbin/rpc.statd
+    start_nfs_locking
+
+    start_nfsdcld
 
     echo "Starting NFS in the background..."
     get_nfs_args nfs_args
@@ -245,7 +330,8 @@ while true; do
       exit 1
     fi
     echo "Starting Mountd in the background..."These
-    /usr/sbin/rpc.mountd --debug all --no-udp --no-nfs-version 2 --no-nfs-version 3
+    get_mountd_args mountd_args
+    /usr/sbin/rpc.mountd ${mountd_args[@]}
 # --exports-file /etc/exports
 
     # Check if NFS is now running by recording it's PID (if it's not running $pid will be null):
@@ -16,9 +16,28 @@ limitations under the License.
 
 package hook
 
+import (
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+)
+
 // Action run hooks for the given object type as per the event
 // Action will skip further hook execution if any error occurred
 func (h *Hook) Action(obj interface{}, resourceType int, eventType EventType) error {
+	err := h.action(obj, resourceType, eventType)
+	recordHookExecution(resourceType, eventType, err)
+	return err
+}
+
+// recordHookExecution records the hook execution metrics
+func recordHookExecution(resourceType int, eventType EventType, err error) {
+	resource := resourceNames[resourceType]
+	metrics.HookExecutionTotal.WithLabelValues(resource, string(eventType)).Inc()
+	if err != nil {
+		metrics.HookExecutionFailedTotal.WithLabelValues(resource, string(eventType)).Inc()
+	}
+}
+
+func (h *Hook) action(obj interface{}, resourceType int, eventType EventType) error {
 	var err error
 	for actionType, cfg := range h.Config {
 		actionEvent, ok := ActionForEventMap[actionType]
@@ -27,7 +27,11 @@ import (
 )
 
 // ExecuteHookOnNFSPV will execute the hook on the given PV and patch it
-func (h *Hook) ExecuteHookOnNFSPV(client kubernetes.Interface, ctx context.Context, pvName string, eventType EventType) error {
+func (h *Hook) ExecuteHookOnNFSPV(client kubernetes.Interface, ctx context.Context, pvName string, eventType EventType) (err error) {
+	defer func() {
+		recordHookExecution(ResourceNFSPV, eventType, err)
+	}()
+
 	pvObjOrig, err := client.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
 	if err != nil {
 		return errors.Wrapf(err, "failed to fetch PV=%s", pvName)
@@ -35,7 +39,7 @@ func (h *Hook) ExecuteHookOnNFSPV(client kubernetes.Interface, ctx context.Conte
 
 	pvObj := pvObjOrig.DeepCopy()
 
-	err = h.Action(pvObj, ResourceNFSPV, eventType)
+	err = h.action(pvObj, ResourceNFSPV, eventType)
 	if err != nil {
 		return errors.Wrapf(err, "failed to execute hook")
 	}
@@ -54,7 +58,11 @@ func (h *Hook) ExecuteHookOnNFSPV(client kubernetes.Interface, ctx context.Conte
 }
 
 // ExecuteHookOnBackendPV will execute the hook on the PV for given PVC and patch it
-func (h *Hook) ExecuteHookOnBackendPV(client kubernetes.Interface, ctx context.Context, ns, backendPvcName string, eventType EventType) error {
+func (h *Hook) ExecuteHookOnBackendPV(client kubernetes.Interface, ctx context.Context, ns, backendPvcName string, eventType EventType) (err error) {
+	defer func() {
+		recordHookExecution(ResourceBackendPV, eventType, err)
+	}()
+
 	pvcObj, err := client.CoreV1().
 		PersistentVolumeClaims(ns).
 		Get(ctx, backendPvcName, metav1.GetOptions{})
@@ -68,7 +76,7 @@ func (h *Hook) ExecuteHookOnBackendPV(client kubernetes.Interface, ctx context.C
 	}
 
 	pvObj := pvObjOrig.DeepCopy()
-	err = h.Action(pvObj, ResourceBackendPV, eventType)
+	err = h.action(pvObj, ResourceBackendPV, eventType)
 	if err != nil {
 		return errors.Wrapf(err, "failed to execute hook")
 	}
@@ -87,7 +95,11 @@ func (h *Hook) ExecuteHookOnBackendPV(client kubernetes.Interface, ctx context.C
 }
 
 // ExecuteHookOnBackendPV will execute the hook on the PV for given PVC and patch it
-func (h *Hook) ExecuteHookOnBackendPVC(client kubernetes.Interface, ctx context.Context, ns, backendPvcName string, eventType EventType) error {
+func (h *Hook) ExecuteHookOnBackendPVC(client kubernetes.Interface, ctx context.Context, ns, backendPvcName string, eventType EventType) (err error) {
+	defer func() {
+		recordHookExecution(ResourceBackendPVC, eventType, err)
+	}()
+
 	pvcObjOrig, err := client.CoreV1().
 		PersistentVolumeClaims(ns).
 		Get(ctx, backendPvcName, metav1.GetOptions{})
@@ -97,7 +109,7 @@ func (h *Hook) ExecuteHookOnBackendPVC(client kubernetes.Interface, ctx context.
 
 	pvcObj := pvcObjOrig.DeepCopy()
 
-	err = h.Action(pvcObj, ResourceBackendPVC, eventType)
+	err = h.action(pvcObj, ResourceBackendPVC, eventType)
 	if err != nil {
 		return errors.Wrapf(err, "failed to execute hook")
 	}
@@ -116,7 +128,11 @@ func (h *Hook) ExecuteHookOnBackendPVC(client kubernetes.Interface, ctx context.
 }
 
 // ExecuteHookOnNFSService will execute the hook on the given service and patch it
-func (h *Hook) ExecuteHookOnNFSService(client kubernetes.Interface, ctx context.Context, ns, serviceName string, eventType EventType) error {
+func (h *Hook) ExecuteHookOnNFSService(client kubernetes.Interface, ctx context.Context, ns, serviceName string, eventType EventType) (err error) {
+	defer func() {
+		recordHookExecution(ResourceNFSService, eventType, err)
+	}()
+
 	svcObjOrig, err := client.CoreV1().
 		Services(ns).
 		Get(ctx, serviceName, metav1.GetOptions{})
@@ -126,7 +142,7 @@ func (h *Hook) ExecuteHookOnNFSService(client kubernetes.Interface, ctx context.
 
 	svcObj := svcObjOrig.DeepCopy()
 
-	err = h.Action(svcObj, ResourceNFSService, eventType)
+	err = h.action(svcObj, ResourceNFSService, eventType)
 	if err != nil {
 		return errors.Wrapf(err, "failed to execute hook")
 	}
@@ -145,7 +161,11 @@ func (h *Hook) ExecuteHookOnNFSService(client kubernetes.Interface, ctx context.
 }
 
 // ExecuteHookOnNFSDeployment will execute the hook on the given deployment and patch it
-func (h *Hook) ExecuteHookOnNFSDeployment(client kubernetes.Interface, ctx context.Context, ns, deployName string, eventType EventType) error {
+func (h *Hook) ExecuteHookOnNFSDeployment(client kubernetes.Interface, ctx context.Context, ns, deployName string, eventType EventType) (err error) {
+	defer func() {
+		recordHookExecution(ResourceNFSServerDeployment, eventType, err)
+	}()
+
 	deployObjOrig, err := client.AppsV1().
 		Deployments(ns).
 		Get(ctx, deployName, metav1.GetOptions{})
@@ -155,7 +175,7 @@ func (h *Hook) ExecuteHookOnNFSDeployment(client kubernetes.Interface, ctx conte
 
 	deployObj := deployObjOrig.DeepCopy()
 
-	err = h.Action(deployObj, ResourceNFSServerDeployment, eventType)
+	err = h.action(deployObj, ResourceNFSServerDeployment, eventType)
 	if err != nil {
 		return errors.Wrapf(err, "failed to execute hook")
 	}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"testing"
 
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -457,3 +459,24 @@ func TestExecuteHookOnBackendPV(t *testing.T) {
 		})
 	}
 }
+
+func TestExecuteHookMetrics(t *testing.T) {
+	hook := &Hook{
+		Config: map[ActionType]HookConfig{
+			ActionAddOnCreateVolumeEvent: HookConfig{
+				NFSPVConfig: buildPVHook(map[string]string{"test.io/key": "val"}, nil),
+			},
+		},
+	}
+	resource := resourceNames[ResourceNFSPV]
+	event := string(EventTypeCreateVolume)
+	total := testutil.ToFloat64(metrics.HookExecutionTotal.WithLabelValues(resource, event))
+	failed := testutil.ToFloat64(metrics.HookExecutionFailedTotal.WithLabelValues(resource, event))
+
+	clientset := fake.NewSimpleClientset(generateFakePvObj("pv1", nil, nil))
+	assert.Nil(t, hook.ExecuteHookOnNFSPV(clientset, context.TODO(), "pv1", EventTypeCreateVolume))
+	assert.NotNil(t, hook.ExecuteHookOnNFSPV(clientset, context.TODO(), "pv2", EventTypeCreateVolume))
+
+	assert.Equal(t, total+2, testutil.ToFloat64(metrics.HookExecutionTotal.WithLabelValues(resource, event)))
+	assert.Equal(t, failed+1, testutil.ToFloat64(metrics.HookExecutionFailedTotal.WithLabelValues(resource, event)))
+}
@@ -28,6 +28,15 @@ const (
 	ResourceNFSServerDeployment
 )
 
+// resourceNames defines the names of the resource types, used in metrics
+var resourceNames = map[int]string{
+	ResourceBackendPVC:          "BackendPVC",
+	ResourceBackendPV:           "BackendPV",
+	ResourceNFSService:          "NFSService",
+	ResourceNFSPV:               "NFSPV",
+	ResourceNFSServerDeployment: "NFSServerDeployment",
+}
+
 // ActionType defines type of action for the hook entry
 type ActionType string
 
@@ -279,6 +279,44 @@ func TestBuilderWithPrivilegedSecurityContext(t *testing.T) {
 	}
 }
 
+func TestBuilderWithSecurityContext(t *testing.T) {
+	privileged := false
+	tests := map[string]struct {
+		securityContext *corev1.SecurityContext
+		builder         *Builder
+		expectErr       bool
+	}{
+		"Test Builder with securityContext": {
+			securityContext: &corev1.SecurityContext{
+				Privileged: &privileged,
+			},
+			builder: &Builder{con: &container{
+				corev1.Container{},
+			}},
+			expectErr: false,
+		},
+		"Test Builder without securityContext": {
+			securityContext: nil,
+			builder: &Builder{con: &container{
+				corev1.Container{},
+			}},
+			expectErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name, mock := name, mock
+		t.Run(name, func(t *testing.T) {
+			b := mock.builder.WithSecurityContext(mock.securityContext)
+			if mock.expectErr && len(b.errors) == 0 {
+				t.Fatalf("Test %q failed: expected error not to be nil", name)
+			}
+			if !mock.expectErr && len(b.errors) > 0 {
+				t.Fatalf("Test %q failed: expected error to be nil", name)
+			}
+		})
+	}
+}
+
 func TestBuilderWithEnvsNew(t *testing.T) {
 	tests := map[string]struct {
 		envList   []corev1.EnvVar
@@ -388,6 +426,41 @@ func TestBuilderWithResources(t *testing.T) {
 	}
 }
 
+func TestBuilderWithReadinessProbe(t *testing.T) {
+	tests := map[string]struct {
+		probe     *corev1.Probe
+		builder   *Builder
+		expectErr bool
+	}{
+		"Test Builder with readiness probe": {
+			probe: &corev1.Probe{},
+			builder: &Builder{con: &container{
+				corev1.Container{},
+			}},
+			expectErr: false,
+		},
+		"Test Builder without readiness probe": {
+			probe: nil,
+			builder: &Builder{con: &container{
+				corev1.Container{},
+			}},
+			expectErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name, mock := name, mock
+		t.Run(name, func(t *testing.T) {
+			b := mock.builder.WithReadinessProbe(mock.probe)
+			if mock.expectErr && len(b.errors) == 0 {
+				t.Fatalf("Test %q failed: expected error not to be nil", name)
+			}
+			if !mock.expectErr && len(b.errors) > 0 {
+				t.Fatalf("Test %q failed: expected error to be nil", name)
+			}
+		})
+	}
+}
+
 func TestBuilderWithVolumeMountsNew(t *testing.T) {
 	tests := map[string]struct {
 		mounts    []corev1.VolumeMount
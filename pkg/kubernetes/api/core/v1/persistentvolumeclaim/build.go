@@ -191,6 +191,19 @@ func (b *Builder) WithVolumeName(volumeName string) *Builder {
 	return b
 }
 
+// WithDataSource sets the dataSource field in PVC with provided arguments
+func (b *Builder) WithDataSource(dataSource *corev1.TypedLocalObjectReference) *Builder {
+	if dataSource == nil {
+		return b
+	}
+	if len(dataSource.Kind) == 0 || len(dataSource.Name) == 0 {
+		b.errs = append(b.errs, errors.New("failed to build PVC object: missing dataSource kind or name"))
+		return b
+	}
+	b.pvc.object.Spec.DataSource = dataSource
+	return b
+}
+
 // Build returns the PVC API instance
 func (b *Builder) Build() (*corev1.PersistentVolumeClaim, error) {
 	if len(b.errs) > 0 {
@@ -284,6 +284,56 @@ func TestBuildWithStorageClass(t *testing.T) {
 	}
 }
 
+func TestBuildWithDataSource(t *testing.T) {
+	apiGroup := "snapshot.storage.k8s.io"
+	tests := map[string]struct {
+		dataSource *corev1.TypedLocalObjectReference
+		builder    *Builder
+		expectErr  bool
+	}{
+		"Test Builder with dataSource": {
+			dataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     "VolumeSnapshot",
+				Name:     "snapshot-1",
+			},
+			builder: &Builder{pvc: &PVC{
+				object: &corev1.PersistentVolumeClaim{},
+			}},
+			expectErr: false,
+		},
+		"Test Builder without dataSource": {
+			dataSource: nil,
+			builder: &Builder{pvc: &PVC{
+				object: &corev1.PersistentVolumeClaim{},
+			}},
+			expectErr: false,
+		},
+		"Test Builder with dataSource without name": {
+			dataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     "VolumeSnapshot",
+			},
+			builder: &Builder{pvc: &PVC{
+				object: &corev1.PersistentVolumeClaim{},
+			}},
+			expectErr: true,
+		},
+	}
+	for name, mock := range tests {
+		name, mock := name, mock
+		t.Run(name, func(t *testing.T) {
+			b := mock.builder.WithDataSource(mock.dataSource)
+			if mock.expectErr && len(b.errs) == 0 {
+				t.Fatalf("Test %q failed: expected error not to be nil", name)
+			}
+			if !mock.expectErr && len(b.errs) > 0 {
+				t.Fatalf("Test %q failed: expected error to be nil", name)
+			}
+		})
+	}
+}
+
 func TestBuildWithCapacity(t *testing.T) {
 	tests := map[string]struct {
 		capacity  string
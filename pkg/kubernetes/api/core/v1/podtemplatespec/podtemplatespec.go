@@ -342,6 +342,37 @@ func (b *Builder) WithNodeAffinityMatchExpressions(
 	return b
 }
 
+// WithNodeAffinityNodeSelectorTerms appends the provided node selector
+// terms to the required node affinity. Node must satisfy one of the terms.
+// If provided terms argument is empty it does not complain.
+//
+// CAUTION: Don't invoke WithAffinity func after calling this function
+//
+//	It will overwrite NodeSelectorTerms
+func (b *Builder) WithNodeAffinityNodeSelectorTerms(terms ...corev1.NodeSelectorTerm) *Builder {
+	if len(terms) == 0 {
+		return b
+	}
+
+	if b.podtemplatespec.Object.Spec.Affinity == nil {
+		b.podtemplatespec.Object.Spec.Affinity = &corev1.Affinity{}
+	}
+	nodeAffinity := b.podtemplatespec.Object.Spec.Affinity.NodeAffinity
+	if nodeAffinity == nil {
+		nodeAffinity = &corev1.NodeAffinity{}
+		b.podtemplatespec.Object.Spec.Affinity.NodeAffinity = nodeAffinity
+	}
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+
+	nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms = append(
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms,
+		terms...,
+	)
+	return b
+}
+
 // WithTolerationsByValue sets pod toleration.
 // If provided tolerations argument is empty it does not complain.
 func (b *Builder) WithTolerationsByValue(tolerations ...corev1.Toleration) *Builder {
@@ -422,6 +453,21 @@ func (b *Builder) WithTolerationsNew(tolerations ...corev1.Toleration) *Builder
 	return b
 }
 
+// WithTopologySpreadConstraints merges the existing topology spread
+// constraints with the provided arguments.
+// If provided constraints argument is empty it does not complain.
+func (b *Builder) WithTopologySpreadConstraints(constraints ...corev1.TopologySpreadConstraint) *Builder {
+	if len(constraints) == 0 {
+		return b
+	}
+
+	b.podtemplatespec.Object.Spec.TopologySpreadConstraints = append(
+		b.podtemplatespec.Object.Spec.TopologySpreadConstraints,
+		constraints...,
+	)
+	return b
+}
+
 // WithContainerBuilders builds the list of containerbuilder
 // provided and merges it to the containers field of the podtemplatespec
 func (b *Builder) WithContainerBuilders(
@@ -346,6 +346,67 @@ func TestBuilderWithContainerBuildersNew(t *testing.T) {
 	}
 }
 
+func TestBuilderWithNodeAffinityNodeSelectorTerms(t *testing.T) {
+	tests := map[string]struct {
+		terms         []corev1.NodeSelectorTerm
+		builder       *Builder
+		expectedTerms int
+	}{
+		"Test Builder with node selector terms": {
+			terms: []corev1.NodeSelectorTerm{
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{
+							Key:      "topology.kubernetes.io/zone",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"zone-1"},
+						},
+					},
+				},
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{
+							Key:      "topology.kubernetes.io/zone",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"zone-2"},
+						},
+					},
+				},
+			},
+			builder: &Builder{podtemplatespec: &PodTemplateSpec{
+				Object: &corev1.PodTemplateSpec{},
+			}},
+			expectedTerms: 2,
+		},
+		"Test Builder without node selector terms": {
+			terms: []corev1.NodeSelectorTerm{},
+			builder: &Builder{podtemplatespec: &PodTemplateSpec{
+				Object: &corev1.PodTemplateSpec{},
+			}},
+		},
+	}
+	for name, mock := range tests {
+		name, mock := name, mock
+		t.Run(name, func(t *testing.T) {
+			b := mock.builder.WithNodeAffinityNodeSelectorTerms(mock.terms...)
+			if len(b.errs) > 0 {
+				t.Fatalf("Test %q failed: expected error to be nil", name)
+			}
+			affinity := b.podtemplatespec.Object.Spec.Affinity
+			if mock.expectedTerms == 0 {
+				if affinity != nil {
+					t.Fatalf("Test %q failed: expected affinity to be nil", name)
+				}
+				return
+			}
+			terms := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+			if len(terms) != mock.expectedTerms {
+				t.Fatalf("Test %q failed: expected %d node selector terms but got %d", name, mock.expectedTerms, len(terms))
+			}
+		})
+	}
+}
+
 func TestBuilderWithTolerations(t *testing.T) {
 	tests := map[string]struct {
 		tolerations []corev1.Toleration
@@ -383,6 +444,47 @@ func TestBuilderWithTolerations(t *testing.T) {
 	}
 }
 
+func TestBuilderWithTopologySpreadConstraints(t *testing.T) {
+	tests := map[string]struct {
+		constraints   []corev1.TopologySpreadConstraint
+		builder       *Builder
+		expectedCount int
+	}{
+		"Test Builder with topology spread constraints": {
+			constraints: []corev1.TopologySpreadConstraint{
+				{
+					MaxSkew:     1,
+					TopologyKey: "kubernetes.io/hostname",
+				},
+			},
+			builder: &Builder{podtemplatespec: &PodTemplateSpec{
+				Object: &corev1.PodTemplateSpec{},
+			}},
+			expectedCount: 1,
+		},
+		"Test Builder without topology spread constraints": {
+			constraints: []corev1.TopologySpreadConstraint{},
+			builder: &Builder{podtemplatespec: &PodTemplateSpec{
+				Object: &corev1.PodTemplateSpec{},
+			}},
+			expectedCount: 0,
+		},
+	}
+	for name, mock := range tests {
+		name, mock := name, mock
+		t.Run(name, func(t *testing.T) {
+			b := mock.builder.WithTopologySpreadConstraints(mock.constraints...)
+			if len(b.errs) > 0 {
+				t.Fatalf("Test %q failed: expected error to be nil", name)
+			}
+			constraints := b.podtemplatespec.Object.Spec.TopologySpreadConstraints
+			if len(constraints) != mock.expectedCount {
+				t.Fatalf("Test %q failed: expected %d constraints but got %d", name, mock.expectedCount, len(constraints))
+			}
+		})
+	}
+}
+
 func TestBuilderWithTolerationsNew(t *testing.T) {
 	tests := map[string]struct {
 		tolerations []corev1.Toleration
@@ -255,6 +255,21 @@ func (b *Builder) WithType(svcType corev1.ServiceType) *Builder {
 	return b
 }
 
+// WithClusterIP sets the ClusterIP field of Service with provided value
+func (b *Builder) WithClusterIP(clusterIP string) *Builder {
+	if len(clusterIP) == 0 {
+		b.errs = append(
+			b.errs,
+			errors.
+				New("failed to build service object: missing cluster IP"),
+		)
+		return b
+	}
+
+	b.service.object.Spec.ClusterIP = clusterIP
+	return b
+}
+
 // Build returns the Service API instance
 func (b *Builder) Build() (*corev1.Service, error) {
 	if len(b.errs) > 0 {
@@ -27,6 +27,15 @@ const (
 	// PersistentVolumeSubsytem is subsystem name for persistentvolume metrics.
 	PersistentVolumeSubsytem = "persistentvolume"
 
+	// NFSServerSubsystem is subsystem name for NFS server metrics.
+	NFSServerSubsystem = "nfs_server"
+
+	// HookSubsystem is subsystem name for hook metrics.
+	HookSubsystem = "hook"
+
+	// GarbageCollectorSubsystem is subsystem name for garbage collector metrics.
+	GarbageCollectorSubsystem = "garbage_collector"
+
 	// Metrics
 	// ProvisionerRequestCreate represents metrics related to create resource request.
 	ProvisionerRequestCreate = "create"
@@ -35,8 +44,34 @@ const (
 
 	// Labels
 	Process = "process"
+	// Resource represents the kind of NFS server resource
+	Resource = "resource"
+	// PersistentVolume represents the name of NFS PV
+	PersistentVolume = "persistentvolume"
+	// ServerType represents the type of NFS server
+	ServerType = "server_type"
+	// Stage represents the stage of NFS server provisioning
+	Stage = "stage"
+	// State represents the state of NFS PV
+	State = "state"
+	// BackendStorageClass represents the StorageClass of the backend volume
+	BackendStorageClass = "backend_storageclass"
+	// Event represents the volume event on which hook is executed
+	Event = "event"
+
+	// Stages of NFS server provisioning
+	// StageBackendPVCBind represents the wait for the backend PVC to be bound
+	StageBackendPVCBind = "backend_pvc_bind"
+	// StageDeploymentReady represents the wait for the NFS server to serve the volume
+	StageDeploymentReady = "deployment_ready"
+	// StageService represents the creation of the NFS server Service
+	StageService = "service"
 )
 
+// provisionDurationBuckets are the buckets of the provisioning latency
+// histograms, from 0.25 seconds to ~4 minutes
+var provisionDurationBuckets = prometheus.ExponentialBuckets(0.25, 2, 11)
+
 var (
 	// PersistentVolumeDeleteTotal is used to collect accumulated count of persistent volumes deleted.
 	PersistentVolumeDeleteTotal = prometheus.NewCounterVec(
@@ -78,4 +113,133 @@ var (
 		},
 		[]string{Process},
 	)
+	// PersistentVolumeProvisionDuration is used to collect the end-to-end latency of successful provisioning requests.
+	PersistentVolumeProvisionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: PersistentVolumeSubsytem,
+			Name:      "provision_duration_seconds",
+			Help:      "Latency in seconds of successful persistent volume provisioning requests",
+			Buckets:   provisionDurationBuckets,
+		},
+		[]string{ServerType},
+	)
+	// PersistentVolumeCount is used to collect the number of NFS persistent volumes, computed from the informer caches.
+	PersistentVolumeCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: PersistentVolumeSubsytem,
+			Name:      "volumes",
+			Help:      "Number of NFS persistent volumes managed by the provisioner, by state and backend StorageClass",
+		},
+		[]string{State, BackendStorageClass},
+	)
+	// NFSServerProvisionStageDuration is used to collect the latency of the stages of NFS server provisioning.
+	NFSServerProvisionStageDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: NFSServerSubsystem,
+			Name:      "provision_stage_duration_seconds",
+			Help:      "Latency in seconds of the successful stages of NFS server provisioning",
+			Buckets:   provisionDurationBuckets,
+		},
+		[]string{Stage},
+	)
+	// NFSServerResourceRepairTotal is used to collect accumulated count of NFS server resources recreated by the reconciler.
+	NFSServerResourceRepairTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: NFSServerSubsystem,
+			Name:      "resource_repair_total",
+			Help:      "Total number of missing NFS server resources recreated",
+		},
+		[]string{Resource},
+	)
+	// NFSServerResourceRepairFailedTotal is used to collect accumulated count of NFS server resource repair failed attempts.
+	NFSServerResourceRepairFailedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: NFSServerSubsystem,
+			Name:      "resource_repair_failed_total",
+			Help:      "Total number of missing NFS server resource repair failed attempts",
+		},
+		[]string{Resource},
+	)
+	// NFSServerAddressMismatch is used to flag the NFS PVs whose NFS server address doesn't match any NFS server Service.
+	NFSServerAddressMismatch = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: NFSServerSubsystem,
+			Name:      "address_mismatch",
+			Help:      "Set to 1 if the NFS server address of the NFS PV doesn't match any NFS server Service",
+		},
+		[]string{PersistentVolume},
+	)
+	// NFSServerSpecDrift is used to flag the NFS PVs whose NFS server Deployment differs from the rendered spec.
+	NFSServerSpecDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: NFSServerSubsystem,
+			Name:      "spec_drift",
+			Help:      "Set to 1 if the NFS server Deployment of the NFS PV differs from the spec rendered by the provisioner",
+		},
+		[]string{PersistentVolume},
+	)
+	// NFSServerUpgradeTotal is used to collect accumulated count of NFS server Deployments upgraded.
+	NFSServerUpgradeTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: NFSServerSubsystem,
+			Name:      "upgrade_total",
+			Help:      "Total number of NFS server Deployments upgraded to the rendered spec",
+		},
+	)
+	// NFSServerUpgradeFailedTotal is used to collect accumulated count of NFS server Deployment upgrade failed attempts.
+	NFSServerUpgradeFailedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: NFSServerSubsystem,
+			Name:      "upgrade_failed_total",
+			Help:      "Total number of NFS server Deployment upgrade failed attempts",
+		},
+	)
+	// HookExecutionTotal is used to collect accumulated count of hook executions.
+	HookExecutionTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: HookSubsystem,
+			Name:      "execution_total",
+			Help:      "Total number of hook executions",
+		},
+		[]string{Resource, Event},
+	)
+	// HookExecutionFailedTotal is used to collect accumulated count of hook execution failed attempts.
+	HookExecutionFailedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: HookSubsystem,
+			Name:      "execution_failed_total",
+			Help:      "Total number of hook execution failed attempts",
+		},
+		[]string{Resource, Event},
+	)
+	// GarbageCollectorRunTotal is used to collect accumulated count of garbage collector runs.
+	GarbageCollectorRunTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: GarbageCollectorSubsystem,
+			Name:      "run_total",
+			Help:      "Total number of garbage collector runs",
+		},
+	)
+	// GarbageCollectorDeleteTotal is used to collect accumulated count of stale resources deleted by the garbage collector.
+	GarbageCollectorDeleteTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: NfsVolumeProvisionerNamespace,
+			Subsystem: GarbageCollectorSubsystem,
+			Name:      "delete_total",
+			Help:      "Total number of stale resources deleted by the garbage collector",
+		},
+		[]string{Resource},
+	)
 )
@@ -0,0 +1,171 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfsmetrics
+
+import (
+	"path/filepath"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// NFSServerNamespace is namespace name for NFS server metrics
+	NFSServerNamespace = "nfs_server"
+
+	// Labels
+	// PersistentVolume represents the name of NFS PV
+	PersistentVolume = "persistentvolume"
+	// PersistentVolumeClaim represents the name of NFS PVC
+	PersistentVolumeClaim = "persistentvolumeclaim"
+	// PersistentVolumeClaimNamespace represents the namespace of NFS PVC
+	PersistentVolumeClaimNamespace = "persistentvolumeclaim_namespace"
+	// Version represents the NFS version of the operation
+	Version = "version"
+	// Operation represents the name of the NFS operation
+	Operation = "operation"
+)
+
+// Config defines the sources of the NFS server metrics,
+// and the volume identified by the metrics
+type Config struct {
+	// ProcPath is the mount path of procfs, default is /proc
+	ProcPath string
+
+	// NFSDPath is the mount path of nfsd filesystem, default
+	// is /proc/fs/nfsd
+	NFSDPath string
+
+	// ExportPath is the path of the exported filesystem
+	ExportPath string
+
+	// PVName, PVCName and PVCNamespace identify the NFS volume
+	PVName       string
+	PVCName      string
+	PVCNamespace string
+}
+
+// Collector collects the NFS server metrics on each scrape
+type Collector struct {
+	config Config
+
+	bytesRead       *prometheus.Desc
+	bytesWritten    *prometheus.Desc
+	threads         *prometheus.Desc
+	threadsFull     *prometheus.Desc
+	operations      *prometheus.Desc
+	clients         *prometheus.Desc
+	exportSize      *prometheus.Desc
+	exportUsed      *prometheus.Desc
+	exportAvailable *prometheus.Desc
+	exportInodes    *prometheus.Desc
+	exportInodesUse *prometheus.Desc
+	scrapeErrors    *prometheus.Desc
+}
+
+// NewCollector returns a new Collector for the given config
+func NewCollector(config Config) *Collector {
+	if len(config.ProcPath) == 0 {
+		config.ProcPath = "/proc"
+	}
+	if len(config.NFSDPath) == 0 {
+		config.NFSDPath = filepath.Join(config.ProcPath, "fs", "nfsd")
+	}
+
+	constLabels := prometheus.Labels{
+		PersistentVolume:               config.PVName,
+		PersistentVolumeClaim:          config.PVCName,
+		PersistentVolumeClaimNamespace: config.PVCNamespace,
+	}
+	newDesc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(NFSServerNamespace, "", name), help, labels, constLabels)
+	}
+
+	return &Collector{
+		config:          config,
+		bytesRead:       newDesc("read_bytes_total", "Total number of bytes read from the disk by the NFS server"),
+		bytesWritten:    newDesc("written_bytes_total", "Total number of bytes written to the disk by the NFS server"),
+		threads:         newDesc("threads", "Number of nfsd threads"),
+		threadsFull:     newDesc("threads_full_total", "Total number of times all the nfsd threads were busy"),
+		operations:      newDesc("operations_total", "Total number of NFS operations served, by NFS version and operation", Version, Operation),
+		clients:         newDesc("connected_clients", "Number of NFSv4 clients connected to the NFS server"),
+		exportSize:      newDesc("export_size_bytes", "Size of the exported filesystem"),
+		exportUsed:      newDesc("export_used_bytes", "Used bytes of the exported filesystem"),
+		exportAvailable: newDesc("export_available_bytes", "Available bytes of the exported filesystem"),
+		exportInodes:    newDesc("export_inodes", "Number of inodes of the exported filesystem"),
+		exportInodesUse: newDesc("export_inodes_used", "Number of used inodes of the exported filesystem"),
+		scrapeErrors:    newDesc("scrape_errors", "Number of the metrics sources which couldn't be read in the scrape"),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.bytesRead, c.bytesWritten, c.threads, c.threadsFull, c.operations, c.clients,
+		c.exportSize, c.exportUsed, c.exportAvailable, c.exportInodes, c.exportInodesUse, c.scrapeErrors,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector. Metrics whose source
+// can't be read are skipped, and reported in scrape_errors.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	scrapeErrors := 0
+
+	stats, err := ReadServerStats(filepath.Join(c.config.ProcPath, "net", "rpc", "nfsd"))
+	if err != nil {
+		klog.V(4).Infof("Failed to read NFS server stats, err=%v", err)
+		scrapeErrors++
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.bytesRead, prometheus.CounterValue, float64(stats.BytesRead))
+		ch <- prometheus.MustNewConstMetric(c.bytesWritten, prometheus.CounterValue, float64(stats.BytesWritten))
+		ch <- prometheus.MustNewConstMetric(c.threads, prometheus.GaugeValue, float64(stats.Threads))
+		ch <- prometheus.MustNewConstMetric(c.threadsFull, prometheus.CounterValue, float64(stats.ThreadsFull))
+		for version, ops := range stats.Operations {
+			for op, count := range ops {
+				ch <- prometheus.MustNewConstMetric(c.operations, prometheus.CounterValue, float64(count), version, op)
+			}
+		}
+	}
+
+	clients, err := CountClients(filepath.Join(c.config.NFSDPath, "clients"))
+	if err != nil {
+		klog.V(4).Infof("Failed to count NFS clients, err=%v", err)
+		scrapeErrors++
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.clients, prometheus.GaugeValue, float64(clients))
+	}
+
+	if len(c.config.ExportPath) != 0 {
+		var fsStat syscall.Statfs_t
+		if err := syscall.Statfs(c.config.ExportPath, &fsStat); err != nil {
+			klog.V(4).Infof("Failed to get filesystem stats of %s, err=%v", c.config.ExportPath, err)
+			scrapeErrors++
+		} else {
+			blockSize := float64(fsStat.Bsize)
+			ch <- prometheus.MustNewConstMetric(c.exportSize, prometheus.GaugeValue, float64(fsStat.Blocks)*blockSize)
+			ch <- prometheus.MustNewConstMetric(c.exportUsed, prometheus.GaugeValue, float64(fsStat.Blocks-fsStat.Bfree)*blockSize)
+			ch <- prometheus.MustNewConstMetric(c.exportAvailable, prometheus.GaugeValue, float64(fsStat.Bavail)*blockSize)
+			ch <- prometheus.MustNewConstMetric(c.exportInodes, prometheus.GaugeValue, float64(fsStat.Files))
+			ch <- prometheus.MustNewConstMetric(c.exportInodesUse, prometheus.GaugeValue, float64(fsStat.Files-fsStat.Ffree))
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.scrapeErrors, prometheus.GaugeValue, float64(scrapeErrors))
+}
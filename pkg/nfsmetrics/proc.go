@@ -0,0 +1,217 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfsmetrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// nfsv3Operations are the NFSv3 procedures, in the order of the
+// counters of the proc3 line of /proc/net/rpc/nfsd
+var nfsv3Operations = []string{
+	"null", "getattr", "setattr", "lookup", "access", "readlink",
+	"read", "write", "create", "mkdir", "symlink", "mknod",
+	"remove", "rmdir", "rename", "link", "readdir", "readdirplus",
+	"fsstat", "fsinfo", "pathconf", "commit",
+}
+
+// nfsv4Operations are the NFSv4 operations, in the order of the
+// counters of the proc4ops line of /proc/net/rpc/nfsd. Counters
+// 0 to 2 are not used by the kernel.
+var nfsv4Operations = []string{
+	"", "", "",
+	"access", "close", "commit", "create", "delegpurge", "delegreturn",
+	"getattr", "getfh", "link", "lock", "lockt", "locku", "lookup",
+	"lookupp", "nverify", "open", "openattr", "open_confirm",
+	"open_downgrade", "putfh", "putpubfh", "putrootfh", "read",
+	"readdir", "readlink", "remove", "rename", "renew", "restorefh",
+	"savefh", "secinfo", "setattr", "setclientid", "setclientid_confirm",
+	"verify", "write", "release_lockowner", "backchannel_ctl",
+	"bind_conn_to_session", "exchange_id", "create_session",
+	"destroy_session", "free_stateid", "get_dir_delegation",
+	"getdeviceinfo", "getdevicelist", "layoutcommit", "layoutget",
+	"layoutreturn", "secinfo_no_name", "sequence", "set_ssv",
+	"test_stateid", "want_delegation", "destroy_clientid",
+	"reclaim_complete", "allocate", "copy", "copy_notify", "deallocate",
+	"io_advise", "layouterror", "layoutstats", "offload_cancel",
+	"offload_status", "read_plus", "seek", "write_same", "clone",
+	"getxattr", "setxattr", "listxattrs", "removexattr",
+}
+
+// ServerStats are the statistics of the kernel NFS server,
+// read from /proc/net/rpc/nfsd
+type ServerStats struct {
+	// BytesRead and BytesWritten are the bytes read from and
+	// written to the disk by the NFS server
+	BytesRead    uint64
+	BytesWritten uint64
+
+	// Threads is the number of nfsd threads, and ThreadsFull is
+	// the number of times all the nfsd threads were busy
+	Threads     uint64
+	ThreadsFull uint64
+
+	// Operations are the counters of the NFS operations,
+	// keyed by the NFS version and the operation name
+	Operations map[string]map[string]uint64
+}
+
+// ReadServerStats reads the NFS server statistics from the given
+// /proc/net/rpc/nfsd file
+func ReadServerStats(path string) (*ServerStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open %s", path)
+	}
+	defer f.Close()
+
+	return parseServerStats(f)
+}
+
+// parseServerStats parses the contents of /proc/net/rpc/nfsd
+func parseServerStats(r io.Reader) (*ServerStats, error) {
+	stats := &ServerStats{
+		Operations: map[string]map[string]uint64{},
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "io":
+			values, err := parseCounters(fields[1:])
+			if err != nil || len(values) < 2 {
+				return nil, errors.Errorf("invalid io line %q", scanner.Text())
+			}
+			stats.BytesRead, stats.BytesWritten = values[0], values[1]
+		case "th":
+			// Kernel also reports the deprecated thread usage
+			// histogram, which is always zero
+			if len(fields) < 3 {
+				return nil, errors.Errorf("invalid th line %q", scanner.Text())
+			}
+			values, err := parseCounters(fields[1:3])
+			if err != nil {
+				return nil, errors.Errorf("invalid th line %q", scanner.Text())
+			}
+			stats.Threads, stats.ThreadsFull = values[0], values[1]
+		case "proc3":
+			ops, err := parseOperations(fields[1:], nfsv3Operations)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid proc3 line %q", scanner.Text())
+			}
+			stats.Operations["3"] = ops
+		case "proc4ops":
+			ops, err := parseOperations(fields[1:], nfsv4Operations)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid proc4ops line %q", scanner.Text())
+			}
+			stats.Operations["4"] = ops
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// parseOperations parses the operation counters, which are
+// prefixed by the number of counters
+func parseOperations(fields []string, names []string) (map[string]uint64, error) {
+	values, err := parseCounters(fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 || int(values[0]) != len(values)-1 {
+		return nil, errors.Errorf("mismatch in the number of counters")
+	}
+
+	ops := map[string]uint64{}
+	for i, value := range values[1:] {
+		name := fmt.Sprintf("op%d", i)
+		if i < len(names) {
+			name = names[i]
+		}
+		if len(name) == 0 {
+			continue
+		}
+		ops[name] = value
+	}
+	return ops, nil
+}
+
+func parseCounters(fields []string) ([]uint64, error) {
+	values := make([]uint64, 0, len(fields))
+	for _, field := range fields {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// CountClients returns the number of NFSv4 clients connected to the
+// NFS server, i.e. the entries of the given /proc/fs/nfsd/clients
+// directory. It is available from Linux 5.3.
+func CountClients(path string) (int, error) {
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read %s", path)
+	}
+
+	count := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// MountNFSD mounts the nfsd filesystem at the given path, unless it is
+// already mounted. nfsd filesystem of the network namespace of the pod
+// is mounted, so the NFS clients of the nfs-server container are listed.
+func MountNFSD(path string) error {
+	if _, err := os.Stat(filepath.Join(path, "threads")); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return errors.Wrapf(err, "failed to create %s", path)
+	}
+	out, err := exec.Command("mount", "-t", "nfsd", "nfsd", path).CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "failed to mount nfsd filesystem at %s: %s", path, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfsmetrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fakeServerStats = `rc 0 1205 40087
+fh 0 0 0 0 0
+io 1048576 2097152
+th 8 3 0.000 0.000 0.000 0.000 0.000 0.000 0.000 0.000 0.000 0.000
+ra 32 0 0 0 0 0 0 0 0 0 0 0
+net 41292 0 41292 21
+rpc 41292 0 0 0 0
+proc3 22 2 10 0 5 0 0 20 30 0 0 0 0 0 0 0 0 0 0 0 0 0 4
+proc4 2 2 41290
+proc4ops 5 0 0 0 7 3
+`
+
+func TestParseServerStats(t *testing.T) {
+	tests := map[string]struct {
+		stats         string
+		expectedStats *ServerStats
+		isErrExpected bool
+	}{
+		"when NFS server stats are valid": {
+			stats: fakeServerStats,
+			expectedStats: &ServerStats{
+				BytesRead:    1048576,
+				BytesWritten: 2097152,
+				Threads:      8,
+				ThreadsFull:  3,
+				Operations: map[string]map[string]uint64{
+					"3": {
+						"null": 2, "getattr": 10, "setattr": 0, "lookup": 5, "access": 0,
+						"readlink": 0, "read": 20, "write": 30, "create": 0, "mkdir": 0,
+						"symlink": 0, "mknod": 0, "remove": 0, "rmdir": 0, "rename": 0,
+						"link": 0, "readdir": 0, "readdirplus": 0, "fsstat": 0, "fsinfo": 0,
+						"pathconf": 0, "commit": 4,
+					},
+					"4": {
+						"access": 7, "close": 3,
+					},
+				},
+			},
+		},
+		"when NFSv3 is not served": {
+			stats: "io 10 20\nth 8 0\n",
+			expectedStats: &ServerStats{
+				BytesRead:    10,
+				BytesWritten: 20,
+				Threads:      8,
+				Operations:   map[string]map[string]uint64{},
+			},
+		},
+		"when number of counters mismatch": {
+			stats:         "proc4ops 6 0 0 0 7 3\n",
+			isErrExpected: true,
+		},
+		"when io line is invalid": {
+			stats:         "io 10\n",
+			isErrExpected: true,
+		},
+		"when th line is invalid": {
+			stats:         "th 8\n",
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			stats, err := parseServerStats(strings.NewReader(test.stats))
+			if test.isErrExpected {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedStats, stats)
+		})
+	}
+}
+
+func TestCountClients(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nfsd-clients")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	for _, client := range []string{"1", "2"} {
+		assert.NoError(t, os.Mkdir(filepath.Join(dir, client), 0755))
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "info"), nil, 0644))
+
+	count, err := CountClients(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	_, err = CountClients(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
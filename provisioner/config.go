@@ -21,6 +21,7 @@ import (
 	"context"
 	"io/ioutil"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 
@@ -32,8 +33,11 @@ import (
 	cast "github.com/openebs/maya/pkg/castemplate/v1alpha1"
 	"github.com/openebs/maya/pkg/util"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/klog/v2"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
 )
 
 const (
@@ -91,6 +95,138 @@ const (
 	// NFSServerResourceLimits holds key name that represent NFS Resource Limits
 	NFSServerResourceLimits = "NFSServerResourceLimits"
 
+	// SharedNFSServer defines if the NFS volume should be created as a
+	// subdirectory of the NFS server shared by all the volumes of the
+	// StorageClass, instead of creating a dedicated NFS server
+	SharedNFSServer = "SharedNFSServer"
+
+	// SharedBackendCapacity defines the capacity of the backend volume
+	// of the shared NFS server. It is required if SharedNFSServer is enabled
+	SharedBackendCapacity = "SharedBackendCapacity"
+
+	// ExternalNFSServer defines the address of the existing NFS server
+	// used to provision the volumes of NFSServerType external
+	ExternalNFSServer = "ExternalNFSServer"
+
+	// ExternalNFSPath defines the path exported by the existing NFS server,
+	// under which the volume directories are created
+	ExternalNFSPath = "ExternalNFSPath"
+
+	// ExternalArchiveOnDelete defines if the volume directory should be
+	// archived instead of being removed, on deletion of the volume
+	ExternalArchiveOnDelete = "ExternalArchiveOnDelete"
+
+	// CloneStrategy defines how the backend volume is populated, when the
+	// volume is cloned from another NFS PVC. Default is auto
+	CloneStrategy = "CloneStrategy"
+
+	// ExportOptions is the cas-template key for all the export option
+	// 'data' keys, which configure the export of the kernel NFS server.
+	// It can't be used together with CustomServerConfig. Sample ExportOptions:
+	//
+	//                    name: ExportOptions
+	//                    data:
+	//                      sync: "true"
+	//                      squash: "all"
+	//                      anonuid: "1000"
+	//                      anongid: "2000"
+	//                      permitted: "10.0.0.0/8,192.168.1.0/24"
+	//                      secure: "false"
+	//                      subtreeCheck: "false"
+	ExportOptions = "ExportOptions"
+
+	// ExportSync defines if the NFS server replies to the requests only
+	// after the changes are committed to the storage. Default is false
+	ExportSync = "sync"
+
+	// ExportSquash defines the users mapped to the anonymous user,
+	// one of none, root or all. Default is none
+	ExportSquash = "squash"
+
+	// ExportAnonUID defines the user ID of the anonymous user
+	ExportAnonUID = "anonuid"
+
+	// ExportAnonGID defines the group ID of the anonymous user
+	ExportAnonGID = "anongid"
+
+	// ExportPermitted defines the comma separated list of the clients
+	// allowed to mount the volume. Default is all the clients
+	ExportPermitted = "permitted"
+
+	// ExportSecure defines if the requests must originate from the
+	// ports less than 1024. Default is false
+	ExportSecure = "secure"
+
+	// ExportSubtreeCheck defines if the NFS server verifies that the
+	// accessed file is in the exported tree. Default is false
+	ExportSubtreeCheck = "subtreeCheck"
+
+	// NFSVersions defines the comma separated list of the NFS versions
+	// served by the kernel NFS server, i.e. 3 and/or 4. Default is 4
+	NFSVersions = "NFSVersions"
+
+	// NetworkPolicy defines if a NetworkPolicy should be created for the
+	// NFS server, which allows the ingress only from the namespace of the
+	// NFS PVC. Additional clients are allowed using the 'data' keys.
+	// Sample NetworkPolicy:
+	//
+	//                    name: NetworkPolicy
+	//                    value: "true"
+	//                    data:
+	//                      namespaceSelectors: "team=frontend; env in (prod)"
+	//                      ipBlocks: "10.0.0.0/16"
+	NetworkPolicy = "NetworkPolicy"
+
+	// NetworkPolicyNamespaceSelectors defines the semicolon separated list
+	// of the label selectors of the additional namespaces allowed to
+	// access the NFS server
+	NetworkPolicyNamespaceSelectors = "namespaceSelectors"
+
+	// NetworkPolicyIPBlocks defines the comma separated list of the
+	// CIDRs allowed to access the NFS server, e.g. CIDR of the nodes
+	NetworkPolicyIPBlocks = "ipBlocks"
+
+	// NFSServerMetrics defines if the metrics exporter sidecar should be
+	// added to the kernel NFS server, which publishes the NFS server
+	// metrics in Prometheus format on the "metrics" port of the NFS
+	// server Service. Default is false
+	NFSServerMetrics = "NFSServerMetrics"
+
+	// NFSServerNodeAffinity defines the node affinity rules of the NFS
+	// servers of the StorageClass, in the same format as the env
+	// OPENEBS_IO_NFS_SERVER_NODE_AFFINITY. Node must satisfy these rules
+	// along with the rules configured using the env. Sample:
+	//
+	//                    name: NFSServerNodeAffinity
+	//                    value: "kubernetes.io/zone:[zone-1,zone-2],openebs.io/nfs-server"
+	NFSServerNodeAffinity = "NFSServerNodeAffinity"
+
+	// NFSServerTolerations defines the list of the tolerations of the
+	// NFS servers of the StorageClass. Sample NFSServerTolerations:
+	//
+	//                    name: NFSServerTolerations
+	//                    value: |-
+	//                      - key: "storage-node"
+	//                        operator: "Exists"
+	//                        effect: "NoSchedule"
+	NFSServerTolerations = "NFSServerTolerations"
+
+	// NFSServerPriorityClassName defines the PriorityClass of the
+	// NFS servers of the StorageClass
+	NFSServerPriorityClassName = "NFSServerPriorityClassName"
+
+	// NFSServerTopologySpreadConstraints defines the list of the
+	// topology spread constraints of the NFS servers of the StorageClass.
+	// If the labelSelector is not set, the constraint selects all the
+	// NFS servers. Sample NFSServerTopologySpreadConstraints:
+	//
+	//                    name: NFSServerTopologySpreadConstraints
+	//                    value: |-
+	//                      - maxSkew: 1
+	//                        topologyKey: "topology.kubernetes.io/zone"
+	//                        whenUnsatisfiable: "ScheduleAnyway"
+	NFSServerTopologySpreadConstraints = "NFSServerTopologySpreadConstraints"
+
 	// HookConfigFileName represent file name for hook configuration
 	HookConfigFileName = "hook-config"
 
@@ -101,6 +237,42 @@ const (
 	HookConfigFilePath = ConfigDirectory + "/" + HookConfigFileName
 )
 
+const (
+	// NFSServerTypeKernel represents the NFS server type which
+	// exports the volume using the kernel NFS server
+	NFSServerTypeKernel = "kernel"
+
+	// NFSServerTypeGanesha represents the NFS server type which
+	// exports the volume using the userspace NFS-Ganesha server
+	NFSServerTypeGanesha = "ganesha"
+
+	// NFSServerTypeExternal represents the NFS server type which
+	// provisions the volume as a subdirectory of an existing NFS export
+	NFSServerTypeExternal = "external"
+)
+
+const (
+	// NFSVersion3 represents the NFSv3 protocol, which requires the
+	// locking daemons for the file locks
+	NFSVersion3 = 3
+
+	// NFSVersion4 represents the NFSv4 protocol
+	NFSVersion4 = 4
+)
+
+const (
+	// CloneStrategyAuto clones the backend volume using the backend CSI
+	// driver if possible, else copies the data from the source NFS server
+	CloneStrategyAuto = "auto"
+
+	// CloneStrategyCSI clones the backend volume using the backend CSI driver
+	CloneStrategyCSI = "csi"
+
+	// CloneStrategyCopy copies the data from the source NFS server
+	// to the backend volume
+	CloneStrategyCopy = "copy"
+)
+
 const (
 	// Some of the PVCs launched with older helm charts, still
 	// refer to the StorageClass via beta annotations.
@@ -121,6 +293,7 @@ func (p *Provisioner) GetVolumeConfig(pvName string, pvc *v1.PersistentVolumeCla
 	if len(strings.TrimSpace(pvcCASConfigStr)) != 0 {
 		pvcCASConfig, err := cast.UnMarshallToConfig(pvcCASConfigStr)
 		if err == nil {
+			pvcCASConfig = removeStorageClassOnlyConfig(pvcCASConfig, pvc)
 			pvConfig = cast.MergeConfig(pvcCASConfig, pvConfig)
 		} else {
 			return nil, errors.Wrapf(err, "failed to get config: invalid config {%v}"+
@@ -177,12 +350,57 @@ func (p *Provisioner) GetVolumeConfig(pvName string, pvc *v1.PersistentVolumeCla
 	return c, nil
 }
 
+// removeStorageClassOnlyConfig returns the given config of the PVC,
+// without the keys which can be set only in the StorageClass
+func removeStorageClassOnlyConfig(pvcCASConfig []mconfig.Config, pvc *v1.PersistentVolumeClaim) []mconfig.Config {
+	var filtered []mconfig.Config
+	for _, config := range pvcCASConfig {
+		if storageClassOnlyConfigKeys[strings.TrimSpace(config.Name)] {
+			klog.Warningf("Ignoring config %s of PVC %s/%s, it can be set only in the StorageClass",
+				config.Name, pvc.Namespace, pvc.Name)
+			continue
+		}
+		filtered = append(filtered, config)
+	}
+	return filtered
+}
+
+// GetVolumeConfigFromParameters creates a new VolumeConfig struct by
+// parsing and merging the configuration provided in the CSI CreateVolume
+// parameter - cas.openebs.io/config with the default configuration
+// of the provisioner.
+func (p *Provisioner) GetVolumeConfigFromParameters(pvName string, params map[string]string) (*VolumeConfig, error) {
+	pvConfig := p.defaultConfig
+
+	casConfigStr := params[string(mconfig.CASConfigKey)]
+	klog.V(4).Infof("Volume %v has config:%v", pvName, casConfigStr)
+	if len(strings.TrimSpace(casConfigStr)) != 0 {
+		casConfig, err := cast.UnMarshallToConfig(casConfigStr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get config: invalid config {%v}", casConfigStr)
+		}
+		pvConfig = cast.MergeConfig(casConfig, pvConfig)
+	}
+
+	pvConfigMap, err := cast.ConfigToMap(pvConfig)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read volume config: volume {%v}", pvName)
+	}
+
+	c := &VolumeConfig{
+		pvName:     pvName,
+		options:    pvConfigMap,
+		configData: dataConfigToMap(pvConfig),
+	}
+	return c, nil
+}
+
 // GetNFSServerTypeFromConfig returns the NFSServerType value configured
 // in StorageClass. Default is kernel
 func (c *VolumeConfig) GetNFSServerTypeFromConfig() string {
 	serverType := c.getValue(KeyPVNFSServerType)
 	if len(strings.TrimSpace(serverType)) == 0 {
-		return "kernel"
+		return NFSServerTypeKernel
 	}
 	return serverType
 }
@@ -361,6 +579,292 @@ func (c *VolumeConfig) GetNFSServerResourceRequirements() (*v1.ResourceRequireme
 	return resourceRequirements, nil
 }
 
+// IsSharedNFSServer returns true if the volume should be created on the
+// shared NFS server of the StorageClass. Default is false
+func (c *VolumeConfig) IsSharedNFSServer() (bool, error) {
+	sharedStr := strings.TrimSpace(c.getValue(SharedNFSServer))
+	if len(sharedStr) == 0 {
+		return false, nil
+	}
+
+	shared, err := strconv.ParseBool(sharedStr)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid %s value %q", SharedNFSServer, sharedStr)
+	}
+	return shared, nil
+}
+
+// IsNFSServerMetricsEnabled returns true if the metrics exporter
+// sidecar should be added to the NFS server. Default is false
+func (c *VolumeConfig) IsNFSServerMetricsEnabled() (bool, error) {
+	enabledStr := strings.TrimSpace(c.getValue(NFSServerMetrics))
+	if len(enabledStr) == 0 {
+		return false, nil
+	}
+
+	enabled, err := strconv.ParseBool(enabledStr)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid %s value %q", NFSServerMetrics, enabledStr)
+	}
+	return enabled, nil
+}
+
+// GetSharedBackendCapacity returns the capacity of the backend volume
+// of the shared NFS server, configured in StorageClass
+func (c *VolumeConfig) GetSharedBackendCapacity() (resource.Quantity, error) {
+	capacityStr := strings.TrimSpace(c.getValue(SharedBackendCapacity))
+	if len(capacityStr) == 0 {
+		return resource.Quantity{}, errors.Errorf("%s must be specified for the shared NFS server", SharedBackendCapacity)
+	}
+
+	capacity, err := resource.ParseQuantity(capacityStr)
+	if err != nil {
+		return resource.Quantity{}, errors.Wrapf(err, "invalid %s value %q", SharedBackendCapacity, capacityStr)
+	}
+	return capacity, nil
+}
+
+// GetExternalNFSServer returns the address of the existing NFS server,
+// configured in StorageClass
+func (c *VolumeConfig) GetExternalNFSServer() (string, error) {
+	server := strings.TrimSpace(c.getValue(ExternalNFSServer))
+	if len(server) == 0 {
+		return "", errors.Errorf("%s must be specified for NFS server type %s", ExternalNFSServer, NFSServerTypeExternal)
+	}
+	return server, nil
+}
+
+// GetExternalNFSPath returns the path exported by the existing NFS
+// server, configured in StorageClass
+func (c *VolumeConfig) GetExternalNFSPath() (string, error) {
+	exportPath := strings.TrimSpace(c.getValue(ExternalNFSPath))
+	if len(exportPath) == 0 {
+		return "", errors.Errorf("%s must be specified for NFS server type %s", ExternalNFSPath, NFSServerTypeExternal)
+	}
+
+	if !path.IsAbs(exportPath) {
+		return "", errors.Errorf("invalid %s value %q, path must be absolute", ExternalNFSPath, exportPath)
+	}
+	return path.Clean(exportPath), nil
+}
+
+// IsExternalArchiveOnDelete returns true if the volume directory on the
+// existing NFS server should be archived on volume deletion. Default is false
+func (c *VolumeConfig) IsExternalArchiveOnDelete() (bool, error) {
+	archiveStr := strings.TrimSpace(c.getValue(ExternalArchiveOnDelete))
+	if len(archiveStr) == 0 {
+		return false, nil
+	}
+
+	archive, err := strconv.ParseBool(archiveStr)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid %s value %q", ExternalArchiveOnDelete, archiveStr)
+	}
+	return archive, nil
+}
+
+// GetCloneStrategy returns the strategy to clone the backend volume,
+// configured in StorageClass. Default is auto
+func (c *VolumeConfig) GetCloneStrategy() (string, error) {
+	strategy := strings.TrimSpace(c.getValue(CloneStrategy))
+	switch strategy {
+	case "":
+		return CloneStrategyAuto, nil
+	case CloneStrategyAuto, CloneStrategyCSI, CloneStrategyCopy:
+		return strategy, nil
+	}
+	return "", errors.Errorf("invalid %s value %q, must be one of %s, %s or %s",
+		CloneStrategy, strategy, CloneStrategyAuto, CloneStrategyCSI, CloneStrategyCopy)
+}
+
+// GetExportOptions returns the export options of the kernel NFS server,
+// configured in StorageClass or PVC. nil is returned if ExportOptions
+// is not configured
+func (c *VolumeConfig) GetExportOptions() (*exportOptions, error) {
+	data, ok := util.GetNestedField(c.configData, ExportOptions).(map[string]string)
+	if !ok {
+		return nil, nil
+	}
+
+	if len(c.GetCustomNFSServerConfig()) != 0 {
+		return nil, errors.Errorf("both '%s' and '%s' cannot be used together",
+			CustomServerConfig, ExportOptions)
+	}
+
+	opts, err := parseExportOptions(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", ExportOptions)
+	}
+	return opts, nil
+}
+
+// GetNFSVersions returns the NFS versions served by the NFS server,
+// configured in StorageClass. Default is NFSv4 only
+func (c *VolumeConfig) GetNFSVersions() ([]int, error) {
+	versionsStr := strings.TrimSpace(c.getValue(NFSVersions))
+	if len(versionsStr) == 0 {
+		return []int{NFSVersion4}, nil
+	}
+
+	var v3, v4 bool
+	for _, versionStr := range strings.Split(versionsStr, ",") {
+		switch strings.TrimSpace(versionStr) {
+		case strconv.Itoa(NFSVersion3):
+			v3 = true
+		case strconv.Itoa(NFSVersion4):
+			v4 = true
+		default:
+			return nil, errors.Errorf("invalid %s value %q, must be a comma separated list of %d or %d",
+				NFSVersions, versionsStr, NFSVersion3, NFSVersion4)
+		}
+	}
+
+	var versions []int
+	if v3 {
+		versions = append(versions, NFSVersion3)
+	}
+	if v4 {
+		versions = append(versions, NFSVersion4)
+	}
+	return versions, nil
+}
+
+// GetNetworkPolicyOptions returns the options of the NetworkPolicy of
+// the NFS server, configured in StorageClass or PVC. nil is returned
+// if NetworkPolicy is not enabled
+func (c *VolumeConfig) GetNetworkPolicyOptions() (*networkPolicyOptions, error) {
+	enabledStr := strings.TrimSpace(c.getValue(NetworkPolicy))
+	if len(enabledStr) == 0 {
+		return nil, nil
+	}
+
+	enabled, err := strconv.ParseBool(enabledStr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s value %q", NetworkPolicy, enabledStr)
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	data, _ := util.GetNestedField(c.configData, NetworkPolicy).(map[string]string)
+	opts, err := parseNetworkPolicyOptions(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s", NetworkPolicy)
+	}
+	return opts, nil
+}
+
+// GetNFSServerNodeAffinity returns the node affinity rules of the
+// NFS servers, configured in StorageClass
+func (c *VolumeConfig) GetNFSServerNodeAffinity() ([]v1.NodeSelectorRequirement, error) {
+	rules := parseNodeAffinityRules(c.getValue(NFSServerNodeAffinity)).MatchExpressions
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	if _, err := v1helper.NodeSelectorRequirementsAsSelector(rules); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s value %q", NFSServerNodeAffinity, c.getValue(NFSServerNodeAffinity))
+	}
+	return rules, nil
+}
+
+// GetNFSServerTolerations returns the tolerations of the
+// NFS servers, configured in StorageClass
+func (c *VolumeConfig) GetNFSServerTolerations() ([]v1.Toleration, error) {
+	dataStr := c.getValue(NFSServerTolerations)
+	if len(strings.TrimSpace(dataStr)) == 0 {
+		return nil, nil
+	}
+
+	var tolerations []v1.Toleration
+	if err := yaml.Unmarshal([]byte(dataStr), &tolerations); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s value %q", NFSServerTolerations, dataStr)
+	}
+
+	for _, toleration := range tolerations {
+		switch toleration.Operator {
+		case "", v1.TolerationOpEqual:
+			if len(toleration.Key) == 0 {
+				return nil, errors.Errorf("invalid %s, key is required for operator %s", NFSServerTolerations, v1.TolerationOpEqual)
+			}
+		case v1.TolerationOpExists:
+			if len(toleration.Value) != 0 {
+				return nil, errors.Errorf("invalid %s, value must be empty for operator %s", NFSServerTolerations, v1.TolerationOpExists)
+			}
+		default:
+			return nil, errors.Errorf("invalid %s operator %q", NFSServerTolerations, toleration.Operator)
+		}
+
+		switch toleration.Effect {
+		case "", v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+		default:
+			return nil, errors.Errorf("invalid %s effect %q", NFSServerTolerations, toleration.Effect)
+		}
+	}
+	return tolerations, nil
+}
+
+// GetNFSServerPriorityClassName returns the PriorityClass of
+// the NFS servers, configured in StorageClass
+func (c *VolumeConfig) GetNFSServerPriorityClassName() (string, error) {
+	priorityClassName := strings.TrimSpace(c.getValue(NFSServerPriorityClassName))
+	if len(priorityClassName) == 0 {
+		return "", nil
+	}
+
+	if errs := validation.IsDNS1123Subdomain(priorityClassName); len(errs) != 0 {
+		return "", errors.Errorf("invalid %s value %q: %s", NFSServerPriorityClassName, priorityClassName, strings.Join(errs, ", "))
+	}
+	return priorityClassName, nil
+}
+
+// GetNFSServerTopologySpreadConstraints returns the topology spread
+// constraints of the NFS servers, configured in StorageClass. Constraints
+// without the labelSelector select all the NFS servers.
+func (c *VolumeConfig) GetNFSServerTopologySpreadConstraints() ([]v1.TopologySpreadConstraint, error) {
+	dataStr := c.getValue(NFSServerTopologySpreadConstraints)
+	if len(strings.TrimSpace(dataStr)) == 0 {
+		return nil, nil
+	}
+
+	var constraints []v1.TopologySpreadConstraint
+	if err := yaml.Unmarshal([]byte(dataStr), &constraints); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s value %q", NFSServerTopologySpreadConstraints, dataStr)
+	}
+
+	for i := range constraints {
+		constraint := &constraints[i]
+		if constraint.MaxSkew <= 0 {
+			return nil, errors.Errorf("invalid %s, maxSkew must be greater than zero", NFSServerTopologySpreadConstraints)
+		}
+		if len(constraint.TopologyKey) == 0 {
+			return nil, errors.Errorf("invalid %s, topologyKey is required", NFSServerTopologySpreadConstraints)
+		}
+
+		switch constraint.WhenUnsatisfiable {
+		case v1.DoNotSchedule, v1.ScheduleAnyway:
+		case "":
+			constraint.WhenUnsatisfiable = v1.DoNotSchedule
+		default:
+			return nil, errors.Errorf("invalid %s whenUnsatisfiable %q", NFSServerTopologySpreadConstraints, constraint.WhenUnsatisfiable)
+		}
+
+		if constraint.LabelSelector == nil {
+			constraint.LabelSelector = &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      nfsServerLabelKey,
+						Operator: metav1.LabelSelectorOpExists,
+					},
+				},
+			}
+		} else if _, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s labelSelector", NFSServerTopologySpreadConstraints)
+		}
+	}
+	return constraints, nil
+}
+
 // getResourceList is a utility function to extract resource list
 // and convert from map[string]interface{} to proper Go struct
 func (c *VolumeConfig) getResourceList(key string) (v1.ResourceList, error) {
@@ -434,10 +938,16 @@ func GetStorageClassNameFromPVC(pvc *v1.PersistentVolumeClaim) *string {
 	return pvc.Spec.StorageClassName
 }
 
-// GetNFSServerTypeFromPV extracts the NFS Server Type name from PV
+// GetNFSServerTypeFromPV extracts the NFS Server Type name from PV.
+// NFS server type is recorded on the PV through the label
+// openebs.io/cas-type=nfs-<server-type>. Default is kernel
 func GetNFSServerTypeFromPV(pv *v1.PersistentVolume) string {
-	//TODO extract this from PV annotations
-	return "kernel"
+	casType := pv.Labels[string(mconfig.CASTypeKey)]
+	serverType := strings.TrimPrefix(casType, "nfs-")
+	if serverType == casType || len(serverType) == 0 {
+		return NFSServerTypeKernel
+	}
+	return serverType
 }
 
 // hookConfigFileExist check if hook config file exists or not
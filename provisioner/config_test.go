@@ -24,6 +24,7 @@ import (
 	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestGetResourceList(t *testing.T) {
@@ -297,3 +298,758 @@ func TestGetFsMode(t *testing.T) {
 		}
 	}
 }
+
+func TestGetNFSServerTypeFromPV(t *testing.T) {
+	tests := map[string]struct {
+		labels         map[string]string
+		expectedOutput string
+	}{
+		"When PV doesn't have cas-type label": {
+			labels:         nil,
+			expectedOutput: NFSServerTypeKernel,
+		},
+		"When PV is provisioned with kernel NFS server": {
+			labels: map[string]string{
+				string(mconfig.CASTypeKey): "nfs-kernel",
+			},
+			expectedOutput: NFSServerTypeKernel,
+		},
+		"When PV is provisioned with NFS-Ganesha server": {
+			labels: map[string]string{
+				string(mconfig.CASTypeKey): "nfs-ganesha",
+			},
+			expectedOutput: NFSServerTypeGanesha,
+		},
+		"When PV is provisioned on external NFS server": {
+			labels: map[string]string{
+				string(mconfig.CASTypeKey): "nfs-external",
+			},
+			expectedOutput: NFSServerTypeExternal,
+		},
+		"When PV is having a non-nfs cas-type label": {
+			labels: map[string]string{
+				string(mconfig.CASTypeKey): "jiva",
+			},
+			expectedOutput: NFSServerTypeKernel,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		pv := &corev1.PersistentVolume{}
+		pv.Labels = test.labels
+		gotOutput := GetNFSServerTypeFromPV(pv)
+		if gotOutput != test.expectedOutput {
+			t.Errorf("%q test: expected %s, but got %s", name, test.expectedOutput, gotOutput)
+		}
+	}
+}
+
+func TestGetSharedNFSServerConfig(t *testing.T) {
+	tests := map[string]struct {
+		volumeConfig          *VolumeConfig
+		expectedShared        bool
+		expectedCapacity      string
+		isSharedErrExpected   bool
+		isCapacityErrExpected bool
+	}{
+		"When shared NFS server is not configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{},
+			},
+			expectedShared:        false,
+			isCapacityErrExpected: true,
+		},
+		"When shared NFS server is enabled with backend capacity": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					SharedNFSServer: map[string]string{
+						"value": "true",
+					},
+					SharedBackendCapacity: map[string]string{
+						"value": "100Gi",
+					},
+				},
+			},
+			expectedShared:   true,
+			expectedCapacity: "100Gi",
+		},
+		"When shared NFS server config is invalid": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					SharedNFSServer: map[string]string{
+						"value": "enabled",
+					},
+					SharedBackendCapacity: map[string]string{
+						"value": "100Gib",
+					},
+				},
+			},
+			isSharedErrExpected:   true,
+			isCapacityErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		shared, err := test.volumeConfig.IsSharedNFSServer()
+		if test.isSharedErrExpected != (err != nil) {
+			t.Errorf("%q test: expected error %t, but got %v", name, test.isSharedErrExpected, err)
+		}
+		if shared != test.expectedShared {
+			t.Errorf("%q test: expected shared %t, but got %t", name, test.expectedShared, shared)
+		}
+
+		capacity, err := test.volumeConfig.GetSharedBackendCapacity()
+		if test.isCapacityErrExpected != (err != nil) {
+			t.Errorf("%q test: expected capacity error %t, but got %v", name, test.isCapacityErrExpected, err)
+		}
+		if err == nil && capacity.String() != test.expectedCapacity {
+			t.Errorf("%q test: expected capacity %s, but got %s", name, test.expectedCapacity, capacity.String())
+		}
+	}
+}
+
+func TestGetExternalNFSServerConfig(t *testing.T) {
+	tests := map[string]struct {
+		volumeConfig        *VolumeConfig
+		expectedServer      string
+		expectedPath        string
+		expectedArchive     bool
+		isServerErrExpected bool
+		isPathErrExpected   bool
+	}{
+		"When external NFS server is not configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{},
+			},
+			isServerErrExpected: true,
+			isPathErrExpected:   true,
+		},
+		"When external NFS server is configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					ExternalNFSServer: map[string]string{
+						"value": "10.0.0.10",
+					},
+					ExternalNFSPath: map[string]string{
+						"value": "/exports/k8s/",
+					},
+					ExternalArchiveOnDelete: map[string]string{
+						"value": "true",
+					},
+				},
+			},
+			expectedServer:  "10.0.0.10",
+			expectedPath:    "/exports/k8s",
+			expectedArchive: true,
+		},
+		"When external NFS path is relative": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					ExternalNFSServer: map[string]string{
+						"value": "nfs.example.com",
+					},
+					ExternalNFSPath: map[string]string{
+						"value": "exports",
+					},
+				},
+			},
+			expectedServer:    "nfs.example.com",
+			isPathErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		server, err := test.volumeConfig.GetExternalNFSServer()
+		if test.isServerErrExpected != (err != nil) {
+			t.Errorf("%q test: expected server error %t, but got %v", name, test.isServerErrExpected, err)
+		}
+		if server != test.expectedServer {
+			t.Errorf("%q test: expected server %q, but got %q", name, test.expectedServer, server)
+		}
+
+		exportPath, err := test.volumeConfig.GetExternalNFSPath()
+		if test.isPathErrExpected != (err != nil) {
+			t.Errorf("%q test: expected path error %t, but got %v", name, test.isPathErrExpected, err)
+		}
+		if exportPath != test.expectedPath {
+			t.Errorf("%q test: expected path %q, but got %q", name, test.expectedPath, exportPath)
+		}
+
+		archive, err := test.volumeConfig.IsExternalArchiveOnDelete()
+		if err != nil {
+			t.Errorf("%q test: expected archive error not to occur, but got %v", name, err)
+		}
+		if archive != test.expectedArchive {
+			t.Errorf("%q test: expected archive %t, but got %t", name, test.expectedArchive, archive)
+		}
+	}
+}
+
+func TestGetCloneStrategy(t *testing.T) {
+	tests := map[string]struct {
+		volumeConfig     *VolumeConfig
+		expectedStrategy string
+		isErrExpected    bool
+	}{
+		"When clone strategy is not configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{},
+			},
+			expectedStrategy: CloneStrategyAuto,
+		},
+		"When copy clone strategy is configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					CloneStrategy: map[string]string{
+						"value": "copy",
+					},
+				},
+			},
+			expectedStrategy: CloneStrategyCopy,
+		},
+		"When invalid clone strategy is configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					CloneStrategy: map[string]string{
+						"value": "snapshot",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		strategy, err := test.volumeConfig.GetCloneStrategy()
+		if test.isErrExpected != (err != nil) {
+			t.Errorf("%q test: expected error %t, but got %v", name, test.isErrExpected, err)
+		}
+		if strategy != test.expectedStrategy {
+			t.Errorf("%q test: expected strategy %q, but got %q", name, test.expectedStrategy, strategy)
+		}
+	}
+}
+
+func TestGetExportOptions(t *testing.T) {
+	tests := map[string]struct {
+		volumeConfig          *VolumeConfig
+		expectedExportsConfig string
+		isErrExpected         bool
+	}{
+		"When export options are not configured": {
+			volumeConfig: &VolumeConfig{},
+		},
+		"When empty export options are configured": {
+			volumeConfig: &VolumeConfig{
+				configData: map[string]interface{}{
+					ExportOptions: map[string]string{},
+				},
+			},
+			expectedExportsConfig: "/nfsshare *(rw,fsid=0,async,no_subtree_check,no_auth_nlm,insecure,no_root_squash)",
+		},
+		"When all the export options are configured": {
+			volumeConfig: &VolumeConfig{
+				configData: map[string]interface{}{
+					ExportOptions: map[string]string{
+						ExportSync:         "true",
+						ExportSquash:       "all",
+						ExportAnonUID:      "1000",
+						ExportAnonGID:      "2000",
+						ExportPermitted:    "10.0.0.0/8, client.example.com",
+						ExportSecure:       "true",
+						ExportSubtreeCheck: "true",
+					},
+				},
+			},
+			expectedExportsConfig: "/nfsshare " +
+				"10.0.0.0/8(rw,fsid=0,sync,subtree_check,no_auth_nlm,secure,all_squash,anonuid=1000,anongid=2000) " +
+				"client.example.com(rw,fsid=0,sync,subtree_check,no_auth_nlm,secure,all_squash,anonuid=1000,anongid=2000)",
+		},
+		"When root squash is configured": {
+			volumeConfig: &VolumeConfig{
+				configData: map[string]interface{}{
+					ExportOptions: map[string]string{
+						ExportSquash: "root",
+					},
+				},
+			},
+			expectedExportsConfig: "/nfsshare *(rw,fsid=0,async,no_subtree_check,no_auth_nlm,insecure,root_squash)",
+		},
+		"When anonuid is configured without squash": {
+			volumeConfig: &VolumeConfig{
+				configData: map[string]interface{}{
+					ExportOptions: map[string]string{
+						ExportAnonUID: "1000",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+		"When invalid squash is configured": {
+			volumeConfig: &VolumeConfig{
+				configData: map[string]interface{}{
+					ExportOptions: map[string]string{
+						ExportSquash: "users",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+		"When invalid anongid is configured": {
+			volumeConfig: &VolumeConfig{
+				configData: map[string]interface{}{
+					ExportOptions: map[string]string{
+						ExportSquash:  "all",
+						ExportAnonGID: "-1",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+		"When invalid permitted client is configured": {
+			volumeConfig: &VolumeConfig{
+				configData: map[string]interface{}{
+					ExportOptions: map[string]string{
+						ExportPermitted: "10.0.0.0/8(rw)",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+		"When unknown export option is configured": {
+			volumeConfig: &VolumeConfig{
+				configData: map[string]interface{}{
+					ExportOptions: map[string]string{
+						"rootSquash": "true",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+		"When export options are configured with CustomServerConfig": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					CustomServerConfig: map[string]string{
+						string(mconfig.ValuePTP): "/nfsshare *(rw,fsid=0,async,no_auth_nlm)",
+					},
+				},
+				configData: map[string]interface{}{
+					ExportOptions: map[string]string{
+						ExportSync: "true",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		opts, err := test.volumeConfig.GetExportOptions()
+		if test.isErrExpected != (err != nil) {
+			t.Errorf("%q test: expected error %t, but got %v", name, test.isErrExpected, err)
+		}
+
+		var exportsConfig string
+		if opts != nil {
+			exportsConfig = opts.exportsConfig("/nfsshare")
+		}
+		if exportsConfig != test.expectedExportsConfig {
+			t.Errorf("%q test: expected exports config %q, but got %q", name, test.expectedExportsConfig, exportsConfig)
+		}
+	}
+}
+
+func TestGetNFSVersions(t *testing.T) {
+	tests := map[string]struct {
+		volumeConfig     *VolumeConfig
+		expectedVersions []int
+		isErrExpected    bool
+	}{
+		"When NFS versions are not configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{},
+			},
+			expectedVersions: []int{NFSVersion4},
+		},
+		"When NFSv3 and NFSv4 are configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSVersions: map[string]string{
+						"value": "4, 3,4",
+					},
+				},
+			},
+			expectedVersions: []int{NFSVersion3, NFSVersion4},
+		},
+		"When only NFSv3 is configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSVersions: map[string]string{
+						"value": "3",
+					},
+				},
+			},
+			expectedVersions: []int{NFSVersion3},
+		},
+		"When invalid NFS version is configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSVersions: map[string]string{
+						"value": "3,4.1",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		versions, err := test.volumeConfig.GetNFSVersions()
+		if test.isErrExpected != (err != nil) {
+			t.Errorf("%q test: expected error %t, but got %v", name, test.isErrExpected, err)
+		}
+		if !reflect.DeepEqual(versions, test.expectedVersions) {
+			t.Errorf("%q test: expected versions %v, but got %v", name, test.expectedVersions, versions)
+		}
+	}
+}
+
+func TestGetNetworkPolicyOptions(t *testing.T) {
+	tests := map[string]struct {
+		volumeConfig          *VolumeConfig
+		isEnabledExpected     bool
+		expectedSelectorCount int
+		expectedIPBlocks      []string
+		isErrExpected         bool
+	}{
+		"When NetworkPolicy is not configured": {
+			volumeConfig: &VolumeConfig{},
+		},
+		"When NetworkPolicy is disabled": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NetworkPolicy: map[string]string{
+						"value": "false",
+					},
+				},
+			},
+		},
+		"When NetworkPolicy is enabled without data": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NetworkPolicy: map[string]string{
+						"value": "true",
+					},
+				},
+			},
+			isEnabledExpected: true,
+		},
+		"When NetworkPolicy is enabled with additional clients": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NetworkPolicy: map[string]string{
+						"value": "true",
+					},
+				},
+				configData: map[string]interface{}{
+					NetworkPolicy: map[string]string{
+						NetworkPolicyNamespaceSelectors: "team=frontend; env in (prod,staging)",
+						NetworkPolicyIPBlocks:           "10.0.0.0/16, 192.168.1.0/24",
+					},
+				},
+			},
+			isEnabledExpected:     true,
+			expectedSelectorCount: 2,
+			expectedIPBlocks:      []string{"10.0.0.0/16", "192.168.1.0/24"},
+		},
+		"When invalid NetworkPolicy value is configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NetworkPolicy: map[string]string{
+						"value": "yes please",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+		"When invalid IP block is configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NetworkPolicy: map[string]string{
+						"value": "true",
+					},
+				},
+				configData: map[string]interface{}{
+					NetworkPolicy: map[string]string{
+						NetworkPolicyIPBlocks: "10.0.0.1",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+		"When unknown NetworkPolicy option is configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NetworkPolicy: map[string]string{
+						"value": "true",
+					},
+				},
+				configData: map[string]interface{}{
+					NetworkPolicy: map[string]string{
+						"podSelectors": "app=web",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		opts, err := test.volumeConfig.GetNetworkPolicyOptions()
+		if test.isErrExpected != (err != nil) {
+			t.Errorf("%q test: expected error %t, but got %v", name, test.isErrExpected, err)
+		}
+		if test.isEnabledExpected != (opts != nil) {
+			t.Errorf("%q test: expected NetworkPolicy enabled %t, but got %t", name, test.isEnabledExpected, opts != nil)
+		}
+		if opts == nil {
+			continue
+		}
+		if len(opts.namespaceSelectors) != test.expectedSelectorCount {
+			t.Errorf("%q test: expected %d namespace selectors, but got %d", name, test.expectedSelectorCount, len(opts.namespaceSelectors))
+		}
+		if !reflect.DeepEqual(opts.ipBlocks, test.expectedIPBlocks) {
+			t.Errorf("%q test: expected IP blocks %v, but got %v", name, test.expectedIPBlocks, opts.ipBlocks)
+		}
+	}
+}
+
+func TestIsNFSServerMetricsEnabled(t *testing.T) {
+	tests := map[string]struct {
+		volumeConfig    *VolumeConfig
+		expectedEnabled bool
+		isErrExpected   bool
+	}{
+		"When NFS server metrics are not configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{},
+			},
+		},
+		"When NFS server metrics are enabled": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSServerMetrics: map[string]string{
+						"value": " true ",
+					},
+				},
+			},
+			expectedEnabled: true,
+		},
+		"When NFS server metrics config is invalid": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSServerMetrics: map[string]string{
+						"value": "enabled",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		enabled, err := test.volumeConfig.IsNFSServerMetricsEnabled()
+		if test.isErrExpected != (err != nil) {
+			t.Errorf("%q test: expected error %t, but got %v", name, test.isErrExpected, err)
+		}
+		if enabled != test.expectedEnabled {
+			t.Errorf("%q test: expected enabled %t, but got %t", name, test.expectedEnabled, enabled)
+		}
+	}
+}
+
+func TestGetNFSServerTolerations(t *testing.T) {
+	tests := map[string]struct {
+		volumeConfig        *VolumeConfig
+		expectedTolerations []corev1.Toleration
+		isErrExpected       bool
+	}{
+		"When tolerations are not configured": {
+			volumeConfig: &VolumeConfig{},
+		},
+		"When tolerations are configured": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSServerTolerations: map[string]string{
+						"value": "- key: storage-node\n  operator: Exists\n  effect: NoSchedule\n- key: zone\n  value: zone-1",
+					},
+				},
+			},
+			expectedTolerations: []corev1.Toleration{
+				{
+					Key:      "storage-node",
+					Operator: corev1.TolerationOpExists,
+					Effect:   corev1.TaintEffectNoSchedule,
+				},
+				{
+					Key:   "zone",
+					Value: "zone-1",
+				},
+			},
+		},
+		"When toleration has value for Exists operator": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSServerTolerations: map[string]string{
+						"value": "- key: storage-node\n  operator: Exists\n  value: \"true\"",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+		"When toleration has invalid effect": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSServerTolerations: map[string]string{
+						"value": "- key: storage-node\n  operator: Exists\n  effect: NoRun",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		tolerations, err := test.volumeConfig.GetNFSServerTolerations()
+		if test.isErrExpected != (err != nil) {
+			t.Errorf("%q test: expected error %t, but got %v", name, test.isErrExpected, err)
+		}
+		if !reflect.DeepEqual(tolerations, test.expectedTolerations) {
+			t.Errorf("%q test: tolerations mismatch %s", name, cmp.Diff(test.expectedTolerations, tolerations))
+		}
+	}
+}
+
+func TestGetNFSServerTopologySpreadConstraints(t *testing.T) {
+	tests := map[string]struct {
+		volumeConfig        *VolumeConfig
+		expectedConstraints []corev1.TopologySpreadConstraint
+		isErrExpected       bool
+	}{
+		"When topology spread constraints are not configured": {
+			volumeConfig: &VolumeConfig{},
+		},
+		"When constraint is configured without labelSelector": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSServerTopologySpreadConstraints: map[string]string{
+						"value": "- maxSkew: 1\n  topologyKey: topology.kubernetes.io/zone",
+					},
+				},
+			},
+			expectedConstraints: []corev1.TopologySpreadConstraint{
+				{
+					MaxSkew:           1,
+					TopologyKey:       "topology.kubernetes.io/zone",
+					WhenUnsatisfiable: corev1.DoNotSchedule,
+					LabelSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{
+								Key:      nfsServerLabelKey,
+								Operator: metav1.LabelSelectorOpExists,
+							},
+						},
+					},
+				},
+			},
+		},
+		"When constraint is configured with labelSelector": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSServerTopologySpreadConstraints: map[string]string{
+						"value": "- maxSkew: 2\n  topologyKey: kubernetes.io/hostname\n  whenUnsatisfiable: ScheduleAnyway\n  labelSelector:\n    matchLabels:\n      team: storage",
+					},
+				},
+			},
+			expectedConstraints: []corev1.TopologySpreadConstraint{
+				{
+					MaxSkew:           2,
+					TopologyKey:       "kubernetes.io/hostname",
+					WhenUnsatisfiable: corev1.ScheduleAnyway,
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"team": "storage"},
+					},
+				},
+			},
+		},
+		"When maxSkew is zero": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSServerTopologySpreadConstraints: map[string]string{
+						"value": "- maxSkew: 0\n  topologyKey: kubernetes.io/hostname",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+		"When whenUnsatisfiable is invalid": {
+			volumeConfig: &VolumeConfig{
+				options: map[string]interface{}{
+					NFSServerTopologySpreadConstraints: map[string]string{
+						"value": "- maxSkew: 1\n  topologyKey: kubernetes.io/hostname\n  whenUnsatisfiable: Ignore",
+					},
+				},
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		constraints, err := test.volumeConfig.GetNFSServerTopologySpreadConstraints()
+		if test.isErrExpected != (err != nil) {
+			t.Errorf("%q test: expected error %t, but got %v", name, test.isErrExpected, err)
+		}
+		if !reflect.DeepEqual(constraints, test.expectedConstraints) {
+			t.Errorf("%q test: constraints mismatch %s", name, cmp.Diff(test.expectedConstraints, constraints))
+		}
+	}
+}
+
+func TestRemoveStorageClassOnlyConfig(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pvc1",
+			Namespace: "default",
+		},
+	}
+	pvcCASConfig := []mconfig.Config{
+		{Name: LeaseTime, Value: "30"},
+		{Name: NFSServerPriorityClassName, Value: "nfs-critical"},
+		{Name: NFSServerTolerations, Value: "- operator: Exists"},
+	}
+
+	filtered := removeStorageClassOnlyConfig(pvcCASConfig, pvc)
+	if !reflect.DeepEqual(filtered, []mconfig.Config{{Name: LeaseTime, Value: "30"}}) {
+		t.Errorf("expected only %s config, but got %v", LeaseTime, filtered)
+	}
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	cast "github.com/openebs/maya/pkg/castemplate/v1alpha1"
+	"github.com/openebs/maya/pkg/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MinLeaseTime and MaxLeaseTime define the range(in seconds)
+	// of LeaseTime and GraceTime accepted by the NFS server
+	MinLeaseTime = 10
+	MaxLeaseTime = 3600
+)
+
+// supportedConfigKeys are the keys of cas.openebs.io/config
+// recognized by the provisioner
+var supportedConfigKeys = map[string]bool{
+	KeyPVNFSServerType:        true,
+	KeyPVBackendStorageClass:  true,
+	CustomServerConfig:        true,
+	LeaseTime:                 true,
+	GraceTime:                 true,
+	FSGroupID:                 true,
+	FilePermissions:           true,
+	NFSServerResourceRequests: true,
+	NFSServerResourceLimits:   true,
+	SharedNFSServer:           true,
+	SharedBackendCapacity:     true,
+	ExternalNFSServer:         true,
+	ExternalNFSPath:           true,
+	ExternalArchiveOnDelete:   true,
+	CloneStrategy:             true,
+	ExportOptions:             true,
+	NFSVersions:               true,
+	NetworkPolicy:             true,
+	NFSServerMetrics:          true,
+
+	NFSServerNodeAffinity:              true,
+	NFSServerTolerations:               true,
+	NFSServerPriorityClassName:         true,
+	NFSServerTopologySpreadConstraints: true,
+}
+
+// storageClassOnlyConfigKeys are the keys of cas.openebs.io/config,
+// which can be set only in the StorageClass. These keys control the
+// scheduling of the NFS servers, and are ignored in the PVC config.
+var storageClassOnlyConfigKeys = map[string]bool{
+	NFSServerNodeAffinity:              true,
+	NFSServerTolerations:               true,
+	NFSServerPriorityClassName:         true,
+	NFSServerTopologySpreadConstraints: true,
+}
+
+// fsModeRegex matches the octal or symbolic file mode accepted by chmod(1)
+var fsModeRegex = regexp.MustCompile(`^([0-7]{1,4}|[ugoa]*([-+=]([rwxXst]*|[ugo]))+(,[ugoa]*([-+=]([rwxXst]*|[ugo]))+)*)$`)
+
+// validateCASConfigKeys returns error if the given cas.openebs.io/config
+// can't be parsed, or it has a key not recognized by the provisioner
+func validateCASConfigKeys(casConfigStr string) error {
+	if len(strings.TrimSpace(casConfigStr)) == 0 {
+		return nil
+	}
+
+	casConfig, err := cast.UnMarshallToConfig(casConfigStr)
+	if err != nil {
+		return errors.Wrapf(err, "invalid config")
+	}
+
+	var unknown []string
+	for _, config := range casConfig {
+		name := strings.TrimSpace(config.Name)
+		if !supportedConfigKeys[name] {
+			unknown = append(unknown, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(unknown) != 0 {
+		return errors.Errorf("unknown config keys %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// validatePVCConfigKeys returns error if the given cas.openebs.io/config
+// of the PVC has a key which can be set only in the StorageClass
+func validatePVCConfigKeys(casConfigStr string) error {
+	casConfig, err := parseCASConfig(casConfigStr)
+	if err != nil {
+		return err
+	}
+
+	var scOnly []string
+	for name := range casConfig {
+		if storageClassOnlyConfigKeys[name] {
+			scOnly = append(scOnly, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(scOnly) != 0 {
+		sort.Strings(scOnly)
+		return errors.Errorf("config keys %s can be set only in the StorageClass", strings.Join(scOnly, ", "))
+	}
+	return nil
+}
+
+// Validate returns error if any of the config values is invalid. All
+// the invalid values are reported in the error, so that the config can
+// be fixed at once.
+func (c *VolumeConfig) Validate() error {
+	var errs []string
+	addErr := func(err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	serverType := c.GetNFSServerTypeFromConfig()
+	switch serverType {
+	case NFSServerTypeKernel, NFSServerTypeGanesha, NFSServerTypeExternal:
+	default:
+		addErr(errors.Errorf("invalid %s value %q, must be one of %s, %s or %s",
+			KeyPVNFSServerType, serverType, NFSServerTypeKernel, NFSServerTypeGanesha, NFSServerTypeExternal))
+	}
+
+	addErr(validateServerTime(LeaseTime, c.getValue(LeaseTime)))
+	addErr(validateServerTime(GraceTime, c.getValue(GraceTime)))
+
+	if _, err := c.GetFSGroupID(); err != nil {
+		addErr(errors.Errorf("invalid %s value %q, must be a numeric group ID", FSGroupID, c.getValue(FSGroupID)))
+	}
+	addErr(c.validateFilePermissions())
+
+	_, err := c.GetNFSServerResourceRequirements()
+	addErr(err)
+
+	shared, err := c.IsSharedNFSServer()
+	addErr(err)
+	if shared {
+		_, err = c.GetSharedBackendCapacity()
+		addErr(err)
+	}
+
+	if serverType == NFSServerTypeExternal {
+		_, err = c.GetExternalNFSServer()
+		addErr(err)
+		_, err = c.GetExternalNFSPath()
+		addErr(err)
+	}
+	_, err = c.IsExternalArchiveOnDelete()
+	addErr(err)
+
+	_, err = c.GetCloneStrategy()
+	addErr(err)
+
+	exportOpts, err := c.GetExportOptions()
+	addErr(err)
+	if exportOpts != nil && serverType != NFSServerTypeKernel {
+		addErr(errors.Errorf("%s is not supported for NFS server type %s", ExportOptions, serverType))
+	}
+
+	versions, err := c.GetNFSVersions()
+	addErr(err)
+	if err == nil && serverType != NFSServerTypeKernel &&
+		(len(versions) != 1 || versions[0] != NFSVersion4) {
+		addErr(errors.Errorf("%s is not supported for NFS server type %s", NFSVersions, serverType))
+	}
+
+	networkPolicy, err := c.GetNetworkPolicyOptions()
+	addErr(err)
+	if networkPolicy != nil && shared {
+		addErr(errors.Errorf("%s is not supported on the shared NFS server", NetworkPolicy))
+	}
+
+	metricsEnabled, err := c.IsNFSServerMetricsEnabled()
+	addErr(err)
+	if metricsEnabled && serverType != NFSServerTypeKernel {
+		addErr(errors.Errorf("%s is not supported for NFS server type %s", NFSServerMetrics, serverType))
+	}
+	if metricsEnabled && shared {
+		addErr(errors.Errorf("%s is not supported on the shared NFS server", NFSServerMetrics))
+	}
+
+	_, err = c.GetNFSServerNodeAffinity()
+	addErr(err)
+	_, err = c.GetNFSServerTolerations()
+	addErr(err)
+	_, err = c.GetNFSServerPriorityClassName()
+	addErr(err)
+	_, err = c.GetNFSServerTopologySpreadConstraints()
+	addErr(err)
+
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validateServerTime returns error if the given LeaseTime or GraceTime
+// value is not a number of seconds in the range supported by the NFS
+// server. 0 is accepted, and the default value is used for it.
+func validateServerTime(key, val string) error {
+	val = strings.TrimSpace(val)
+	if len(val) == 0 {
+		return nil
+	}
+
+	seconds, err := strconv.Atoi(val)
+	if err != nil {
+		return errors.Errorf("invalid %s value %q, must be a number of seconds", key, val)
+	}
+	if seconds != 0 && (seconds < MinLeaseTime || seconds > MaxLeaseTime) {
+		return errors.Errorf("invalid %s value %d, must be in range from %d to %d seconds",
+			key, seconds, MinLeaseTime, MaxLeaseTime)
+	}
+	return nil
+}
+
+// validateFilePermissions returns error if the FilePermissions data has
+// an unknown key, or the owner IDs or the file mode are invalid
+func (c *VolumeConfig) validateFilePermissions() error {
+	data, _ := util.GetNestedField(c.configData, FilePermissions).(map[string]string)
+	for key := range data {
+		if key != FsUID && key != FsGID && key != FsMode {
+			return errors.Errorf("unknown %s option %q", FilePermissions, key)
+		}
+	}
+
+	if uid := c.GetFsUID(); len(uid) != 0 {
+		if _, err := strconv.ParseUint(uid, 10, 32); err != nil {
+			return errors.Errorf("invalid %s.%s value %q, must be a numeric user ID", FilePermissions, FsUID, uid)
+		}
+	}
+
+	gid, err := c.GetFsGID()
+	if err != nil {
+		return err
+	}
+	if len(gid) != 0 {
+		if _, err := strconv.ParseUint(gid, 10, 32); err != nil {
+			return errors.Errorf("invalid %s.%s value %q, must be a numeric group ID", FilePermissions, FsGID, gid)
+		}
+	}
+
+	mode, err := c.GetFsMode()
+	if err != nil {
+		return err
+	}
+	if len(mode) != 0 && !fsModeRegex.MatchString(mode) {
+		return errors.Errorf("invalid %s.%s value %q, must be an octal or symbolic file mode", FilePermissions, FsMode, mode)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCASConfigKeys(t *testing.T) {
+	tests := map[string]struct {
+		casConfig     string
+		isErrExpected bool
+	}{
+		"when config is empty": {
+			casConfig: "",
+		},
+		"when config has supported keys": {
+			casConfig: `- name: NFSServerType
+  value: kernel
+- name: FilePermissions
+  data:
+    UID: "1000"
+    mode: "0744"`,
+		},
+		"when config has unknown key": {
+			casConfig: `- name: NFSServerType
+  value: kernel
+- name: LeaseTme
+  value: "30"`,
+			isErrExpected: true,
+		},
+		"when config is malformed": {
+			casConfig:     `- name: NFSServerType: kernel`,
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			err := validateCASConfigKeys(test.casConfig)
+			assert.Equal(t, test.isErrExpected, err != nil, "error: %v", err)
+		})
+	}
+}
+
+func TestValidatePVCConfigKeys(t *testing.T) {
+	tests := map[string]struct {
+		casConfig     string
+		isErrExpected bool
+	}{
+		"when config is empty": {
+			casConfig: "",
+		},
+		"when config has only volume keys": {
+			casConfig: `- name: NFSServerType
+  value: kernel
+- name: LeaseTime
+  value: "30"`,
+		},
+		"when config has StorageClass only keys": {
+			casConfig: `- name: NFSServerType
+  value: kernel
+- name: NFSServerPriorityClassName
+  value: nfs-critical`,
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			err := validatePVCConfigKeys(test.casConfig)
+			assert.Equal(t, test.isErrExpected, err != nil, "error: %v", err)
+		})
+	}
+}
+
+func TestVolumeConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		options       map[string]interface{}
+		configData    map[string]interface{}
+		isErrExpected bool
+	}{
+		"when config is empty": {},
+		"when config is valid": {
+			options: map[string]interface{}{
+				LeaseTime:                 map[string]string{"value": "30"},
+				GraceTime:                 map[string]string{"value": "0"},
+				NFSServerResourceRequests: map[string]string{"value": "cpu: 50m\nmemory: 50Mi"},
+			},
+			configData: map[string]interface{}{
+				FilePermissions: map[string]string{
+					FsUID:  "1000",
+					FsGID:  "2000",
+					FsMode: "g+s",
+				},
+			},
+		},
+		"when NFSServerType is unknown": {
+			options: map[string]interface{}{
+				KeyPVNFSServerType: map[string]string{"value": "nfsd"},
+			},
+			isErrExpected: true,
+		},
+		"when LeaseTime is out of range": {
+			options: map[string]interface{}{
+				LeaseTime: map[string]string{"value": "5"},
+			},
+			isErrExpected: true,
+		},
+		"when GraceTime is not a number": {
+			options: map[string]interface{}{
+				GraceTime: map[string]string{"value": "90s"},
+			},
+			isErrExpected: true,
+		},
+		"when resource list is malformed": {
+			options: map[string]interface{}{
+				NFSServerResourceLimits: map[string]string{"value": "cpu: [50m]"},
+			},
+			isErrExpected: true,
+		},
+		"when FilePermissions mode is invalid": {
+			configData: map[string]interface{}{
+				FilePermissions: map[string]string{FsMode: "0999"},
+			},
+			isErrExpected: true,
+		},
+		"when FilePermissions UID is not numeric": {
+			configData: map[string]interface{}{
+				FilePermissions: map[string]string{FsUID: "nobody"},
+			},
+			isErrExpected: true,
+		},
+		"when FilePermissions has unknown key": {
+			configData: map[string]interface{}{
+				FilePermissions: map[string]string{"owner": "1000"},
+			},
+			isErrExpected: true,
+		},
+		"when FSGID and FilePermissions GID are used together": {
+			options: map[string]interface{}{
+				FSGroupID: map[string]string{"value": "1000"},
+			},
+			configData: map[string]interface{}{
+				FilePermissions: map[string]string{FsGID: "2000"},
+			},
+			isErrExpected: true,
+		},
+		"when external NFS server is not configured": {
+			options: map[string]interface{}{
+				KeyPVNFSServerType: map[string]string{"value": NFSServerTypeExternal},
+			},
+			isErrExpected: true,
+		},
+		"when NFSv3 is requested for ganesha NFS server": {
+			options: map[string]interface{}{
+				KeyPVNFSServerType: map[string]string{"value": NFSServerTypeGanesha},
+				NFSVersions:        map[string]string{"value": "3"},
+			},
+			isErrExpected: true,
+		},
+		"when NFSServerMetrics is enabled for shared NFS server": {
+			options: map[string]interface{}{
+				SharedNFSServer:  map[string]string{"value": "true"},
+				NFSServerMetrics: map[string]string{"value": "true"},
+			},
+			isErrExpected: true,
+		},
+		"when NFS server scheduling config is valid": {
+			options: map[string]interface{}{
+				NFSServerNodeAffinity:              map[string]string{"value": "kubernetes.io/zone:[zone-1,zone-2],openebs.io/nfs-server"},
+				NFSServerTolerations:               map[string]string{"value": "- key: storage-node\n  operator: Exists\n  effect: NoSchedule"},
+				NFSServerPriorityClassName:         map[string]string{"value": "nfs-critical"},
+				NFSServerTopologySpreadConstraints: map[string]string{"value": "- maxSkew: 1\n  topologyKey: topology.kubernetes.io/zone"},
+			},
+		},
+		"when NFSServerNodeAffinity has invalid key": {
+			options: map[string]interface{}{
+				NFSServerNodeAffinity: map[string]string{"value": "kubernetes.io/zone*:[zone-1]"},
+			},
+			isErrExpected: true,
+		},
+		"when NFSServerTolerations has invalid operator": {
+			options: map[string]interface{}{
+				NFSServerTolerations: map[string]string{"value": "- key: storage-node\n  operator: In"},
+			},
+			isErrExpected: true,
+		},
+		"when NFSServerPriorityClassName is invalid": {
+			options: map[string]interface{}{
+				NFSServerPriorityClassName: map[string]string{"value": "NFS_Critical"},
+			},
+			isErrExpected: true,
+		},
+		"when NFSServerTopologySpreadConstraints has no topologyKey": {
+			options: map[string]interface{}{
+				NFSServerTopologySpreadConstraints: map[string]string{"value": "- maxSkew: 1"},
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			volumeConfig := &VolumeConfig{
+				options:    test.options,
+				configData: test.configData,
+			}
+
+			err := volumeConfig.Validate()
+			assert.Equal(t, test.isErrExpected, err != nil, "error: %v", err)
+		})
+	}
+}
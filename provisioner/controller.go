@@ -19,6 +19,7 @@ package provisioner
 import (
 	"context"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -28,6 +29,7 @@ import (
 	analytics "github.com/openebs/google-analytics-4/usage"
 	menv "github.com/openebs/maya/pkg/env/v1alpha1"
 	"github.com/openebs/maya/pkg/version"
+	kubeinformers "k8s.io/client-go/informers"
 	pvController "sigs.k8s.io/sig-storage-lib-external-provisioner/v7/controller"
 )
 
@@ -38,8 +40,9 @@ var (
 	LeaderElectionKey = "LEADER_ELECTION_ENABLED"
 )
 
-// Start will initialize and run the dynamic provisioner daemon
-func Start(ctx context.Context) error {
+// Start will initialize and run the dynamic provisioner daemon. The
+// webhook server is started as well, if it is configured.
+func Start(ctx context.Context, webhookCfg WebhookConfig) error {
 	klog.Infof("Starting Provisioner...")
 
 	// Dynamic Provisioner can run successfully if it can establish
@@ -65,6 +68,10 @@ func Start(ctx context.Context) error {
 		return err
 	}
 
+	if len(webhookCfg.ListenAddress) != 0 {
+		go startWebhookServer(ctx, provisioner, webhookCfg)
+	}
+
 	//Create an instance of the Dynamic Provisioner Controller
 	// that has the reconciliation loops for PVC create and delete
 	// events and invokes the Provisioner Handler.
@@ -79,6 +86,50 @@ func Start(ctx context.Context) error {
 	//Run the provisioner till a shutdown signal is received.
 	go pc.Run(ctx)
 
+	// Resize controller expands the NFS volumes on NFS PVC
+	// resize requests, by expanding the backend PVC
+	informerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+	resizeController := NewResizeController(kubeClient, informerFactory, getNfsServerNamespace())
+
+	// Inventory controller updates the NFS volume inventory
+	// metrics from the informer caches
+	inventoryController := NewVolumeInventoryController(getNfsServerNamespace(), informerFactory)
+
+	serverInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0,
+		kubeinformers.WithNamespace(getNfsServerNamespace()))
+
+	// Reconcile controller recreates the missing NFS server
+	// resources of the NFS volumes
+	reconcileStr := getNfsServerReconcileEnable()
+	reconcileEnable, err := strconv.ParseBool(reconcileStr)
+	if err != nil {
+		klog.Warningf("Invalid %s value=%s, using default value=true", NFSServerReconcileEnable, reconcileStr)
+		reconcileEnable = true
+	}
+	if reconcileEnable {
+		reconcileController := NewReconcileController(provisioner, informerFactory, serverInformerFactory)
+		go reconcileController.Run(ctx)
+	} else {
+		klog.Warning("NFS server reconciler is disabled")
+	}
+
+	// Upgrade controller rolls out the NFS server Deployments,
+	// which differ from the spec rendered by the provisioner
+	upgradePolicy, upgradeWindow := getUpgradePolicy()
+	upgradeController := NewUpgradeController(provisioner, upgradePolicy, upgradeWindow,
+		informerFactory, serverInformerFactory)
+	go upgradeController.Run(ctx)
+
+	// Reconfigure controller applies the changes of the mutable
+	// config of the NFS PVCs to their NFS server Deployments
+	reconfigureController := NewReconfigureController(provisioner, informerFactory, serverInformerFactory)
+	go reconfigureController.Run(ctx)
+
+	informerFactory.Start(ctx.Done())
+	serverInformerFactory.Start(ctx.Done())
+	go resizeController.Run(ctx)
+	go inventoryController.Run(ctx)
+
 	if menv.Truthy(menv.OpenEBSEnableAnalytics) {
 		analytics.RegisterVersionGetter(version.GetVersionDetails)
 		analytics.New().CommonBuild(DefaultCASType).InstallBuilder(true).Send()
@@ -91,6 +142,27 @@ func Start(ctx context.Context) error {
 	return nil
 }
 
+// getUpgradePolicy returns the upgrade policy of the NFS server Deployments,
+// and the maintenance window for maintenance-window policy. Manual policy is
+// used, if the configuration is invalid.
+func getUpgradePolicy() (UpgradePolicy, *MaintenanceWindow) {
+	policy, err := ParseUpgradePolicy(getNfsServerUpgradePolicy())
+	if err != nil {
+		klog.Warningf("Invalid %s value, using default policy=%s, err=%v", NFSServerUpgradePolicy, UpgradePolicyManual, err)
+		return UpgradePolicyManual, nil
+	}
+	if policy != UpgradePolicyMaintenanceWindow {
+		return policy, nil
+	}
+
+	window, err := ParseMaintenanceWindow(getNfsServerMaintenanceWindow())
+	if err != nil {
+		klog.Warningf("Invalid %s value, using default policy=%s, err=%v", NFSServerMaintenanceWindow, UpgradePolicyManual, err)
+		return UpgradePolicyManual, nil
+	}
+	return policy, window
+}
+
 // isLeaderElectionEnabled returns true/false based on the ENV
 // LEADER_ELECTION_ENABLED set via provisioner deployment.
 // Defaults to true, means leaderElection enabled by default.
@@ -0,0 +1,555 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/protobuf/ptypes"
+	errors "github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	mayav1alpha1 "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+)
+
+const (
+	// csiVolumeLabelKey is set on the NFS server resources created by
+	// the CSI driver. Value of the label is the name of the CSI driver
+	csiVolumeLabelKey = "nfs.openebs.io/csi-driver"
+
+	// csiDefaultVolumeSize is the size of the volume, if capacity
+	// is not specified in CreateVolume request
+	csiDefaultVolumeSize int64 = 1 << 30
+
+	// csiParamPVCName and csiParamPVCNamespace are the CreateVolume
+	// parameters set by the external-provisioner with --extra-create-metadata
+	csiParamPVCName      = "csi.storage.k8s.io/pvc/name"
+	csiParamPVCNamespace = "csi.storage.k8s.io/pvc/namespace"
+
+	// csiContextServer and csiContextShare are the volume context keys
+	// which hold the address and the export path of the NFS server
+	csiContextServer = "server"
+	csiContextShare  = "share"
+)
+
+// getCSIVolumeID returns the volume ID for the given volume name. NFS
+// server resources are named as nfs-<volume ID>, so the volume name is
+// used as is only if it is a valid DNS label within the length limit.
+// Otherwise the volume ID is derived from the hash of the volume name.
+func getCSIVolumeID(name string) string {
+	return getCSIObjectID(name, "pvc-")
+}
+
+// getCSISnapshotID returns the snapshot ID for the given snapshot name.
+// VolumeSnapshot of the backend volume is named as nfs-<snapshot ID>.
+func getCSISnapshotID(name string) string {
+	return getCSIObjectID(name, "snapshot-")
+}
+
+// getCSIObjectID returns the name as is, if nfs-<name> is a valid DNS
+// label. Otherwise it returns the hash of the name with the given prefix.
+func getCSIObjectID(name, hashPrefix string) string {
+	if len(validation.IsDNS1035Label("nfs-"+name)) == 0 {
+		return name
+	}
+
+	hash := sha256.Sum256([]byte(name))
+	return hashPrefix + hex.EncodeToString(hash[:])[:32]
+}
+
+// getCSIVolumeSize returns the size of the volume for the given
+// capacity range
+func getCSIVolumeSize(capRange *csi.CapacityRange) (int64, error) {
+	required := capRange.GetRequiredBytes()
+	limit := capRange.GetLimitBytes()
+
+	if required < 0 || limit < 0 {
+		return 0, status.Error(codes.InvalidArgument, "capacity range must not be negative")
+	}
+	if limit != 0 && required > limit {
+		return 0, status.Errorf(codes.InvalidArgument, "required bytes %d exceeds limit bytes %d", required, limit)
+	}
+
+	if required != 0 {
+		return required, nil
+	}
+	if limit != 0 && limit < csiDefaultVolumeSize {
+		return limit, nil
+	}
+	return csiDefaultVolumeSize, nil
+}
+
+// validateCSIVolumeCapabilities returns error if any of the given
+// capabilities is not supported by the NFS volume
+func validateCSIVolumeCapabilities(caps []*csi.VolumeCapability) error {
+	if len(caps) == 0 {
+		return status.Error(codes.InvalidArgument, "volume capabilities are missing")
+	}
+
+	for _, c := range caps {
+		if c.GetBlock() != nil {
+			return status.Error(codes.InvalidArgument, "block access type is not supported")
+		}
+		if c.GetMount() == nil {
+			return status.Error(codes.InvalidArgument, "access type is missing")
+		}
+		if c.GetAccessMode() == nil {
+			return status.Error(codes.InvalidArgument, "access mode is missing")
+		}
+	}
+	return nil
+}
+
+// isReadOnlyCSIVolumeCapabilities returns true if the given
+// capabilities allow only the read-only access to the volume
+func isReadOnlyCSIVolumeCapabilities(caps []*csi.VolumeCapability) bool {
+	for _, c := range caps {
+		switch c.GetAccessMode().GetMode() {
+		case csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
+			csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY:
+		default:
+			return false
+		}
+	}
+	return len(caps) != 0
+}
+
+// getBackendPVC returns the backend PVC of the given CSI volume
+func (d *CSIDriver) getBackendPVC(ctx context.Context, volumeID string) (*corev1.PersistentVolumeClaim, error) {
+	p := d.provisioner
+	return p.kubeClient.CoreV1().
+		PersistentVolumeClaims(p.serverNamespace).
+		Get(ctx, "nfs-"+volumeID, metav1.GetOptions{})
+}
+
+// CreateVolume creates the NFS server for the volume, and returns
+// the address of the NFS server in the volume context
+func (d *CSIDriver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
+	p := d.provisioner
+
+	if len(req.GetName()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume name is missing")
+	}
+	if err := validateCSIVolumeCapabilities(req.GetVolumeCapabilities()); err != nil {
+		return nil, err
+	}
+	if req.GetVolumeContentSource() != nil && req.GetVolumeContentSource().GetSnapshot() == nil {
+		return nil, status.Error(codes.InvalidArgument, "volume content source is not supported")
+	}
+
+	size, err := getCSIVolumeSize(req.GetCapacityRange())
+	if err != nil {
+		return nil, err
+	}
+
+	var backendDataSource *corev1.TypedLocalObjectReference
+	var dataDir string
+	if snapshotSource := req.GetVolumeContentSource().GetSnapshot(); snapshotSource != nil {
+		snapshotName := getBackendSnapshotName(snapshotSource.GetSnapshotId())
+		obj, err := p.getBackendSnapshot(ctx, snapshotName)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil, status.Errorf(codes.NotFound, "snapshot %s doesn't exist", snapshotSource.GetSnapshotId())
+			}
+			return nil, status.Errorf(codes.Internal, "failed to get snapshot %s: %v", snapshotSource.GetSnapshotId(), err)
+		}
+
+		snapshot, err := parseBackendSnapshot(obj)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if size < snapshot.restoreSize {
+			return nil, status.Errorf(codes.OutOfRange, "requested size %d is less than the size %d of snapshot %s",
+				size, snapshot.restoreSize, snapshotSource.GetSnapshotId())
+		}
+		backendDataSource = getBackendSnapshotDataSource(snapshotName)
+		dataDir = obj.GetAnnotations()[nfsServerDataDirAnnotation]
+	}
+	capacity := resource.NewQuantity(size, resource.BinarySI)
+
+	volumeID := getCSIVolumeID(req.GetName())
+	volumeConfig, err := p.GetVolumeConfigFromParameters(volumeID, req.GetParameters())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	serverType := volumeConfig.GetNFSServerTypeFromConfig()
+	if serverType != NFSServerTypeKernel && serverType != NFSServerTypeGanesha {
+		return nil, status.Errorf(codes.InvalidArgument, "NFS server type %s is not supported by the CSI driver", serverType)
+	}
+
+	shared, err := volumeConfig.IsSharedNFSServer()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if shared {
+		return nil, status.Error(codes.InvalidArgument, "shared NFS server is not supported by the CSI driver")
+	}
+
+	backendPvc, err := d.getBackendPVC(ctx, volumeID)
+	if err == nil {
+		existingCapacity := backendPvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		if existingCapacity.Cmp(*capacity) != 0 {
+			return nil, status.Errorf(codes.AlreadyExists, "volume %s already exists with capacity %s", volumeID, existingCapacity.String())
+		}
+	} else if !k8serrors.IsNotFound(err) {
+		return nil, status.Errorf(codes.Internal, "failed to get backend PVC of volume %s: %v", volumeID, err)
+	}
+
+	nfsServerOpts, err := newKernelNFSServerOptions(ctx, volumeID, capacity.String(), volumeConfig, serverType)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	nfsServerOpts.provisionerNS = p.namespace
+	nfsServerOpts.pvcName = req.GetParameters()[csiParamPVCName]
+	nfsServerOpts.pvcNamespace = req.GetParameters()[csiParamPVCNamespace]
+	nfsServerOpts.csiVolume = true
+	nfsServerOpts.backendDataSource = backendDataSource
+	nfsServerOpts.dataDir = dataDir
+
+	if isReadOnlyCSIVolumeCapabilities(req.GetVolumeCapabilities()) {
+		if err = nfsServerOpts.setReadOnlyExport(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	p.pvTracker.Add(volumeID)
+	defer p.pvTracker.Delete(volumeID)
+
+	nfsService, err := p.getNFSServerAddress(nfsServerOpts)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create NFS server for volume %s: %v", volumeID, err)
+	}
+
+	klog.Infof("Created CSI volume %v pointing at %v", volumeID, nfsService)
+	return &csi.CreateVolumeResponse{
+		Volume: &csi.Volume{
+			VolumeId:      volumeID,
+			CapacityBytes: size,
+			VolumeContext: map[string]string{
+				csiContextServer: nfsService,
+				csiContextShare:  nfsServerOpts.getExportRoot(),
+			},
+			ContentSource: req.GetVolumeContentSource(),
+		},
+	}, nil
+}
+
+// DeleteVolume deletes the NFS server of the volume
+func (d *CSIDriver) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
+	p := d.provisioner
+	volumeID := req.GetVolumeId()
+
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume ID is missing")
+	}
+
+	// NFS server type is recorded on the backend PVC
+	serverType := NFSServerTypeKernel
+	backendPvc, err := d.getBackendPVC(ctx, volumeID)
+	if err == nil {
+		serverType = strings.TrimPrefix(backendPvc.Labels[string(mayav1alpha1.CASTypeKey)], "nfs-")
+	} else if !k8serrors.IsNotFound(err) {
+		return nil, status.Errorf(codes.Internal, "failed to get backend PVC of volume %s: %v", volumeID, err)
+	}
+
+	p.pvTracker.Add(volumeID)
+	defer p.pvTracker.Delete(volumeID)
+
+	nfsServerOpts := &KernelNFSServerOptions{
+		pvName:     volumeID,
+		serverType: serverType,
+		ctx:        ctx,
+	}
+
+	if err = p.deleteNFSServer(nfsServerOpts); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete NFS server of volume %s: %v", volumeID, err)
+	}
+
+	klog.Infof("Deleted CSI volume %v", volumeID)
+	return &csi.DeleteVolumeResponse{}, nil
+}
+
+// ValidateVolumeCapabilities confirms the given capabilities, if
+// those are supported by the volume
+func (d *CSIDriver) ValidateVolumeCapabilities(ctx context.Context, req *csi.ValidateVolumeCapabilitiesRequest) (*csi.ValidateVolumeCapabilitiesResponse, error) {
+	volumeID := req.GetVolumeId()
+
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume ID is missing")
+	}
+	if len(req.GetVolumeCapabilities()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume capabilities are missing")
+	}
+
+	_, err := d.getBackendPVC(ctx, volumeID)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "volume %s doesn't exist", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get backend PVC of volume %s: %v", volumeID, err)
+	}
+
+	if err = validateCSIVolumeCapabilities(req.GetVolumeCapabilities()); err != nil {
+		return &csi.ValidateVolumeCapabilitiesResponse{Message: err.Error()}, nil
+	}
+
+	return &csi.ValidateVolumeCapabilitiesResponse{
+		Confirmed: &csi.ValidateVolumeCapabilitiesResponse_Confirmed{
+			VolumeContext:      req.GetVolumeContext(),
+			VolumeCapabilities: req.GetVolumeCapabilities(),
+			Parameters:         req.GetParameters(),
+		},
+	}, nil
+}
+
+// ControllerExpandVolume expands the backend PVC of the volume. NFS
+// clients see the new size once the backend volume is expanded.
+func (d *CSIDriver) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
+	p := d.provisioner
+	volumeID := req.GetVolumeId()
+
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume ID is missing")
+	}
+	if req.GetCapacityRange() == nil {
+		return nil, status.Error(codes.InvalidArgument, "capacity range is missing")
+	}
+
+	size, err := getCSIVolumeSize(req.GetCapacityRange())
+	if err != nil {
+		return nil, err
+	}
+	capacity := resource.NewQuantity(size, resource.BinarySI)
+
+	backendPvc, err := d.getBackendPVC(ctx, volumeID)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "volume %s doesn't exist", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get backend PVC of volume %s: %v", volumeID, err)
+	}
+
+	existingCapacity := backendPvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if existingCapacity.Cmp(*capacity) < 0 {
+		klog.Infof("Expanding backend PVC {%s/%s} from %s to %s for CSI volume %s",
+			backendPvc.Namespace, backendPvc.Name, existingCapacity.String(), capacity.String(), volumeID)
+
+		backendPvc = backendPvc.DeepCopy()
+		backendPvc.Spec.Resources.Requests[corev1.ResourceStorage] = *capacity
+		_, err = p.kubeClient.CoreV1().
+			PersistentVolumeClaims(backendPvc.Namespace).
+			Update(ctx, backendPvc, metav1.UpdateOptions{})
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to expand backend PVC {%s/%s}: %v", backendPvc.Namespace, backendPvc.Name, err)
+		}
+	} else {
+		size = existingCapacity.Value()
+	}
+
+	return &csi.ControllerExpandVolumeResponse{
+		CapacityBytes:         size,
+		NodeExpansionRequired: false,
+	}, nil
+}
+
+// ControllerGetCapabilities returns the capabilities of the controller service
+func (d *CSIDriver) ControllerGetCapabilities(ctx context.Context, req *csi.ControllerGetCapabilitiesRequest) (*csi.ControllerGetCapabilitiesResponse, error) {
+	var capabilities []*csi.ControllerServiceCapability
+	for _, c := range []csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+	} {
+		capabilities = append(capabilities, &csi.ControllerServiceCapability{
+			Type: &csi.ControllerServiceCapability_Rpc{
+				Rpc: &csi.ControllerServiceCapability_RPC{
+					Type: c,
+				},
+			},
+		})
+	}
+
+	return &csi.ControllerGetCapabilitiesResponse{
+		Capabilities: capabilities,
+	}, nil
+}
+
+// ControllerPublishVolume is not supported, NFS volumes
+// don't need to be attached to the node
+func (d *CSIDriver) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+// ControllerUnpublishVolume is not supported, NFS volumes
+// don't need to be detached from the node
+func (d *CSIDriver) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+// ListVolumes is not supported
+func (d *CSIDriver) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+// GetCapacity is not supported
+func (d *CSIDriver) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+// CreateSnapshot creates the VolumeSnapshot of the backend volume. Exports
+// of the NFS server are stopped and the data is flushed to the backend
+// volume till the snapshot is taken, so that the snapshot is consistent.
+func (d *CSIDriver) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
+	p := d.provisioner
+	volumeID := req.GetSourceVolumeId()
+
+	if len(req.GetName()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "snapshot name is missing")
+	}
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "source volume ID is missing")
+	}
+
+	snapshotID := getCSISnapshotID(req.GetName())
+	snapshotName := getBackendSnapshotName(snapshotID)
+
+	snapshotExists := false
+	obj, err := p.getBackendSnapshot(ctx, snapshotName)
+	if err == nil {
+		snapshotExists = true
+		snapshot, err := parseBackendSnapshot(obj)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if snapshot.sourceVolume != volumeID {
+			return nil, status.Errorf(codes.AlreadyExists, "snapshot %s already exists for volume %s", snapshotID, snapshot.sourceVolume)
+		}
+		if snapshot.creationTime != nil {
+			return d.getCreateSnapshotResponse(ctx, snapshotID, volumeID, snapshot)
+		}
+	} else if !k8serrors.IsNotFound(err) {
+		return nil, status.Errorf(codes.Internal, "failed to get snapshot %s: %v", snapshotID, err)
+	}
+
+	backendPvc, err := d.getBackendPVC(ctx, volumeID)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, "volume %s doesn't exist", volumeID)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get backend PVC of volume %s: %v", volumeID, err)
+	}
+
+	serverType := strings.TrimPrefix(backendPvc.Labels[string(mayav1alpha1.CASTypeKey)], "nfs-")
+	if serverType != NFSServerTypeKernel {
+		return nil, status.Errorf(codes.FailedPrecondition, "snapshot is not supported for NFS server type %s", serverType)
+	}
+
+	podName, err := p.quiesceNFSServer(ctx, "nfs-"+volumeID)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to quiesce NFS server of volume %s: %v", volumeID, err)
+	}
+	defer func() {
+		if err := p.resumeNFSServer(podName); err != nil {
+			klog.Errorf("Failed to resume NFS server of volume %s, err=%v", volumeID, err)
+		}
+	}()
+
+	if !snapshotExists {
+		err = p.createBackendSnapshot(ctx, snapshotName, volumeID, req.GetName(), req.GetParameters()[BackendVolumeSnapshotClass],
+			backendPvc.Annotations[nfsServerDataDirAnnotation])
+		if err != nil && !k8serrors.IsAlreadyExists(errors.Cause(err)) {
+			return nil, status.Errorf(codes.Internal, "failed to create snapshot %s: %v", snapshotID, err)
+		}
+	}
+
+	snapshot, err := p.waitForBackendSnapshotCut(ctx, snapshotName, p.backendPvcTimeout)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create snapshot %s: %v", snapshotID, err)
+	}
+
+	klog.Infof("Created CSI snapshot %s of volume %s", snapshotID, volumeID)
+	return d.getCreateSnapshotResponse(ctx, snapshotID, volumeID, snapshot)
+}
+
+// getCreateSnapshotResponse returns the CreateSnapshot response for the
+// given backend snapshot. If restore size of the backend snapshot is not
+// known yet, then size of the backend volume is used.
+func (d *CSIDriver) getCreateSnapshotResponse(ctx context.Context, snapshotID, volumeID string, snapshot *backendSnapshot) (*csi.CreateSnapshotResponse, error) {
+	size := snapshot.restoreSize
+	if size == 0 {
+		backendPvc, err := d.getBackendPVC(ctx, volumeID)
+		if err == nil {
+			capacity := backendPvc.Spec.Resources.Requests[corev1.ResourceStorage]
+			size = capacity.Value()
+		}
+	}
+
+	creationTime, err := ptypes.TimestampProto(*snapshot.creationTime)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "invalid creation time of snapshot %s: %v", snapshotID, err)
+	}
+
+	return &csi.CreateSnapshotResponse{
+		Snapshot: &csi.Snapshot{
+			SnapshotId:     snapshotID,
+			SourceVolumeId: volumeID,
+			SizeBytes:      size,
+			CreationTime:   creationTime,
+			ReadyToUse:     snapshot.readyToUse,
+		},
+	}, nil
+}
+
+// DeleteSnapshot deletes the VolumeSnapshot of the backend volume
+func (d *CSIDriver) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
+	p := d.provisioner
+	snapshotID := req.GetSnapshotId()
+
+	if len(snapshotID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "snapshot ID is missing")
+	}
+
+	err := p.deleteBackendSnapshot(ctx, getBackendSnapshotName(snapshotID))
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return nil, status.Errorf(codes.Internal, "failed to delete snapshot %s: %v", snapshotID, err)
+	}
+
+	klog.Infof("Deleted CSI snapshot %s", snapshotID)
+	return &csi.DeleteSnapshotResponse{}, nil
+}
+
+// ListSnapshots is not supported
+func (d *CSIDriver) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+// ControllerGetVolume is not supported
+func (d *CSIDriver) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
+
+	mKube "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/client"
+)
+
+const (
+	// CSIDriverName is the name of the NFS CSI driver
+	CSIDriverName = "nfs.openebs.io"
+
+	// DefaultCSIEndpoint is the default unix socket on which
+	// the NFS CSI driver serves the CSI gRPC services
+	DefaultCSIEndpoint = "unix:///var/lib/csi/sockets/pluginproxy/csi.sock"
+
+	// CSIRoleController runs the controller plugin of the NFS CSI driver,
+	// which serves the CSI Identity and Controller services
+	CSIRoleController = "controller"
+
+	// CSIRoleNode runs the node plugin of the NFS CSI driver, which
+	// serves the CSI Identity and Node services
+	CSIRoleNode = "node"
+)
+
+// CSIDriver serves the CSI Identity, Controller and Node services.
+// Controller service creates and deletes the NFS server of the volume
+// using the Provisioner, and Node service mounts the NFS volume.
+type CSIDriver struct {
+	// provisioner creates and deletes the NFS server resources.
+	// Controller service is served only if it is set.
+	provisioner *Provisioner
+
+	// nodeID is the name of the node on which the driver is running.
+	// Node service is served only if it is set.
+	nodeID string
+
+	// mounter mounts and unmounts the NFS volumes on the node
+	mounter csiMounter
+}
+
+// NewCSIDriver returns a new CSIDriver which manages the NFS
+// volumes using the given Provisioner
+func NewCSIDriver(p *Provisioner, nodeID string) *CSIDriver {
+	return &CSIDriver{
+		provisioner: p,
+		nodeID:      nodeID,
+		mounter:     newNFSMounter(),
+	}
+}
+
+// StartCSIDriver will initialize and run the given role of the NFS CSI
+// driver on the given endpoint, till the context is cancelled. Only the
+// controller plugin runs the Provisioner, along with its garbage collector
+// and node informer, so those are not run by the node plugin on every node.
+func StartCSIDriver(ctx context.Context, endpoint, role, nodeID string) error {
+	klog.Infof("Starting NFS CSI driver %s plugin...", role)
+
+	switch role {
+	case CSIRoleController:
+		kubeClient, err := mKube.New().Clientset()
+		if err != nil {
+			return errors.Wrap(err, "unable to get k8s client")
+		}
+
+		p, err := NewProvisioner(ctx, kubeClient)
+		if err != nil {
+			return err
+		}
+		return NewCSIDriver(p, "").Run(ctx, endpoint)
+	case CSIRoleNode:
+		if len(nodeID) == 0 {
+			return errors.Errorf("node ID is required by the %s plugin", CSIRoleNode)
+		}
+		return NewCSIDriver(nil, nodeID).Run(ctx, endpoint)
+	default:
+		return errors.Errorf("invalid CSI driver role %q, supported roles are %s and %s", role, CSIRoleController, CSIRoleNode)
+	}
+}
+
+// Run serves the CSI gRPC services on the given unix socket endpoint,
+// till the context is cancelled
+func (d *CSIDriver) Run(ctx context.Context, endpoint string) error {
+	socketPath, err := parseUnixEndpoint(endpoint)
+	if err != nil {
+		return err
+	}
+
+	// Remove the socket left behind by the previous instance
+	if err = os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to remove stale socket %s", socketPath)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", endpoint)
+	}
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(logGRPC))
+	csi.RegisterIdentityServer(server, d)
+	if d.provisioner != nil {
+		csi.RegisterControllerServer(server, d)
+	}
+	if len(d.nodeID) != 0 {
+		csi.RegisterNodeServer(server, d)
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	klog.Infof("NFS CSI driver listening on %s", endpoint)
+	return server.Serve(listener)
+}
+
+// parseUnixEndpoint returns the socket path of the given
+// unix:///path or unix:/path endpoint
+func parseUnixEndpoint(endpoint string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(endpoint), "unix:") {
+		return "", errors.Errorf("invalid endpoint %q, only unix socket endpoints are supported", endpoint)
+	}
+
+	socketPath := endpoint[len("unix:"):]
+	if strings.HasPrefix(socketPath, "//") {
+		socketPath = socketPath[len("//"):]
+	}
+
+	if len(socketPath) == 0 {
+		return "", errors.Errorf("invalid endpoint %q, socket path is empty", endpoint)
+	}
+	return socketPath, nil
+}
+
+// logGRPC logs the CSI gRPC requests and their errors
+func logGRPC(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	klog.V(4).Infof("GRPC call: %s, request: %+v", info.FullMethod, req)
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		klog.Errorf("GRPC call: %s failed, error: %v", info.FullMethod, err)
+	} else {
+		klog.V(4).Infof("GRPC call: %s, response: %+v", info.FullMethod, resp)
+	}
+	return resp, err
+}
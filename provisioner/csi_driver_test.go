@@ -0,0 +1,268 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/kubernetes-csi/csi-test/v4/pkg/sanity"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// fakeMounter records the mounted target paths
+type fakeMounter struct {
+	sync.Mutex
+	mounts map[string]string
+}
+
+func newFakeMounter() *fakeMounter {
+	return &fakeMounter{
+		mounts: map[string]string{},
+	}
+}
+
+func (m *fakeMounter) Mount(source, target string, options []string) error {
+	m.Lock()
+	defer m.Unlock()
+	m.mounts[filepath.Clean(target)] = source
+	return nil
+}
+
+func (m *fakeMounter) Unmount(target string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.mounts, filepath.Clean(target))
+	return nil
+}
+
+func (m *fakeMounter) IsMounted(target string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	_, ok := m.mounts[filepath.Clean(target)]
+	return ok, nil
+}
+
+// newFakeCSIProvisioner returns a Provisioner with fake clientsets,
+// which binds the backend PVCs, runs the NFS server pods and takes
+// the backend volume snapshots on creation
+func newFakeCSIProvisioner() *Provisioner {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "persistentvolumeclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pvc := action.(k8stesting.CreateAction).GetObject().(*corev1.PersistentVolumeClaim)
+		pvc.Status.Phase = corev1.ClaimBound
+		return false, nil, nil
+	})
+	client.PrependReactor("create", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deploy := action.(k8stesting.CreateAction).GetObject().(*appsv1.Deployment)
+		_ = client.Tracker().Add(getFakeNFSServerPod(deploy.Namespace, deploy.Name, true))
+		return false, nil, nil
+	})
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{volumeSnapshotGVR: "VolumeSnapshotList"})
+	dynamicClient.PrependReactor("create", "volumesnapshots", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		snapshot := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		_ = unstructured.SetNestedField(snapshot.Object, time.Now().UTC().Format(time.RFC3339), "status", "creationTime")
+		_ = unstructured.SetNestedField(snapshot.Object, true, "status", "readyToUse")
+		return false, nil, nil
+	})
+
+	return &Provisioner{
+		kubeClient:        client,
+		dynamicClient:     dynamicClient,
+		serverNamespace:   "nfs-ns",
+		namespace:         "openebs",
+		pvTracker:         NewProvisioningTracker(),
+		backendPvcTimeout: 10 * time.Second,
+		executor:          newFakeCommandExecutor(),
+	}
+}
+
+func TestCSIDriverSanity(t *testing.T) {
+	os.Setenv(string(NFSServerImageKey), "openebs/nfs-server-alpine:ci")
+	defer os.Unsetenv(string(NFSServerImageKey))
+
+	tmpDir, err := os.MkdirTemp("", "nfs-csi-sanity")
+	if err != nil {
+		t.Fatalf("failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	driver := &CSIDriver{
+		provisioner: newFakeCSIProvisioner(),
+		nodeID:      "node-1",
+		mounter:     newFakeMounter(),
+	}
+
+	endpoint := "unix://" + filepath.Join(tmpDir, "csi.sock")
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	go func() {
+		if err := driver.Run(ctx, endpoint); err != nil {
+			t.Errorf("failed to run CSI driver: %v", err)
+		}
+	}()
+
+	config := sanity.NewTestConfig()
+	config.Address = endpoint
+	config.TargetPath = filepath.Join(tmpDir, "target")
+	config.StagingPath = filepath.Join(tmpDir, "staging")
+	sanity.Test(t, config)
+}
+
+func TestGetCSIVolumeID(t *testing.T) {
+	tests := map[string]struct {
+		name             string
+		expectedVolumeID string
+	}{
+		"When volume name is a valid DNS label": {
+			name:             "pvc-8a1f6c3e-2b4d-4f5e-9a7b-1c2d3e4f5a6b",
+			expectedVolumeID: "pvc-8a1f6c3e-2b4d-4f5e-9a7b-1c2d3e4f5a6b",
+		},
+		"When volume name is not a valid DNS label": {
+			name:             "Volume_1",
+			expectedVolumeID: getCSIVolumeID("Volume_1"),
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			volumeID := getCSIVolumeID(test.name)
+			assert.Equal(t, test.expectedVolumeID, volumeID)
+			assert.LessOrEqual(t, len("nfs-"+volumeID), 63)
+		})
+	}
+	assert.NotEqual(t, "Volume_1", getCSIVolumeID("Volume_1"))
+}
+
+func TestCSICreateSnapshot(t *testing.T) {
+	os.Setenv(string(NFSServerImageKey), "openebs/nfs-server-alpine:ci")
+	defer os.Unsetenv(string(NFSServerImageKey))
+
+	driver := &CSIDriver{
+		provisioner: newFakeCSIProvisioner(),
+		nodeID:      "node-1",
+		mounter:     newFakeMounter(),
+	}
+	p := driver.provisioner
+	executor := p.executor.(*fakeCommandExecutor)
+	ctx := context.Background()
+	mountCap := []*csi.VolumeCapability{
+		{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+		},
+	}
+
+	_, err := driver.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-1",
+		VolumeCapabilities: mountCap,
+	})
+	assert.NoError(t, err)
+
+	resp, err := driver.CreateSnapshot(ctx, &csi.CreateSnapshotRequest{
+		Name:           "snapshot-1",
+		SourceVolumeId: "pvc-1",
+		Parameters: map[string]string{
+			BackendVolumeSnapshotClass: "backend-snapclass",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "snapshot-1", resp.GetSnapshot().GetSnapshotId())
+	assert.True(t, resp.GetSnapshot().GetReadyToUse())
+
+	// filesystem must be frozen before the snapshot and thawed after it,
+	// without stopping the exports
+	assert.Equal(t, [][]string{
+		{"sync"},
+		{"fsfreeze", "--freeze", "/nfsshare"},
+		{"fsfreeze", "--unfreeze", "/nfsshare"},
+	}, executor.commands)
+
+	obj, err := p.getBackendSnapshot(ctx, "nfs-snapshot-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "pvc-1", obj.GetLabels()[snapshotSourceVolumeLabelKey])
+	assert.Equal(t, "snapshot-1", obj.GetAnnotations()[snapshotNameAnnotationKey])
+	pvcName, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "persistentVolumeClaimName")
+	assert.Equal(t, "nfs-pvc-1", pvcName)
+	snapClass, _, _ := unstructured.NestedString(obj.Object, "spec", "volumeSnapshotClassName")
+	assert.Equal(t, "backend-snapclass", snapClass)
+
+	_, err = driver.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               "pvc-2",
+		VolumeCapabilities: mountCap,
+		VolumeContentSource: &csi.VolumeContentSource{
+			Type: &csi.VolumeContentSource_Snapshot{
+				Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: "snapshot-1"},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	backendPvc, err := p.kubeClient.CoreV1().PersistentVolumeClaims("nfs-ns").Get(ctx, "nfs-pvc-2", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, getBackendSnapshotDataSource("nfs-snapshot-1"), backendPvc.Spec.DataSource)
+}
+
+func TestCSIGetPluginCapabilities(t *testing.T) {
+	tests := map[string]struct {
+		driver                  *CSIDriver
+		isControllerCapExpected bool
+	}{
+		"When driver runs the controller plugin": {
+			driver:                  NewCSIDriver(newFakeCSIProvisioner(), ""),
+			isControllerCapExpected: true,
+		},
+		"When driver runs the node plugin": {
+			driver: NewCSIDriver(nil, "node-1"),
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			resp, err := test.driver.GetPluginCapabilities(context.Background(), &csi.GetPluginCapabilitiesRequest{})
+			assert.NoError(t, err)
+
+			var hasControllerCap bool
+			for _, capability := range resp.GetCapabilities() {
+				if capability.GetService().GetType() == csi.PluginCapability_Service_CONTROLLER_SERVICE {
+					hasControllerCap = true
+				}
+			}
+			assert.Equal(t, test.isControllerCapExpected, hasControllerCap)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// csiMounter mounts and unmounts the NFS volumes on the node
+type csiMounter interface {
+	Mount(source, target string, options []string) error
+	Unmount(target string) error
+	IsMounted(target string) (bool, error)
+}
+
+// nfsMounter mounts the NFS volumes using the mount utilities of the host
+type nfsMounter struct {
+	// mountsFile lists the mount points of the host
+	mountsFile string
+}
+
+// newNFSMounter returns a new csiMounter which uses the mount
+// utilities of the host
+func newNFSMounter() csiMounter {
+	return &nfsMounter{
+		mountsFile: "/proc/mounts",
+	}
+}
+
+// Mount mounts the NFS source on the target path with the given options
+func (m *nfsMounter) Mount(source, target string, options []string) error {
+	args := []string{"-t", "nfs"}
+	if len(options) != 0 {
+		args = append(args, "-o", strings.Join(options, ","))
+	}
+	args = append(args, source, target)
+
+	out, err := exec.Command("mount", args...).CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "mount failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Unmount unmounts the target path
+func (m *nfsMounter) Unmount(target string) error {
+	out, err := exec.Command("umount", target).CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "umount failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// IsMounted returns true if the target path is a mount point
+func (m *nfsMounter) IsMounted(target string) (bool, error) {
+	target = filepath.Clean(target)
+
+	f, err := os.Open(m.mountsFile)
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to read %s", m.mountsFile)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 1 && fields[1] == target {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
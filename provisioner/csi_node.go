@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"os"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+// NodePublishVolume mounts the NFS volume on the target path
+func (d *CSIDriver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
+	volumeID := req.GetVolumeId()
+	targetPath := req.GetTargetPath()
+
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume ID is missing")
+	}
+	if len(targetPath) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "target path is missing")
+	}
+	if req.GetVolumeCapability() == nil {
+		return nil, status.Error(codes.InvalidArgument, "volume capability is missing")
+	}
+	if err := validateCSIVolumeCapabilities([]*csi.VolumeCapability{req.GetVolumeCapability()}); err != nil {
+		return nil, err
+	}
+
+	server := req.GetVolumeContext()[csiContextServer]
+	share := req.GetVolumeContext()[csiContextShare]
+	if len(server) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "volume context %q is missing", csiContextServer)
+	}
+	if len(share) == 0 {
+		share = "/"
+	}
+
+	mounted, err := d.mounter.IsMounted(targetPath)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check mount point %s: %v", targetPath, err)
+	}
+	if mounted {
+		return &csi.NodePublishVolumeResponse{}, nil
+	}
+
+	if err = os.MkdirAll(targetPath, 0750); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create target path %s: %v", targetPath, err)
+	}
+
+	options := req.GetVolumeCapability().GetMount().GetMountFlags()
+	if req.GetReadonly() {
+		options = append(options, "ro")
+	}
+
+	source := server + ":" + share
+	if err = d.mounter.Mount(source, targetPath, options); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to mount %s on %s: %v", source, targetPath, err)
+	}
+
+	klog.Infof("Volume %s mounted on %s", volumeID, targetPath)
+	return &csi.NodePublishVolumeResponse{}, nil
+}
+
+// NodeUnpublishVolume unmounts the NFS volume from the target path
+func (d *CSIDriver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
+	volumeID := req.GetVolumeId()
+	targetPath := req.GetTargetPath()
+
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "volume ID is missing")
+	}
+	if len(targetPath) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "target path is missing")
+	}
+
+	mounted, err := d.mounter.IsMounted(targetPath)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check mount point %s: %v", targetPath, err)
+	}
+	if mounted {
+		if err = d.mounter.Unmount(targetPath); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to unmount %s: %v", targetPath, err)
+		}
+	}
+
+	if err = os.Remove(targetPath); err != nil && !os.IsNotExist(err) {
+		return nil, status.Errorf(codes.Internal, "failed to remove target path %s: %v", targetPath, err)
+	}
+
+	klog.Infof("Volume %s unmounted from %s", volumeID, targetPath)
+	return &csi.NodeUnpublishVolumeResponse{}, nil
+}
+
+// NodeGetInfo returns the ID of the node
+func (d *CSIDriver) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoRequest) (*csi.NodeGetInfoResponse, error) {
+	return &csi.NodeGetInfoResponse{
+		NodeId: d.nodeID,
+	}, nil
+}
+
+// NodeGetCapabilities returns the capabilities of the node service.
+// NFS volumes are mounted directly on the target path, so staging
+// is not required.
+func (d *CSIDriver) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetCapabilitiesRequest) (*csi.NodeGetCapabilitiesResponse, error) {
+	return &csi.NodeGetCapabilitiesResponse{
+		Capabilities: []*csi.NodeServiceCapability{},
+	}, nil
+}
+
+// NodeStageVolume is not supported
+func (d *CSIDriver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+// NodeUnstageVolume is not supported
+func (d *CSIDriver) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+// NodeGetVolumeStats is not supported
+func (d *CSIDriver) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
+
+// NodeExpandVolume is not supported, NFS volumes are
+// expanded by expanding the backend volume
+func (d *CSIDriver) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandVolumeRequest) (*csi.NodeExpandVolumeResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "")
+}
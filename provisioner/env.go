@@ -48,6 +48,21 @@ const (
 	//
 	NFSServerImageKey menv.ENVKey = "OPENEBS_IO_NFS_SERVER_IMG"
 
+	// NFSGaneshaServerImageKey is the environment variable that
+	// store the container image name to be used for nfs-ganesha server deployment
+	//
+	// Note: If image name is not mentioned then provisioner.NFSGaneshaServerDefaultImage
+	//
+	NFSGaneshaServerImageKey menv.ENVKey = "OPENEBS_IO_NFS_GANESHA_SERVER_IMG"
+
+	// NFSMetricsExporterImageKey is the environment variable that
+	// store the container image name to be used for the metrics exporter
+	// sidecar of the nfs-server deployment
+	//
+	// Note: If image name is not mentioned then provisioner.NFSMetricsExporterDefaultImage
+	//
+	NFSMetricsExporterImageKey menv.ENVKey = "OPENEBS_IO_NFS_METRICS_EXPORTER_IMG"
+
 	// NFSServerNamespace defines the namespace for nfs server objects
 	// Default value is menv.OpenEBSNamespace(operator namespace)
 	NFSServerNamespace menv.ENVKey = "OPENEBS_IO_NFS_SERVER_NS"
@@ -58,21 +73,51 @@ const (
 	// NFSBackendPvcTimeout defines env name to store BackendPvcBoundTimeout value
 	NFSBackendPvcTimeout menv.ENVKey = "OPENEBS_IO_NFS_SERVER_BACKEND_PVC_TIMEOUT"
 
+	// NFSServerReadyTimeout defines env name to store the timeout(in seconds)
+	// for the NFS server to serve the volume, before provisioning of the
+	// volume is continued in the background
+	NFSServerReadyTimeout menv.ENVKey = "OPENEBS_IO_NFS_SERVER_READY_TIMEOUT"
+
 	// The NFSGarbageCollectionEnable environment variable is the switch for the garbage collector.(default true)
 	NFSGarbageCollectionEnable menv.ENVKey = "OPENEBS_IO_NFS_SERVER_GARBAGE_COLLECTION_ENABLED"
 
+	// The NFSServerReconcileEnable environment variable is the switch for the reconciler,
+	// which recreates the missing NFS server resources.(default true)
+	NFSServerReconcileEnable menv.ENVKey = "OPENEBS_IO_NFS_SERVER_RECONCILE_ENABLED"
+
 	// NFSServerImagePullSecret defines the env name to store the name of the image pull secret
 	NFSServerImagePullSecret menv.ENVKey = "OPENEBS_IO_NFS_SERVER_IMAGE_PULL_SECRET"
+
+	// NFSServerUpgradePolicy defines the env name to store the policy to roll out
+	// the NFS server Deployments which differ from the rendered spec. Supported
+	// values are manual, automatic and maintenance-window.(default manual)
+	NFSServerUpgradePolicy menv.ENVKey = "OPENEBS_IO_NFS_SERVER_UPGRADE_POLICY"
+
+	// NFSServerMaintenanceWindow defines the env name to store the window, in UTC,
+	// to roll out the NFS server Deployments with maintenance-window policy.
+	// Format is "[<days>] <start>-<end>", e.g. "Sat,Sun 02:00-04:00"
+	NFSServerMaintenanceWindow menv.ENVKey = "OPENEBS_IO_NFS_SERVER_MAINTENANCE_WINDOW"
 )
 
 var (
-	defaultNFSServerType = "kernel"
+	defaultNFSServerType = NFSServerTypeKernel
 	defaultExportsSC     = ""
 
 	// NFSServerDefaultImage specifies the image name to be used in
 	// nfs server deployment. If image name is mentioned as a env variable
 	// provisioner.NFSServerImageKey then value from env variable will be used
 	NFSServerDefaultImage string
+
+	// NFSGaneshaServerDefaultImage specifies the image name to be used in
+	// nfs-ganesha server deployment. If image name is mentioned as a env variable
+	// provisioner.NFSGaneshaServerImageKey then value from env variable will be used
+	NFSGaneshaServerDefaultImage string
+
+	// NFSMetricsExporterDefaultImage specifies the image name to be used in
+	// the metrics exporter sidecar of nfs server deployment. If image name is
+	// mentioned as a env variable provisioner.NFSMetricsExporterImageKey then
+	// value from env variable will be used
+	NFSMetricsExporterDefaultImage string
 )
 
 func getOpenEBSNamespace() string {
@@ -100,6 +145,14 @@ func getNFSServerImage() string {
 	return menv.GetOrDefault(NFSServerImageKey, string(NFSServerDefaultImage))
 }
 
+func getNFSGaneshaServerImage() string {
+	return menv.GetOrDefault(NFSGaneshaServerImageKey, string(NFSGaneshaServerDefaultImage))
+}
+
+func getNFSMetricsExporterImage() string {
+	return menv.GetOrDefault(NFSMetricsExporterImageKey, string(NFSMetricsExporterDefaultImage))
+}
+
 func getNfsServerNodeAffinity() string {
 	return menv.Get(NodeAffinityKey)
 }
@@ -108,9 +161,26 @@ func getBackendPvcTimeout() string {
 	return menv.Get(NFSBackendPvcTimeout)
 }
 
+func getNfsServerReadyTimeout() string {
+	return menv.Get(NFSServerReadyTimeout)
+}
+
 func getNfsGarbageCollectionEnable() string {
 	return menv.GetOrDefault(NFSGarbageCollectionEnable, "true")
 }
+
+func getNfsServerReconcileEnable() string {
+	return menv.GetOrDefault(NFSServerReconcileEnable, "true")
+}
+
 func getNfsServerImagePullSecret() string {
 	return menv.GetOrDefault(NFSServerImagePullSecret, "")
 }
+
+func getNfsServerUpgradePolicy() string {
+	return menv.GetOrDefault(NFSServerUpgradePolicy, string(UpgradePolicyManual))
+}
+
+func getNfsServerMaintenanceWindow() string {
+	return menv.Get(NFSServerMaintenanceWindow)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// provisionerComponentName is used as event source component
+	provisionerComponentName = "nfs-provisioner"
+
+	// Event reasons for the stages of the NFS server creation,
+	// recorded on the NFS PVC
+	backendPVCCreatedReason          = "BackendPVCCreated"
+	backendPVCCreateFailedReason     = "BackendPVCCreateFailed"
+	backendVolumeClonedReason        = "BackendVolumeCloned"
+	backendVolumeCloneFailedReason   = "BackendVolumeCloneFailed"
+	nfsServerDeploymentCreatedReason = "NFSServerDeploymentCreated"
+	nfsServerDeploymentFailedReason  = "NFSServerDeploymentCreateFailed"
+	backendPVCBoundReason            = "BackendPVCBound"
+	backendPVCPendingReason          = "BackendPVCPending"
+	backendPVHookFailedReason        = "BackendPVHookFailed"
+	nfsServerServiceCreatedReason    = "NFSServerServiceCreated"
+	nfsServerServiceFailedReason     = "NFSServerServiceCreateFailed"
+	networkPolicyCreatedReason       = "NetworkPolicyCreated"
+	networkPolicyFailedReason        = "NetworkPolicyCreateFailed"
+	nfsServerReadyReason             = "NFSServerReady"
+	nfsServerNotReadyReason          = "NFSServerNotReady"
+)
+
+// recordPVCEvent records an event on the NFS PVC of the given NFS
+// server options. Events are not recorded if the NFS PVC is unknown.
+func (p *Provisioner) recordPVCEvent(nfsServerOpts *KernelNFSServerOptions, eventtype, reason, messageFmt string, args ...interface{}) {
+	if p.recorder == nil || len(nfsServerOpts.pvcName) == 0 || len(nfsServerOpts.pvcNamespace) == 0 {
+		return
+	}
+
+	ref := &corev1.ObjectReference{
+		Kind:       "PersistentVolumeClaim",
+		APIVersion: "v1",
+		Namespace:  nfsServerOpts.pvcNamespace,
+		Name:       nfsServerOpts.pvcName,
+		UID:        types.UID(nfsServerOpts.pvcUID),
+	}
+	p.recorder.Eventf(ref, eventtype, reason, messageFmt, args...)
+}
+
+// getBackendPVCPendingReason returns the reason of the latest warning
+// event of the given backend PVC, e.g. provisioning failure of the
+// backend volume. Empty string is returned if there is no such event.
+func getBackendPVCPendingReason(ctx context.Context, client kubernetes.Interface, namespace, name string) string {
+	eventList, err := client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=PersistentVolumeClaim,involvedObject.name=" + name,
+	})
+	if err != nil {
+		klog.V(4).Infof("Failed to list events of PVC %s/%s, err=%v", namespace, name, err)
+		return ""
+	}
+
+	var latest *corev1.Event
+	for i := range eventList.Items {
+		event := &eventList.Items[i]
+		if event.Type != corev1.EventTypeWarning ||
+			event.InvolvedObject.Kind != "PersistentVolumeClaim" || event.InvolvedObject.Name != name {
+			continue
+		}
+		if latest == nil || latest.LastTimestamp.Before(&event.LastTimestamp) {
+			latest = event
+		}
+	}
+	if latest == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s", latest.Reason, latest.Message)
+}
+
+// getNFSServerPodReason returns the reason due to which the pods of the
+// given NFS server Deployment are not ready, i.e. the scheduling failure
+// or the container waiting reason. Empty string is returned if the reason
+// is unknown.
+func getNFSServerPodReason(ctx context.Context, client kubernetes.Interface, namespace, deployName string) string {
+	podList, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: nfsServerLabelKey + "=" + deployName,
+	})
+	if err != nil {
+		klog.V(4).Infof("Failed to list pods of NFS server %s/%s, err=%v", namespace, deployName, err)
+		return ""
+	}
+
+	for _, pod := range podList.Items {
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+				return fmt.Sprintf("pod %s is not scheduled: %s: %s", pod.Name, cond.Reason, cond.Message)
+			}
+		}
+
+		var statuses []corev1.ContainerStatus
+		statuses = append(statuses, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if status.State.Waiting != nil && len(status.State.Waiting.Reason) != 0 {
+				return fmt.Sprintf("container %s of pod %s is waiting: %s: %s",
+					status.Name, pod.Name, status.State.Waiting.Reason, status.State.Waiting.Message)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func getFakePVCEvent(namespace, pvcName, name, eventType, reason, message string, ts time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "PersistentVolumeClaim",
+			Namespace: namespace,
+			Name:      pvcName,
+		},
+		Type:          eventType,
+		Reason:        reason,
+		Message:       message,
+		LastTimestamp: metav1.NewTime(ts),
+	}
+}
+
+func TestGetBackendPVCPendingReason(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		objs           []runtime.Object
+		expectedReason string
+	}{
+		"when backend PVC has no events": {},
+		"when backend PVC has warning events": {
+			objs: []runtime.Object{
+				getFakePVCEvent("nfs-ns", "nfs-pv1", "e1", corev1.EventTypeWarning,
+					"ProvisioningFailed", "storageclass not found", now.Add(-time.Minute)),
+				getFakePVCEvent("nfs-ns", "nfs-pv1", "e2", corev1.EventTypeWarning,
+					"ProvisioningFailed", "no space left", now),
+				getFakePVCEvent("nfs-ns", "nfs-pv1", "e3", corev1.EventTypeNormal,
+					"WaitForFirstConsumer", "waiting for first consumer", now.Add(time.Minute)),
+			},
+			expectedReason: "ProvisioningFailed: no space left",
+		},
+		"when warning event belongs to other PVC": {
+			objs: []runtime.Object{
+				getFakePVCEvent("nfs-ns", "nfs-pv2", "e1", corev1.EventTypeWarning,
+					"ProvisioningFailed", "storageclass not found", now),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(test.objs...)
+			reason := getBackendPVCPendingReason(context.TODO(), client, "nfs-ns", "nfs-pv1")
+			assert.Equal(t, test.expectedReason, reason)
+		})
+	}
+}
+
+func TestGetNFSServerPodReason(t *testing.T) {
+	getPod := func(status corev1.PodStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "nfs-ns",
+				Name:      "nfs-pv1-abc",
+				Labels:    map[string]string{nfsServerLabelKey: "nfs-pv1"},
+			},
+			Status: status,
+		}
+	}
+
+	tests := map[string]struct {
+		objs           []runtime.Object
+		expectedReason string
+	}{
+		"when NFS server pod doesn't exist": {},
+		"when NFS server pod is unschedulable": {
+			objs: []runtime.Object{
+				getPod(corev1.PodStatus{
+					Conditions: []corev1.PodCondition{
+						{
+							Type:    corev1.PodScheduled,
+							Status:  corev1.ConditionFalse,
+							Reason:  "Unschedulable",
+							Message: "0/3 nodes are available",
+						},
+					},
+				}),
+			},
+			expectedReason: "pod nfs-pv1-abc is not scheduled: Unschedulable: 0/3 nodes are available",
+		},
+		"when NFS server container is waiting": {
+			objs: []runtime.Object{
+				getPod(corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name: "nfs-server",
+							State: corev1.ContainerState{
+								Waiting: &corev1.ContainerStateWaiting{
+									Reason:  "ImagePullBackOff",
+									Message: "image not found",
+								},
+							},
+						},
+					},
+				}),
+			},
+			expectedReason: "container nfs-server of pod nfs-pv1-abc is waiting: ImagePullBackOff: image not found",
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(test.objs...)
+			reason := getNFSServerPodReason(context.TODO(), client, "nfs-ns", "nfs-pv1")
+			assert.Equal(t, test.expectedReason, reason)
+		})
+	}
+}
+
+func TestCreateNFSServerEvents(t *testing.T) {
+	os.Setenv(string(NFSServerImageKey), "openebs/nfs-server:ci")
+	defer os.Unsetenv(string(NFSServerImageKey))
+
+	recorder := record.NewFakeRecorder(10)
+	p := &Provisioner{
+		kubeClient: fake.NewSimpleClientset(
+			getFakePVCEvent("nfs-ns", "nfs-pv1", "e1", corev1.EventTypeWarning,
+				"ProvisioningFailed", "storageclass.storage.k8s.io \"test-sc\" not found", time.Now()),
+		),
+		serverNamespace:   "nfs-ns",
+		backendPvcTimeout: time.Second,
+		recorder:          recorder,
+	}
+	opts := &KernelNFSServerOptions{
+		provisionerNS:       "openebs",
+		pvName:              "pv1",
+		capacity:            "5G",
+		pvcName:             "app-pvc",
+		pvcNamespace:        "app",
+		backendStorageClass: "test-sc",
+		deploymentName:      "nfs-pv1",
+		ctx:                 context.TODO(),
+	}
+
+	err := p.createNFSServer(opts)
+	assert.Error(t, err)
+
+	var events []string
+	close(recorder.Events)
+	for event := range recorder.Events {
+		events = append(events, event)
+	}
+	if !assert.Len(t, events, 3) {
+		return
+	}
+	assert.True(t, strings.HasPrefix(events[0], "Normal "+backendPVCCreatedReason), events[0])
+	assert.True(t, strings.HasPrefix(events[1], "Normal "+nfsServerDeploymentCreatedReason), events[1])
+	assert.True(t, strings.HasPrefix(events[2], "Warning "+backendPVCPendingReason), events[2])
+	assert.Contains(t, events[2], "ProvisioningFailed: storageclass.storage.k8s.io \"test-sc\" not found")
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// commandExecutor executes the command in the given container of a pod
+type commandExecutor interface {
+	Exec(ctx context.Context, namespace, podName, containerName string, command []string) (stdout string, stderr string, err error)
+}
+
+// podCommandExecutor executes the command in a pod using the
+// exec subresource of the pod
+type podCommandExecutor struct {
+	kubeClient clientset.Interface
+	config     *rest.Config
+}
+
+// newPodCommandExecutor returns a new commandExecutor which executes
+// the commands using the given client and config
+func newPodCommandExecutor(kubeClient clientset.Interface, config *rest.Config) commandExecutor {
+	return &podCommandExecutor{
+		kubeClient: kubeClient,
+		config:     config,
+	}
+}
+
+// Exec executes the given command in namespace/podName/containerName
+// and returns the stdout and stderr of the command
+func (e *podCommandExecutor) Exec(ctx context.Context, namespace, podName, containerName string, command []string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+
+	req := e.kubeClient.CoreV1().
+		RESTClient().
+		Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Command:   command,
+			Container: containerName,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	exec, err := remotecommand.NewSPDYExecutor(e.config, "POST", req.URL())
+	if err != nil {
+		return "", "", errors.Wrapf(err, "failed to create executor for pod %s/%s", namespace, podName)
+	}
+
+	err = exec.Stream(remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		return stdout.String(), stderr.String(), errors.Wrapf(err, "failed to execute command %v in pod %s/%s", command, namespace, podName)
+	}
+
+	return stdout.String(), stderr.String(), nil
+}
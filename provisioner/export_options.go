@@ -0,0 +1,180 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SquashNone doesn't map any user to the anonymous user
+	SquashNone = "none"
+
+	// SquashRoot maps the root user to the anonymous user
+	SquashRoot = "root"
+
+	// SquashAll maps all the users to the anonymous user
+	SquashAll = "all"
+)
+
+// exportClientRegex matches the client specification of an export,
+// i.e. a host name, wildcard, IP network or netgroup
+var exportClientRegex = regexp.MustCompile(`^[A-Za-z0-9_.*?:/@\[\]-]+$`)
+
+// exportOptions defines the export configuration of the kernel NFS server
+type exportOptions struct {
+	readOnly     bool
+	sync         bool
+	squash       string
+	anonUID      string
+	anonGID      string
+	permitted    []string
+	secure       bool
+	subtreeCheck bool
+}
+
+// newExportOptions returns the default export options of the NFS server
+func newExportOptions() *exportOptions {
+	return &exportOptions{
+		squash:    SquashNone,
+		permitted: []string{"*"},
+	}
+}
+
+// parseExportOptions validates the given ExportOptions data and returns
+// the export options. Options which are not set have the default value
+// of the NFS server export
+func parseExportOptions(data map[string]string) (*exportOptions, error) {
+	opts := newExportOptions()
+
+	var err error
+	for key, val := range data {
+		val = strings.TrimSpace(val)
+		if len(val) == 0 {
+			continue
+		}
+
+		switch key {
+		case ExportSync:
+			opts.sync, err = strconv.ParseBool(val)
+		case ExportSecure:
+			opts.secure, err = strconv.ParseBool(val)
+		case ExportSubtreeCheck:
+			opts.subtreeCheck, err = strconv.ParseBool(val)
+		case ExportSquash:
+			if val != SquashNone && val != SquashRoot && val != SquashAll {
+				err = errors.Errorf("must be one of %s, %s or %s", SquashNone, SquashRoot, SquashAll)
+			}
+			opts.squash = val
+		case ExportAnonUID:
+			_, err = strconv.ParseUint(val, 10, 32)
+			opts.anonUID = val
+		case ExportAnonGID:
+			_, err = strconv.ParseUint(val, 10, 32)
+			opts.anonGID = val
+		case ExportPermitted:
+			opts.permitted, err = parseExportClients(val)
+		default:
+			return nil, errors.Errorf("unknown option %q", key)
+		}
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s value %q", key, val)
+		}
+	}
+
+	if opts.squash == SquashNone && (len(opts.anonUID) != 0 || len(opts.anonGID) != 0) {
+		return nil, errors.Errorf("%s and %s can't be used with %s=%s",
+			ExportAnonUID, ExportAnonGID, ExportSquash, SquashNone)
+	}
+	return opts, nil
+}
+
+// parseExportClients returns the clients of the given comma
+// separated list of client specifications
+func parseExportClients(val string) ([]string, error) {
+	var clients []string
+	for _, client := range strings.Split(val, ",") {
+		client = strings.TrimSpace(client)
+		if !exportClientRegex.MatchString(client) {
+			return nil, errors.Errorf("invalid client %q", client)
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+// options returns the export options in the format of exports(5)
+func (e *exportOptions) options() string {
+	opts := []string{"rw", "fsid=0"}
+	if e.readOnly {
+		opts[0] = "ro"
+	}
+
+	if e.sync {
+		opts = append(opts, "sync")
+	} else {
+		opts = append(opts, "async")
+	}
+
+	if e.subtreeCheck {
+		opts = append(opts, "subtree_check")
+	} else {
+		opts = append(opts, "no_subtree_check")
+	}
+
+	opts = append(opts, "no_auth_nlm")
+
+	if e.secure {
+		opts = append(opts, "secure")
+	} else {
+		opts = append(opts, "insecure")
+	}
+
+	switch e.squash {
+	case SquashRoot:
+		opts = append(opts, "root_squash")
+	case SquashAll:
+		opts = append(opts, "all_squash")
+	default:
+		opts = append(opts, "no_root_squash")
+	}
+
+	if len(e.anonUID) != 0 {
+		opts = append(opts, "anonuid="+e.anonUID)
+	}
+	if len(e.anonGID) != 0 {
+		opts = append(opts, "anongid="+e.anonGID)
+	}
+	return strings.Join(opts, ",")
+}
+
+// exportsConfig returns the exports(5) entry which exports the
+// given path to the permitted clients
+func (e *exportOptions) exportsConfig(path string) string {
+	opts := e.options()
+
+	entry := []string{path}
+	for _, client := range e.permitted {
+		entry = append(entry, client+"("+opts+")")
+	}
+	return strings.Join(entry, " ")
+}
@@ -19,8 +19,10 @@ package provisioner
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
 	mayav1alpha1 "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
 	errors "github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -30,18 +32,24 @@ import (
 	"k8s.io/klog/v2"
 )
 
+const (
+	// Kinds of the stale resources deleted by the garbage collector,
+	// used in metrics
+	gcResourceNFSServer       = "NFSServer"
+	gcResourceSharedNFSVolume = "SharedNFSVolume"
+	gcResourceNetworkPolicy   = "NetworkPolicy"
+)
+
 var (
 	// GarbageCollectorInterval defines periodic interval to run garbage collector
 	GarbageCollectorInterval = 5 * time.Minute
 )
 
-func RunGarbageCollector(ctx context.Context, client kubernetes.Interface, pvTracker ProvisioningTracker, ns string) {
+func RunGarbageCollector(ctx context.Context, client kubernetes.Interface, executor commandExecutor, pvTracker ProvisioningTracker, ns string) {
 	// NewTicker sends tick only after mentioned interval.
 	// So to ensure that the garbage collector gets executed at the beginning,
 	// we are running it here.
-	klog.V(4).Infof("Running garbage collector for stale NFS resources")
-	err := cleanUpStalePvc(ctx, client, pvTracker, ns)
-	klog.V(4).Infof("Garbage collection completed for stale NFS resources with error=%v", err)
+	runGarbageCollection(ctx, client, executor, pvTracker, ns)
 
 	ticker := time.NewTicker(GarbageCollectorInterval)
 
@@ -51,15 +59,26 @@ func RunGarbageCollector(ctx context.Context, client kubernetes.Interface, pvTra
 			ticker.Stop()
 			return
 		case <-ticker.C:
-			klog.V(4).Infof("Running garbage collector for stale NFS resources")
-			err = cleanUpStalePvc(ctx, client, pvTracker, ns)
-			klog.V(4).Infof("Garbage collection completed for stale NFS resources with error=%v", err)
+			runGarbageCollection(ctx, client, executor, pvTracker, ns)
 		}
 	}
 }
 
+// runGarbageCollection performs a single run of the garbage collector
+func runGarbageCollection(ctx context.Context, client kubernetes.Interface, executor commandExecutor, pvTracker ProvisioningTracker, ns string) {
+	metrics.GarbageCollectorRunTotal.Inc()
+
+	klog.V(4).Infof("Running garbage collector for stale NFS resources")
+	err := cleanUpStalePvc(ctx, client, pvTracker, ns)
+	klog.V(4).Infof("Garbage collection completed for stale NFS resources with error=%v", err)
+	err = cleanUpStaleSharedVolumes(ctx, client, executor, pvTracker, ns)
+	klog.V(4).Infof("Garbage collection completed for stale shared NFS volumes with error=%v", err)
+	err = cleanUpStaleNetworkPolicies(ctx, client, pvTracker, ns)
+	klog.V(4).Infof("Garbage collection completed for stale NFS NetworkPolicies with error=%v", err)
+}
+
 func cleanUpStalePvc(ctx context.Context, client kubernetes.Interface, pvTracker ProvisioningTracker, ns string) error {
-	backendPvcLabel := fmt.Sprintf("%s=%s", mayav1alpha1.CASTypeKey, "nfs-kernel")
+	backendPvcLabel := fmt.Sprintf("%s in (nfs-%s,nfs-%s)", mayav1alpha1.CASTypeKey, NFSServerTypeKernel, NFSServerTypeGanesha)
 	pvcList, err := client.CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{LabelSelector: backendPvcLabel})
 	if err != nil {
 		klog.Errorf("Failed to list PVC, err=%s", err)
@@ -67,6 +86,18 @@ func cleanUpStalePvc(ctx context.Context, client kubernetes.Interface, pvTracker
 	}
 
 	for _, pvc := range pvcList.Items {
+		if _, ok := pvc.Labels[sharedNFSServerLabelKey]; ok {
+			// backend PVC of shared NFS server is not owned by
+			// a single NFS PVC, it is handled by cleanUpStaleSharedVolumes
+			continue
+		}
+
+		if _, ok := pvc.Labels[csiVolumeLabelKey]; ok {
+			// backend PVC of CSI volume is deleted by the CSI driver
+			// on DeleteVolume request
+			continue
+		}
+
 		pvcExists, err := nfsPvcExists(ctx, client, pvc)
 		if err != nil {
 			// failed to check NFS PVC existence,
@@ -105,17 +136,120 @@ func cleanUpStalePvc(ctx context.Context, client kubernetes.Interface, pvTracker
 		}
 
 		// perform cleanup for stale NFS resource for this backend PVC
-		err = deleteBackendStaleResources(ctx, client, pvc.Namespace, nfsPvName)
+		// NFS server type is recorded on the backend PVC, since NFS PV
+		// doesn't exist anymore
+		serverType := strings.TrimPrefix(pvc.Labels[string(mayav1alpha1.CASTypeKey)], "nfs-")
+		err = deleteBackendStaleResources(ctx, client, pvc.Namespace, nfsPvName, serverType)
 		if err != nil {
 			klog.Errorf("Failed to delete NFS resources for backendPVC=%s/%s, err=%v", ns, pvc.Name, err)
+			continue
 		}
+		metrics.GarbageCollectorDeleteTotal.WithLabelValues(gcResourceNFSServer).Inc()
 	}
 
 	return nil
 }
 
-func deleteBackendStaleResources(ctx context.Context, client kubernetes.Interface, nfsServerNs, nfsPvName string) error {
-	klog.Infof("Deleting stale resources for PV=%s", nfsPvName)
+// cleanUpStaleSharedVolumes removes the volume directories of the shared
+// NFS servers whose NFS PV doesn't exist. If all the volumes of a shared
+// NFS server are removed and its StorageClass doesn't exist, then the
+// shared NFS server is removed.
+func cleanUpStaleSharedVolumes(ctx context.Context, client kubernetes.Interface, executor commandExecutor, pvTracker ProvisioningTracker, ns string) error {
+	pvcList, err := client.CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{LabelSelector: sharedNFSServerLabelKey})
+	if err != nil {
+		klog.Errorf("Failed to list shared NFS server PVC, err=%s", err)
+		return err
+	}
+
+	for _, pvc := range pvcList.Items {
+		serverName := pvc.Labels[sharedNFSServerLabelKey]
+
+		pvList, err := client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s", sharedNFSServerLabelKey, serverName),
+		})
+		if err != nil {
+			klog.Errorf("Failed to list NFS PVs of shared NFS server=%s, err=%v", serverName, err)
+			continue
+		}
+
+		if len(pvList.Items) == 0 {
+			scExists, err := sharedNFSServerStorageClassExists(ctx, client, serverName)
+			if err != nil {
+				klog.Errorf("Failed to check StorageClass of shared NFS server=%s, err=%v", serverName, err)
+				continue
+			}
+			if !scExists {
+				// no more volumes can be provisioned on the shared NFS server
+				err = deleteBackendStaleResources(ctx, client, pvc.Namespace, serverName, NFSServerTypeKernel)
+				if err != nil {
+					klog.Errorf("Failed to delete shared NFS server=%s, err=%v", serverName, err)
+					continue
+				}
+				metrics.GarbageCollectorDeleteTotal.WithLabelValues(gcResourceNFSServer).Inc()
+				continue
+			}
+		}
+
+		volumes, err := listSharedNFSVolumes(ctx, client, executor, ns, serverName)
+		if err != nil {
+			klog.Errorf("Failed to list volumes of shared NFS server=%s, err=%v", serverName, err)
+			continue
+		}
+
+		existingPvs := map[string]bool{}
+		for _, pv := range pvList.Items {
+			existingPvs[pv.Name] = true
+		}
+
+		// NFS PV is created only after the volume directory is added and
+		// the provisioning is completed, so the staleness of the volume is
+		// decided on the existence of its NFS PVC. NFS PVCs are listed after
+		// the volume directories, so that the NFS PVC of every listed
+		// volume directory is also listed, unless it is deleted.
+		nfsPvcUIDs, err := listNFSPvcUIDs(ctx, client)
+		if err != nil {
+			klog.Errorf("Failed to list NFS PVCs of shared NFS server=%s, err=%v", serverName, err)
+			continue
+		}
+
+		for _, volume := range volumes {
+			if existingPvs[volume] || pvTracker.Inprogress(volume) {
+				continue
+			}
+
+			if nfsPvcUIDs[getNFSPvcUID(volume)] {
+				// NFS PVC of the volume exists, NFS PV is yet to be
+				// created or it is deleted with Retain reclaim policy
+				continue
+			}
+
+			// NFS PV may be created after the NFS PVs were listed
+			exists, err := pvExists(ctx, client, volume)
+			if err != nil {
+				klog.Errorf("Failed to check NFS PV of volume=%s of shared NFS server=%s, err=%v", volume, serverName, err)
+				continue
+			}
+			if exists {
+				continue
+			}
+
+			// Neither NFS PVC nor NFS PV exists for the volume directory, it
+			// can happen if those were deleted while the shared NFS server was down
+			klog.Infof("Deleting stale volume=%s of shared NFS server=%s", volume, serverName)
+			err = removeSharedNFSVolume(ctx, client, executor, ns, serverName, volume)
+			if err != nil {
+				klog.Errorf("Failed to delete volume=%s of shared NFS server=%s, err=%v", volume, serverName, err)
+				continue
+			}
+			metrics.GarbageCollectorDeleteTotal.WithLabelValues(gcResourceSharedNFSVolume).Inc()
+		}
+	}
+
+	return nil
+}
+
+func deleteBackendStaleResources(ctx context.Context, client kubernetes.Interface, nfsServerNs, nfsPvName, serverType string) error {
+	klog.Infof("Deleting stale resources for PV=%s of NFS server type=%s", nfsPvName, serverType)
 
 	p := &Provisioner{
 		kubeClient:      client,
@@ -123,8 +257,9 @@ func deleteBackendStaleResources(ctx context.Context, client kubernetes.Interfac
 	}
 
 	nfsServerOpts := &KernelNFSServerOptions{
-		pvName: nfsPvName,
-		ctx:    ctx,
+		pvName:     nfsPvName,
+		serverType: serverType,
+		ctx:        ctx,
 	}
 
 	return p.deleteNFSServer(nfsServerOpts)
@@ -159,6 +294,44 @@ func nfsPvcExists(ctx context.Context, client kubernetes.Interface, backendPvcOb
 	return true, nil
 }
 
+// listNFSPvcUIDs returns the UIDs of all the PVCs of the cluster
+func listNFSPvcUIDs(ctx context.Context, client kubernetes.Interface) (map[string]bool, error) {
+	pvcList, err := client.CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	uids := make(map[string]bool, len(pvcList.Items))
+	for _, pvc := range pvcList.Items {
+		uids[string(pvc.UID)] = true
+	}
+	return uids, nil
+}
+
+// getNFSPvcUID returns the UID of the NFS PVC of the given NFS PV name.
+// NFS PVs are named as pvc-<NFS PVC UID> by the provisioner controller.
+func getNFSPvcUID(pvName string) string {
+	return strings.TrimPrefix(pvName, "pvc-")
+}
+
+// sharedNFSServerStorageClassExists returns true if the StorageClass of
+// the given shared NFS server exists. Shared NFS server name may contain
+// the hash of the StorageClass name, so the StorageClasses are listed
+// and matched by their shared NFS server name.
+func sharedNFSServerStorageClassExists(ctx context.Context, client kubernetes.Interface, serverName string) (bool, error) {
+	scList, err := client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	for _, sc := range scList.Items {
+		if getSharedNFSServerName(sc.Name) == serverName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func pvExists(ctx context.Context, client kubernetes.Interface, pvName string) (bool, error) {
 	_, err := client.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
 	if err == nil {
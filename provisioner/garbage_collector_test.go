@@ -65,6 +65,12 @@ func generateBackendPvcLabel(nfsPvcNs, nfsPvcName, nfsPvcUID, nfsPvName string)
 	}
 }
 
+func generateGaneshaBackendPvcLabel(nfsPvcNs, nfsPvcName, nfsPvcUID, nfsPvName string) map[string]string {
+	labels := generateBackendPvcLabel(nfsPvcNs, nfsPvcName, nfsPvcUID, nfsPvName)
+	labels["openebs.io/cas-type"] = "nfs-ganesha"
+	return labels
+}
+
 func getProvisioningTracker(pvName ...string) ProvisioningTracker {
 	tracker := NewProvisioningTracker()
 
@@ -93,7 +99,7 @@ func TestRunGarbageCollector(t *testing.T) {
 	assert.NoError(t, createService(clientset, nfsService), "on creating nfs-server service resourec")
 
 	ctx, cancelFn := context.WithCancel(context.TODO())
-	go RunGarbageCollector(ctx, clientset, pvTracker, nfsServerNs)
+	go RunGarbageCollector(ctx, clientset, newFakeCommandExecutor(), pvTracker, nfsServerNs)
 
 	time.Sleep(GarbageCollectorInterval + 10*time.Second /* to ensure cleanUpStalePvc run */)
 	cancelFn()
@@ -219,6 +225,18 @@ func TestCleanUpStalePvc(t *testing.T) {
 			nfsDeployment: getFakeDeploymentObject(nfsServerNs, "nfs-pv7"),
 			nfsService:    getFakeServiceObject(nfsServerNs, "nfs-pv7"),
 		},
+		{
+			name: "when NFS PVC and NFS PV of ganesha server type doesn't exist, NFS resources should be destroyed",
+
+			clientset:     fake.NewSimpleClientset(),
+			pvTracker:     getProvisioningTracker(),
+			shouldCleanup: true,
+
+			backendPvc: generateFakePvcObj(nfsServerNs, "nfs-pv9", "backend-pvc9-uid", corev1.ClaimBound,
+				generateGaneshaBackendPvcLabel("ns9", "pvc9", "uid9", "pv9")),
+			nfsDeployment: getFakeDeploymentObject(nfsServerNs, "nfs-pv9"),
+			nfsService:    getFakeServiceObject(nfsServerNs, "nfs-pv9"),
+		},
 		{
 			name: "when backend PVC is not having nfs-pvc labels, backend PVC should not be removed",
 
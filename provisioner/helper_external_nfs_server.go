@@ -0,0 +1,223 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"time"
+
+	container "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/container"
+	errors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// externalNFSArchiveAnnotation is set on the NFS PV created on the
+	// external NFS server, if the volume directory should be archived
+	// instead of being removed on volume deletion
+	externalNFSArchiveAnnotation = "nfs.openebs.io/archive-on-delete"
+
+	// externalNFSArchivePrefix is the prefix of the archived volume directory
+	externalNFSArchivePrefix = "archived-"
+
+	// externalNFSHelperLabelKey is set on the helper pods, which manage
+	// the volume directories on the external NFS server
+	externalNFSHelperLabelKey = "openebs.io/nfs-helper"
+
+	// externalNFSMountPath is the path where the external NFS export
+	// is mounted in the helper pod
+	externalNFSMountPath = "/export"
+)
+
+const (
+	// createVolumeDirScript creates the volume directory $1 and applies
+	// the file permissions (owner $2, group $3, mode $4), if specified.
+	// Arguments are passed as positional parameters to avoid shell injection.
+	createVolumeDirScript = `set -e
+dir=` + externalNFSMountPath + `/$1
+mkdir -p "$dir"
+if [ -n "$2" ]; then chown "$2" "$dir"; fi
+if [ -n "$3" ]; then chown ":$3" "$dir"; fi
+if [ -n "$4" ]; then chmod "$4" "$dir"; fi`
+
+	// removeVolumeDirScript removes the volume directory $1
+	removeVolumeDirScript = `set -e
+dir=` + externalNFSMountPath + `/$1
+if [ -e "$dir" ]; then rm -rf "$dir"; fi`
+
+	// archiveVolumeDirScript renames the volume directory $1 to $2
+	archiveVolumeDirScript = `set -e
+dir=` + externalNFSMountPath + `/$1
+if [ -e "$dir" ]; then mv "$dir" "` + externalNFSMountPath + `/$2"; fi`
+)
+
+// ExternalNFSServerOptions contains the details of the external NFS
+// server export, on which the volume directory is managed
+type ExternalNFSServerOptions struct {
+	// pvName is the name of the NFS PV, used as the volume directory name
+	pvName string
+
+	// server is the address of the external NFS server
+	server string
+
+	// exportPath is the path exported by the external NFS server
+	exportPath string
+
+	// permissionsUID, permissionsGID and permissionsMode are
+	// applied on the volume directory, if specified
+	permissionsUID  string
+	permissionsGID  string
+	permissionsMode string
+
+	// ctx defines the context which is usually populated from callers
+	ctx context.Context
+}
+
+// createVolumeDirectory creates the volume directory on the external
+// NFS server export, using a helper pod
+func (p *Provisioner) createVolumeDirectory(opts *ExternalNFSServerOptions) error {
+	klog.V(4).Infof("Creating volume directory %s on external NFS server %s:%s", opts.pvName, opts.server, opts.exportPath)
+
+	return p.runExternalNFSHelperPod(opts, "init-"+opts.pvName,
+		[]string{createVolumeDirScript, opts.pvName, opts.permissionsUID, opts.permissionsGID, opts.permissionsMode})
+}
+
+// removeVolumeDirectory removes the volume directory from the external
+// NFS server export, using a helper pod
+func (p *Provisioner) removeVolumeDirectory(opts *ExternalNFSServerOptions) error {
+	klog.V(4).Infof("Removing volume directory %s from external NFS server %s:%s", opts.pvName, opts.server, opts.exportPath)
+
+	return p.runExternalNFSHelperPod(opts, "cleanup-"+opts.pvName,
+		[]string{removeVolumeDirScript, opts.pvName})
+}
+
+// archiveVolumeDirectory renames the volume directory on the external
+// NFS server export to archived-<volume name>, using a helper pod
+func (p *Provisioner) archiveVolumeDirectory(opts *ExternalNFSServerOptions) error {
+	klog.V(4).Infof("Archiving volume directory %s on external NFS server %s:%s", opts.pvName, opts.server, opts.exportPath)
+
+	return p.runExternalNFSHelperPod(opts, "cleanup-"+opts.pvName,
+		[]string{archiveVolumeDirScript, opts.pvName, externalNFSArchivePrefix + opts.pvName})
+}
+
+// runExternalNFSHelperPod runs the given script in a helper pod which mounts
+// the external NFS server export, and waits for the pod to complete. The
+// helper pod is deleted once it completes.
+func (p *Provisioner) runExternalNFSHelperPod(opts *ExternalNFSServerOptions, podName string, script []string) error {
+	containerObj, err := container.NewBuilder().
+		WithName("helper").
+		WithImage(getNFSServerImage()).
+		WithImagePullPolicy(corev1.PullIfNotPresent).
+		WithCommandNew([]string{"/bin/sh", "-c"}).
+		// first argument after the script is used as $0
+		WithArgumentsNew(append([]string{script[0], "helper"}, script[1:]...)).
+		WithVolumeMountsNew(
+			[]corev1.VolumeMount{
+				{
+					Name:      "export-dir",
+					MountPath: externalNFSMountPath,
+				},
+			},
+		).
+		Build()
+	if err != nil {
+		return errors.Wrapf(err, "unable to build helper container")
+	}
+
+	podObj := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      podName,
+			Namespace: p.serverNamespace,
+			Labels: map[string]string{
+				externalNFSHelperLabelKey: opts.pvName,
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers:    []corev1.Container{containerObj},
+			Volumes: []corev1.Volume{
+				{
+					Name: "export-dir",
+					VolumeSource: corev1.VolumeSource{
+						NFS: &corev1.NFSVolumeSource{
+							Server: opts.server,
+							Path:   opts.exportPath,
+						},
+					},
+				},
+			},
+		},
+	}
+	if secret := getNfsServerImagePullSecret(); secret != "" {
+		podObj.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: secret}}
+	}
+
+	_, err = p.kubeClient.CoreV1().
+		Pods(p.serverNamespace).
+		Create(opts.ctx, podObj, metav1.CreateOptions{})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "failed to create helper pod {%s/%s}", p.serverNamespace, podName)
+	}
+
+	defer func() {
+		e := p.kubeClient.CoreV1().
+			Pods(p.serverNamespace).
+			Delete(opts.ctx, podName, metav1.DeleteOptions{})
+		if e != nil && !k8serrors.IsNotFound(e) {
+			klog.Errorf("Failed to delete helper pod {%s/%s}, err=%v", p.serverNamespace, podName, e)
+		}
+	}()
+
+	return p.waitForHelperPod(opts.ctx, podName, p.backendPvcTimeout)
+}
+
+// waitForHelperPod waits for the given helper pod to complete, for the
+// timeout period. It returns error if the pod fails.
+func (p *Provisioner) waitForHelperPod(ctx context.Context, podName string, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	timeoutCh := timer.C
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	for {
+		pod, err := p.kubeClient.CoreV1().
+			Pods(p.serverNamespace).
+			Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get helper pod {%s/%s}", p.serverNamespace, podName)
+		}
+
+		switch pod.Status.Phase {
+		case corev1.PodSucceeded:
+			return nil
+		case corev1.PodFailed:
+			return errors.Errorf("helper pod {%s/%s} failed: %s", p.serverNamespace, podName, pod.Status.Message)
+		}
+
+		select {
+		case <-timeoutCh:
+			return errors.Errorf("timed out waiting for helper pod {%s/%s} to complete", p.serverNamespace, podName)
+		case <-tick.C:
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newFakeHelperPodClientset returns a fake clientset which completes
+// the created helper pods with the given phase, and records them
+func newFakeHelperPodClientset(phase corev1.PodPhase, createdPods *[]*corev1.Pod) *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		pod.Status.Phase = phase
+		*createdPods = append(*createdPods, pod.DeepCopy())
+		return false, nil, nil
+	})
+	return client
+}
+
+func TestExternalNFSVolumeDirectory(t *testing.T) {
+	tests := map[string]struct {
+		phase           corev1.PodPhase
+		operation       func(*Provisioner, *ExternalNFSServerOptions) error
+		expectedPodName string
+		expectedArgs    []string
+		isErrExpected   bool
+	}{
+		"when volume directory is created": {
+			phase:           corev1.PodSucceeded,
+			operation:       (*Provisioner).createVolumeDirectory,
+			expectedPodName: "init-pvc-1",
+			expectedArgs:    []string{createVolumeDirScript, "helper", "pvc-1", "1000", "2000", "0755"},
+		},
+		"when volume directory is removed": {
+			phase:           corev1.PodSucceeded,
+			operation:       (*Provisioner).removeVolumeDirectory,
+			expectedPodName: "cleanup-pvc-1",
+			expectedArgs:    []string{removeVolumeDirScript, "helper", "pvc-1"},
+		},
+		"when volume directory is archived": {
+			phase:           corev1.PodSucceeded,
+			operation:       (*Provisioner).archiveVolumeDirectory,
+			expectedPodName: "cleanup-pvc-1",
+			expectedArgs:    []string{archiveVolumeDirScript, "helper", "pvc-1", "archived-pvc-1"},
+		},
+		"when helper pod fails": {
+			phase:           corev1.PodFailed,
+			operation:       (*Provisioner).createVolumeDirectory,
+			expectedPodName: "init-pvc-1",
+			expectedArgs:    []string{createVolumeDirScript, "helper", "pvc-1", "1000", "2000", "0755"},
+			isErrExpected:   true,
+		},
+	}
+	os.Setenv(string(NFSServerImageKey), "openebs/nfs-server-alpine:ci")
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			var createdPods []*corev1.Pod
+			p := &Provisioner{
+				kubeClient:        newFakeHelperPodClientset(test.phase, &createdPods),
+				serverNamespace:   "nfs-ns",
+				backendPvcTimeout: 5 * time.Second,
+			}
+			opts := &ExternalNFSServerOptions{
+				pvName:          "pvc-1",
+				server:          "10.0.0.10",
+				exportPath:      "/exports",
+				permissionsUID:  "1000",
+				permissionsGID:  "2000",
+				permissionsMode: "0755",
+				ctx:             context.TODO(),
+			}
+
+			err := test.operation(p, opts)
+			if test.isErrExpected {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			if assert.Len(t, createdPods, 1) {
+				pod := createdPods[0]
+				assert.Equal(t, test.expectedPodName, pod.Name)
+				assert.Equal(t, test.expectedArgs, pod.Spec.Containers[0].Args)
+				assert.Equal(t, "10.0.0.10", pod.Spec.Volumes[0].NFS.Server)
+				assert.Equal(t, "/exports", pod.Spec.Volumes[0].NFS.Path)
+			}
+
+			// helper pod should be removed once it is completed
+			_, err = p.kubeClient.CoreV1().Pods("nfs-ns").Get(context.TODO(), test.expectedPodName, metav1.GetOptions{})
+			assert.True(t, k8serrors.IsNotFound(err), "helper pod should be deleted, err=%v", err)
+		})
+	}
+	os.Unsetenv(string(NFSServerImageKey))
+}
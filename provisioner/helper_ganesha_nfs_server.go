@@ -0,0 +1,44 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	// ganeshaServerCapabilities are the capabilities required by
+	// NFS-Ganesha server. VFS FSAL of NFS-Ganesha uses file handle
+	// syscalls which requires DAC_READ_SEARCH capability.
+	ganeshaServerCapabilities = []corev1.Capability{
+		"DAC_READ_SEARCH",
+	}
+)
+
+// getGaneshaSecurityContext returns the security context of the
+// NFS-Ganesha server container. NFS-Ganesha runs unprivileged, with
+// the capabilities required by the VFS FSAL.
+func getGaneshaSecurityContext() *corev1.SecurityContext {
+	privileged := false
+	return &corev1.SecurityContext{
+		Privileged:               &privileged,
+		AllowPrivilegeEscalation: &privileged,
+		Capabilities: &corev1.Capabilities{
+			Add: ganeshaServerCapabilities,
+		},
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	errors "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func verifyDeploymentUnprivileged() func(*appsv1.Deployment) error {
+	return func(deployment *appsv1.Deployment) error {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if container.SecurityContext == nil || container.SecurityContext.Privileged == nil {
+				return errors.Errorf("expected container %s to have privileged set to false", container.Name)
+			}
+			if *container.SecurityContext.Privileged {
+				return errors.Errorf("expected container %s to be unprivileged", container.Name)
+			}
+		}
+		return nil
+	}
+}
+
+func verifyDeploymentImage(image string) func(*appsv1.Deployment) error {
+	return func(deployment *appsv1.Deployment) error {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if container.Name == "nfs-server" && container.Image != image {
+				return errors.Errorf("expected container image %s but got %s", image, container.Image)
+			}
+		}
+		return nil
+	}
+}
+
+func TestCreateGaneshaDeployment(t *testing.T) {
+	tests := map[string]struct {
+		options                  *KernelNFSServerOptions
+		provisioner              *Provisioner
+		isErrExpected            bool
+		expectedDeploymentFields []func(*appsv1.Deployment) error
+	}{
+		"when there are no errors ganesha deployment should get created": {
+			options: &KernelNFSServerOptions{
+				provisionerNS:  "openebs",
+				pvName:         "test1-pv",
+				backendPvcName: "nfs-test1-pv",
+				serverType:     NFSServerTypeGanesha,
+				leaseTime:      100,
+				graceTime:      90,
+			},
+			provisioner: &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(),
+				serverNamespace: "nfs-server-ns1",
+			},
+			expectedDeploymentFields: []func(*appsv1.Deployment) error{
+				verifyDeploymentExistence("nfs-server-ns1", "nfs-test1-pv"),
+				verifyDeploymentImage("openebs/nfs-ganesha-server:ci"),
+				verifyDeploymentUnprivileged(),
+				verifyDeploymentEnvValues("NFS_LEASE_TIME", "100"),
+				verifyDeploymentEnvValues("NFS_GRACE_TIME", "90"),
+			},
+		},
+	}
+	os.Setenv(string(NFSGaneshaServerImageKey), "openebs/nfs-ganesha-server:ci")
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			err := test.provisioner.createDeployment(test.options)
+			if test.isErrExpected && err == nil {
+				t.Errorf("%q test failed expected error to occur but got nil", name)
+			}
+			if !test.isErrExpected && err != nil {
+				t.Errorf("%q test failed expected error not to occur but got %v", name, err)
+			}
+
+			if !test.isErrExpected {
+				deployName := "nfs-" + test.options.pvName
+				nfsDeployObj, err := test.provisioner.kubeClient.
+					AppsV1().
+					Deployments(test.provisioner.serverNamespace).
+					Get(context.TODO(), deployName, metav1.GetOptions{})
+				if err != nil {
+					t.Errorf("failed to get deployment %s/%s error: %v", test.provisioner.serverNamespace, deployName, err)
+				} else {
+					for _, fn := range test.expectedDeploymentFields {
+						err = fn(nfsDeployObj)
+						if err != nil {
+							t.Errorf("%q test failed expected error not to occur but got %v", name, err)
+						}
+					}
+				}
+			}
+		})
+	}
+	os.Unsetenv(string(NFSGaneshaServerImageKey))
+}
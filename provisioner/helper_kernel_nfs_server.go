@@ -18,7 +18,10 @@ package provisioner
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	errors "github.com/pkg/errors"
@@ -31,6 +34,8 @@ import (
 	pts "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/podtemplatespec"
 	service "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/service"
 	volume "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/volume"
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -46,7 +51,12 @@ const (
 	nfsPvcUIDLabelKey  = "nfs.openebs.io/nfs-pvc-uid"
 	nfsPvcNsLabelKey   = "nfs.openebs.io/nfs-pvc-namespace"
 
-	// NFSPVFinalizer represents finalizer string used by NFSPV
+	// nfsServerClusterIPAnnotation is set on the NFS PV and the NFS server
+	// Service, when the NFS PV refers to the ClusterIP of the Service
+	nfsServerClusterIPAnnotation = "nfs.openebs.io/cluster-ip"
+
+	// NFSPVFinalizer represents finalizer string added to the NFS server
+	// resources of the NFS PV, to protect them while the NFS PV exists
 	NFSPVFinalizer = "nfs.openebs.io/finalizer"
 
 	//NFSServerPort set the NFS Server Port
@@ -55,16 +65,75 @@ const (
 	//RPCBindPort set the RPC Bind Port
 	RPCBindPort = 111
 
+	// MountdPort sets the port of rpc.mountd, used by NFSv3 clients
+	MountdPort = 20048
+
+	// StatdPort sets the port of rpc.statd, used by NFSv3 clients
+	// to recover the locks after the NFS server restarts
+	StatdPort = 32765
+
+	// StatdOutgoingPort sets the port used by rpc.statd to notify
+	// the NFSv3 clients
+	StatdOutgoingPort = 32766
+
+	// LockdPort sets the port of the kernel lock manager, used by
+	// NFSv3 clients for the file locks
+	LockdPort = 32768
+
+	// NFSServerMetricsPort sets the port of the metrics exporter
+	// sidecar of the NFS server
+	NFSServerMetricsPort = 9500
+
+	// nfsMetricsExporterName is the name of the metrics exporter
+	// sidecar container of the NFS server
+	nfsMetricsExporterName = "nfs-metrics-exporter"
+
 	// DefaultBackendPvcBoundTimeout defines the timeout for PVC Bound check.
 	// set to 60 seconds
 	DefaultBackendPvcBoundTimeout = 60
+
+	// nfsServerProbeScript checks the health of the NFS server in
+	// the nfs-server container
+	nfsServerProbeScript = "/usr/bin/nfs-probe.sh"
+
+	// nfsServerProbePeriod defines the interval(in seconds) of the
+	// liveness and readiness probes of the nfs-server container
+	nfsServerProbePeriod = 10
+
+	// nfsDataDir is the directory on the backend volume which is
+	// exported by the kernel NFS server. Other directories of the
+	// backend volume are not accessible to the NFS clients.
+	nfsDataDir = "data"
+
+	// nfsServerDataDirAnnotation is set on the backend PVC, to record
+	// the directory of the backend volume which is exported by the NFS
+	// server. Root of the backend volume is exported if it is not set.
+	nfsServerDataDirAnnotation = "nfs.openebs.io/nfs-server-data-dir"
+
+	// nfsRecoveryDir is the directory on the backend volume, beside
+	// the nfsDataDir, which stores the NFSv4 client recovery records,
+	// so that the clients can reclaim their state after the NFS server
+	// restarts
+	nfsRecoveryDir = ".nfs-v4recovery"
+
+	// nfsRecoveryMountPath is the path of the NFSv4 recovery directory
+	// in the nfs-server container
+	nfsRecoveryMountPath = "/var/lib/nfs/v4recovery"
+
+	// DefaultNFSServerReadyTimeout defines the timeout for NFS server
+	// readiness check. set to 60 seconds
+	DefaultNFSServerReadyTimeout = 60
 )
 
 var (
-	//WaitForNFSServerRetries specifies the number of times provisioner
-	// should wait and check if the NFS server is initialized.
-	//The duration is the value specified here multiplied by 5
-	WaitForNFSServerRetries = 12
+	// NFSServerReadyCheckInterval specifies the interval to check
+	// if the NFS server is serving the volume
+	NFSServerReadyCheckInterval = 5 * time.Second
+
+	// errNFSServerNotReady is returned if the NFS server doesn't serve
+	// the volume within the timeout. Provisioning of such volume is
+	// continued in the background.
+	errNFSServerNotReady = errors.New("NFS server is not ready")
 )
 
 // KernelNFSServerOptions contains the options that
@@ -83,6 +152,23 @@ type KernelNFSServerOptions struct {
 	deploymentName        string
 	nfsServerCustomConfig string
 
+	// exportOptions defines the export options of the kernel NFS
+	// server, configured using ExportOptions. nfsServerCustomConfig
+	// is rendered from these options, if set
+	exportOptions *exportOptions
+
+	// networkPolicy defines the clients allowed by the NetworkPolicy
+	// of the NFS server. NetworkPolicy is not created if it is nil
+	networkPolicy *networkPolicyOptions
+
+	// nfsVersions defines the NFS versions served by the NFS server.
+	// Default is NFSv4 only
+	nfsVersions []int
+
+	// metricsEnabled defines if the metrics exporter sidecar is
+	// added to the NFS server
+	metricsEnabled bool
+
 	// leaseTime defines the renewal period(in seconds) for client state
 	// this should be in range from 10 to 3600 seconds
 	leaseTime int
@@ -119,6 +205,62 @@ type KernelNFSServerOptions struct {
 	// Server container
 	resources *corev1.ResourceRequirements
 
+	// serverType defines the type of NFS server(kernel/ganesha)
+	// which exports the backend volume. Default is kernel
+	serverType string
+
+	// nodeAffinity, tolerations, priorityClassName and
+	// topologySpreadConstraints define the scheduling of the NFS
+	// server, configured in StorageClass. nodeAffinity is applied
+	// along with the node affinity rules configured using the env
+	nodeAffinity              []corev1.NodeSelectorRequirement
+	tolerations               []corev1.Toleration
+	priorityClassName         string
+	topologySpreadConstraints []corev1.TopologySpreadConstraint
+
+	// selectedNode is the node selected by the scheduler for the first
+	// consumer of the NFS PVC, if the StorageClass has WaitForFirstConsumer
+	// volume binding mode. It is propagated to the backend PVC, so that
+	// the backend volume is provisioned in the topology of the consumer
+	selectedNode string
+
+	// topology defines the node selector terms of the topology of the
+	// first consumer, derived from the selected node and the allowed
+	// topologies of the StorageClass. Node must satisfy one of the terms
+	// along with the other node affinity rules
+	topology []corev1.NodeSelectorTerm
+
+	// backendDataSource is the VolumeSnapshot or the PVC of the backend
+	// volume, from which the backend PVC is restored or cloned
+	backendDataSource *corev1.TypedLocalObjectReference
+
+	// cloneSource is the NFS PV, whose data is copied to the
+	// backend volume before exporting it
+	cloneSource *nfsCloneSource
+
+	// dataDir is the directory of the backend volume, which is exported
+	// by the NFS server. NFSv4 recovery records are stored beside it. If
+	// it is empty, root of the backend volume is exported and the NFSv4
+	// recovery records are not persisted. It is recorded on the backend
+	// PVC, so that the volumes created before it was introduced continue
+	// to export the root of the backend volume.
+	dataDir string
+
+	// clusterIP is the ClusterIP requested for the NFS server Service.
+	// It is set while recreating the Service of an existing NFS PV,
+	// which refers to the ClusterIP of the deleted Service
+	clusterIP string
+
+	// shared defines if the NFS server is shared across the volumes
+	// of a StorageClass. If set, pvName refers to the name of the
+	// shared NFS server
+	shared bool
+
+	// csiVolume defines if the NFS server is created by the CSI driver.
+	// Resources of the NFS server are owned by the CSI driver, instead
+	// of the NFS PVC
+	csiVolume bool
+
 	// ctx defines the context which is usually populated from callers
 	ctx context.Context
 }
@@ -129,6 +271,183 @@ func (nfsServerOpts *KernelNFSServerOptions) validate() error {
 	return nil
 }
 
+// hasNFSVersion returns true if the NFS server serves the given NFS version
+func (nfsServerOpts *KernelNFSServerOptions) hasNFSVersion(version int) bool {
+	if len(nfsServerOpts.nfsVersions) == 0 {
+		return version == NFSVersion4
+	}
+	for _, v := range nfsServerOpts.nfsVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// getNFSVersionsEnv returns the comma separated NFS versions
+// served by the NFS server
+func (nfsServerOpts *KernelNFSServerOptions) getNFSVersionsEnv() string {
+	if len(nfsServerOpts.nfsVersions) == 0 {
+		return strconv.Itoa(NFSVersion4)
+	}
+
+	var versions []string
+	for _, v := range nfsServerOpts.nfsVersions {
+		versions = append(versions, strconv.Itoa(v))
+	}
+	return strings.Join(versions, ",")
+}
+
+// getContainerPorts returns the ports of the NFS server container
+func (nfsServerOpts *KernelNFSServerOptions) getContainerPorts() []corev1.ContainerPort {
+	ports := []corev1.ContainerPort{
+		{
+			Name:          "nfs",
+			ContainerPort: NFSServerPort,
+		},
+		{
+			Name:          "rpcbind",
+			ContainerPort: RPCBindPort,
+		},
+	}
+
+	if nfsServerOpts.hasNFSVersion(NFSVersion3) {
+		ports = append(ports,
+			corev1.ContainerPort{Name: "mountd", ContainerPort: MountdPort},
+			corev1.ContainerPort{Name: "statd", ContainerPort: StatdPort},
+			corev1.ContainerPort{Name: "lockd", ContainerPort: LockdPort},
+		)
+	}
+	return ports
+}
+
+// getServicePorts returns the ports of the NFS server service
+func (nfsServerOpts *KernelNFSServerOptions) getServicePorts() []corev1.ServicePort {
+	ports := []corev1.ServicePort{
+		{
+			Name: "nfs",
+			Port: NFSServerPort,
+		},
+		{
+			Name: "rpcbind",
+			Port: RPCBindPort,
+		},
+	}
+
+	if nfsServerOpts.hasNFSVersion(NFSVersion3) {
+		ports = append(ports,
+			corev1.ServicePort{Name: "mountd", Port: MountdPort},
+			corev1.ServicePort{Name: "statd", Port: StatdPort},
+			corev1.ServicePort{Name: "lockd", Port: LockdPort},
+		)
+	}
+
+	if nfsServerOpts.metricsEnabled {
+		ports = append(ports, corev1.ServicePort{Name: "metrics", Port: NFSServerMetricsPort})
+	}
+	return ports
+}
+
+// getMetricsExporterContainer returns the builder of the metrics exporter
+// sidecar, which reads the NFS server statistics from the procfs of the
+// pod and the usage of the exported filesystem
+func (nfsServerOpts *KernelNFSServerOptions) getMetricsExporterContainer() *container.Builder {
+	return container.NewBuilder().
+		WithName(nfsMetricsExporterName).
+		WithImage(getNFSMetricsExporterImage()).
+		WithImagePullPolicy(corev1.PullIfNotPresent).
+		WithArgumentsNew([]string{
+			"--mode=exporter",
+			fmt.Sprintf("--listen-address=:%d", NFSServerMetricsPort),
+			"--export-path=/nfsshare",
+			"--pv-name=" + nfsServerOpts.pvName,
+			"--pvc-name=" + nfsServerOpts.pvcName,
+			"--pvc-namespace=" + nfsServerOpts.pvcNamespace,
+		}).
+		WithPortsNew([]corev1.ContainerPort{
+			{
+				Name:          "metrics",
+				ContainerPort: NFSServerMetricsPort,
+			},
+		}).
+		// SYS_ADMIN is required to mount the nfsd filesystem, which
+		// lists the clients connected to the NFS server
+		WithSecurityContext(&corev1.SecurityContext{
+			Capabilities: &corev1.Capabilities{
+				Add: []corev1.Capability{"SYS_ADMIN"},
+			},
+		}).
+		WithVolumeMountsNew([]corev1.VolumeMount{
+			{
+				Name:      "exports-dir",
+				MountPath: "/nfsshare",
+				SubPath:   nfsServerOpts.dataDir,
+				ReadOnly:  true,
+			},
+		})
+}
+
+// getExportRoot returns the path of the NFS server export, mounted by
+// the clients. NFSv4 clients mount the export through the NFSv4
+// pseudo-root, whereas NFSv3 clients mount the exported directory.
+func (nfsServerOpts *KernelNFSServerOptions) getExportRoot() string {
+	if nfsServerOpts.hasNFSVersion(NFSVersion4) {
+		return "/"
+	}
+	return "/nfsshare"
+}
+
+// getMountOptions returns the mount options of the NFS PV. NFS version
+// is set to 3 if the NFS server doesn't serve NFSv4, unless the version
+// is set in the given mount options
+func (nfsServerOpts *KernelNFSServerOptions) getMountOptions(mountOptions []string) []string {
+	if nfsServerOpts.hasNFSVersion(NFSVersion4) {
+		return mountOptions
+	}
+
+	for _, option := range mountOptions {
+		key := strings.TrimSpace(strings.SplitN(option, "=", 2)[0])
+		if key == "nfsvers" || key == "vers" {
+			return mountOptions
+		}
+	}
+	return append(append([]string{}, mountOptions...), "nfsvers="+strconv.Itoa(NFSVersion3))
+}
+
+// setReadOnlyExport exports the volume read-only, so that the clients
+// can't write to the volume. Export of the shared NFS server and the
+// NFS-Ganesha server is not changed, since it is not configured by
+// the export options
+func (nfsServerOpts *KernelNFSServerOptions) setReadOnlyExport() error {
+	if nfsServerOpts.shared || nfsServerOpts.getServerType() != NFSServerTypeKernel {
+		return nil
+	}
+
+	opts := nfsServerOpts.exportOptions
+	if opts == nil {
+		if len(nfsServerOpts.nfsServerCustomConfig) != 0 {
+			return errors.Errorf("%s can't be used with %s access mode, use %s instead",
+				CustomServerConfig, corev1.ReadOnlyMany, ExportOptions)
+		}
+		opts = newExportOptions()
+	}
+
+	opts.readOnly = true
+	nfsServerOpts.nfsServerCustomConfig = opts.exportsConfig("/nfsshare")
+	return nil
+}
+
+// isReadOnlyAccessModes returns true if the given access modes
+// allow only the read-only access to the volume
+func isReadOnlyAccessModes(accessModes []corev1.PersistentVolumeAccessMode) bool {
+	for _, accessMode := range accessModes {
+		if accessMode != corev1.ReadOnlyMany {
+			return false
+		}
+	}
+	return len(accessModes) != 0
+}
+
 // createBackendPVC creates a new exports PVC for a given NFS PVC
 func (p *Provisioner) createBackendPVC(nfsServerOpts *KernelNFSServerOptions) error {
 	if err := nfsServerOpts.validate(); err != nil {
@@ -141,21 +460,28 @@ func (p *Provisioner) createBackendPVC(nfsServerOpts *KernelNFSServerOptions) er
 	//Check if the PVC is already created. This can happen
 	//if the previous reconciliation of PVC-PV, resulted in
 	//creating a PVC, but was not yet available for 60+ seconds
-	_, err := p.kubeClient.CoreV1().
+	backendPvc, err := p.kubeClient.CoreV1().
 		PersistentVolumeClaims(p.serverNamespace).
 		Get(nfsServerOpts.ctx, backendPvcName, metav1.GetOptions{})
 	if err != nil && !k8serrors.IsNotFound(err) {
 		return errors.Wrapf(err, "failed to check existence of backend PVC {%s/%s}", p.serverNamespace, backendPvcName)
 	} else if err == nil {
 		nfsServerOpts.backendPvcName = backendPvcName
+		nfsServerOpts.dataDir = backendPvc.Annotations[nfsServerDataDirAnnotation]
 		klog.Infof("Volume %v has been initialized with PVC {%s/%s}", nfsServerOpts.pvName, p.serverNamespace, backendPvcName)
 		return nil
 	}
 
+	if nfsServerOpts.backendDataSource == nil {
+		// Backend volume populated from the data source has the
+		// layout of the source, which is set by the caller
+		nfsServerOpts.dataDir = nfsServerOpts.getDefaultDataDir()
+	}
+
 	pvcLabel := nfsServerOpts.getLabels()
-	pvcLabel[nfsPvcNameLabelKey] = nfsServerOpts.pvcName
-	pvcLabel[nfsPvcUIDLabelKey] = nfsServerOpts.pvcUID
-	pvcLabel[nfsPvcNsLabelKey] = nfsServerOpts.pvcNamespace
+	for k, v := range nfsServerOpts.getOwnerLabels() {
+		pvcLabel[k] = v
+	}
 
 	// Create PVC using the provided capacity and SC details
 	pvcObjBuilder := persistentvolumeclaim.NewBuilder().
@@ -164,7 +490,21 @@ func (p *Provisioner) createBackendPVC(nfsServerOpts *KernelNFSServerOptions) er
 		WithLabels(pvcLabel).
 		WithCapacity(nfsServerOpts.capacity).
 		WithAccessModeRWO().
-		WithStorageClass(nfsServerOpts.backendStorageClass)
+		WithStorageClass(nfsServerOpts.backendStorageClass).
+		WithDataSource(nfsServerOpts.backendDataSource)
+
+	pvcAnnotations := map[string]string{}
+	if nfsServerOpts.selectedNode != "" {
+		// Backend volume is provisioned for the node of the first
+		// consumer, instead of waiting for the NFS server pod
+		pvcAnnotations[selectedNodeAnnotation] = nfsServerOpts.selectedNode
+	}
+	if nfsServerOpts.dataDir != "" {
+		pvcAnnotations[nfsServerDataDirAnnotation] = nfsServerOpts.dataDir
+	}
+	if len(pvcAnnotations) != 0 {
+		pvcObjBuilder = pvcObjBuilder.WithAnnotations(pvcAnnotations)
+	}
 
 	pvcObj, err := pvcObjBuilder.Build()
 
@@ -213,8 +553,6 @@ func (p *Provisioner) deleteBackendPVC(nfsServerOpts *KernelNFSServerOptions) er
 		return nil
 	}
 
-	//TODO
-	// remove finalizer
 	if p.hook != nil && p.hook.ActionExists(nfshook.ResourceBackendPV, nfshook.EventTypeDeleteVolume) {
 		err = p.hook.ExecuteHookOnBackendPV(p.kubeClient, nfsServerOpts.ctx, p.serverNamespace, "nfs-"+nfsServerOpts.pvName, nfshook.EventTypeDeleteVolume)
 		if err != nil {
@@ -229,6 +567,11 @@ func (p *Provisioner) deleteBackendPVC(nfsServerOpts *KernelNFSServerOptions) er
 		}
 	}
 
+	err = p.removeNFSServerFinalizer(nfsServerOpts.ctx, resourceBackendPVC, backendPvcName)
+	if err != nil {
+		return err
+	}
+
 	// Delete PVC
 	err = p.kubeClient.CoreV1().
 		PersistentVolumeClaims(p.serverNamespace).
@@ -239,14 +582,160 @@ func (p *Provisioner) deleteBackendPVC(nfsServerOpts *KernelNFSServerOptions) er
 	return nil
 }
 
-// createDeployment creates a new NFS Server Deployment for a given NFS PVC
-func (p *Provisioner) createDeployment(nfsServerOpts *KernelNFSServerOptions) error {
+// getNFSServerProbe returns the given probe of the nfs-server container.
+// NFS server recovers the client state during the NFSv4 grace period after
+// the start, so the probe is started only after the given initial delay.
+// Images without the probe script are considered healthy.
+func getNFSServerProbe(probe string, initialDelay int) *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			Exec: &corev1.ExecAction{
+				Command: []string{
+					"sh", "-c",
+					fmt.Sprintf("test ! -x %[1]s || %[1]s %s", nfsServerProbeScript, probe),
+				},
+			},
+		},
+		InitialDelaySeconds: int32(initialDelay),
+		PeriodSeconds:       nfsServerProbePeriod,
+		TimeoutSeconds:      5,
+		FailureThreshold:    3,
+	}
+}
+
+// getNFSServerContainer returns the builder of the NFS server container.
+// Image, security context, env, volume mounts and probes of the container
+// depend on the NFS server type.
+func (nfsServerOpts *KernelNFSServerOptions) getNFSServerContainer() *container.Builder {
 	var resourceRequirements corev1.ResourceRequirements
-	klog.V(4).Infof("Creating Deployment")
-	if err := nfsServerOpts.validate(); err != nil {
-		return err
+	if nfsServerOpts.resources != nil {
+		resourceRequirements = *nfsServerOpts.resources
+	}
+
+	envs := []corev1.EnvVar{
+		{
+			Name:  "SHARED_DIRECTORY",
+			Value: "/nfsshare",
+		},
+		{
+			Name:  "CUSTOM_EXPORTS_CONFIG",
+			Value: nfsServerOpts.nfsServerCustomConfig,
+		},
+		{
+			Name:  "NFS_LEASE_TIME",
+			Value: strconv.Itoa(nfsServerOpts.leaseTime),
+		},
+		{
+			Name:  "NFS_GRACE_TIME",
+			Value: strconv.Itoa(nfsServerOpts.graceTime),
+		},
+		{
+			Name:  "FILEPERMISSIONS_UID",
+			Value: nfsServerOpts.permissionsUID,
+		},
+		{
+			Name:  "FILEPERMISSIONS_GID",
+			Value: nfsServerOpts.permissionsGID,
+		},
+		{
+			Name:  "FILEPERMISSIONS_MODE",
+			Value: nfsServerOpts.permissionsMode,
+		},
+	}
+
+	containerBuilder := container.NewBuilder().
+		WithName("nfs-server").
+		WithImagePullPolicy(corev1.PullIfNotPresent).
+		WithPortsNew(nfsServerOpts.getContainerPorts()).
+		WithResources(&resourceRequirements)
+
+	if nfsServerOpts.getServerType() == NFSServerTypeGanesha {
+		// NFS-Ganesha runs in userspace, so unlike kernel NFS
+		// server the container doesn't require privileged access
+		return containerBuilder.
+			WithImage(getNFSGaneshaServerImage()).
+			WithEnvsNew(envs).
+			WithSecurityContext(getGaneshaSecurityContext()).
+			WithVolumeMountsNew(
+				[]corev1.VolumeMount{
+					{
+						Name:      "exports-dir",
+						MountPath: "/nfsshare",
+						SubPath:   nfsServerOpts.dataDir,
+					},
+				},
+			)
 	}
 
+	envs = append(envs, corev1.EnvVar{
+		Name:  "NFS_VERSIONS",
+		Value: nfsServerOpts.getNFSVersionsEnv(),
+	})
+	if nfsServerOpts.hasNFSVersion(NFSVersion3) {
+		// rpc.mountd, rpc.statd and the kernel lock manager listen on
+		// the fixed ports, so that NFSv3 clients can reach them through
+		// the NFS service
+		envs = append(envs,
+			corev1.EnvVar{Name: "MOUNTD_PORT", Value: strconv.Itoa(MountdPort)},
+			corev1.EnvVar{Name: "STATD_PORT", Value: strconv.Itoa(StatdPort)},
+			corev1.EnvVar{Name: "STATD_OUTGOING_PORT", Value: strconv.Itoa(StatdOutgoingPort)},
+			corev1.EnvVar{Name: "LOCKD_PORT", Value: strconv.Itoa(LockdPort)},
+		)
+	}
+
+	//TODO
+	secContext := true
+
+	return containerBuilder.
+		WithImage(getNFSServerImage()).
+		WithEnvsNew(envs).
+		WithPrivilegedSecurityContext(&secContext).
+		WithVolumeMountsNew(nfsServerOpts.getNFSServerVolumeMounts()).
+		WithLivenessProbe(getNFSServerProbe("liveness", nfsServerOpts.graceTime)).
+		WithReadinessProbe(getNFSServerProbe("readiness", 0))
+}
+
+// getNFSServerVolumeMounts returns the volume mounts of the kernel NFS
+// server container. Only the data directory of the backend volume is
+// mounted on the export path, so the NFS clients can't access the NFSv4
+// recovery records stored beside it.
+func (nfsServerOpts *KernelNFSServerOptions) getNFSServerVolumeMounts() []corev1.VolumeMount {
+	if nfsServerOpts.dataDir == "" {
+		return []corev1.VolumeMount{
+			{
+				Name:      "exports-dir",
+				MountPath: "/nfsshare",
+			},
+		}
+	}
+
+	return []corev1.VolumeMount{
+		{
+			Name:      "exports-dir",
+			MountPath: "/nfsshare",
+			SubPath:   nfsServerOpts.dataDir,
+		},
+		{
+			Name:      "exports-dir",
+			MountPath: nfsRecoveryMountPath,
+			SubPath:   nfsRecoveryDir,
+		},
+	}
+}
+
+// getDefaultDataDir returns the data directory of the new backend volume.
+// Only the kernel NFS server persists the NFSv4 recovery records, so the
+// root of the backend volume is exported by other NFS servers.
+func (nfsServerOpts *KernelNFSServerOptions) getDefaultDataDir() string {
+	if nfsServerOpts.getServerType() != NFSServerTypeKernel {
+		return ""
+	}
+	return nfsDataDir
+}
+
+// createDeployment creates a new NFS Server Deployment for a given NFS PVC
+func (p *Provisioner) createDeployment(nfsServerOpts *KernelNFSServerOptions) error {
+	klog.V(4).Infof("Creating Deployment")
 	deployName := "nfs-" + nfsServerOpts.pvName
 	klog.V(4).Infof("Verifying if Deployment(%v) for NFS storage was already created.", deployName)
 
@@ -265,19 +754,47 @@ func (p *Provisioner) createDeployment(nfsServerOpts *KernelNFSServerOptions) er
 		return nil
 	}
 
+	deployObj, err := p.buildDeployment(nfsServerOpts)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.kubeClient.AppsV1().
+		Deployments(p.serverNamespace).
+		Create(nfsServerOpts.ctx, deployObj, metav1.CreateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to create NFS server deployment {%s/%s}", p.serverNamespace, deployName)
+	}
+
+	nfsServerOpts.deploymentName = deployName
+
+	return nil
+}
+
+// buildDeployment builds the NFS Server Deployment of the NFS server
+// type of the given NFS PVC, and executes the hook on it. Deployment is
+// stamped with the provisioner version and the hash of its spec, to
+// detect the NFS servers which need to be upgraded.
+func (p *Provisioner) buildDeployment(nfsServerOpts *KernelNFSServerOptions) (*appsv1.Deployment, error) {
+	if err := nfsServerOpts.validate(); err != nil {
+		return nil, err
+	}
+
+	deployName := "nfs-" + nfsServerOpts.pvName
+
 	nfsDeployLabelSelector := map[string]string{
 		"openebs.io/nfs-server": deployName,
 	}
-	nfsDeployLabelSelector[nfsPvcNameLabelKey] = nfsServerOpts.pvcName
-	nfsDeployLabelSelector[nfsPvcUIDLabelKey] = nfsServerOpts.pvcUID
-	nfsDeployLabelSelector[nfsPvcNsLabelKey] = nfsServerOpts.pvcNamespace
-
-	if nfsServerOpts.resources != nil {
-		resourceRequirements = *nfsServerOpts.resources
+	for k, v := range nfsServerOpts.getOwnerLabels() {
+		nfsDeployLabelSelector[k] = v
 	}
 
-	//TODO
-	secContext := true
+	containerBuilders := []*container.Builder{
+		nfsServerOpts.getNFSServerContainer(),
+	}
+	if nfsServerOpts.metricsEnabled {
+		containerBuilders = append(containerBuilders, nfsServerOpts.getMetricsExporterContainer())
+	}
 
 	// Create Deployment for NFS Server and mount the exports PVC.
 	deployObjBuilder := deployment.NewBuilder().
@@ -292,68 +809,12 @@ func (p *Provisioner) createDeployment(nfsServerOpts *KernelNFSServerOptions) er
 				WithSecurityContext(&corev1.PodSecurityContext{
 					FSGroup: nfsServerOpts.fsGroup,
 				}).
-				WithNodeAffinityMatchExpressions(p.nodeAffinity.MatchExpressions).
+				WithNodeAffinityNodeSelectorTerms(p.getNodeSelectorTerms(nfsServerOpts)...).
+				WithTolerationsByValue(nfsServerOpts.tolerations...).
+				WithPriorityClassName(nfsServerOpts.priorityClassName).
+				WithTopologySpreadConstraints(nfsServerOpts.topologySpreadConstraints...).
 				WithImagePullSecret(getNfsServerImagePullSecret()).
-				WithContainerBuildersNew(
-					container.NewBuilder().
-						WithName("nfs-server").
-						WithImage(getNFSServerImage()).
-						WithImagePullPolicy(corev1.PullIfNotPresent).
-						WithEnvsNew(
-							[]corev1.EnvVar{
-								{
-									Name:  "SHARED_DIRECTORY",
-									Value: "/nfsshare",
-								},
-								{
-									Name:  "CUSTOM_EXPORTS_CONFIG",
-									Value: nfsServerOpts.nfsServerCustomConfig,
-								},
-								{
-									Name:  "NFS_LEASE_TIME",
-									Value: strconv.Itoa(nfsServerOpts.leaseTime),
-								},
-								{
-									Name:  "NFS_GRACE_TIME",
-									Value: strconv.Itoa(nfsServerOpts.graceTime),
-								},
-								{
-									Name:  "FILEPERMISSIONS_UID",
-									Value: nfsServerOpts.permissionsUID,
-								},
-								{
-									Name:  "FILEPERMISSIONS_GID",
-									Value: nfsServerOpts.permissionsGID,
-								},
-								{
-									Name:  "FILEPERMISSIONS_MODE",
-									Value: nfsServerOpts.permissionsMode,
-								},
-							},
-						).
-						WithPortsNew(
-							[]corev1.ContainerPort{
-								{
-									Name:          "nfs",
-									ContainerPort: NFSServerPort,
-								},
-								{
-									Name:          "rpcbind",
-									ContainerPort: RPCBindPort,
-								},
-							},
-						).
-						WithPrivilegedSecurityContext(&secContext).
-						WithVolumeMountsNew(
-							[]corev1.VolumeMount{
-								{
-									Name:      "exports-dir",
-									MountPath: "/nfsshare",
-								},
-							},
-						).
-						WithResources(&resourceRequirements),
-				).
+				WithContainerBuildersNew(containerBuilders...).
 				WithVolumeBuilders(
 					volume.NewBuilder().
 						WithName("exports-dir").
@@ -362,29 +823,24 @@ func (p *Provisioner) createDeployment(nfsServerOpts *KernelNFSServerOptions) er
 		)
 
 	deployObj, err := deployObjBuilder.Build()
-
 	if err != nil {
 		//TODO : Need to relook at this error
-		return errors.Wrapf(err, "unable to build Deployment")
+		return nil, errors.Wrapf(err, "unable to build Deployment")
 	}
 
+	// Hook is executed while rendering the Deployment, so that the
+	// Deployment is created and upgraded with the same spec
 	if p.hook != nil && p.hook.ActionExists(nfshook.ResourceNFSServerDeployment, nfshook.EventTypeCreateVolume) {
 		err = p.hook.Action(deployObj, nfshook.ResourceNFSServerDeployment, nfshook.EventTypeCreateVolume)
 		if err != nil {
-			return errors.Wrapf(err, "failed to execute hook on nfs-server deployment object")
+			return nil, errors.Wrapf(err, "failed to execute hook on nfs-server deployment object")
 		}
 	}
 
-	_, err = p.kubeClient.AppsV1().
-		Deployments(p.serverNamespace).
-		Create(nfsServerOpts.ctx, deployObj, metav1.CreateOptions{})
-	if err != nil {
-		return errors.Wrapf(err, "failed to create NFS server deployment {%s/%s}", p.serverNamespace, deployName)
+	if err = setDeploymentRevision(deployObj); err != nil {
+		return nil, err
 	}
-
-	nfsServerOpts.deploymentName = deployName
-
-	return nil
+	return deployObj, nil
 }
 
 // deleteDeployment deletes the NFS Server Deployment for a given NFS PVC
@@ -416,8 +872,10 @@ func (p *Provisioner) deleteDeployment(nfsServerOpts *KernelNFSServerOptions) er
 		}
 	}
 
-	//TODO
-	// remove finalizer
+	err = p.removeNFSServerFinalizer(nfsServerOpts.ctx, resourceDeployment, deployName)
+	if err != nil {
+		return err
+	}
 
 	// Delete NFS Server Deployment
 	err = p.kubeClient.AppsV1().
@@ -458,25 +916,33 @@ func (p *Provisioner) createService(nfsServerOpts *KernelNFSServerOptions) error
 		"openebs.io/nfs-server": nfsServerOpts.deploymentName,
 	}
 
+	// Service is labeled with the NFS server and the owner labels, so
+	// that the metrics port of the NFS servers can be selected by the
+	// Prometheus ServiceMonitor
+	svcLabels := map[string]string{
+		"openebs.io/nfs-server": nfsServerOpts.deploymentName,
+	}
+	for k, v := range nfsServerOpts.getOwnerLabels() {
+		svcLabels[k] = v
+	}
+
 	//TODO
 	// Create Service
 	svcObjBuilder := service.NewBuilder().
 		WithNamespace(p.serverNamespace).
 		WithName(svcName).
-		WithPorts(
-			[]corev1.ServicePort{
-				{
-					Name: "nfs",
-					Port: NFSServerPort,
-				},
-				{
-					Name: "rpcbind",
-					Port: RPCBindPort,
-				},
-			},
-		).
+		WithLabelsNew(svcLabels).
+		WithPorts(nfsServerOpts.getServicePorts()).
 		WithSelectorsNew(nfsDeployLabelSelector)
 
+	if len(nfsServerOpts.clusterIP) != 0 {
+		svcObjBuilder = svcObjBuilder.
+			WithClusterIP(nfsServerOpts.clusterIP).
+			WithAnnotationsNew(map[string]string{
+				nfsServerClusterIPAnnotation: nfsServerOpts.clusterIP,
+			})
+	}
+
 	svcObj, err := svcObjBuilder.Build()
 
 	if err != nil {
@@ -533,8 +999,11 @@ func (p *Provisioner) deleteService(nfsServerOpts *KernelNFSServerOptions) error
 			return errors.Wrapf(err, "failed to execute hook on NFS Service")
 		}
 	}
-	//TODO
-	// remove finalizer
+
+	err = p.removeNFSServerFinalizer(nfsServerOpts.ctx, resourceService, svcName)
+	if err != nil {
+		return err
+	}
 
 	// Delete Service
 	err = p.kubeClient.CoreV1().
@@ -568,6 +1037,11 @@ func (p *Provisioner) getNFSServerAddress(nfsServerOpts *KernelNFSServerOptions)
 		if err != nil || nfsService == nil {
 			return "", errors.Wrapf(err, "failed to get NFS Service for PVC{%v}", nfsServerOpts.backendPvcName)
 		}
+
+		err = p.recordServiceClusterIP(nfsServerOpts.ctx, nfsService)
+		if err != nil {
+			return "", err
+		}
 		return nfsService.Spec.ClusterIP, nil
 	}
 
@@ -576,41 +1050,199 @@ func (p *Provisioner) getNFSServerAddress(nfsServerOpts *KernelNFSServerOptions)
 	return nfsServerOpts.serviceName + "." + p.serverNamespace + ".svc.cluster.local", nil
 }
 
+// checkNFSServerReady returns error if the NFS server is not serving at
+// the given address. NFS server is ready once its Deployment is rolled out
+// with all the replicas ready, and it accepts the NFS NULL call.
+func (p *Provisioner) checkNFSServerReady(nfsServerOpts *KernelNFSServerOptions, address string) error {
+	deployObj, err := p.kubeClient.AppsV1().
+		Deployments(p.serverNamespace).
+		Get(nfsServerOpts.ctx, nfsServerOpts.deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get NFS server deployment {%s/%s}", p.serverNamespace, nfsServerOpts.deploymentName)
+	}
+
+	deploy := deployment.NewForAPIObject(deployObj)
+	status, err := deploy.RolloutStatus()
+	if err != nil {
+		return errors.Wrapf(err, "failed to get rollout status of NFS server deployment {%s/%s}", p.serverNamespace, deployObj.Name)
+	}
+	if !status.IsRolledout {
+		return errors.Errorf("NFS server deployment {%s/%s} is not rolled out: %s", p.serverNamespace, deployObj.Name, status.Message)
+	}
+
+	if err = deploy.VerifyReplicaStatus(); err != nil {
+		return err
+	}
+
+	return p.prober.Probe(nfsServerOpts.ctx, address)
+}
+
+// waitForNFSServerReady waits for the NFS server to serve at the given
+// address, for the NFS server ready timeout period
+func (p *Provisioner) waitForNFSServerReady(nfsServerOpts *KernelNFSServerOptions, address string) error {
+	timer := time.NewTimer(p.serverReadyTimeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(NFSServerReadyCheckInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for {
+		err := p.checkNFSServerReady(nfsServerOpts, address)
+		if err == nil {
+			klog.Infof("NFS server of volume %s is serving at %s", nfsServerOpts.pvName, address)
+			metrics.NFSServerProvisionStageDuration.WithLabelValues(metrics.StageDeploymentReady).Observe(time.Since(start).Seconds())
+			p.recordPVCEvent(nfsServerOpts, corev1.EventTypeNormal, nfsServerReadyReason,
+				"NFS server %s/%s is serving at %s", p.serverNamespace, nfsServerOpts.deploymentName, address)
+			return nil
+		}
+		klog.V(4).Infof("NFS server of volume %s is not ready, err=%v", nfsServerOpts.pvName, err)
+
+		select {
+		case <-nfsServerOpts.ctx.Done():
+			return errors.Wrapf(errNFSServerNotReady, "%v", nfsServerOpts.ctx.Err())
+		case <-timer.C:
+			// Pod scheduling failure or container waiting reason
+			// is more useful than the rollout status
+			reason := getNFSServerPodReason(nfsServerOpts.ctx, p.kubeClient, p.serverNamespace, nfsServerOpts.deploymentName)
+			if len(reason) == 0 {
+				reason = err.Error()
+			}
+			p.recordPVCEvent(nfsServerOpts, corev1.EventTypeWarning, nfsServerNotReadyReason,
+				"NFS server %s/%s is not ready: %s", p.serverNamespace, nfsServerOpts.deploymentName, reason)
+			return errors.Wrapf(errNFSServerNotReady, "timed out waiting for NFS server at %s: %v", address, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordServiceClusterIP records the ClusterIP assigned to the NFS server
+// Service in its annotations, so that the same ClusterIP can be requested
+// if the Service is recreated
+func (p *Provisioner) recordServiceClusterIP(ctx context.Context, svc *corev1.Service) error {
+	if len(svc.Spec.ClusterIP) == 0 || svc.Spec.ClusterIP == corev1.ClusterIPNone ||
+		svc.Annotations[nfsServerClusterIPAnnotation] == svc.Spec.ClusterIP {
+		return nil
+	}
+
+	svc = svc.DeepCopy()
+	if svc.Annotations == nil {
+		svc.Annotations = map[string]string{}
+	}
+	svc.Annotations[nfsServerClusterIPAnnotation] = svc.Spec.ClusterIP
+
+	_, err := p.kubeClient.CoreV1().
+		Services(svc.Namespace).
+		Update(ctx, svc, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to record ClusterIP of NFS service {%s/%s}", svc.Namespace, svc.Name)
+	}
+	return nil
+}
+
+// getNFSServerClusterIP returns the ClusterIP of the NFS server Service
+// which is referred by the given NFS PV. NFS PVs created before the
+// ClusterIP was recorded refer to it in the NFS source only.
+func getNFSServerClusterIP(pv *corev1.PersistentVolume) string {
+	if clusterIP, ok := pv.Annotations[nfsServerClusterIPAnnotation]; ok {
+		return clusterIP
+	}
+
+	if pv.Spec.NFS != nil && net.ParseIP(pv.Spec.NFS.Server) != nil {
+		return pv.Spec.NFS.Server
+	}
+	return ""
+}
+
 // createNFSServer creates the NFS Server deployment and related
 // objects created for the given PV
 func (p *Provisioner) createNFSServer(nfsServerOpts *KernelNFSServerOptions) error {
 	klog.V(4).Infof("Create NFS Server")
+	backendPvcName := "nfs-" + nfsServerOpts.pvName
+
 	// Create PVC, Deployment and Service
 	err := p.createBackendPVC(nfsServerOpts)
 	if err != nil {
+		p.recordPVCEvent(nfsServerOpts, corev1.EventTypeWarning, backendPVCCreateFailedReason,
+			"Failed to create backend PVC %s/%s: %v", p.serverNamespace, backendPvcName, err)
 		return errors.Wrapf(err, "failed to initialize NFS Storage PVC for RWX PVC{%v}", nfsServerOpts.pvName)
 	}
+	p.recordPVCEvent(nfsServerOpts, corev1.EventTypeNormal, backendPVCCreatedReason,
+		"Backend PVC %s/%s created", p.serverNamespace, backendPvcName)
+
+	if nfsServerOpts.cloneSource != nil {
+		err = p.copyCloneSource(nfsServerOpts)
+		if err != nil {
+			p.recordPVCEvent(nfsServerOpts, corev1.EventTypeWarning, backendVolumeCloneFailedReason,
+				"Failed to clone backend volume: %v", err)
+			return errors.Wrapf(err, "failed to clone NFS Storage PVC for RWX PVC{%v}", nfsServerOpts.pvName)
+		}
+		p.recordPVCEvent(nfsServerOpts, corev1.EventTypeNormal, backendVolumeClonedReason,
+			"Backend volume cloned from volume %s", nfsServerOpts.cloneSource.pvName)
+	}
 
 	err = p.createDeployment(nfsServerOpts)
 	if err != nil {
+		p.recordPVCEvent(nfsServerOpts, corev1.EventTypeWarning, nfsServerDeploymentFailedReason,
+			"Failed to create NFS server deployment %s/%s: %v", p.serverNamespace, nfsServerOpts.deploymentName, err)
 		return errors.Wrapf(err, "failed to initialize NFS Storage Deployment for RWX PVC{%v}", nfsServerOpts.pvName)
 	}
+	p.recordPVCEvent(nfsServerOpts, corev1.EventTypeNormal, nfsServerDeploymentCreatedReason,
+		"NFS server deployment %s/%s created", p.serverNamespace, nfsServerOpts.deploymentName)
 
-	err = waitForPvcBound(nfsServerOpts.ctx, p.kubeClient, p.serverNamespace, "nfs-"+nfsServerOpts.pvName, p.backendPvcTimeout)
+	bindStart := time.Now()
+	err = waitForPvcBound(nfsServerOpts.ctx, p.kubeClient, p.serverNamespace, backendPvcName, p.backendPvcTimeout)
 	if err != nil {
+		// Reason of the pending backend PVC is reported
+		// in the events of the backend PVC
+		reason := getBackendPVCPendingReason(nfsServerOpts.ctx, p.kubeClient, p.serverNamespace, backendPvcName)
+		if len(reason) == 0 {
+			reason = err.Error()
+		}
+		p.recordPVCEvent(nfsServerOpts, corev1.EventTypeWarning, backendPVCPendingReason,
+			"Backend PVC %s/%s is not bound: %s", p.serverNamespace, backendPvcName, reason)
 		return err
 	}
+	metrics.NFSServerProvisionStageDuration.WithLabelValues(metrics.StageBackendPVCBind).Observe(time.Since(bindStart).Seconds())
+	p.recordPVCEvent(nfsServerOpts, corev1.EventTypeNormal, backendPVCBoundReason,
+		"Backend PVC %s/%s is bound", p.serverNamespace, backendPvcName)
 
 	if p.hook != nil && p.hook.ActionExists(nfshook.ResourceBackendPV, nfshook.EventTypeCreateVolume) {
-		err = p.hook.ExecuteHookOnBackendPV(p.kubeClient, nfsServerOpts.ctx, p.serverNamespace, "nfs-"+nfsServerOpts.pvName, nfshook.EventTypeCreateVolume)
+		err = p.hook.ExecuteHookOnBackendPV(p.kubeClient, nfsServerOpts.ctx, p.serverNamespace, backendPvcName, nfshook.EventTypeCreateVolume)
 		if err != nil {
+			p.recordPVCEvent(nfsServerOpts, corev1.EventTypeWarning, backendPVHookFailedReason,
+				"Failed to execute hook on backend PV of PVC %s/%s: %v", p.serverNamespace, backendPvcName, err)
 			return errors.Wrapf(err, "failed to execute hook on backend PV")
 		}
 	}
 
+	serviceStart := time.Now()
 	err = p.createService(nfsServerOpts)
 	if err != nil {
+		p.recordPVCEvent(nfsServerOpts, corev1.EventTypeWarning, nfsServerServiceFailedReason,
+			"Failed to create NFS server service %s/%s: %v", p.serverNamespace, nfsServerOpts.serviceName, err)
 		return errors.Wrapf(err, "failed to initialize NFS Storage Service for RWX PVC{%v}", nfsServerOpts.pvName)
 	}
+	metrics.NFSServerProvisionStageDuration.WithLabelValues(metrics.StageService).Observe(time.Since(serviceStart).Seconds())
+	p.recordPVCEvent(nfsServerOpts, corev1.EventTypeNormal, nfsServerServiceCreatedReason,
+		"NFS server service %s/%s created", p.serverNamespace, nfsServerOpts.serviceName)
+
+	err = p.createNetworkPolicy(nfsServerOpts)
+	if err != nil {
+		p.recordPVCEvent(nfsServerOpts, corev1.EventTypeWarning, networkPolicyFailedReason,
+			"Failed to create NetworkPolicy %s/%s: %v", p.serverNamespace, getNetworkPolicyName(nfsServerOpts.pvName), err)
+		return errors.Wrapf(err, "failed to initialize NFS Storage NetworkPolicy for RWX PVC{%v}", nfsServerOpts.pvName)
+	}
+	if nfsServerOpts.networkPolicy != nil {
+		p.recordPVCEvent(nfsServerOpts, corev1.EventTypeNormal, networkPolicyCreatedReason,
+			"NetworkPolicy %s/%s created", p.serverNamespace, getNetworkPolicyName(nfsServerOpts.pvName))
+	}
 
-	//TODO
 	// Add finalizers once the objects have been setup
-	// Use the service to setup or return PV details
+	err = p.protectNFSServerResources(nfsServerOpts)
+	if err != nil {
+		return errors.Wrapf(err, "failed to protect NFS server resources of RWX PVC{%v}", nfsServerOpts.pvName)
+	}
 	return nil
 }
 
@@ -624,11 +1256,21 @@ func (p *Provisioner) deleteNFSServer(nfsServerOpts *KernelNFSServerOptions) err
 		return errors.Wrapf(err, "failed to delete NFS Storage Service for RWX PVC{%v}", nfsServerOpts.pvName)
 	}
 
+	err = p.deleteNetworkPolicy(nfsServerOpts)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete NFS Storage NetworkPolicy for RWX PVC{%v}", nfsServerOpts.pvName)
+	}
+
 	err = p.deleteDeployment(nfsServerOpts)
 	if err != nil {
 		return errors.Wrapf(err, "failed to delete NFS Storage Deployment for RWX PVC{%v}", nfsServerOpts.pvName)
 	}
 
+	err = p.deleteCloneJob(nfsServerOpts.ctx, nfsServerOpts.pvName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete NFS Storage clone job for RWX PVC{%v}", nfsServerOpts.pvName)
+	}
+
 	err = p.deleteBackendPVC(nfsServerOpts)
 	if err != nil {
 		return errors.Wrapf(err, "failed to delete NFS Storage PVC for RWX PVC{%v}", nfsServerOpts.pvName)
@@ -640,8 +1282,40 @@ func (p *Provisioner) deleteNFSServer(nfsServerOpts *KernelNFSServerOptions) err
 func (nfsServerOpts *KernelNFSServerOptions) getLabels() map[string]string {
 	return map[string]string{
 		"persistent-volume":   nfsServerOpts.pvName,
-		"openebs.io/cas-type": "nfs-kernel",
+		"openebs.io/cas-type": "nfs-" + nfsServerOpts.getServerType(),
+	}
+}
+
+// getOwnerLabels returns the labels which identify the owner of NFS
+// server resources. For the shared NFS server, resources are owned by
+// all the volumes of the shared NFS server, so shared NFS server name
+// is returned instead of NFS PVC details.
+func (nfsServerOpts *KernelNFSServerOptions) getOwnerLabels() map[string]string {
+	if nfsServerOpts.shared {
+		return map[string]string{
+			sharedNFSServerLabelKey: nfsServerOpts.pvName,
+		}
+	}
+
+	if nfsServerOpts.csiVolume {
+		return map[string]string{
+			csiVolumeLabelKey: CSIDriverName,
+		}
+	}
+
+	return map[string]string{
+		nfsPvcNameLabelKey: nfsServerOpts.pvcName,
+		nfsPvcUIDLabelKey:  nfsServerOpts.pvcUID,
+		nfsPvcNsLabelKey:   nfsServerOpts.pvcNamespace,
+	}
+}
+
+// getServerType returns the type of NFS server, default is kernel
+func (nfsServerOpts *KernelNFSServerOptions) getServerType() string {
+	if nfsServerOpts.serverType == "" {
+		return NFSServerTypeKernel
 	}
+	return nfsServerOpts.serverType
 }
 
 // waitForPvcBound wait for PVC to bound for timeout period
@@ -110,6 +110,35 @@ func verifyDeploymentFSGIDValue(expectedFSGID *int64) func(*appsv1.Deployment) e
 	}
 }
 
+func verifyDeploymentScheduling(nodeAffinity []corev1.NodeSelectorRequirement, tolerations []corev1.Toleration,
+	priorityClassName string, spreadConstraints []corev1.TopologySpreadConstraint) func(*appsv1.Deployment) error {
+	return func(deployment *appsv1.Deployment) error {
+		podSpec := deployment.Spec.Template.Spec
+		var matchExpressions []corev1.NodeSelectorRequirement
+		if podSpec.Affinity != nil && podSpec.Affinity.NodeAffinity != nil &&
+			podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+			terms := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+			if len(terms) != 1 {
+				return errors.Errorf("expected 1 node selector term but got %d", len(terms))
+			}
+			matchExpressions = terms[0].MatchExpressions
+		}
+		if !assert.ObjectsAreEqual(nodeAffinity, matchExpressions) {
+			return errors.Errorf("expected node affinity %v but got %v", nodeAffinity, matchExpressions)
+		}
+		if !assert.ObjectsAreEqual(tolerations, podSpec.Tolerations) {
+			return errors.Errorf("expected tolerations %v but got %v", tolerations, podSpec.Tolerations)
+		}
+		if podSpec.PriorityClassName != priorityClassName {
+			return errors.Errorf("expected priorityClassName %q but got %q", priorityClassName, podSpec.PriorityClassName)
+		}
+		if !assert.ObjectsAreEqual(spreadConstraints, podSpec.TopologySpreadConstraints) {
+			return errors.Errorf("expected topology spread constraints %v but got %v", spreadConstraints, podSpec.TopologySpreadConstraints)
+		}
+		return nil
+	}
+}
+
 func verifyDeploymentEnvValues(envKey, envValue string) func(*appsv1.Deployment) error {
 	return func(deployment *appsv1.Deployment) error {
 		for _, container := range deployment.Spec.Template.Spec.Containers {
@@ -135,11 +164,13 @@ func verifyDeploymentEnvValues(envKey, envValue string) func(*appsv1.Deployment)
 
 func TestCreateBackendPVC(t *testing.T) {
 	tests := map[string]struct {
-		options           *KernelNFSServerOptions
-		provisioner       *Provisioner
-		preProvisionedPVC *corev1.PersistentVolumeClaim
-		isErrExpected     bool
-		expectedPVCName   string
+		options              *KernelNFSServerOptions
+		provisioner          *Provisioner
+		preProvisionedPVC    *corev1.PersistentVolumeClaim
+		isErrExpected        bool
+		expectedPVCName      string
+		expectedSelectedNode string
+		expectedDataDir      string
 	}{
 		"when there are no errors PVC should get created": {
 			// NOTE: Populated only fields required for test
@@ -154,6 +185,7 @@ func TestCreateBackendPVC(t *testing.T) {
 				serverNamespace: "nfs-server-ns1",
 			},
 			expectedPVCName: "nfs-test1-pv",
+			expectedDataDir: nfsDataDir,
 		},
 		"when PVC is pre-provisioned": {
 			options: &KernelNFSServerOptions{
@@ -181,8 +213,25 @@ func TestCreateBackendPVC(t *testing.T) {
 				serverNamespace: "nfs-server-ns3",
 			},
 			expectedPVCName:   "nfs-test3-pv",
+			expectedDataDir:   nfsDataDir,
 			preProvisionedPVC: getFakePVCObject("openebs", "nfs-test3-pv", "test3-sc", "uid"),
 		},
+		"when node is selected for the consumer PVC should get created with selected node": {
+			options: &KernelNFSServerOptions{
+				provisionerNS:       "openebs",
+				pvName:              "test4-pv",
+				capacity:            "5G",
+				backendStorageClass: "test4-sc",
+				selectedNode:        "node-1",
+			},
+			provisioner: &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(),
+				serverNamespace: "nfs-server-ns4",
+			},
+			expectedPVCName:      "nfs-test4-pv",
+			expectedSelectedNode: "node-1",
+			expectedDataDir:      nfsDataDir,
+		},
 	}
 
 	for name, test := range tests {
@@ -215,6 +264,15 @@ func TestCreateBackendPVC(t *testing.T) {
 				if test.expectedPVCName != nfsPVCObj.Name {
 					t.Errorf("%q test failed expected PVC name %s but got %s", name, test.expectedPVCName, nfsPVCObj.Name)
 				}
+				if test.expectedSelectedNode != nfsPVCObj.Annotations[selectedNodeAnnotation] {
+					t.Errorf("%q test failed expected selected node %q but got %q",
+						name, test.expectedSelectedNode, nfsPVCObj.Annotations[selectedNodeAnnotation])
+				}
+				if test.expectedDataDir != nfsPVCObj.Annotations[nfsServerDataDirAnnotation] ||
+					test.expectedDataDir != test.options.dataDir {
+					t.Errorf("%q test failed expected data directory %q but got %q, recorded on PVC %q",
+						name, test.expectedDataDir, test.options.dataDir, nfsPVCObj.Annotations[nfsServerDataDirAnnotation])
+				}
 			}
 		}
 	}
@@ -289,6 +347,106 @@ func TestDeleteBackendPVC(t *testing.T) {
 	}
 }
 
+func verifyDeploymentProbes(livenessDelay int32) func(*appsv1.Deployment) error {
+	return func(deployment *appsv1.Deployment) error {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if container.Name != "nfs-server" {
+				continue
+			}
+			if container.LivenessProbe == nil || container.ReadinessProbe == nil {
+				return errors.Errorf("expected nfs-server container to have liveness and readiness probes")
+			}
+			if container.LivenessProbe.InitialDelaySeconds != livenessDelay {
+				return errors.Errorf("expected liveness probe initial delay %d but got %d",
+					livenessDelay, container.LivenessProbe.InitialDelaySeconds)
+			}
+			return nil
+		}
+		return errors.Errorf("nfs-server container doesn't exist")
+	}
+}
+
+func verifyDeploymentRecoveryMount(dataDir string) func(*appsv1.Deployment) error {
+	return func(deployment *appsv1.Deployment) error {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if container.Name != "nfs-server" {
+				continue
+			}
+			var hasRecoveryMount bool
+			for _, mount := range container.VolumeMounts {
+				switch mount.MountPath {
+				case "/nfsshare":
+					if mount.SubPath != dataDir {
+						return errors.Errorf("expected %q of backend volume to be exported but got %q", dataDir, mount.SubPath)
+					}
+				case nfsRecoveryMountPath:
+					if mount.Name != "exports-dir" || mount.SubPath != nfsRecoveryDir {
+						return errors.Errorf("expected recovery directory to be mounted from %s of backend volume but got %s of %s",
+							nfsRecoveryDir, mount.SubPath, mount.Name)
+					}
+					hasRecoveryMount = true
+				}
+			}
+			if hasRecoveryMount != (dataDir != "") {
+				return errors.Errorf("expected recovery directory mount %t but got %t", dataDir != "", hasRecoveryMount)
+			}
+			return nil
+		}
+		return errors.Errorf("nfs-server container doesn't exist")
+	}
+}
+
+func verifyDeploymentPorts(portNames ...string) func(*appsv1.Deployment) error {
+	return func(deployment *appsv1.Deployment) error {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if container.Name != "nfs-server" {
+				continue
+			}
+			var names []string
+			for _, port := range container.Ports {
+				names = append(names, port.Name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(portNames) {
+				return errors.Errorf("expected nfs-server container ports %v but got %v", portNames, names)
+			}
+			return nil
+		}
+		return errors.Errorf("nfs-server container doesn't exist")
+	}
+}
+
+func verifyDeploymentMetricsExporter(enabled bool, pvName, pvcName, pvcNamespace string) func(*appsv1.Deployment) error {
+	return func(deployment *appsv1.Deployment) error {
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if container.Name != nfsMetricsExporterName {
+				continue
+			}
+			if !enabled {
+				return errors.Errorf("%s container should not exist", nfsMetricsExporterName)
+			}
+			expectedArgs := []string{
+				"--mode=exporter",
+				"--listen-address=:9500",
+				"--export-path=/nfsshare",
+				"--pv-name=" + pvName,
+				"--pvc-name=" + pvcName,
+				"--pvc-namespace=" + pvcNamespace,
+			}
+			if fmt.Sprint(container.Args) != fmt.Sprint(expectedArgs) {
+				return errors.Errorf("expected %s container args %v but got %v", nfsMetricsExporterName, expectedArgs, container.Args)
+			}
+			if len(container.VolumeMounts) != 1 || !container.VolumeMounts[0].ReadOnly {
+				return errors.Errorf("expected read-only exports-dir mount but got %v", container.VolumeMounts)
+			}
+			return nil
+		}
+		if enabled {
+			return errors.Errorf("%s container doesn't exist", nfsMetricsExporterName)
+		}
+		return nil
+	}
+}
+
 func TestCreateDeployment(t *testing.T) {
 	tests := map[string]struct {
 		options                  *KernelNFSServerOptions
@@ -303,6 +461,7 @@ func TestCreateDeployment(t *testing.T) {
 				provisionerNS:  "openebs",
 				pvName:         "test1-pv",
 				backendPvcName: "nfs-test1-pv",
+				dataDir:        nfsDataDir,
 			},
 			provisioner: &Provisioner{
 				kubeClient:      fake.NewSimpleClientset(),
@@ -314,6 +473,12 @@ func TestCreateDeployment(t *testing.T) {
 				verifyDeploymentEnvValues("CUSTOM_EXPORTS_CONFIG", ""),
 				verifyDeploymentEnvValues("NFS_LEASE_TIME", "0"),
 				verifyDeploymentEnvValues("NFS_GRACE_TIME", "0"),
+				verifyDeploymentEnvValues("NFS_VERSIONS", "4"),
+				verifyDeploymentProbes(0),
+				verifyDeploymentRecoveryMount(nfsDataDir),
+				verifyDeploymentPorts("nfs", "rpcbind"),
+				verifyDeploymentMetricsExporter(false, "", "", ""),
+				verifyDeploymentScheduling(nil, nil, "", nil),
 			},
 		},
 		"when deployment is pre-provisioned": {
@@ -371,10 +536,125 @@ func TestCreateDeployment(t *testing.T) {
 				verifyDeploymentEnvValues("CUSTOM_EXPORTS_CONFIG", "/nfsshare *(rw,fsid=0,async,no_auth_nlm)"),
 				verifyDeploymentEnvValues("NFS_LEASE_TIME", "100"),
 				verifyDeploymentEnvValues("NFS_GRACE_TIME", "100"),
+				verifyDeploymentProbes(100),
+				verifyDeploymentRecoveryMount(""),
+			},
+		},
+		"when NFSv3 is enabled then deployment should create with NFSv3 ports": {
+			options: &KernelNFSServerOptions{
+				provisionerNS:  "openebs",
+				pvName:         "test5-pv",
+				backendPvcName: "nfs-test5-pv",
+				nfsVersions:    []int{NFSVersion3, NFSVersion4},
+			},
+			provisioner: &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(),
+				serverNamespace: "nfs-server-ns5",
+			},
+			expectedDeploymentFields: []func(*appsv1.Deployment) error{
+				verifyDeploymentExistence("nfs-server-ns5", "nfs-test5-pv"),
+				verifyDeploymentEnvValues("NFS_VERSIONS", "3,4"),
+				verifyDeploymentEnvValues("MOUNTD_PORT", "20048"),
+				verifyDeploymentEnvValues("STATD_PORT", "32765"),
+				verifyDeploymentEnvValues("STATD_OUTGOING_PORT", "32766"),
+				verifyDeploymentEnvValues("LOCKD_PORT", "32768"),
+				verifyDeploymentPorts("nfs", "rpcbind", "mountd", "statd", "lockd"),
+			},
+		},
+		"when NFS server metrics are enabled then deployment should create with metrics exporter": {
+			options: &KernelNFSServerOptions{
+				provisionerNS:  "openebs",
+				pvName:         "test6-pv",
+				pvcName:        "test6-pvc",
+				pvcNamespace:   "app",
+				backendPvcName: "nfs-test6-pv",
+				metricsEnabled: true,
+			},
+			provisioner: &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(),
+				serverNamespace: "nfs-server-ns6",
+			},
+			expectedDeploymentFields: []func(*appsv1.Deployment) error{
+				verifyDeploymentExistence("nfs-server-ns6", "nfs-test6-pv"),
+				verifyDeploymentPorts("nfs", "rpcbind"),
+				verifyDeploymentMetricsExporter(true, "test6-pv", "test6-pvc", "app"),
+			},
+		},
+		"when NFS server scheduling options are specified then deployment should create with those": {
+			options: &KernelNFSServerOptions{
+				provisionerNS:  "openebs",
+				pvName:         "test7-pv",
+				backendPvcName: "nfs-test7-pv",
+				nodeAffinity: []corev1.NodeSelectorRequirement{
+					{
+						Key:      "kubernetes.io/zone",
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{"zone-1"},
+					},
+				},
+				tolerations: []corev1.Toleration{
+					{
+						Key:      "storage-node",
+						Operator: corev1.TolerationOpExists,
+						Effect:   corev1.TaintEffectNoSchedule,
+					},
+				},
+				priorityClassName: "nfs-critical",
+				topologySpreadConstraints: []corev1.TopologySpreadConstraint{
+					{
+						MaxSkew:           1,
+						TopologyKey:       "kubernetes.io/hostname",
+						WhenUnsatisfiable: corev1.ScheduleAnyway,
+					},
+				},
+			},
+			provisioner: &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(),
+				serverNamespace: "nfs-server-ns7",
+				nodeAffinity: NodeAffinity{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{
+							Key:      "openebs.io/nfs-server",
+							Operator: corev1.NodeSelectorOpExists,
+						},
+					},
+				},
+			},
+			expectedDeploymentFields: []func(*appsv1.Deployment) error{
+				verifyDeploymentExistence("nfs-server-ns7", "nfs-test7-pv"),
+				verifyDeploymentScheduling(
+					[]corev1.NodeSelectorRequirement{
+						{
+							Key:      "openebs.io/nfs-server",
+							Operator: corev1.NodeSelectorOpExists,
+						},
+						{
+							Key:      "kubernetes.io/zone",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"zone-1"},
+						},
+					},
+					[]corev1.Toleration{
+						{
+							Key:      "storage-node",
+							Operator: corev1.TolerationOpExists,
+							Effect:   corev1.TaintEffectNoSchedule,
+						},
+					},
+					"nfs-critical",
+					[]corev1.TopologySpreadConstraint{
+						{
+							MaxSkew:           1,
+							TopologyKey:       "kubernetes.io/hostname",
+							WhenUnsatisfiable: corev1.ScheduleAnyway,
+						},
+					},
+				),
 			},
 		},
 	}
 	os.Setenv(string(NFSServerImageKey), "openebs/nfs-server:ci")
+	os.Setenv(string(NFSMetricsExporterImageKey), "openebs/provisioner-nfs:ci")
 
 	for name, test := range tests {
 		name := name
@@ -418,6 +698,7 @@ func TestCreateDeployment(t *testing.T) {
 		})
 	}
 	os.Unsetenv(string(NFSServerImageKey))
+	os.Unsetenv(string(NFSMetricsExporterImageKey))
 }
 
 func TestDeleteDeployment(t *testing.T) {
@@ -495,6 +776,8 @@ func TestCreateService(t *testing.T) {
 		preProvisionedService *corev1.Service
 		isErrExpected         bool
 		expectedServiceName   string
+		expectedClusterIP     string
+		expectedPorts         []int32
 	}{
 		"when there are no errors service should get created": {
 			// NOTE: Populated only fields required for test
@@ -507,6 +790,7 @@ func TestCreateService(t *testing.T) {
 				serverNamespace: "nfs-server-ns1",
 			},
 			expectedServiceName: "nfs-test1-pv",
+			expectedPorts:       []int32{NFSServerPort, RPCBindPort},
 		},
 		"when service is pre-provisioned": {
 			options: &KernelNFSServerOptions{
@@ -532,6 +816,45 @@ func TestCreateService(t *testing.T) {
 			expectedServiceName:   "nfs-test3-pv",
 			preProvisionedService: getFakeServiceObject("openebs", "nfs-test3-pv"),
 		},
+		"when ClusterIP of deleted service is requested": {
+			options: &KernelNFSServerOptions{
+				provisionerNS: "openebs",
+				pvName:        "test4-pv",
+				clusterIP:     "10.0.0.10",
+			},
+			provisioner: &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(),
+				serverNamespace: "nfs-server-ns4",
+			},
+			expectedServiceName: "nfs-test4-pv",
+			expectedClusterIP:   "10.0.0.10",
+		},
+		"when NFSv3 is enabled": {
+			options: &KernelNFSServerOptions{
+				provisionerNS: "openebs",
+				pvName:        "test5-pv",
+				nfsVersions:   []int{NFSVersion3},
+			},
+			provisioner: &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(),
+				serverNamespace: "nfs-server-ns5",
+			},
+			expectedServiceName: "nfs-test5-pv",
+			expectedPorts:       []int32{NFSServerPort, RPCBindPort, MountdPort, StatdPort, LockdPort},
+		},
+		"when NFS server metrics are enabled": {
+			options: &KernelNFSServerOptions{
+				provisionerNS:  "openebs",
+				pvName:         "test6-pv",
+				metricsEnabled: true,
+			},
+			provisioner: &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(),
+				serverNamespace: "nfs-server-ns6",
+			},
+			expectedServiceName: "nfs-test6-pv",
+			expectedPorts:       []int32{NFSServerPort, RPCBindPort, NFSServerMetricsPort},
+		},
 	}
 
 	for name, test := range tests {
@@ -566,6 +889,15 @@ func TestCreateService(t *testing.T) {
 					if test.expectedServiceName != svcObj.Name {
 						t.Errorf("%q test failed expected service name %s but got %s", name, test.expectedServiceName, svcObj.Name)
 					}
+					assert.Equal(t, test.expectedClusterIP, svcObj.Spec.ClusterIP)
+					assert.Equal(t, test.expectedClusterIP, svcObj.Annotations[nfsServerClusterIPAnnotation])
+					if test.expectedPorts != nil {
+						var ports []int32
+						for _, port := range svcObj.Spec.Ports {
+							ports = append(ports, port.Port)
+						}
+						assert.Equal(t, test.expectedPorts, ports)
+					}
 				}
 			}
 		})
@@ -779,3 +1111,152 @@ func boundPvc(client kubernetes.Interface, obj interface{}) {
 	}
 	return
 }
+
+func TestWaitForNFSServerReady(t *testing.T) {
+	replicas := int32(1)
+	getDeployment := func(name string, readyReplicas int32) *appsv1.Deployment {
+		deploy := getFakeDeploymentObject("nfs-ns", name)
+		deploy.Spec.Replicas = &replicas
+		deploy.Status.Replicas = replicas
+		deploy.Status.UpdatedReplicas = replicas
+		deploy.Status.AvailableReplicas = readyReplicas
+		deploy.Status.ReadyReplicas = readyReplicas
+		return deploy
+	}
+
+	tests := map[string]struct {
+		deployment    *appsv1.Deployment
+		probeErr      error
+		isErrExpected bool
+	}{
+		"when NFS server is serving the volume": {
+			deployment: getDeployment("nfs-pv1", 1),
+		},
+		"when NFS server pod is not ready": {
+			deployment:    getDeployment("nfs-pv2", 0),
+			isErrExpected: true,
+		},
+		"when NFS server is not accepting NFS calls": {
+			deployment:    getDeployment("nfs-pv3", 1),
+			probeErr:      errors.New("connection refused"),
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			p := &Provisioner{
+				kubeClient:         fake.NewSimpleClientset(test.deployment),
+				serverNamespace:    "nfs-ns",
+				serverReadyTimeout: 100 * time.Millisecond,
+				prober:             &fakeNFSServerProber{err: test.probeErr},
+			}
+			opts := &KernelNFSServerOptions{
+				pvName:         test.deployment.Name,
+				deploymentName: test.deployment.Name,
+				ctx:            context.TODO(),
+			}
+
+			err := p.waitForNFSServerReady(opts, "10.0.0.10")
+			if test.isErrExpected {
+				assert.True(t, errors.Is(err, errNFSServerNotReady), "expected NFS server not ready error, got %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSetReadOnlyExport(t *testing.T) {
+	tests := map[string]struct {
+		options               *KernelNFSServerOptions
+		expectedExportsConfig string
+		isErrExpected         bool
+	}{
+		"when export options are not configured": {
+			options:               &KernelNFSServerOptions{},
+			expectedExportsConfig: "/nfsshare *(ro,fsid=0,async,no_subtree_check,no_auth_nlm,insecure,no_root_squash)",
+		},
+		"when export options are configured": {
+			options: &KernelNFSServerOptions{
+				exportOptions: &exportOptions{
+					sync:      true,
+					squash:    SquashRoot,
+					permitted: []string{"10.0.0.0/8"},
+				},
+			},
+			expectedExportsConfig: "/nfsshare 10.0.0.0/8(ro,fsid=0,sync,no_subtree_check,no_auth_nlm,insecure,root_squash)",
+		},
+		"when custom server config is configured": {
+			options: &KernelNFSServerOptions{
+				nfsServerCustomConfig: "/nfsshare *(rw,fsid=0,async,no_auth_nlm)",
+			},
+			expectedExportsConfig: "/nfsshare *(rw,fsid=0,async,no_auth_nlm)",
+			isErrExpected:         true,
+		},
+		"when NFS server is shared": {
+			options: &KernelNFSServerOptions{
+				shared: true,
+			},
+		},
+		"when NFS server type is ganesha": {
+			options: &KernelNFSServerOptions{
+				serverType: NFSServerTypeGanesha,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			err := test.options.setReadOnlyExport()
+			assert.Equal(t, test.isErrExpected, err != nil, "error: %v", err)
+			assert.Equal(t, test.expectedExportsConfig, test.options.nfsServerCustomConfig)
+		})
+	}
+}
+
+func TestGetMountOptions(t *testing.T) {
+	tests := map[string]struct {
+		nfsVersions          []int
+		mountOptions         []string
+		expectedMountOptions []string
+		expectedExportRoot   string
+	}{
+		"when NFS versions are not configured": {
+			mountOptions:         []string{"hard"},
+			expectedMountOptions: []string{"hard"},
+			expectedExportRoot:   "/",
+		},
+		"when NFSv3 and NFSv4 are enabled": {
+			nfsVersions:          []int{NFSVersion3, NFSVersion4},
+			expectedMountOptions: nil,
+			expectedExportRoot:   "/",
+		},
+		"when only NFSv3 is enabled": {
+			nfsVersions:          []int{NFSVersion3},
+			mountOptions:         []string{"hard"},
+			expectedMountOptions: []string{"hard", "nfsvers=3"},
+			expectedExportRoot:   "/nfsshare",
+		},
+		"when only NFSv3 is enabled and NFS version is set in mount options": {
+			nfsVersions:          []int{NFSVersion3},
+			mountOptions:         []string{"vers=3.0"},
+			expectedMountOptions: []string{"vers=3.0"},
+			expectedExportRoot:   "/nfsshare",
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			options := &KernelNFSServerOptions{nfsVersions: test.nfsVersions}
+			assert.Equal(t, test.expectedMountOptions, options.getMountOptions(test.mountOptions))
+			assert.Equal(t, test.expectedExportRoot, options.getExportRoot())
+		})
+	}
+}
@@ -0,0 +1,394 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	container "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/container"
+	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	errors "github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// nfsCloneLabelKey is set on the clone job. Value of the
+	// label is the name of the NFS PV being populated
+	nfsCloneLabelKey = "openebs.io/nfs-clone"
+
+	// nfsClonedFromAnnotation is set on the backend PVC, once the data
+	// is copied from the source NFS PV. Value of the annotation is the
+	// name of the source NFS PV
+	nfsClonedFromAnnotation = "nfs.openebs.io/cloned-from"
+
+	// defaultStorageClassAnnotation is set on the default StorageClass
+	defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+	// cloneJobBackoffLimit is the number of retries of the clone job
+	cloneJobBackoffLimit int32 = 3
+)
+
+// nfsCloneSource contains the details of the source NFS PV, from which
+// the data is copied to the backend volume of the clone
+type nfsCloneSource struct {
+	// pvName is the name of the source NFS PV
+	pvName string
+
+	// server is the address of the source NFS server
+	server string
+
+	// path is the path exported by the source NFS server
+	path string
+}
+
+// initCloneSource updates the NFS server options to populate the backend
+// volume from the NFS PVC referred by the dataSource of the given PVC. If
+// the backend CSI driver can clone the source backend PVC, the backend PVC
+// is created with the source backend PVC as dataSource. Otherwise the data
+// is copied from the source NFS server by a clone job.
+func (p *Provisioner) initCloneSource(nfsServerOpts *KernelNFSServerOptions, pvc *corev1.PersistentVolumeClaim, volumeConfig *VolumeConfig) error {
+	ctx := nfsServerOpts.ctx
+	dataSource := pvc.Spec.DataSource
+
+	if dataSource.Kind != "PersistentVolumeClaim" ||
+		(dataSource.APIGroup != nil && *dataSource.APIGroup != "") {
+		return errors.Errorf("unsupported dataSource %s of PVC {%s/%s}, only PersistentVolumeClaim is supported",
+			dataSource.Kind, pvc.Namespace, pvc.Name)
+	}
+
+	strategy, err := volumeConfig.GetCloneStrategy()
+	if err != nil {
+		return err
+	}
+
+	sourcePvc, err := p.kubeClient.CoreV1().
+		PersistentVolumeClaims(pvc.Namespace).
+		Get(ctx, dataSource.Name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get source PVC {%s/%s}", pvc.Namespace, dataSource.Name)
+	}
+	if sourcePvc.Status.Phase != corev1.ClaimBound || len(sourcePvc.Spec.VolumeName) == 0 {
+		return errors.Errorf("source PVC {%s/%s} is not bound", sourcePvc.Namespace, sourcePvc.Name)
+	}
+
+	sourceCapacity := sourcePvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if capacity.Cmp(sourceCapacity) < 0 {
+		return errors.Errorf("capacity %s of PVC {%s/%s} is less than capacity %s of source PVC {%s/%s}",
+			capacity.String(), pvc.Namespace, pvc.Name, sourceCapacity.String(), sourcePvc.Namespace, sourcePvc.Name)
+	}
+
+	sourcePv, err := p.kubeClient.CoreV1().
+		PersistentVolumes().
+		Get(ctx, sourcePvc.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get source PV %s", sourcePvc.Spec.VolumeName)
+	}
+	if sourcePv.Spec.NFS == nil ||
+		!strings.HasPrefix(sourcePv.Labels[string(mconfig.CASTypeKey)], "nfs-") {
+		return errors.Errorf("source PV %s is not a NFS PV", sourcePv.Name)
+	}
+
+	if strategy != CloneStrategyCopy {
+		sourceBackendPvc, err := p.getCloneableBackendPVC(ctx, sourcePv, nfsServerOpts.backendStorageClass)
+		if err != nil {
+			return err
+		}
+		if sourceBackendPvc != nil {
+			klog.Infof("Cloning backend PVC nfs-%s for volume %s", sourcePv.Name, nfsServerOpts.pvName)
+			nfsServerOpts.backendDataSource = &corev1.TypedLocalObjectReference{
+				Kind: "PersistentVolumeClaim",
+				Name: "nfs-" + sourcePv.Name,
+			}
+			// cloned backend volume has the layout of the source
+			nfsServerOpts.dataDir = sourceBackendPvc.Annotations[nfsServerDataDirAnnotation]
+			return nil
+		}
+		if strategy == CloneStrategyCSI {
+			return errors.Errorf("backend PVC of source PV %s can't be cloned by the backend CSI driver", sourcePv.Name)
+		}
+	}
+
+	klog.Infof("Copying data of source PV %s for volume %s", sourcePv.Name, nfsServerOpts.pvName)
+	nfsServerOpts.cloneSource = &nfsCloneSource{
+		pvName: sourcePv.Name,
+		server: sourcePv.Spec.NFS.Server,
+		path:   sourcePv.Spec.NFS.Path,
+	}
+	return nil
+}
+
+// getCloneableBackendPVC returns the backend PVC of the given source NFS
+// PV, if it can be cloned by the backend CSI driver. Otherwise it returns
+// nil. CSI volume cloning requires the source and clone to be of the same
+// StorageClass, which is provisioned by a CSI driver.
+func (p *Provisioner) getCloneableBackendPVC(ctx context.Context, sourcePv *corev1.PersistentVolume, backendStorageClass string) (*corev1.PersistentVolumeClaim, error) {
+	if isSharedNFSVolume(sourcePv) || GetNFSServerTypeFromPV(sourcePv) == NFSServerTypeExternal {
+		// source volume doesn't have a dedicated backend PVC
+		return nil, nil
+	}
+
+	sourceBackendPvc, err := p.kubeClient.CoreV1().
+		PersistentVolumeClaims(p.serverNamespace).
+		Get(ctx, "nfs-"+sourcePv.Name, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get backend PVC of source PV %s", sourcePv.Name)
+	}
+
+	scName := backendStorageClass
+	if len(scName) == 0 {
+		scName, err = p.getDefaultStorageClass(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if sourceBackendPvc.Spec.StorageClassName == nil || *sourceBackendPvc.Spec.StorageClassName != scName {
+		return nil, nil
+	}
+
+	sc, err := p.kubeClient.StorageV1().StorageClasses().Get(ctx, scName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get StorageClass %s", scName)
+	}
+
+	_, err = p.kubeClient.StorageV1().CSIDrivers().Get(ctx, sc.Provisioner, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// backend volume is not provisioned by a CSI driver
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to get CSIDriver %s", sc.Provisioner)
+	}
+	return sourceBackendPvc, nil
+}
+
+// getDefaultStorageClass returns the name of the default StorageClass
+func (p *Provisioner) getDefaultStorageClass(ctx context.Context) (string, error) {
+	scList, err := p.kubeClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list StorageClasses")
+	}
+
+	for _, sc := range scList.Items {
+		if sc.Annotations[defaultStorageClassAnnotation] == "true" {
+			return sc.Name, nil
+		}
+	}
+	return "", nil
+}
+
+// copyCloneSource copies the data of the source NFS PV to the backend
+// volume using the clone job, and records the source NFS PV on the
+// backend PVC once the copy is completed. If the data is already copied,
+// it returns without running the clone job.
+func (p *Provisioner) copyCloneSource(nfsServerOpts *KernelNFSServerOptions) error {
+	ctx := nfsServerOpts.ctx
+	backendPvcName := "nfs-" + nfsServerOpts.pvName
+	source := nfsServerOpts.cloneSource
+
+	backendPvc, err := p.kubeClient.CoreV1().
+		PersistentVolumeClaims(p.serverNamespace).
+		Get(ctx, backendPvcName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get backend PVC {%s/%s}", p.serverNamespace, backendPvcName)
+	}
+	if backendPvc.Annotations[nfsClonedFromAnnotation] == source.pvName {
+		return nil
+	}
+
+	if err = p.createCloneJob(nfsServerOpts); err != nil {
+		return err
+	}
+
+	err = p.waitForCloneJob(ctx, nfsServerOpts.pvName, p.backendPvcTimeout)
+	if err != nil {
+		return err
+	}
+
+	patch := []byte(`{"metadata":{"annotations":{"` + nfsClonedFromAnnotation + `":"` + source.pvName + `"}}}`)
+	_, err = p.kubeClient.CoreV1().
+		PersistentVolumeClaims(p.serverNamespace).
+		Patch(ctx, backendPvcName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update backend PVC {%s/%s}", p.serverNamespace, backendPvcName)
+	}
+
+	klog.Infof("Copied data of source PV %s to backend PVC {%s/%s}", source.pvName, p.serverNamespace, backendPvcName)
+	return p.deleteCloneJob(ctx, nfsServerOpts.pvName)
+}
+
+// getCloneJobName returns the name of the clone job of the given NFS PV
+func getCloneJobName(pvName string) string {
+	return "clone-" + pvName
+}
+
+// createCloneJob creates the job which copies the data from the source
+// NFS server to the backend volume, if it doesn't exist
+func (p *Provisioner) createCloneJob(nfsServerOpts *KernelNFSServerOptions) error {
+	jobName := getCloneJobName(nfsServerOpts.pvName)
+	source := nfsServerOpts.cloneSource
+
+	// NFSv4 recovery records belong to the clients of the source
+	// NFS server, so these are not copied, if the source NFS server
+	// exports the root of its backend volume. Data is copied to the
+	// data directory of the backend volume.
+	containerObj, err := container.NewBuilder().
+		WithName("clone").
+		WithImage(getNFSServerImage()).
+		WithImagePullPolicy(corev1.PullIfNotPresent).
+		WithCommandNew([]string{"rsync"}).
+		WithArgumentsNew([]string{"-aH", "--exclude", "/" + nfsRecoveryDir, "/source/", "/dest/"}).
+		WithVolumeMountsNew(
+			[]corev1.VolumeMount{
+				{
+					Name:      "source",
+					MountPath: "/source",
+					ReadOnly:  true,
+				},
+				{
+					Name:      "dest",
+					MountPath: "/dest",
+					SubPath:   nfsServerOpts.dataDir,
+				},
+			},
+		).
+		Build()
+	if err != nil {
+		return errors.Wrapf(err, "unable to build clone container")
+	}
+
+	backoffLimit := cloneJobBackoffLimit
+	labels := map[string]string{
+		nfsCloneLabelKey: nfsServerOpts.pvName,
+	}
+	jobObj := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: p.serverNamespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers:    []corev1.Container{containerObj},
+					Volumes: []corev1.Volume{
+						{
+							Name: "source",
+							VolumeSource: corev1.VolumeSource{
+								NFS: &corev1.NFSVolumeSource{
+									Server:   source.server,
+									Path:     source.path,
+									ReadOnly: true,
+								},
+							},
+						},
+						{
+							Name: "dest",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: "nfs-" + nfsServerOpts.pvName,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if secret := getNfsServerImagePullSecret(); secret != "" {
+		jobObj.Spec.Template.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: secret}}
+	}
+
+	_, err = p.kubeClient.BatchV1().
+		Jobs(p.serverNamespace).
+		Create(nfsServerOpts.ctx, jobObj, metav1.CreateOptions{})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "failed to create clone job {%s/%s}", p.serverNamespace, jobName)
+	}
+	return nil
+}
+
+// waitForCloneJob waits for the clone job of the given NFS PV to complete,
+// for the timeout period. If the job fails, it is deleted so that it is
+// recreated on retry.
+func (p *Provisioner) waitForCloneJob(ctx context.Context, pvName string, timeout time.Duration) error {
+	jobName := getCloneJobName(pvName)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	timeoutCh := timer.C
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	for {
+		job, err := p.kubeClient.BatchV1().
+			Jobs(p.serverNamespace).
+			Get(ctx, jobName, metav1.GetOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to get clone job {%s/%s}", p.serverNamespace, jobName)
+		}
+
+		if job.Status.Succeeded > 0 {
+			return nil
+		}
+		for _, cond := range job.Status.Conditions {
+			if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+				if e := p.deleteCloneJob(ctx, pvName); e != nil {
+					klog.Errorf("Failed to delete clone job {%s/%s}, err=%v", p.serverNamespace, jobName, e)
+				}
+				return errors.Errorf("clone job {%s/%s} failed: %s", p.serverNamespace, jobName, cond.Message)
+			}
+		}
+
+		select {
+		case <-timeoutCh:
+			return errors.Errorf("clone job {%s/%s} is in progress", p.serverNamespace, jobName)
+		case <-tick.C:
+		}
+	}
+}
+
+// deleteCloneJob deletes the clone job of the given NFS PV, if exists
+func (p *Provisioner) deleteCloneJob(ctx context.Context, pvName string) error {
+	jobName := getCloneJobName(pvName)
+	propagation := metav1.DeletePropagationBackground
+
+	err := p.kubeClient.BatchV1().
+		Jobs(p.serverNamespace).
+		Delete(ctx, jobName, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete clone job {%s/%s}", p.serverNamespace, jobName)
+	}
+	return nil
+}
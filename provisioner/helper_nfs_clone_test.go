@@ -0,0 +1,259 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func getFakeClonePvc(name, sourceName, capacity string) *corev1.PersistentVolumeClaim {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "app",
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse(capacity),
+				},
+			},
+		},
+	}
+	if sourceName != "" {
+		pvc.Spec.DataSource = &corev1.TypedLocalObjectReference{
+			Kind: "PersistentVolumeClaim",
+			Name: sourceName,
+		}
+	}
+	return pvc
+}
+
+func getFakeCloneSourceObjects(backendSC string, csiDriver bool) []runtime.Object {
+	sourcePvc := getFakeClonePvc("source", "", "5Gi")
+	sourcePvc.Spec.VolumeName = "pvc-source"
+	sourcePvc.Status.Phase = corev1.ClaimBound
+
+	sourcePv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "pvc-source",
+			Labels: map[string]string{
+				"openebs.io/cas-type": "nfs-kernel",
+			},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				NFS: &corev1.NFSVolumeSource{
+					Server: "10.0.0.20",
+					Path:   "/",
+				},
+			},
+		},
+	}
+
+	sourceBackendPvc := getFakeClonePvc("nfs-pvc-source", "", "5Gi")
+	sourceBackendPvc.Namespace = "nfs-ns"
+	sourceBackendPvc.Spec.StorageClassName = &backendSC
+
+	objs := []runtime.Object{
+		sourcePvc,
+		sourcePv,
+		sourceBackendPvc,
+		&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: backendSC},
+			Provisioner: "backend.csi.io",
+		},
+	}
+	if csiDriver {
+		objs = append(objs, &storagev1.CSIDriver{
+			ObjectMeta: metav1.ObjectMeta{Name: "backend.csi.io"},
+		})
+	}
+	return objs
+}
+
+func TestInitCloneSource(t *testing.T) {
+	tests := map[string]struct {
+		pvc                       *corev1.PersistentVolumeClaim
+		objs                      []runtime.Object
+		cloneStrategy             string
+		expectedBackendDataSource *corev1.TypedLocalObjectReference
+		expectedCloneSource       *nfsCloneSource
+		isErrExpected             bool
+	}{
+		"when backend CSI driver can clone the source backend PVC": {
+			pvc:  getFakeClonePvc("clone", "source", "5Gi"),
+			objs: getFakeCloneSourceObjects("backend-sc", true),
+			expectedBackendDataSource: &corev1.TypedLocalObjectReference{
+				Kind: "PersistentVolumeClaim",
+				Name: "nfs-pvc-source",
+			},
+		},
+		"when backend volume is not provisioned by a CSI driver": {
+			pvc:  getFakeClonePvc("clone", "source", "5Gi"),
+			objs: getFakeCloneSourceObjects("backend-sc", false),
+			expectedCloneSource: &nfsCloneSource{
+				pvName: "pvc-source",
+				server: "10.0.0.20",
+				path:   "/",
+			},
+		},
+		"when backend StorageClass of source is different": {
+			pvc:  getFakeClonePvc("clone", "source", "5Gi"),
+			objs: getFakeCloneSourceObjects("other-sc", true),
+			expectedCloneSource: &nfsCloneSource{
+				pvName: "pvc-source",
+				server: "10.0.0.20",
+				path:   "/",
+			},
+		},
+		"when copy clone strategy is configured": {
+			pvc:           getFakeClonePvc("clone", "source", "5Gi"),
+			objs:          getFakeCloneSourceObjects("backend-sc", true),
+			cloneStrategy: CloneStrategyCopy,
+			expectedCloneSource: &nfsCloneSource{
+				pvName: "pvc-source",
+				server: "10.0.0.20",
+				path:   "/",
+			},
+		},
+		"when csi clone strategy is configured but not supported": {
+			pvc:           getFakeClonePvc("clone", "source", "5Gi"),
+			objs:          getFakeCloneSourceObjects("backend-sc", false),
+			cloneStrategy: CloneStrategyCSI,
+			isErrExpected: true,
+		},
+		"when capacity is less than the source": {
+			pvc:           getFakeClonePvc("clone", "source", "1Gi"),
+			objs:          getFakeCloneSourceObjects("backend-sc", true),
+			isErrExpected: true,
+		},
+		"when source PVC doesn't exist": {
+			pvc:           getFakeClonePvc("clone", "missing", "5Gi"),
+			objs:          getFakeCloneSourceObjects("backend-sc", true),
+			isErrExpected: true,
+		},
+		"when dataSource is not a PVC": {
+			pvc: func() *corev1.PersistentVolumeClaim {
+				pvc := getFakeClonePvc("clone", "source", "5Gi")
+				pvc.Spec.DataSource.Kind = "VolumeSnapshot"
+				return pvc
+			}(),
+			objs:          getFakeCloneSourceObjects("backend-sc", true),
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			p := &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(test.objs...),
+				serverNamespace: "nfs-ns",
+			}
+			volumeConfig := &VolumeConfig{
+				options: map[string]interface{}{},
+			}
+			if test.cloneStrategy != "" {
+				volumeConfig.options[CloneStrategy] = map[string]string{
+					"value": test.cloneStrategy,
+				}
+			}
+			opts := &KernelNFSServerOptions{
+				pvName:              "pvc-clone",
+				backendStorageClass: "backend-sc",
+				ctx:                 context.TODO(),
+			}
+
+			err := p.initCloneSource(opts, test.pvc, volumeConfig)
+			if test.isErrExpected {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedBackendDataSource, opts.backendDataSource)
+			assert.Equal(t, test.expectedCloneSource, opts.cloneSource)
+		})
+	}
+}
+
+func TestCopyCloneSource(t *testing.T) {
+	os.Setenv(string(NFSServerImageKey), "openebs/nfs-server-alpine:ci")
+	defer os.Unsetenv(string(NFSServerImageKey))
+
+	backendPvc := getFakeClonePvc("nfs-pvc-clone", "", "5Gi")
+	backendPvc.Namespace = "nfs-ns"
+
+	var createdJobs []*batchv1.Job
+	client := fake.NewSimpleClientset(backendPvc)
+	client.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+		job.Status.Succeeded = 1
+		createdJobs = append(createdJobs, job.DeepCopy())
+		return false, nil, nil
+	})
+
+	p := &Provisioner{
+		kubeClient:        client,
+		serverNamespace:   "nfs-ns",
+		backendPvcTimeout: 5 * time.Second,
+	}
+	opts := &KernelNFSServerOptions{
+		pvName: "pvc-clone",
+		cloneSource: &nfsCloneSource{
+			pvName: "pvc-source",
+			server: "10.0.0.20",
+			path:   "/",
+		},
+		ctx: context.TODO(),
+	}
+
+	assert.NoError(t, p.copyCloneSource(opts))
+	if assert.Len(t, createdJobs, 1) {
+		podSpec := createdJobs[0].Spec.Template.Spec
+		assert.Equal(t, "clone-pvc-clone", createdJobs[0].Name)
+		assert.Equal(t, "10.0.0.20", podSpec.Volumes[0].NFS.Server)
+		assert.Equal(t, "nfs-pvc-clone", podSpec.Volumes[1].PersistentVolumeClaim.ClaimName)
+	}
+
+	// clone job should be removed once it is completed
+	_, err := client.BatchV1().Jobs("nfs-ns").Get(context.TODO(), "clone-pvc-clone", metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "clone job should be deleted, err=%v", err)
+
+	pvc, err := client.CoreV1().PersistentVolumeClaims("nfs-ns").Get(context.TODO(), "nfs-pvc-clone", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "pvc-source", pvc.Annotations[nfsClonedFromAnnotation])
+
+	// data is copied only once
+	assert.NoError(t, p.copyCloneSource(opts))
+	assert.Len(t, createdJobs, 1)
+}
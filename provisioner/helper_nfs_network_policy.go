@@ -0,0 +1,266 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+	errors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// nfsServerLabelKey is set on the NFS server pods and on the
+	// NetworkPolicy of the NFS server. Value of the label is the
+	// name of the NFS server Deployment
+	nfsServerLabelKey = "openebs.io/nfs-server"
+
+	// namespaceNameLabelKey is set by Kubernetes on every namespace.
+	// Value of the label is the name of the namespace
+	namespaceNameLabelKey = "kubernetes.io/metadata.name"
+)
+
+// networkPolicyOptions defines the clients allowed, in addition to the
+// namespace of the NFS PVC, by the NetworkPolicy of the NFS server
+type networkPolicyOptions struct {
+	namespaceSelectors []*metav1.LabelSelector
+	ipBlocks           []string
+}
+
+// parseNetworkPolicyOptions validates the given NetworkPolicy data
+// and returns the NetworkPolicy options
+func parseNetworkPolicyOptions(data map[string]string) (*networkPolicyOptions, error) {
+	opts := &networkPolicyOptions{}
+
+	for key, val := range data {
+		val = strings.TrimSpace(val)
+		if len(val) == 0 {
+			continue
+		}
+
+		switch key {
+		case NetworkPolicyNamespaceSelectors:
+			for _, selectorStr := range strings.Split(val, ";") {
+				selectorStr = strings.TrimSpace(selectorStr)
+				if len(selectorStr) == 0 {
+					continue
+				}
+				selector, err := metav1.ParseToLabelSelector(selectorStr)
+				if err != nil {
+					return nil, errors.Wrapf(err, "invalid %s value %q", key, selectorStr)
+				}
+				opts.namespaceSelectors = append(opts.namespaceSelectors, selector)
+			}
+		case NetworkPolicyIPBlocks:
+			for _, cidr := range strings.Split(val, ",") {
+				cidr = strings.TrimSpace(cidr)
+				if _, _, err := net.ParseCIDR(cidr); err != nil {
+					return nil, errors.Wrapf(err, "invalid %s value %q", key, cidr)
+				}
+				opts.ipBlocks = append(opts.ipBlocks, cidr)
+			}
+		default:
+			return nil, errors.Errorf("unknown option %q", key)
+		}
+	}
+	return opts, nil
+}
+
+// getNetworkPolicyName returns the name of the NetworkPolicy
+// of the NFS server of the given PV
+func getNetworkPolicyName(pvName string) string {
+	return "nfs-" + pvName
+}
+
+// createNetworkPolicy creates the NetworkPolicy of the NFS server, if it
+// is enabled for the volume. The NetworkPolicy allows the ingress on the
+// NFS server ports from the namespace of the NFS PVC, the namespace of
+// the provisioner and the clients configured in NetworkPolicy options.
+func (p *Provisioner) createNetworkPolicy(nfsServerOpts *KernelNFSServerOptions) error {
+	if nfsServerOpts.networkPolicy == nil {
+		return nil
+	}
+
+	klog.V(4).Infof("Creating NetworkPolicy")
+	if err := nfsServerOpts.validate(); err != nil {
+		return err
+	}
+
+	if len(nfsServerOpts.pvcNamespace) == 0 {
+		return errors.Errorf("namespace of the NFS PVC of volume %s is unknown, required by %s",
+			nfsServerOpts.pvName, NetworkPolicy)
+	}
+
+	policyName := getNetworkPolicyName(nfsServerOpts.pvName)
+
+	_, err := p.kubeClient.NetworkingV1().
+		NetworkPolicies(p.serverNamespace).
+		Get(nfsServerOpts.ctx, policyName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to check existence of NetworkPolicy {%s/%s}", p.serverNamespace, policyName)
+	}
+	if err == nil {
+		klog.Infof("Volume %v has been initialized with NetworkPolicy {%s/%s}", nfsServerOpts.pvName, p.serverNamespace, policyName)
+		return nil
+	}
+
+	deployName := "nfs-" + nfsServerOpts.pvName
+	labels := map[string]string{
+		nfsServerLabelKey: deployName,
+	}
+	for k, v := range nfsServerOpts.getOwnerLabels() {
+		labels[k] = v
+	}
+
+	var peers []networkingv1.NetworkPolicyPeer
+	for _, ns := range []string{nfsServerOpts.pvcNamespace, nfsServerOpts.provisionerNS} {
+		if len(ns) == 0 {
+			continue
+		}
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{namespaceNameLabelKey: ns},
+			},
+		})
+	}
+	for _, selector := range nfsServerOpts.networkPolicy.namespaceSelectors {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: selector.DeepCopy(),
+		})
+	}
+	for _, cidr := range nfsServerOpts.networkPolicy.ipBlocks {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			IPBlock: &networkingv1.IPBlock{CIDR: cidr},
+		})
+	}
+
+	var ports []networkingv1.NetworkPolicyPort
+	for _, servicePort := range nfsServerOpts.getServicePorts() {
+		port := intstr.FromInt(int(servicePort.Port))
+		protocol := corev1.ProtocolTCP
+		ports = append(ports, networkingv1.NetworkPolicyPort{
+			Protocol: &protocol,
+			Port:     &port,
+		})
+	}
+
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      policyName,
+			Namespace: p.serverNamespace,
+			Labels:    labels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{nfsServerLabelKey: deployName},
+			},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From:  peers,
+					Ports: ports,
+				},
+			},
+		},
+	}
+
+	_, err = p.kubeClient.NetworkingV1().
+		NetworkPolicies(p.serverNamespace).
+		Create(nfsServerOpts.ctx, policy, metav1.CreateOptions{})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "failed to create NetworkPolicy {%s/%s}", p.serverNamespace, policyName)
+	}
+
+	klog.Infof("NetworkPolicy {%s/%s} created for volume %s", p.serverNamespace, policyName, nfsServerOpts.pvName)
+	return nil
+}
+
+// deleteNetworkPolicy deletes the NetworkPolicy of the NFS server, if exists
+func (p *Provisioner) deleteNetworkPolicy(nfsServerOpts *KernelNFSServerOptions) error {
+	klog.V(4).Infof("Deleting NetworkPolicy")
+	if err := nfsServerOpts.validate(); err != nil {
+		return err
+	}
+
+	policyName := getNetworkPolicyName(nfsServerOpts.pvName)
+
+	err := p.kubeClient.NetworkingV1().
+		NetworkPolicies(p.serverNamespace).
+		Delete(nfsServerOpts.ctx, policyName, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete NetworkPolicy {%s/%s} associated with PV:%s",
+			p.serverNamespace, policyName, nfsServerOpts.pvName)
+	}
+	return nil
+}
+
+// cleanUpStaleNetworkPolicies removes the NetworkPolicies of the NFS
+// servers whose backend PVC and NFS PV don't exist. It can happen if the
+// NFS server was deleted while the NetworkPolicy couldn't be deleted.
+func cleanUpStaleNetworkPolicies(ctx context.Context, client kubernetes.Interface, pvTracker ProvisioningTracker, ns string) error {
+	policyList, err := client.NetworkingV1().NetworkPolicies(ns).List(ctx, metav1.ListOptions{LabelSelector: nfsServerLabelKey})
+	if err != nil {
+		klog.Errorf("Failed to list NetworkPolicies, err=%s", err)
+		return err
+	}
+
+	for _, policy := range policyList.Items {
+		nfsPvName := strings.TrimPrefix(policy.Name, "nfs-")
+		if nfsPvName == policy.Name || pvTracker.Inprogress(nfsPvName) {
+			continue
+		}
+
+		_, err = client.CoreV1().PersistentVolumeClaims(ns).Get(ctx, policy.Name, metav1.GetOptions{})
+		if err == nil {
+			// NFS server resources are cleaned up along with the backend PVC
+			continue
+		}
+		if !k8serrors.IsNotFound(err) {
+			klog.Errorf("Failed to check backend PVC of NetworkPolicy=%s/%s, err=%v", ns, policy.Name, err)
+			continue
+		}
+
+		// NFS PV is checked as well, since the backend PVC may be
+		// recreated by the reconciler
+		exists, err := pvExists(ctx, client, nfsPvName)
+		if err != nil {
+			klog.Errorf("Failed to check NFS PV of NetworkPolicy=%s/%s, err=%v", ns, policy.Name, err)
+			continue
+		}
+		if exists {
+			continue
+		}
+
+		klog.Infof("Deleting stale NetworkPolicy=%s/%s", ns, policy.Name)
+		err = client.NetworkingV1().NetworkPolicies(ns).Delete(ctx, policy.Name, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			klog.Errorf("Failed to delete NetworkPolicy=%s/%s, err=%v", ns, policy.Name, err)
+			continue
+		}
+		metrics.GarbageCollectorDeleteTotal.WithLabelValues(gcResourceNetworkPolicy).Inc()
+	}
+	return nil
+}
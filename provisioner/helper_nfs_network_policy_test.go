@@ -0,0 +1,204 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func getFakeNetworkPolicyObject(namespace, name string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{nfsServerLabelKey: name},
+		},
+	}
+}
+
+func TestCreateNetworkPolicy(t *testing.T) {
+	tests := map[string]struct {
+		options               *KernelNFSServerOptions
+		expectedNamespaces    []string
+		expectedSelectorCount int
+		expectedIPBlocks      []string
+		expectedPorts         []int
+		isPolicyExpected      bool
+		isErrExpected         bool
+	}{
+		"when NetworkPolicy is not enabled": {
+			options: &KernelNFSServerOptions{
+				pvName:       "test1-pv",
+				pvcNamespace: "app",
+			},
+		},
+		"when NetworkPolicy is enabled": {
+			options: &KernelNFSServerOptions{
+				provisionerNS: "openebs",
+				pvName:        "test2-pv",
+				pvcNamespace:  "app",
+				networkPolicy: &networkPolicyOptions{},
+			},
+			expectedNamespaces: []string{"app", "openebs"},
+			expectedPorts:      []int{NFSServerPort, RPCBindPort},
+			isPolicyExpected:   true,
+		},
+		"when NetworkPolicy allows additional clients and NFSv3 is enabled": {
+			options: &KernelNFSServerOptions{
+				provisionerNS: "openebs",
+				pvName:        "test3-pv",
+				pvcNamespace:  "app",
+				nfsVersions:   []int{NFSVersion3, NFSVersion4},
+				networkPolicy: &networkPolicyOptions{
+					namespaceSelectors: []*metav1.LabelSelector{
+						{MatchLabels: map[string]string{"team": "frontend"}},
+					},
+					ipBlocks: []string{"10.0.0.0/16"},
+				},
+			},
+			expectedNamespaces:    []string{"app", "openebs"},
+			expectedSelectorCount: 1,
+			expectedIPBlocks:      []string{"10.0.0.0/16"},
+			expectedPorts:         []int{NFSServerPort, RPCBindPort, MountdPort, StatdPort, LockdPort},
+			isPolicyExpected:      true,
+		},
+		"when namespace of NFS PVC is unknown": {
+			options: &KernelNFSServerOptions{
+				pvName:        "test4-pv",
+				networkPolicy: &networkPolicyOptions{},
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			p := &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(),
+				serverNamespace: "nfs-server-ns",
+			}
+			test.options.ctx = context.TODO()
+
+			err := p.createNetworkPolicy(test.options)
+			assert.Equal(t, test.isErrExpected, err != nil, "error: %v", err)
+
+			policy, err := p.kubeClient.NetworkingV1().
+				NetworkPolicies(p.serverNamespace).
+				Get(context.TODO(), "nfs-"+test.options.pvName, metav1.GetOptions{})
+			if !test.isPolicyExpected {
+				assert.True(t, k8serrors.IsNotFound(err), "expected NetworkPolicy not to exist, error: %v", err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, "nfs-"+test.options.pvName, policy.Spec.PodSelector.MatchLabels[nfsServerLabelKey])
+			assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}, policy.Spec.PolicyTypes)
+			if !assert.Len(t, policy.Spec.Ingress, 1) {
+				return
+			}
+
+			var namespaces, ipBlocks []string
+			var selectorCount int
+			for _, peer := range policy.Spec.Ingress[0].From {
+				switch {
+				case peer.IPBlock != nil:
+					ipBlocks = append(ipBlocks, peer.IPBlock.CIDR)
+				case len(peer.NamespaceSelector.MatchLabels[namespaceNameLabelKey]) != 0:
+					namespaces = append(namespaces, peer.NamespaceSelector.MatchLabels[namespaceNameLabelKey])
+				default:
+					selectorCount++
+				}
+			}
+			assert.Equal(t, test.expectedNamespaces, namespaces)
+			assert.Equal(t, test.expectedSelectorCount, selectorCount)
+			assert.Equal(t, test.expectedIPBlocks, ipBlocks)
+
+			var ports []int
+			for _, port := range policy.Spec.Ingress[0].Ports {
+				ports = append(ports, port.Port.IntValue())
+			}
+			assert.Equal(t, test.expectedPorts, ports)
+		})
+	}
+}
+
+func TestCleanUpStaleNetworkPolicies(t *testing.T) {
+	ns := "nfs-server-ns"
+
+	tests := map[string]struct {
+		objs                 []runtime.Object
+		inProgressPV         string
+		expectedPolicyExists bool
+	}{
+		"when backend PVC and NFS PV don't exist": {
+			objs: []runtime.Object{
+				getFakeNetworkPolicyObject(ns, "nfs-test1-pv"),
+			},
+		},
+		"when backend PVC exists": {
+			objs: []runtime.Object{
+				getFakeNetworkPolicyObject(ns, "nfs-test1-pv"),
+				getFakePVCObject(ns, "nfs-test1-pv", "openebs-hostpath", "uid"),
+			},
+			expectedPolicyExists: true,
+		},
+		"when NFS PV exists": {
+			objs: []runtime.Object{
+				getFakeNetworkPolicyObject(ns, "nfs-test1-pv"),
+				getFakeNFSPvObject("test1-pv", "5Gi", provisionerName),
+			},
+			expectedPolicyExists: true,
+		},
+		"when NFS PV is being provisioned": {
+			objs: []runtime.Object{
+				getFakeNetworkPolicyObject(ns, "nfs-test1-pv"),
+			},
+			inProgressPV:         "test1-pv",
+			expectedPolicyExists: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(test.objs...)
+			pvTracker := NewProvisioningTracker()
+			if len(test.inProgressPV) != 0 {
+				pvTracker.Add(test.inProgressPV)
+			}
+
+			err := cleanUpStaleNetworkPolicies(context.TODO(), client, pvTracker, ns)
+			assert.NoError(t, err)
+
+			_, err = client.NetworkingV1().NetworkPolicies(ns).Get(context.TODO(), "nfs-test1-pv", metav1.GetOptions{})
+			assert.Equal(t, test.expectedPolicyExists, err == nil, "error: %v", err)
+		})
+	}
+}
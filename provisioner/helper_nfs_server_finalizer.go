@@ -0,0 +1,211 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/helper"
+	errors "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// protectNFSServerResources adds the NFSPVFinalizer to the backend PVC,
+// Deployment and Service of the given NFS PV, so that they are not
+// deleted while the NFS PV exists. Deployment and Service are also owned
+// by the backend PVC, so that they are garbage collected along with it.
+// Finalizers are removed by deleteNFSServer.
+func (p *Provisioner) protectNFSServerResources(nfsServerOpts *KernelNFSServerOptions) error {
+	if nfsServerOpts.shared {
+		// Shared NFS server is not owned by a single NFS PV,
+		// and it is not deleted along with the NFS PV
+		return nil
+	}
+
+	ctx := nfsServerOpts.ctx
+	name := "nfs-" + nfsServerOpts.pvName
+
+	pvcObj, err := p.kubeClient.CoreV1().
+		PersistentVolumeClaims(p.serverNamespace).
+		Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get backend PVC {%s/%s}", p.serverNamespace, name)
+	}
+	if !hasFinalizer(pvcObj.ObjectMeta, NFSPVFinalizer) {
+		helper.AddFinalizers(&pvcObj.ObjectMeta, []string{NFSPVFinalizer})
+		pvcObj, err = p.kubeClient.CoreV1().
+			PersistentVolumeClaims(p.serverNamespace).
+			Update(ctx, pvcObj, metav1.UpdateOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to add finalizer to backend PVC {%s/%s}", p.serverNamespace, name)
+		}
+		klog.V(4).Infof("Added finalizer %s to backend PVC {%s/%s}", NFSPVFinalizer, p.serverNamespace, name)
+	}
+	ownerRef := getBackendPVCOwnerReference(pvcObj)
+
+	deployObj, err := p.kubeClient.AppsV1().
+		Deployments(p.serverNamespace).
+		Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get NFS server deployment {%s/%s}", p.serverNamespace, name)
+	}
+	if setNFSServerOwnership(&deployObj.ObjectMeta, ownerRef) {
+		_, err = p.kubeClient.AppsV1().
+			Deployments(p.serverNamespace).
+			Update(ctx, deployObj, metav1.UpdateOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to add finalizer to NFS server deployment {%s/%s}", p.serverNamespace, name)
+		}
+		klog.V(4).Infof("Added finalizer %s to NFS server deployment {%s/%s}", NFSPVFinalizer, p.serverNamespace, name)
+	}
+
+	svcObj, err := p.kubeClient.CoreV1().
+		Services(p.serverNamespace).
+		Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get NFS service {%s/%s}", p.serverNamespace, name)
+	}
+	if setNFSServerOwnership(&svcObj.ObjectMeta, ownerRef) {
+		_, err = p.kubeClient.CoreV1().
+			Services(p.serverNamespace).
+			Update(ctx, svcObj, metav1.UpdateOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "failed to add finalizer to NFS service {%s/%s}", p.serverNamespace, name)
+		}
+		klog.V(4).Infof("Added finalizer %s to NFS service {%s/%s}", NFSPVFinalizer, p.serverNamespace, name)
+	}
+	return nil
+}
+
+// removeNFSServerFinalizer removes the NFSPVFinalizer from the given kind
+// of NFS server resource, so that the resource can be deleted
+func (p *Provisioner) removeNFSServerFinalizer(ctx context.Context, resource, name string) error {
+	var err error
+
+	switch resource {
+	case resourceBackendPVC:
+		var pvcObj *corev1.PersistentVolumeClaim
+		pvcObj, err = p.kubeClient.CoreV1().
+			PersistentVolumeClaims(p.serverNamespace).
+			Get(ctx, name, metav1.GetOptions{})
+		if err == nil && hasFinalizer(pvcObj.ObjectMeta, NFSPVFinalizer) {
+			helper.RemoveFinalizers(&pvcObj.ObjectMeta, []string{NFSPVFinalizer})
+			_, err = p.kubeClient.CoreV1().
+				PersistentVolumeClaims(p.serverNamespace).
+				Update(ctx, pvcObj, metav1.UpdateOptions{})
+		}
+	case resourceDeployment:
+		var deployObj *appsv1.Deployment
+		deployObj, err = p.kubeClient.AppsV1().
+			Deployments(p.serverNamespace).
+			Get(ctx, name, metav1.GetOptions{})
+		if err == nil && hasFinalizer(deployObj.ObjectMeta, NFSPVFinalizer) {
+			helper.RemoveFinalizers(&deployObj.ObjectMeta, []string{NFSPVFinalizer})
+			_, err = p.kubeClient.AppsV1().
+				Deployments(p.serverNamespace).
+				Update(ctx, deployObj, metav1.UpdateOptions{})
+		}
+	case resourceService:
+		var svcObj *corev1.Service
+		svcObj, err = p.kubeClient.CoreV1().
+			Services(p.serverNamespace).
+			Get(ctx, name, metav1.GetOptions{})
+		if err == nil && hasFinalizer(svcObj.ObjectMeta, NFSPVFinalizer) {
+			helper.RemoveFinalizers(&svcObj.ObjectMeta, []string{NFSPVFinalizer})
+			_, err = p.kubeClient.CoreV1().
+				Services(p.serverNamespace).
+				Update(ctx, svcObj, metav1.UpdateOptions{})
+		}
+	default:
+		return errors.Errorf("unknown NFS server resource %s", resource)
+	}
+
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to remove finalizer of %s {%s/%s}", resource, p.serverNamespace, name)
+	}
+	return nil
+}
+
+// isNFSServerResourceProtected returns true if the given NFS server
+// resource has the NFSPVFinalizer, and it is owned by the backend PVC
+// of the given UID, if the resource is not the backend PVC itself
+func isNFSServerResourceProtected(objMeta metav1.ObjectMeta, backendPvcUID string) bool {
+	if !hasFinalizer(objMeta, NFSPVFinalizer) {
+		return false
+	}
+	if string(objMeta.UID) == backendPvcUID {
+		return true
+	}
+
+	for _, ref := range objMeta.OwnerReferences {
+		if ref.Kind == resourceBackendPVC && string(ref.UID) == backendPvcUID {
+			return true
+		}
+	}
+	return false
+}
+
+// setNFSServerOwnership adds the NFSPVFinalizer and the given owner
+// reference to the object, and returns true if the object is changed
+func setNFSServerOwnership(objMeta *metav1.ObjectMeta, ownerRef metav1.OwnerReference) bool {
+	changed := false
+	if !hasFinalizer(*objMeta, NFSPVFinalizer) {
+		helper.AddFinalizers(objMeta, []string{NFSPVFinalizer})
+		changed = true
+	}
+
+	for i, ref := range objMeta.OwnerReferences {
+		if ref.Kind != ownerRef.Kind || ref.Name != ownerRef.Name {
+			continue
+		}
+		if ref.UID == ownerRef.UID {
+			return changed
+		}
+
+		// Backend PVC is recreated
+		objMeta.OwnerReferences[i] = ownerRef
+		return true
+	}
+
+	objMeta.OwnerReferences = append(objMeta.OwnerReferences, ownerRef)
+	return true
+}
+
+// getBackendPVCOwnerReference returns the owner reference
+// of the given backend PVC
+func getBackendPVCOwnerReference(pvcObj *corev1.PersistentVolumeClaim) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: "v1",
+		Kind:       resourceBackendPVC,
+		Name:       pvcObj.Name,
+		UID:        pvcObj.UID,
+	}
+}
+
+// hasFinalizer returns true if the given object has the finalizer
+func hasFinalizer(objMeta metav1.ObjectMeta, finalizer string) bool {
+	for _, f := range objMeta.Finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
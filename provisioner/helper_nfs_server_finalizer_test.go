@@ -0,0 +1,194 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"testing"
+
+	errors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestProtectNFSServerResources(t *testing.T) {
+	serverNs := "nfs-ns"
+	name := "nfs-pv1"
+
+	getBackendPVC := func() *corev1.PersistentVolumeClaim {
+		return getFakePVCObject(serverNs, name, "openebs-hostpath", "backend-uid")
+	}
+	getOwnedDeployment := func(ownerUID string) *appsv1.Deployment {
+		deployObj := getFakeDeploymentObject(serverNs, name)
+		deployObj.Finalizers = []string{NFSPVFinalizer}
+		deployObj.OwnerReferences = []metav1.OwnerReference{
+			{
+				APIVersion: "v1",
+				Kind:       resourceBackendPVC,
+				Name:       name,
+				UID:        types.UID(ownerUID),
+			},
+		}
+		return deployObj
+	}
+
+	tests := map[string]struct {
+		options            *KernelNFSServerOptions
+		existingObjects    []runtime.Object
+		expectedProtection bool
+		isErrExpected      bool
+	}{
+		"when resources are not protected": {
+			options: &KernelNFSServerOptions{
+				ctx:    context.TODO(),
+				pvName: "pv1",
+			},
+			existingObjects: []runtime.Object{
+				getBackendPVC(),
+				getFakeDeploymentObject(serverNs, name),
+				getFakeServiceObject(serverNs, name),
+			},
+			expectedProtection: true,
+		},
+		"when deployment is owned by the deleted backend PVC": {
+			options: &KernelNFSServerOptions{
+				ctx:    context.TODO(),
+				pvName: "pv1",
+			},
+			existingObjects: []runtime.Object{
+				getBackendPVC(),
+				getOwnedDeployment("old-backend-uid"),
+				getFakeServiceObject(serverNs, name),
+			},
+			expectedProtection: true,
+		},
+		"when NFS server is shared": {
+			options: &KernelNFSServerOptions{
+				ctx:    context.TODO(),
+				pvName: "pv1",
+				shared: true,
+			},
+			existingObjects: []runtime.Object{
+				getBackendPVC(),
+				getFakeDeploymentObject(serverNs, name),
+				getFakeServiceObject(serverNs, name),
+			},
+		},
+		"when service doesn't exist": {
+			options: &KernelNFSServerOptions{
+				ctx:    context.TODO(),
+				pvName: "pv1",
+			},
+			existingObjects: []runtime.Object{
+				getBackendPVC(),
+				getFakeDeploymentObject(serverNs, name),
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			p := &Provisioner{
+				kubeClient:      fake.NewSimpleClientset(test.existingObjects...),
+				serverNamespace: serverNs,
+			}
+
+			err := p.protectNFSServerResources(test.options)
+			assert.Equal(t, test.isErrExpected, err != nil, "%v", err)
+			if test.isErrExpected {
+				return
+			}
+
+			resourceName := "nfs-" + test.options.pvName
+			pvcObj, err := p.kubeClient.CoreV1().PersistentVolumeClaims(serverNs).Get(context.TODO(), resourceName, metav1.GetOptions{})
+			assert.NoError(t, err)
+			deployObj, err := p.kubeClient.AppsV1().Deployments(serverNs).Get(context.TODO(), resourceName, metav1.GetOptions{})
+			assert.NoError(t, err)
+			svcObj, err := p.kubeClient.CoreV1().Services(serverNs).Get(context.TODO(), resourceName, metav1.GetOptions{})
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedProtection, isNFSServerResourceProtected(pvcObj.ObjectMeta, "backend-uid"))
+			assert.Equal(t, test.expectedProtection, isNFSServerResourceProtected(deployObj.ObjectMeta, "backend-uid"))
+			assert.Equal(t, test.expectedProtection, isNFSServerResourceProtected(svcObj.ObjectMeta, "backend-uid"))
+			if test.expectedProtection {
+				assert.Equal(t, 1, len(deployObj.OwnerReferences))
+				assert.Equal(t, []string{NFSPVFinalizer}, deployObj.Finalizers)
+			}
+		})
+	}
+}
+
+func TestDeleteNFSServerWithFinalizers(t *testing.T) {
+	serverNs := "nfs-ns"
+	name := "nfs-pv1"
+
+	backendPvc := getFakePVCObject(serverNs, name, "openebs-hostpath", "backend-uid")
+	backendPvc.Finalizers = []string{NFSPVFinalizer, "kubernetes.io/pvc-protection"}
+	deployObj := getFakeDeploymentObject(serverNs, name)
+	deployObj.Finalizers = []string{NFSPVFinalizer}
+	svcObj := getFakeServiceObject(serverNs, name)
+	svcObj.Finalizers = []string{NFSPVFinalizer}
+
+	client := fake.NewSimpleClientset(backendPvc, deployObj, svcObj)
+	// fake clientset deletes the object without waiting for the finalizers,
+	// so the deletion fails if the object has the finalizer of provisioner
+	client.PrependReactor("delete", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deleteAction := action.(k8stesting.DeleteAction)
+		obj, err := client.Tracker().Get(deleteAction.GetResource(), deleteAction.GetNamespace(), deleteAction.GetName())
+		if err != nil {
+			return false, nil, nil
+		}
+		objMeta, err := apimeta.Accessor(obj)
+		if err != nil {
+			return true, nil, err
+		}
+		for _, f := range objMeta.GetFinalizers() {
+			if f == NFSPVFinalizer {
+				return true, nil, errors.Errorf("%s %s has finalizer %s", deleteAction.GetResource().Resource, deleteAction.GetName(), f)
+			}
+		}
+		return false, nil, nil
+	})
+
+	p := &Provisioner{
+		kubeClient:      client,
+		serverNamespace: serverNs,
+	}
+	err := p.deleteNFSServer(&KernelNFSServerOptions{
+		ctx:    context.TODO(),
+		pvName: "pv1",
+	})
+	assert.NoError(t, err)
+
+	_, err = client.CoreV1().PersistentVolumeClaims(serverNs).Get(context.TODO(), name, metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "backend PVC should be deleted, err=%v", err)
+	_, err = client.AppsV1().Deployments(serverNs).Get(context.TODO(), name, metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "deployment should be deleted, err=%v", err)
+	_, err = client.CoreV1().Services(serverNs).Get(context.TODO(), name, metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "service should be deleted, err=%v", err)
+}
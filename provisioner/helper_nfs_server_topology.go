@@ -0,0 +1,122 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"encoding/json"
+
+	errors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// selectedNodeAnnotation is set on the PVC by the scheduler, if the
+	// StorageClass has WaitForFirstConsumer volume binding mode
+	selectedNodeAnnotation = "volume.kubernetes.io/selected-node"
+
+	// nfsServerTopologyAnnotation is set on the NFS PV, to record the
+	// topology of the NFS server, so that the NFS server is recreated
+	// in the same topology
+	nfsServerTopologyAnnotation = "nfs.openebs.io/nfs-server-topology"
+)
+
+// getNFSServerTopology returns the node selector terms, which place the
+// NFS server in the topology of the first consumer of the volume.
+//
+// If the node is selected for the first consumer, NFS server is placed
+// in the same domain of the topology keys of the allowed topologies, or
+// in the zone of the selected node if the StorageClass doesn't restrict
+// the topology. NFS server is not pinned to the selected node, since the
+// topology is recorded on the NFS PV and the NFS server couldn't move
+// if the node is removed. If the backend volume is node-local, NFS server
+// follows the node affinity of the backend PV provisioned for the selected
+// node. Otherwise, NFS server is placed in one of the allowed topologies.
+func getNFSServerTopology(selectedNode *corev1.Node, allowedTopologies []corev1.TopologySelectorTerm) []corev1.NodeSelectorTerm {
+	if selectedNode != nil {
+		var topologyKeys []string
+		keySet := map[string]bool{}
+		for _, term := range allowedTopologies {
+			for _, expression := range term.MatchLabelExpressions {
+				if !keySet[expression.Key] {
+					keySet[expression.Key] = true
+					topologyKeys = append(topologyKeys, expression.Key)
+				}
+			}
+		}
+		if len(topologyKeys) == 0 {
+			topologyKeys = []string{corev1.LabelTopologyZone}
+		}
+
+		var matchExpressions []corev1.NodeSelectorRequirement
+		for _, key := range topologyKeys {
+			value, ok := selectedNode.Labels[key]
+			if !ok {
+				continue
+			}
+			matchExpressions = append(matchExpressions, corev1.NodeSelectorRequirement{
+				Key:      key,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{value},
+			})
+		}
+		if len(matchExpressions) == 0 {
+			return nil
+		}
+		return []corev1.NodeSelectorTerm{{MatchExpressions: matchExpressions}}
+	}
+
+	var terms []corev1.NodeSelectorTerm
+	for _, term := range allowedTopologies {
+		var matchExpressions []corev1.NodeSelectorRequirement
+		for _, expression := range term.MatchLabelExpressions {
+			matchExpressions = append(matchExpressions, corev1.NodeSelectorRequirement{
+				Key:      expression.Key,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   expression.Values,
+			})
+		}
+		if len(matchExpressions) != 0 {
+			terms = append(terms, corev1.NodeSelectorTerm{MatchExpressions: matchExpressions})
+		}
+	}
+	return terms
+}
+
+// encodeNFSServerTopology returns the given topology of the NFS server,
+// as the value of the nfsServerTopologyAnnotation
+func encodeNFSServerTopology(topology []corev1.NodeSelectorTerm) (string, error) {
+	value, err := json.Marshal(topology)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to encode NFS server topology")
+	}
+	return string(value), nil
+}
+
+// getNFSServerTopologyFromPV returns the topology of the NFS server,
+// recorded on the given NFS PV
+func getNFSServerTopologyFromPV(pv *corev1.PersistentVolume) ([]corev1.NodeSelectorTerm, error) {
+	value, ok := pv.Annotations[nfsServerTopologyAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	var topology []corev1.NodeSelectorTerm
+	if err := json.Unmarshal([]byte(value), &topology); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s annotation of PV %s", nfsServerTopologyAnnotation, pv.Name)
+	}
+	return topology, nil
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetNFSServerTopology(t *testing.T) {
+	selectedNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				corev1.LabelHostname:            "node-1-hostname",
+				"topology.kubernetes.io/zone":   "zone-1",
+				"topology.kubernetes.io/region": "region-1",
+			},
+		},
+	}
+	allowedTopologies := []corev1.TopologySelectorTerm{
+		{
+			MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{
+				{
+					Key:    "topology.kubernetes.io/zone",
+					Values: []string{"zone-1", "zone-2"},
+				},
+			},
+		},
+		{
+			MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{
+				{
+					Key:    "topology.kubernetes.io/region",
+					Values: []string{"region-2"},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		selectedNode      *corev1.Node
+		allowedTopologies []corev1.TopologySelectorTerm
+		expectedTopology  []corev1.NodeSelectorTerm
+	}{
+		"when node is not selected and topology is not restricted": {},
+		"when node is selected and topology is not restricted, NFS server should be placed in the zone of the node": {
+			selectedNode: selectedNode,
+			expectedTopology: []corev1.NodeSelectorTerm{
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{
+							Key:      corev1.LabelTopologyZone,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"zone-1"},
+						},
+					},
+				},
+			},
+		},
+		"when node without zone is selected and topology is not restricted, NFS server shouldn't be pinned to the node": {
+			selectedNode: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "node-2",
+					Labels: map[string]string{
+						corev1.LabelHostname: "node-2-hostname",
+					},
+				},
+			},
+		},
+		"when node is selected and topology is restricted": {
+			selectedNode:      selectedNode,
+			allowedTopologies: allowedTopologies,
+			expectedTopology: []corev1.NodeSelectorTerm{
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{
+							Key:      "topology.kubernetes.io/zone",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"zone-1"},
+						},
+						{
+							Key:      "topology.kubernetes.io/region",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"region-1"},
+						},
+					},
+				},
+			},
+		},
+		"when node is not selected and topology is restricted": {
+			allowedTopologies: allowedTopologies,
+			expectedTopology: []corev1.NodeSelectorTerm{
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{
+							Key:      "topology.kubernetes.io/zone",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"zone-1", "zone-2"},
+						},
+					},
+				},
+				{
+					MatchExpressions: []corev1.NodeSelectorRequirement{
+						{
+							Key:      "topology.kubernetes.io/region",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"region-2"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			topology := getNFSServerTopology(test.selectedNode, test.allowedTopologies)
+			assert.Equal(t, test.expectedTopology, topology)
+		})
+	}
+}
+
+func TestGetNFSServerTopologyFromPV(t *testing.T) {
+	topology := []corev1.NodeSelectorTerm{
+		{
+			MatchExpressions: []corev1.NodeSelectorRequirement{
+				{
+					Key:      "topology.kubernetes.io/zone",
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"zone-1"},
+				},
+			},
+		},
+	}
+	encodedTopology, err := encodeNFSServerTopology(topology)
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		annotations      map[string]string
+		expectedTopology []corev1.NodeSelectorTerm
+		isErrExpected    bool
+	}{
+		"when topology is not recorded": {},
+		"when topology is recorded": {
+			annotations: map[string]string{
+				nfsServerTopologyAnnotation: encodedTopology,
+			},
+			expectedTopology: topology,
+		},
+		"when recorded topology is malformed": {
+			annotations: map[string]string{
+				nfsServerTopologyAnnotation: "zone-1",
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			pv := &corev1.PersistentVolume{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "pv1",
+					Annotations: test.annotations,
+				},
+			}
+
+			gotTopology, err := getNFSServerTopologyFromPV(pv)
+			assert.Equal(t, test.isErrExpected, err != nil, "error: %v", err)
+			assert.Equal(t, test.expectedTopology, gotTopology)
+		})
+	}
+}
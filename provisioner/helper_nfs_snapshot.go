@@ -0,0 +1,265 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	errors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// snapshotSourceVolumeLabelKey is set on the VolumeSnapshot of the
+	// backend volume. Value of the label is the ID of the NFS volume
+	snapshotSourceVolumeLabelKey = "nfs.openebs.io/source-volume"
+
+	// snapshotNameAnnotationKey is set on the VolumeSnapshot of the
+	// backend volume. Value of the annotation is the name of the NFS
+	// volume snapshot, requested by the CO
+	snapshotNameAnnotationKey = "nfs.openebs.io/snapshot-name"
+
+	// BackendVolumeSnapshotClass is the VolumeSnapshotClass parameter
+	// which defines the VolumeSnapshotClass of the backend volume snapshot.
+	// If it is not set then default VolumeSnapshotClass is used
+	BackendVolumeSnapshotClass = "BackendVolumeSnapshotClass"
+)
+
+var (
+	// volumeSnapshotGVR is the resource of the VolumeSnapshots
+	volumeSnapshotGVR = schema.GroupVersionResource{
+		Group:    "snapshot.storage.k8s.io",
+		Version:  "v1",
+		Resource: "volumesnapshots",
+	}
+)
+
+// backendSnapshot holds the details of the VolumeSnapshot of the backend volume
+type backendSnapshot struct {
+	name         string
+	sourceVolume string
+	creationTime *time.Time
+	readyToUse   bool
+	restoreSize  int64
+}
+
+// getBackendSnapshotName returns the name of the VolumeSnapshot of the
+// backend volume for the given NFS volume snapshot ID
+func getBackendSnapshotName(snapshotID string) string {
+	return "nfs-" + snapshotID
+}
+
+// getBackendSnapshotDataSource returns the dataSource of the backend PVC,
+// to restore the backend volume from the given backend snapshot
+func getBackendSnapshotDataSource(snapshotName string) *corev1.TypedLocalObjectReference {
+	apiGroup := volumeSnapshotGVR.Group
+	return &corev1.TypedLocalObjectReference{
+		APIGroup: &apiGroup,
+		Kind:     "VolumeSnapshot",
+		Name:     snapshotName,
+	}
+}
+
+// parseBackendSnapshot returns the backendSnapshot from the
+// VolumeSnapshot object
+func parseBackendSnapshot(obj *unstructured.Unstructured) (*backendSnapshot, error) {
+	snapshot := &backendSnapshot{
+		name:         obj.GetName(),
+		sourceVolume: obj.GetLabels()[snapshotSourceVolumeLabelKey],
+	}
+
+	creationTime, _, err := unstructured.NestedString(obj.Object, "status", "creationTime")
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid creationTime of VolumeSnapshot %s", obj.GetName())
+	}
+	if len(creationTime) != 0 {
+		t, err := time.Parse(time.RFC3339, creationTime)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid creationTime of VolumeSnapshot %s", obj.GetName())
+		}
+		snapshot.creationTime = &t
+	}
+
+	snapshot.readyToUse, _, err = unstructured.NestedBool(obj.Object, "status", "readyToUse")
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid readyToUse of VolumeSnapshot %s", obj.GetName())
+	}
+
+	restoreSize, _, err := unstructured.NestedString(obj.Object, "status", "restoreSize")
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid restoreSize of VolumeSnapshot %s", obj.GetName())
+	}
+	if len(restoreSize) != 0 {
+		size, err := resource.ParseQuantity(restoreSize)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid restoreSize of VolumeSnapshot %s", obj.GetName())
+		}
+		snapshot.restoreSize = size.Value()
+	}
+
+	errMsg, _, _ := unstructured.NestedString(obj.Object, "status", "error", "message")
+	if len(errMsg) != 0 {
+		return snapshot, errors.Errorf("VolumeSnapshot %s failed: %s", obj.GetName(), errMsg)
+	}
+	return snapshot, nil
+}
+
+// getBackendSnapshot returns the VolumeSnapshot of the backend volume
+func (p *Provisioner) getBackendSnapshot(ctx context.Context, snapshotName string) (*unstructured.Unstructured, error) {
+	return p.dynamicClient.Resource(volumeSnapshotGVR).
+		Namespace(p.serverNamespace).
+		Get(ctx, snapshotName, metav1.GetOptions{})
+}
+
+// createBackendSnapshot creates the VolumeSnapshot of the backend PVC of
+// the given NFS volume. The VolumeSnapshot records the NFS volume and the
+// name of the NFS volume snapshot, to map it with the NFS volume snapshot.
+func (p *Provisioner) createBackendSnapshot(ctx context.Context, snapshotName, volumeName, nfsSnapshotName, snapshotClass, dataDir string) error {
+	spec := map[string]interface{}{
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": "nfs-" + volumeName,
+		},
+	}
+	if len(snapshotClass) != 0 {
+		spec["volumeSnapshotClassName"] = snapshotClass
+	}
+
+	annotations := map[string]interface{}{
+		snapshotNameAnnotationKey: nfsSnapshotName,
+	}
+	if len(dataDir) != 0 {
+		// volume restored from the snapshot has the layout of the source
+		annotations[nfsServerDataDirAnnotation] = dataDir
+	}
+
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": volumeSnapshotGVR.GroupVersion().String(),
+			"kind":       "VolumeSnapshot",
+			"metadata": map[string]interface{}{
+				"name":      snapshotName,
+				"namespace": p.serverNamespace,
+				"labels": map[string]interface{}{
+					snapshotSourceVolumeLabelKey: volumeName,
+				},
+				"annotations": annotations,
+			},
+			"spec": spec,
+		},
+	}
+
+	_, err := p.dynamicClient.Resource(volumeSnapshotGVR).
+		Namespace(p.serverNamespace).
+		Create(ctx, obj, metav1.CreateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to create VolumeSnapshot {%s/%s}", p.serverNamespace, snapshotName)
+	}
+
+	klog.Infof("Created VolumeSnapshot {%s/%s} of backend PVC nfs-%s", p.serverNamespace, snapshotName, volumeName)
+	return nil
+}
+
+// deleteBackendSnapshot deletes the VolumeSnapshot of the backend volume
+func (p *Provisioner) deleteBackendSnapshot(ctx context.Context, snapshotName string) error {
+	return p.dynamicClient.Resource(volumeSnapshotGVR).
+		Namespace(p.serverNamespace).
+		Delete(ctx, snapshotName, metav1.DeleteOptions{})
+}
+
+// waitForBackendSnapshotCut waits till the snapshot of the backend volume is
+// taken, i.e. creationTime is set on the VolumeSnapshot. The snapshot may not
+// be ready to use yet, but the NFS server can resume the exports.
+func (p *Provisioner) waitForBackendSnapshotCut(ctx context.Context, snapshotName string, timeout time.Duration) (*backendSnapshot, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		obj, err := p.getBackendSnapshot(ctx, snapshotName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get VolumeSnapshot {%s/%s}", p.serverNamespace, snapshotName)
+		}
+
+		snapshot, err := parseBackendSnapshot(obj)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot.creationTime != nil {
+			return snapshot, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, errors.Errorf("timed out waiting for VolumeSnapshot {%s/%s} to be taken", p.serverNamespace, snapshotName)
+		case <-ticker.C:
+		}
+	}
+}
+
+// quiesceNFSServer flushes the data of the NFS server to the backend
+// volume and freezes its filesystem, so that the snapshot of the backend
+// volume is consistent. Exports are not stopped, so NFS clients don't
+// observe errors, and their IO blocks till the filesystem is thawed.
+// It returns the name of the NFS server pod.
+func (p *Provisioner) quiesceNFSServer(ctx context.Context, deployName string) (string, error) {
+	podName, err := getRunningNFSServerPod(ctx, p.kubeClient, p.serverNamespace, deployName)
+	if err != nil {
+		return "", err
+	}
+
+	for _, command := range [][]string{
+		{"sync"},
+		{"fsfreeze", "--freeze", "/nfsshare"},
+	} {
+		_, stderr, err := p.executor.Exec(ctx, p.serverNamespace, podName, nfsServerContainerName, command)
+		if err != nil {
+			// thaw the filesystem which may have been frozen
+			_ = p.resumeNFSServer(podName)
+			return "", errors.Wrapf(err, "failed to execute %q on NFS server pod %s, stderr=%s",
+				strings.Join(command, " "), podName, stderr)
+		}
+	}
+
+	klog.Infof("Quiesced NFS server pod {%s/%s}", p.serverNamespace, podName)
+	return podName, nil
+}
+
+// resumeNFSServer thaws the filesystem of the NFS server pod. It doesn't
+// use the request context, since the filesystem must be thawed even if
+// the request is cancelled.
+func (p *Provisioner) resumeNFSServer(podName string) error {
+	command := []string{"fsfreeze", "--unfreeze", "/nfsshare"}
+	_, stderr, err := p.executor.Exec(context.Background(), p.serverNamespace, podName, nfsServerContainerName, command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to execute %q on NFS server pod %s, stderr=%s",
+			strings.Join(command, " "), podName, stderr)
+	}
+
+	klog.Infof("Resumed IO of NFS server pod {%s/%s}", p.serverNamespace, podName)
+	return nil
+}
@@ -0,0 +1,255 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	errors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// sharedNFSServerLabelKey is set on the backend PVC of the shared
+	// NFS server and on the NFS PVs created on the shared NFS server.
+	// Value of the label is the name of the shared NFS server
+	sharedNFSServerLabelKey = "nfs.openebs.io/shared-server"
+
+	// sharedNFSServerPrefix is the prefix of the shared NFS server name,
+	// followed by the name of the StorageClass
+	sharedNFSServerPrefix = "shared-"
+
+	// sharedNFSServerHashLen is the length of the hash suffix of the
+	// shared NFS server name, if the StorageClass name is truncated
+	sharedNFSServerHashLen = 10
+
+	// nfsServerContainerName is the name of the NFS server container
+	nfsServerContainerName = "nfs-server"
+
+	// nfsVolumeScript manages the volume directories on the shared
+	// NFS server. It is shipped with the nfs-server-alpine image
+	nfsVolumeScript = "/usr/bin/nfs-volume.sh"
+)
+
+// getSharedNFSServerName returns the name of the shared NFS server
+// for the given StorageClass. NFS server resources of the shared NFS
+// server are named as nfs-<shared NFS server name>, and the name is used
+// as label value, so it must be a valid DNS label. If it isn't, the
+// StorageClass name is truncated and suffixed with its hash.
+func getSharedNFSServerName(scName string) string {
+	name := sharedNFSServerPrefix + scName
+	if len(validation.IsDNS1035Label("nfs-"+name)) == 0 {
+		return name
+	}
+
+	hash := sha256.Sum256([]byte(scName))
+	maxLen := validation.DNS1035LabelMaxLength - len("nfs-"+sharedNFSServerPrefix) - sharedNFSServerHashLen - 1
+	truncated := strings.ReplaceAll(scName, ".", "-")
+	if len(truncated) > maxLen {
+		truncated = truncated[:maxLen]
+	}
+	truncated = strings.TrimRight(truncated, "-")
+	return sharedNFSServerPrefix + truncated + "-" + hex.EncodeToString(hash[:])[:sharedNFSServerHashLen]
+}
+
+// isSharedNFSVolume returns true if the given NFS PV is created
+// on the shared NFS server
+func isSharedNFSVolume(pv *corev1.PersistentVolume) bool {
+	return len(pv.Labels[sharedNFSServerLabelKey]) != 0
+}
+
+// initSharedNFSServerOptions updates the NFS server options of the volume
+// to refer the shared NFS server of the volume's StorageClass. File
+// permissions are not applied on the shared NFS server, since those are
+// applied on the volume directory.
+func (p *Provisioner) initSharedNFSServerOptions(nfsServerOpts *KernelNFSServerOptions, volumeConfig *VolumeConfig) error {
+	if nfsServerOpts.getServerType() != NFSServerTypeKernel {
+		return errors.Errorf("shared NFS server is not supported for NFS server type %s", nfsServerOpts.getServerType())
+	}
+
+	if nfsServerOpts.networkPolicy != nil {
+		// shared NFS server serves the NFS PVCs of multiple namespaces
+		return errors.Errorf("%s is not supported on the shared NFS server", NetworkPolicy)
+	}
+
+	if nfsServerOpts.metricsEnabled {
+		// metrics of the NFS server are reported for a single NFS PVC,
+		// while the shared NFS server serves multiple NFS PVCs
+		return errors.Errorf("%s is not supported on the shared NFS server", NFSServerMetrics)
+	}
+
+	capacity, err := volumeConfig.GetSharedBackendCapacity()
+	if err != nil {
+		return err
+	}
+
+	nfsServerOpts.pvName = getSharedNFSServerName(volumeConfig.scName)
+	nfsServerOpts.capacity = capacity.String()
+	nfsServerOpts.shared = true
+	nfsServerOpts.fsGroup = nil
+	nfsServerOpts.permissionsUID = ""
+	nfsServerOpts.permissionsGID = ""
+	nfsServerOpts.permissionsMode = ""
+	return nil
+}
+
+// getSharedNFSServerOptions returns the options of the shared NFS server
+// of the given StorageClass. Shared NFS server is rendered only from the
+// StorageClass config, since it serves all the NFS PVCs of the StorageClass
+// and the config of an NFS PVC must not change the shared NFS server.
+func (p *Provisioner) getSharedNFSServerOptions(ctx context.Context, scName, serverType string) (*KernelNFSServerOptions, error) {
+	// PVC without cas.openebs.io/config refers only the StorageClass config
+	scPvc := &corev1.PersistentVolumeClaim{
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &scName,
+		},
+	}
+	volumeConfig, err := p.getVolumeConfig(getSharedNFSServerName(scName), scPvc)
+	if err != nil {
+		return nil, err
+	}
+
+	nfsServerOpts, err := newKernelNFSServerOptions(ctx, "", "", volumeConfig, serverType)
+	if err != nil {
+		return nil, err
+	}
+	nfsServerOpts.provisionerNS = p.namespace
+
+	if err = p.initSharedNFSServerOptions(nfsServerOpts, volumeConfig); err != nil {
+		return nil, err
+	}
+	return nfsServerOpts, nil
+}
+
+// addSharedNFSVolume creates the volume directory on the shared NFS server
+// and limits its size using project quota. File permissions are applied
+// on the volume directory only, if specified.
+func (p *Provisioner) addSharedNFSVolume(nfsServerOpts *KernelNFSServerOptions, volumeName string, size int64, uid, gid, mode string) error {
+	klog.V(4).Infof("Adding volume %s to shared NFS server %s", volumeName, nfsServerOpts.pvName)
+
+	deployName := "nfs-" + nfsServerOpts.pvName
+	podName, err := waitForNFSServerPod(nfsServerOpts.ctx, p.kubeClient, p.serverNamespace, deployName, p.backendPvcTimeout)
+	if err != nil {
+		return err
+	}
+
+	command := []string{nfsVolumeScript, "add", volumeName, strconv.FormatInt(size, 10), uid, gid, mode}
+	_, stderr, err := p.executor.Exec(nfsServerOpts.ctx, p.serverNamespace, podName, nfsServerContainerName, command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to add volume %s to shared NFS server {%s/%s}: %s", volumeName, p.serverNamespace, deployName, stderr)
+	}
+
+	klog.Infof("Volume %s has been added to shared NFS server {%s/%s}", volumeName, p.serverNamespace, deployName)
+	return nil
+}
+
+// removeSharedNFSVolume removes the volume directory and its project quota
+// from the shared NFS server
+func removeSharedNFSVolume(ctx context.Context, client kubernetes.Interface, executor commandExecutor, nfsServerNs, serverName, volumeName string) error {
+	klog.V(4).Infof("Removing volume %s from shared NFS server %s", volumeName, serverName)
+
+	deployName := "nfs-" + serverName
+	podName, err := getRunningNFSServerPod(ctx, client, nfsServerNs, deployName)
+	if err != nil {
+		return err
+	}
+
+	command := []string{nfsVolumeScript, "remove", volumeName}
+	_, stderr, err := executor.Exec(ctx, nfsServerNs, podName, nfsServerContainerName, command)
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove volume %s from shared NFS server {%s/%s}: %s", volumeName, nfsServerNs, deployName, stderr)
+	}
+
+	klog.Infof("Volume %s has been removed from shared NFS server {%s/%s}", volumeName, nfsServerNs, deployName)
+	return nil
+}
+
+// listSharedNFSVolumes returns the name of the volume directories
+// present on the shared NFS server
+func listSharedNFSVolumes(ctx context.Context, client kubernetes.Interface, executor commandExecutor, nfsServerNs, serverName string) ([]string, error) {
+	deployName := "nfs-" + serverName
+	podName, err := getRunningNFSServerPod(ctx, client, nfsServerNs, deployName)
+	if err != nil {
+		return nil, err
+	}
+
+	command := []string{nfsVolumeScript, "list"}
+	stdout, stderr, err := executor.Exec(ctx, nfsServerNs, podName, nfsServerContainerName, command)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list volumes of shared NFS server {%s/%s}: %s", nfsServerNs, deployName, stderr)
+	}
+
+	return strings.Fields(stdout), nil
+}
+
+// getRunningNFSServerPod returns the name of the running and ready
+// pod of the given NFS server deployment
+func getRunningNFSServerPod(ctx context.Context, client kubernetes.Interface, namespace, deployName string) (string, error) {
+	podList, err := client.CoreV1().
+		Pods(namespace).
+		List(ctx, metav1.ListOptions{LabelSelector: "openebs.io/nfs-server=" + deployName})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list pods of NFS server deployment {%s/%s}", namespace, deployName)
+	}
+
+	for _, pod := range podList.Items {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				return pod.Name, nil
+			}
+		}
+	}
+
+	return "", errors.Errorf("no running pod found for NFS server deployment {%s/%s}", namespace, deployName)
+}
+
+// waitForNFSServerPod waits for the pod of the given NFS server
+// deployment to be running, for the timeout period
+func waitForNFSServerPod(ctx context.Context, client kubernetes.Interface, namespace, deployName string, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	timeoutCh := timer.C
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
+	for {
+		podName, err := getRunningNFSServerPod(ctx, client, namespace, deployName)
+		if err == nil {
+			return podName, nil
+		}
+
+		select {
+		case <-timeoutCh:
+			return "", errors.Wrapf(err, "timed out waiting for NFS server pod")
+		case <-tick.C:
+		}
+	}
+}
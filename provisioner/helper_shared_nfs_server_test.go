@@ -0,0 +1,285 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeCommandExecutor records the executed commands and returns
+// the configured output for the command
+type fakeCommandExecutor struct {
+	// outputs maps the command arguments (joined by space) to stdout
+	outputs  map[string]string
+	commands [][]string
+}
+
+func newFakeCommandExecutor() *fakeCommandExecutor {
+	return &fakeCommandExecutor{
+		outputs: map[string]string{},
+	}
+}
+
+func (e *fakeCommandExecutor) Exec(ctx context.Context, namespace, podName, containerName string, command []string) (string, string, error) {
+	e.commands = append(e.commands, command)
+	return e.outputs[strings.Join(command, " ")], "", nil
+}
+
+func getFakeNFSServerPod(namespace, deployName string, ready bool) *corev1.Pod {
+	readyStatus := corev1.ConditionFalse
+	if ready {
+		readyStatus = corev1.ConditionTrue
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deployName + "-pod",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"openebs.io/nfs-server": deployName,
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{
+				{
+					Type:   corev1.PodReady,
+					Status: readyStatus,
+				},
+			},
+		},
+	}
+}
+
+func getFakeSharedNFSPvObject(name, serverName string) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				"openebs.io/cas-type":   "nfs-kernel",
+				sharedNFSServerLabelKey: serverName,
+			},
+		},
+	}
+}
+
+func TestAddSharedNFSVolume(t *testing.T) {
+	tests := map[string]struct {
+		pod             *corev1.Pod
+		isErrExpected   bool
+		expectedCommand []string
+	}{
+		"when shared NFS server pod is running, volume should be added": {
+			pod:             getFakeNFSServerPod("nfs-ns", "nfs-shared-sc1", true),
+			expectedCommand: []string{nfsVolumeScript, "add", "pv1", "1073741824", "1000", "2000", "0755"},
+		},
+		"when shared NFS server pod is not ready, volume shouldn't be added": {
+			pod:           getFakeNFSServerPod("nfs-ns", "nfs-shared-sc1", false),
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			executor := newFakeCommandExecutor()
+			p := &Provisioner{
+				kubeClient:        fake.NewSimpleClientset(test.pod),
+				serverNamespace:   "nfs-ns",
+				backendPvcTimeout: 2 * time.Second,
+				executor:          executor,
+			}
+			nfsServerOpts := &KernelNFSServerOptions{
+				pvName: "shared-sc1",
+				shared: true,
+				ctx:    context.TODO(),
+			}
+
+			err := p.addSharedNFSVolume(nfsServerOpts, "pv1", 1073741824, "1000", "2000", "0755")
+			if test.isErrExpected {
+				assert.Error(t, err)
+				assert.Empty(t, executor.commands)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, [][]string{test.expectedCommand}, executor.commands)
+		})
+	}
+}
+
+func TestCleanUpStaleSharedVolumes(t *testing.T) {
+	nfsServerNs := "nfs-ns"
+	sharedBackendPvcLabel := map[string]string{
+		"persistent-volume":     "shared-sc1",
+		"openebs.io/cas-type":   "nfs-kernel",
+		sharedNFSServerLabelKey: "shared-sc1",
+	}
+
+	tests := map[string]struct {
+		storageClass    *storagev1.StorageClass
+		pvs             []*corev1.PersistentVolume
+		nfsPvcs         []*corev1.PersistentVolumeClaim
+		pvTracker       ProvisioningTracker
+		volumes         string
+		removedVolumes  []string
+		shouldCleanupSv bool
+	}{
+		"when volume directories don't have NFS PV, directories should be removed": {
+			storageClass: getFakeStorageClass("sc1", false),
+			pvs: []*corev1.PersistentVolume{
+				getFakeSharedNFSPvObject("pv1", "shared-sc1"),
+			},
+			pvTracker:      getProvisioningTracker("pv3"),
+			volumes:        "pv1\npv2\npv3\n",
+			removedVolumes: []string{"pv2"},
+		},
+		"when NFS PV of the volume directory is not created yet, directory should not be removed": {
+			storageClass: getFakeStorageClass("sc1", false),
+			pvs: []*corev1.PersistentVolume{
+				getFakeSharedNFSPvObject("pvc-uid1", "shared-sc1"),
+			},
+			nfsPvcs: []*corev1.PersistentVolumeClaim{
+				getFakePVCObject("app", "nfs-pvc2", "sc1", "uid2"),
+			},
+			pvTracker:      getProvisioningTracker(),
+			volumes:        "pvc-uid1\npvc-uid2\npvc-uid3\n",
+			removedVolumes: []string{"pvc-uid3"},
+		},
+		"when shared NFS server doesn't have volumes and StorageClass exists, shared NFS server should not be removed": {
+			storageClass: getFakeStorageClass("sc1", false),
+			pvTracker:    getProvisioningTracker(),
+		},
+		"when shared NFS server doesn't have volumes and StorageClass doesn't exist, shared NFS server should be removed": {
+			pvTracker:       getProvisioningTracker(),
+			shouldCleanupSv: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset()
+			executor := newFakeCommandExecutor()
+			executor.outputs[nfsVolumeScript+" list"] = test.volumes
+
+			backendPvc := generateFakePvcObj(nfsServerNs, "nfs-shared-sc1", "backend-pvc-uid", corev1.ClaimBound, sharedBackendPvcLabel)
+			assert.NoError(t, createPvc(clientset, backendPvc))
+			assert.NoError(t, createDeployment(clientset, getFakeDeploymentObject(nfsServerNs, "nfs-shared-sc1")))
+			assert.NoError(t, createService(clientset, getFakeServiceObject(nfsServerNs, "nfs-shared-sc1")))
+			_, err := clientset.CoreV1().Pods(nfsServerNs).Create(context.TODO(), getFakeNFSServerPod(nfsServerNs, "nfs-shared-sc1", true), metav1.CreateOptions{})
+			assert.NoError(t, err)
+			for _, pv := range test.pvs {
+				assert.NoError(t, createPv(clientset, pv))
+			}
+			for _, pvc := range test.nfsPvcs {
+				assert.NoError(t, createPvc(clientset, pvc))
+			}
+			if test.storageClass != nil {
+				_, err = clientset.StorageV1().StorageClasses().Create(context.TODO(), test.storageClass, metav1.CreateOptions{})
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, cleanUpStaleSharedVolumes(context.TODO(), clientset, executor, test.pvTracker, nfsServerNs))
+
+			var removedVolumes []string
+			for _, command := range executor.commands {
+				if len(command) == 3 && command[1] == "remove" {
+					removedVolumes = append(removedVolumes, command[2])
+				}
+			}
+			assert.Equal(t, test.removedVolumes, removedVolumes)
+
+			exists, err := pvcExists(clientset, nfsServerNs, backendPvc.Name)
+			assert.NoError(t, err, "checking shared backend PVC existence")
+			assert.NotEqual(t, test.shouldCleanupSv, exists, "shared backend PVC %s", ternary(test.shouldCleanupSv, "should be removed", "shouldn't be removed"))
+
+			exists, err = deploymentExists(clientset, nfsServerNs, "nfs-shared-sc1")
+			assert.NoError(t, err, "checking shared nfs-server deployment existence")
+			assert.NotEqual(t, test.shouldCleanupSv, exists, "shared nfs-server deployment %s", ternary(test.shouldCleanupSv, "should be removed", "shouldn't be removed"))
+		})
+	}
+}
+
+func TestDeleteSharedNFSVolume(t *testing.T) {
+	nfsServerNs := "nfs-ns"
+	executor := newFakeCommandExecutor()
+	backendPvc := generateFakePvcObj(nfsServerNs, "nfs-shared-sc1", "backend-pvc-uid", corev1.ClaimBound,
+		map[string]string{sharedNFSServerLabelKey: "shared-sc1"})
+
+	p := &Provisioner{
+		kubeClient:      fake.NewSimpleClientset(backendPvc, getFakeNFSServerPod(nfsServerNs, "nfs-shared-sc1", true)),
+		serverNamespace: nfsServerNs,
+		executor:        executor,
+	}
+
+	err := p.DeleteSharedNFSVolume(context.TODO(), getFakeSharedNFSPvObject("pv1", "shared-sc1"))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{nfsVolumeScript, "remove", "pv1"}}, executor.commands)
+
+	// shared NFS server resources should not be removed on volume delete
+	exists, err := pvcExists(p.kubeClient.(*fake.Clientset), nfsServerNs, backendPvc.Name)
+	assert.NoError(t, err)
+	assert.True(t, exists, "shared backend PVC shouldn't be removed")
+}
+
+func TestGetSharedNFSServerName(t *testing.T) {
+	tests := map[string]struct {
+		scName       string
+		expectedName string
+	}{
+		"when StorageClass name is short, it should be used as is": {
+			scName:       "sc1",
+			expectedName: "shared-sc1",
+		},
+		"when StorageClass name is long, it should be truncated with hash suffix": {
+			scName:       strings.Repeat("a", 60),
+			expectedName: "shared-" + strings.Repeat("a", 41) + "-",
+		},
+		"when StorageClass name has dots, it should be converted to a DNS label": {
+			scName:       "nfs.openebs.io",
+			expectedName: "shared-nfs-openebs-io-",
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			serverName := getSharedNFSServerName(test.scName)
+			assert.Empty(t, validation.IsDNS1035Label("nfs-"+serverName))
+			if test.expectedName == "shared-"+test.scName {
+				assert.Equal(t, test.expectedName, serverName)
+				return
+			}
+			assert.True(t, strings.HasPrefix(serverName, test.expectedName), "shared NFS server name %s", serverName)
+			assert.Len(t, serverName, len(test.expectedName)+sharedNFSServerHashLen)
+			assert.NotEqual(t, serverName, getSharedNFSServerName(test.scName+"b"))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"time"
+
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// volumeStateTerminating is the state of the NFS PVs being deleted
+	volumeStateTerminating = "Terminating"
+)
+
+var (
+	// VolumeInventoryInterval defines the interval to update the
+	// inventory metrics of the NFS PVs
+	VolumeInventoryInterval = 30 * time.Second
+)
+
+// VolumeInventoryController updates the inventory metrics of the NFS PVs,
+// i.e. number of NFS PVs by state and backend StorageClass. Metrics are
+// computed from the informer caches of PVs and PVCs.
+type VolumeInventoryController struct {
+	serverNamespace string
+
+	pvLister  listersv1.PersistentVolumeLister
+	pvcLister listersv1.PersistentVolumeClaimLister
+
+	pvSynced  cache.InformerSynced
+	pvcSynced cache.InformerSynced
+}
+
+// NewVolumeInventoryController returns a new VolumeInventoryController,
+// which uses the given informer factory to list NFS PVs and backend PVCs
+func NewVolumeInventoryController(serverNamespace string, informerFactory kubeinformers.SharedInformerFactory) *VolumeInventoryController {
+	pvInformer := informerFactory.Core().V1().PersistentVolumes()
+	pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
+
+	return &VolumeInventoryController{
+		serverNamespace: serverNamespace,
+		pvLister:        pvInformer.Lister(),
+		pvcLister:       pvcInformer.Lister(),
+		pvSynced:        pvInformer.Informer().HasSynced,
+		pvcSynced:       pvcInformer.Informer().HasSynced,
+	}
+}
+
+// Run updates the inventory metrics periodically, till the
+// given context is cancelled
+func (ic *VolumeInventoryController) Run(ctx context.Context) {
+	defer utilruntime.HandleCrash()
+
+	if !cache.WaitForCacheSync(ctx.Done(), ic.pvSynced, ic.pvcSynced) {
+		klog.Error("Failed to sync caches for NFS volume inventory controller")
+		return
+	}
+
+	wait.UntilWithContext(ctx, func(context.Context) {
+		ic.updateInventory()
+	}, VolumeInventoryInterval)
+}
+
+// volumeInventoryKey identifies the series of the inventory metrics
+type volumeInventoryKey struct {
+	state               string
+	backendStorageClass string
+}
+
+// updateInventory updates the inventory metrics from the informer caches
+func (ic *VolumeInventoryController) updateInventory() {
+	pvList, err := ic.pvLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list PVs, err=%v", err)
+		return
+	}
+
+	counts := map[volumeInventoryKey]int{}
+	for _, pv := range pvList {
+		provisionedBy := pv.Annotations[annDynamicallyProvisioned]
+		if provisionedBy != provisionerName && provisionedBy != CSIDriverName {
+			continue
+		}
+
+		state := string(pv.Status.Phase)
+		if pv.DeletionTimestamp != nil {
+			state = volumeStateTerminating
+		}
+		counts[volumeInventoryKey{
+			state:               state,
+			backendStorageClass: ic.getBackendStorageClass(pv),
+		}]++
+	}
+
+	// Series of the states which no longer have any NFS PV are removed
+	metrics.PersistentVolumeCount.Reset()
+	for key, count := range counts {
+		metrics.PersistentVolumeCount.WithLabelValues(key.state, key.backendStorageClass).Set(float64(count))
+	}
+}
+
+// getBackendStorageClass returns the StorageClass of the backend PVC of
+// the given NFS PV. Empty string is returned for the NFS PVs of external
+// NFS server, and if the backend PVC doesn't exist.
+func (ic *VolumeInventoryController) getBackendStorageClass(pv *corev1.PersistentVolume) string {
+	if GetNFSServerTypeFromPV(pv) == NFSServerTypeExternal {
+		return ""
+	}
+
+	serverName := pv.Name
+	switch {
+	case isSharedNFSVolume(pv):
+		serverName = pv.Labels[sharedNFSServerLabelKey]
+	case isReadOnlyNFSVolume(pv):
+		serverName = pv.Labels[readOnlySourceLabelKey]
+	case pv.Spec.CSI != nil:
+		serverName = pv.Spec.CSI.VolumeHandle
+	}
+
+	backendPvc, err := ic.pvcLister.PersistentVolumeClaims(ic.serverNamespace).Get("nfs-" + serverName)
+	if err != nil || backendPvc.Spec.StorageClassName == nil {
+		return ""
+	}
+	return *backendPvc.Spec.StorageClassName
+}
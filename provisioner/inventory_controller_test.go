@@ -0,0 +1,89 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"testing"
+
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestVolumeInventoryControllerUpdateInventory(t *testing.T) {
+	backendSC := "openebs-hostpath"
+
+	getPV := func(name, provisioner string, phase corev1.PersistentVolumePhase, labels map[string]string) *corev1.PersistentVolume {
+		pv := getFakeNFSPvObject(name, "5Gi", provisioner)
+		pv.Labels = labels
+		pv.Status.Phase = phase
+		return pv
+	}
+	getBackendPVC := func(name string) *corev1.PersistentVolumeClaim {
+		pvc := getFakeBoundPvcObject("nfs-ns", name, "backend-"+name, "5Gi", "5Gi")
+		pvc.Spec.StorageClassName = &backendSC
+		return pvc
+	}
+
+	terminatingPV := getPV("pv3", provisionerName, corev1.VolumeBound, nil)
+	now := metav1.Now()
+	terminatingPV.DeletionTimestamp = &now
+
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, pv := range []*corev1.PersistentVolume{
+		getPV("pv1", provisionerName, corev1.VolumeBound, nil),
+		getPV("pv2", provisionerName, corev1.VolumeBound, map[string]string{
+			sharedNFSServerLabelKey: "shared-nfs-sc",
+		}),
+		terminatingPV,
+		getPV("pv4", provisionerName, corev1.VolumeReleased, nil),
+		getPV("pv5", provisionerName, corev1.VolumeBound, map[string]string{
+			"openebs.io/cas-type": "nfs-" + NFSServerTypeExternal,
+		}),
+		getPV("pv6", "other-provisioner", corev1.VolumeBound, nil),
+	} {
+		_ = pvIndexer.Add(pv)
+	}
+	for _, pvc := range []*corev1.PersistentVolumeClaim{
+		getBackendPVC("nfs-pv1"),
+		getBackendPVC("nfs-shared-nfs-sc"),
+		getBackendPVC("nfs-pv3"),
+	} {
+		_ = pvcIndexer.Add(pvc)
+	}
+
+	ic := &VolumeInventoryController{
+		serverNamespace: "nfs-ns",
+		pvLister:        listersv1.NewPersistentVolumeLister(pvIndexer),
+		pvcLister:       listersv1.NewPersistentVolumeClaimLister(pvcIndexer),
+	}
+
+	// stale series should be removed by the update
+	metrics.PersistentVolumeCount.WithLabelValues(string(corev1.VolumeFailed), backendSC).Set(1)
+	ic.updateInventory()
+
+	assert.Equal(t, 4, testutil.CollectAndCount(metrics.PersistentVolumeCount))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.PersistentVolumeCount.WithLabelValues(string(corev1.VolumeBound), backendSC)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PersistentVolumeCount.WithLabelValues(volumeStateTerminating, backendSC)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PersistentVolumeCount.WithLabelValues(string(corev1.VolumeReleased), "")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PersistentVolumeCount.WithLabelValues(string(corev1.VolumeBound), "")))
+}
@@ -26,10 +26,16 @@ import (
 // getNodeAffinityRules fetchs node affinity rules from
 // environment value
 func getNodeAffinityRules() NodeAffinity {
+	return parseNodeAffinityRules(getNfsServerNodeAffinity())
+}
+
+// parseNodeAffinityRules converts the node affinity rules from
+// plain string to NodeAffinity. Same format is used by the env
+// and the NFSServerNodeAffinity config of StorageClass
+func parseNodeAffinityRules(affinityValue string) NodeAffinity {
 	var nodeAffinity NodeAffinity
 
-	affinityValue := getNfsServerNodeAffinity()
-	if affinityValue == "" {
+	if strings.TrimSpace(affinityValue) == "" {
 		return nodeAffinity
 	}
 
@@ -41,6 +47,42 @@ func getNodeAffinityRules() NodeAffinity {
 	return nodeAffinity
 }
 
+// getNodeSelectorRequirements returns the node affinity rules of the
+// NFS server, i.e. the rules configured using the env followed by the
+// given rules of the StorageClass. Node must satisfy all the rules.
+func (p *Provisioner) getNodeSelectorRequirements(scRules []corev1.NodeSelectorRequirement) []corev1.NodeSelectorRequirement {
+	if len(scRules) == 0 {
+		return p.nodeAffinity.MatchExpressions
+	}
+
+	rules := make([]corev1.NodeSelectorRequirement, 0, len(p.nodeAffinity.MatchExpressions)+len(scRules))
+	rules = append(rules, p.nodeAffinity.MatchExpressions...)
+	return append(rules, scRules...)
+}
+
+// getNodeSelectorTerms returns the node selector terms of the NFS server.
+// Node must satisfy the node affinity rules of the env and the StorageClass,
+// and one of the topology terms of the first consumer, if any.
+func (p *Provisioner) getNodeSelectorTerms(nfsServerOpts *KernelNFSServerOptions) []corev1.NodeSelectorTerm {
+	rules := p.getNodeSelectorRequirements(nfsServerOpts.nodeAffinity)
+	if len(nfsServerOpts.topology) == 0 {
+		if len(rules) == 0 {
+			return nil
+		}
+		return []corev1.NodeSelectorTerm{{MatchExpressions: rules}}
+	}
+
+	terms := make([]corev1.NodeSelectorTerm, 0, len(nfsServerOpts.topology))
+	for _, term := range nfsServerOpts.topology {
+		matchExpressions := make([]corev1.NodeSelectorRequirement, 0, len(rules)+len(term.MatchExpressions))
+		matchExpressions = append(matchExpressions, rules...)
+		terms = append(terms, corev1.NodeSelectorTerm{
+			MatchExpressions: append(matchExpressions, term.MatchExpressions...),
+		})
+	}
+	return terms
+}
+
 // getOneOrMoreNodeSelectorRequirements can take one or more node affinity requirements
 // as string and convert them to structured form of Requirements
 // Ex:
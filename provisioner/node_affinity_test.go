@@ -175,6 +175,66 @@ func TestGetNodeAffinityRules(t *testing.T) {
 	}
 }
 
+func TestGetNodeSelectorTerms(t *testing.T) {
+	envRule := corev1.NodeSelectorRequirement{
+		Key:      "openebs.io/nfs-server",
+		Operator: corev1.NodeSelectorOpExists,
+	}
+	scRule := corev1.NodeSelectorRequirement{
+		Key:      "openebs.io/storage",
+		Operator: corev1.NodeSelectorOpExists,
+	}
+	zoneRule := func(zone string) corev1.NodeSelectorRequirement {
+		return corev1.NodeSelectorRequirement{
+			Key:      "topology.kubernetes.io/zone",
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{zone},
+		}
+	}
+
+	tests := map[string]struct {
+		envRules      []corev1.NodeSelectorRequirement
+		nfsServerOpts *KernelNFSServerOptions
+		expectedTerms []corev1.NodeSelectorTerm
+	}{
+		"when there are no rules": {
+			nfsServerOpts: &KernelNFSServerOptions{},
+		},
+		"when there are only node affinity rules": {
+			envRules: []corev1.NodeSelectorRequirement{envRule},
+			nfsServerOpts: &KernelNFSServerOptions{
+				nodeAffinity: []corev1.NodeSelectorRequirement{scRule},
+			},
+			expectedTerms: []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{envRule, scRule}},
+			},
+		},
+		"when there are node affinity rules and topology": {
+			envRules: []corev1.NodeSelectorRequirement{envRule},
+			nfsServerOpts: &KernelNFSServerOptions{
+				topology: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{zoneRule("zone-1")}},
+					{MatchExpressions: []corev1.NodeSelectorRequirement{zoneRule("zone-2")}},
+				},
+			},
+			expectedTerms: []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{envRule, zoneRule("zone-1")}},
+				{MatchExpressions: []corev1.NodeSelectorRequirement{envRule, zoneRule("zone-2")}},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		p := &Provisioner{
+			nodeAffinity: NodeAffinity{MatchExpressions: test.envRules},
+		}
+		gotTerms := p.getNodeSelectorTerms(test.nfsServerOpts)
+		if !reflect.DeepEqual(gotTerms, test.expectedTerms) {
+			t.Errorf("%q test got failed expected %v but got %v", name, test.expectedTerms, gotTerms)
+		}
+	}
+}
+
 func TestGetRightMostMatchingIndex(t *testing.T) {
 	tests := map[string]struct {
 		regexp         *regexp.Regexp
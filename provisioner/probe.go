@@ -0,0 +1,146 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ONC RPC(RFC 5531) constants used by the NFS NULL call
+	rpcMsgCall            = 0
+	rpcMsgReply           = 1
+	rpcVersion            = 2
+	rpcMsgAccepted        = 0
+	rpcAcceptSuccess      = 0
+	rpcAcceptProgMismatch = 2
+	rpcLastFragment       = 1 << 31
+	rpcMaxReplyFragment   = 1024
+
+	// nfsProgram is the RPC program number of NFS
+	nfsProgram = 100003
+
+	// nfsProbeVersion is the NFS version used by the NULL call.
+	// NFS servers which don't serve NFSv4 reply with the program
+	// version mismatch, which is treated as serving.
+	nfsProbeVersion = 4
+
+	// nfsProcNull is the NFS NULL procedure, which does no work
+	nfsProcNull = 0
+
+	// DefaultNFSProbeTimeout defines the timeout for a NFS probe
+	DefaultNFSProbeTimeout = 5 * time.Second
+)
+
+// nfsServerProber checks if the NFS server is serving at the given address
+type nfsServerProber interface {
+	Probe(ctx context.Context, address string) error
+}
+
+// nfsNullProber probes the NFS server by sending the NFS NULL call over TCP
+type nfsNullProber struct {
+	port    int
+	timeout time.Duration
+}
+
+// newNFSNullProber returns a new nfsServerProber which sends
+// the NFS NULL call to the NFS server port
+func newNFSNullProber() nfsServerProber {
+	return &nfsNullProber{
+		port:    NFSServerPort,
+		timeout: DefaultNFSProbeTimeout,
+	}
+}
+
+// Probe sends the NFS NULL call to the given address, and returns
+// error if the NFS server doesn't accept the call
+func (np *nfsNullProber) Probe(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, np.timeout)
+	defer cancel()
+
+	endpoint := net.JoinHostPort(address, strconv.Itoa(np.port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to NFS server %s", endpoint)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	// Call message is sent as a single record fragment, with the
+	// AUTH_NONE credential and verifier
+	xid := rand.Uint32()
+	call := make([]byte, 44)
+	binary.BigEndian.PutUint32(call[0:], rpcLastFragment|40)
+	for i, v := range []uint32{
+		xid, rpcMsgCall, rpcVersion, nfsProgram, nfsProbeVersion, nfsProcNull,
+		0, 0, // credential flavor and length
+		0, 0, // verifier flavor and length
+	} {
+		binary.BigEndian.PutUint32(call[4+4*i:], v)
+	}
+
+	if _, err = conn.Write(call); err != nil {
+		return errors.Wrapf(err, "failed to send NFS NULL call to %s", endpoint)
+	}
+
+	header := make([]byte, 4)
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return errors.Wrapf(err, "failed to read NFS NULL reply from %s", endpoint)
+	}
+	length := binary.BigEndian.Uint32(header) &^ rpcLastFragment
+	if length < 24 || length > rpcMaxReplyFragment {
+		return errors.Errorf("invalid NFS NULL reply from %s: fragment length %d", endpoint, length)
+	}
+
+	reply := make([]byte, length)
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		return errors.Wrapf(err, "failed to read NFS NULL reply from %s", endpoint)
+	}
+
+	// reply: xid, message type, reply status, verifier(flavor, length,
+	// body), accept status
+	if binary.BigEndian.Uint32(reply[0:]) != xid ||
+		binary.BigEndian.Uint32(reply[4:]) != rpcMsgReply {
+		return errors.Errorf("invalid NFS NULL reply from %s", endpoint)
+	}
+	if stat := binary.BigEndian.Uint32(reply[8:]); stat != rpcMsgAccepted {
+		return errors.Errorf("NFS NULL call denied by %s: reply status %d", endpoint, stat)
+	}
+
+	verfLen := binary.BigEndian.Uint32(reply[16:])
+	offset := 20 + ((verfLen + 3) &^ 3)
+	if offset+4 > length {
+		return errors.Errorf("invalid NFS NULL reply from %s: verifier length %d", endpoint, verfLen)
+	}
+	if stat := binary.BigEndian.Uint32(reply[offset:]); stat != rpcAcceptSuccess && stat != rpcAcceptProgMismatch {
+		return errors.Errorf("NFS NULL call failed on %s: accept status %d", endpoint, stat)
+	}
+	return nil
+}
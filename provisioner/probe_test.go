@@ -0,0 +1,134 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNFSServerProber returns the configured error on probe
+type fakeNFSServerProber struct {
+	err error
+}
+
+func (fp *fakeNFSServerProber) Probe(ctx context.Context, address string) error {
+	return fp.err
+}
+
+// runFakeRPCServer replies to the RPC calls with the given reply
+// status and accept status, and returns the port of the server
+func runFakeRPCServer(t *testing.T, replyStat, acceptStat uint32) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			call := make([]byte, 44)
+			if _, err := io.ReadFull(conn, call); err != nil {
+				conn.Close()
+				continue
+			}
+
+			reply := make([]byte, 28)
+			binary.BigEndian.PutUint32(reply[0:], rpcLastFragment|24)
+			copy(reply[4:8], call[4:8])
+			binary.BigEndian.PutUint32(reply[8:], rpcMsgReply)
+			binary.BigEndian.PutUint32(reply[12:], replyStat)
+			binary.BigEndian.PutUint32(reply[24:], acceptStat)
+			_, _ = conn.Write(reply)
+			conn.Close()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestNFSNullProberProbe(t *testing.T) {
+	tests := map[string]struct {
+		port          func(t *testing.T) int
+		isErrExpected bool
+	}{
+		"when NFS server accepts the NULL call": {
+			port: func(t *testing.T) int {
+				return runFakeRPCServer(t, rpcMsgAccepted, rpcAcceptSuccess)
+			},
+		},
+		"when NFS server denies the NULL call": {
+			port: func(t *testing.T) int {
+				return runFakeRPCServer(t, 1, rpcAcceptSuccess)
+			},
+			isErrExpected: true,
+		},
+		"when NFS server doesn't serve NFSv4": {
+			port: func(t *testing.T) int {
+				return runFakeRPCServer(t, rpcMsgAccepted, rpcAcceptProgMismatch)
+			},
+		},
+		"when NFS program is unavailable": {
+			port: func(t *testing.T) int {
+				// PROG_UNAVAIL
+				return runFakeRPCServer(t, rpcMsgAccepted, 1)
+			},
+			isErrExpected: true,
+		},
+		"when NFS server is not listening": {
+			port: func(t *testing.T) int {
+				listener, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatalf("failed to listen: %v", err)
+				}
+				port := listener.Addr().(*net.TCPAddr).Port
+				listener.Close()
+				return port
+			},
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			prober := &nfsNullProber{
+				port:    test.port(t),
+				timeout: 2 * time.Second,
+			}
+
+			err := prober.Probe(context.TODO(), "127.0.0.1")
+			if test.isErrExpected {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
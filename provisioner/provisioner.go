@@ -50,11 +50,16 @@ import (
 	menv "github.com/openebs/maya/pkg/env/v1alpha1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/dynamic"
 	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
 
 	nfshook "github.com/openebs/dynamic-nfs-provisioner/pkg/hook"
+	mKube "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/client"
 	clientset "k8s.io/client-go/kubernetes"
 )
 
@@ -88,6 +93,13 @@ func NewProvisioner(ctx context.Context, kubeClient *clientset.Clientset) (*Prov
 		backendPvcTimeoutVal = DefaultBackendPvcBoundTimeout
 	}
 
+	serverReadyTimeoutStr := getNfsServerReadyTimeout()
+	serverReadyTimeoutVal, err := strconv.Atoi(serverReadyTimeoutStr)
+	if err != nil || serverReadyTimeoutVal <= 0 {
+		klog.Warningf("Invalid serverReadyTimeout value=%s, using default value %d", serverReadyTimeoutStr, DefaultNFSServerReadyTimeout)
+		serverReadyTimeoutVal = DefaultNFSServerReadyTimeout
+	}
+
 	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
 	k8sNodeInformer := kubeInformerFactory.Core().V1().Nodes().Informer()
 
@@ -101,10 +113,26 @@ func NewProvisioner(ctx context.Context, kubeClient *clientset.Clientset) (*Prov
 
 	pvTracker := NewProvisioningTracker()
 
+	kubeConfig, err := mKube.New().Config()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get k8s config")
+	}
+	executor := newPodCommandExecutor(kubeClient, kubeConfig)
+
+	dynamicClient, err := dynamic.NewForConfig(kubeConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create dynamic client")
+	}
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartStructuredLogging(4)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+
 	p := &Provisioner{
 		stopCh: ctx.Done(),
 
 		kubeClient:      kubeClient,
+		dynamicClient:   dynamicClient,
 		namespace:       namespace,
 		serverNamespace: nfsServerNs,
 		defaultConfig: []mconfig.Config{
@@ -119,6 +147,11 @@ func NewProvisioner(ctx context.Context, kubeClient *clientset.Clientset) (*Prov
 		pvTracker:         pvTracker,
 		backendPvcTimeout: time.Duration(backendPvcTimeoutVal) * time.Second,
 		hook:              hook,
+		executor:          executor,
+		recorder:          eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: provisionerComponentName}),
+
+		serverReadyTimeout: time.Duration(serverReadyTimeoutVal) * time.Second,
+		prober:             newNFSNullProber(),
 	}
 	p.getVolumeConfig = p.GetVolumeConfig
 
@@ -134,7 +167,7 @@ func NewProvisioner(ctx context.Context, kubeClient *clientset.Clientset) (*Prov
 	}
 	if gcEnable {
 		// Running garbage collector to perform cleanup for stale NFS resources
-		go RunGarbageCollector(ctx, kubeClient, pvTracker, nfsServerNs)
+		go RunGarbageCollector(ctx, kubeClient, executor, pvTracker, nfsServerNs)
 	} else {
 		klog.Warning("Garbage collector is disabled")
 	}
@@ -158,10 +191,15 @@ func (p *Provisioner) Provision(ctx context.Context, opts pvController.Provision
 	p.pvTracker.Add(opts.PVName)
 	defer p.pvTracker.Delete(opts.PVName)
 
-	for _, accessMode := range pvc.Spec.AccessModes {
-		if accessMode != v1.ReadWriteMany {
-			klog.Infof("Received PVC provision request for non-rwx mode %v", accessMode)
+	if _, ok := pvc.Annotations[ReadOnlySourceAnnotation]; ok {
+		// NFS server of the source NFS PVC is exposed read-only
+		pv, err := p.ProvisionReadOnlyNFSVolume(ctx, opts)
+		if err != nil {
+			metrics.PersistentVolumeCreateFailedTotal.WithLabelValues(metrics.ProvisionerRequestCreate).Inc()
+			return nil, pvController.ProvisioningNoChange, err
 		}
+		metrics.PersistentVolumeCreateTotal.WithLabelValues(metrics.ProvisionerRequestCreate).Inc()
+		return pv, pvController.ProvisioningFinished, nil
 	}
 
 	name := opts.PVName
@@ -177,7 +215,11 @@ func (p *Provisioner) Provision(ctx context.Context, opts pvController.Provision
 	// Validate nodeAffinity rules for scheduling
 	// There might be changes to node after deploying
 	// NFS Provisioner
-	err = p.validateNodeAffinityRules()
+	scNodeAffinity, err := pvCASConfig.GetNFSServerNodeAffinity()
+	if err != nil {
+		return nil, pvController.ProvisioningNoChange, err
+	}
+	err = p.validateNodeAffinityRules(p.getNodeSelectorRequirements(scNodeAffinity))
 	if err != nil {
 		return nil, pvController.ProvisioningNoChange, err
 	}
@@ -192,13 +234,30 @@ func (p *Provisioner) Provision(ctx context.Context, opts pvController.Provision
 
 	sendEventOrIgnore(pvc.Name, name, size.String(), nfsServerType, analytics.VolumeProvision)
 
-	if nfsServerType == "kernel" {
-		pv, err := p.ProvisionKernalNFSServer(ctx, opts, pvCASConfig)
+	var provisionFn func(context.Context, pvController.ProvisionOptions, *VolumeConfig) (*v1.PersistentVolume, error)
+	switch nfsServerType {
+	case NFSServerTypeKernel:
+		provisionFn = p.ProvisionKernalNFSServer
+	case NFSServerTypeGanesha:
+		provisionFn = p.ProvisionGaneshaNFSServer
+	case NFSServerTypeExternal:
+		provisionFn = p.ProvisionExternalNFSServer
+	}
+
+	if provisionFn != nil {
+		start := time.Now()
+		pv, err := provisionFn(ctx, opts, pvCASConfig)
 		if err != nil {
+			if errors.Is(err, errNFSServerNotReady) {
+				// NFS server resources are created, provisioning
+				// is completed once the NFS server is ready
+				return nil, pvController.ProvisioningInBackground, err
+			}
 			metrics.PersistentVolumeCreateFailedTotal.WithLabelValues(metrics.ProvisionerRequestCreate).Inc()
 			return nil, pvController.ProvisioningNoChange, err
 		}
 		metrics.PersistentVolumeCreateTotal.WithLabelValues(metrics.ProvisionerRequestCreate).Inc()
+		metrics.PersistentVolumeProvisionDuration.WithLabelValues(nfsServerType).Observe(time.Since(start).Seconds())
 		return pv, pvController.ProvisioningFinished, nil
 	}
 
@@ -227,7 +286,6 @@ func (p *Provisioner) Delete(ctx context.Context, pv *v1.PersistentVolume) (err
 	//Initiate clean up only when reclaim policy is not retain.
 	if pv.Spec.PersistentVolumeReclaimPolicy != v1.PersistentVolumeReclaimRetain {
 
-		//TODO: extract the nfs server type from PV annotation
 		nfsServerType := GetNFSServerTypeFromPV(pv)
 		pvType := "nfs-" + nfsServerType
 
@@ -243,11 +301,31 @@ func (p *Provisioner) Delete(ctx context.Context, pv *v1.PersistentVolume) (err
 		}
 		sendEventOrIgnore(pvcName, pv.Name, size.String(), pvType, analytics.VolumeDeprovision)
 
-		if nfsServerType == "kernel" {
-			if err = p.DeleteKernalNFSServer(ctx, pv); err == nil {
-				if p.hook != nil && p.hook.ActionExists(nfshook.ResourceNFSPV, nfshook.EventTypeDeleteVolume) {
-					err = p.hook.ExecuteHookOnNFSPV(p.kubeClient, ctx, pv.Name, nfshook.EventTypeDeleteVolume)
-				}
+		if !isReadOnlyNFSVolume(pv) {
+			if err = p.verifyNoReadOnlyNFSVolumes(ctx, pv); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case isReadOnlyNFSVolume(pv):
+			// NFS server is owned by the source NFS PV
+			klog.Infof("Deleting read-only volume %v of volume %v", pv.Name, pv.Labels[readOnlySourceLabelKey])
+		case isSharedNFSVolume(pv):
+			err = p.DeleteSharedNFSVolume(ctx, pv)
+		case nfsServerType == NFSServerTypeKernel:
+			err = p.DeleteKernalNFSServer(ctx, pv)
+		case nfsServerType == NFSServerTypeGanesha:
+			err = p.DeleteGaneshaNFSServer(ctx, pv)
+		case nfsServerType == NFSServerTypeExternal:
+			err = p.DeleteExternalNFSServer(ctx, pv)
+		default:
+			err = errors.Errorf("PV with NFS Server of type(%v) is not supported", nfsServerType)
+		}
+
+		if err == nil {
+			if p.hook != nil && p.hook.ActionExists(nfshook.ResourceNFSPV, nfshook.EventTypeDeleteVolume) {
+				err = p.hook.ExecuteHookOnNFSPV(p.kubeClient, ctx, pv.Name, nfshook.EventTypeDeleteVolume)
 			}
 		}
 
@@ -277,12 +355,12 @@ func (p *Provisioner) Delete(ctx context.Context, pv *v1.PersistentVolume) (err
 
 // validateNodeAffinityRules will returns error if there are no
 // node exist for given affinity rules
-func (p *Provisioner) validateNodeAffinityRules() error {
-	if len(p.nodeAffinity.MatchExpressions) == 0 {
+func (p *Provisioner) validateNodeAffinityRules(matchExpressions []v1.NodeSelectorRequirement) error {
+	if len(matchExpressions) == 0 {
 		return nil
 	}
 
-	nodeSelector, err := v1helper.NodeSelectorRequirementsAsSelector(p.nodeAffinity.MatchExpressions)
+	nodeSelector, err := v1helper.NodeSelectorRequirementsAsSelector(matchExpressions)
 	if err != nil {
 		return err
 	}
@@ -298,6 +376,18 @@ func (p *Provisioner) validateNodeAffinityRules() error {
 	return nil
 }
 
+// validateNodeSelectorTerms returns error if none of the given
+// node selector terms is satisfied by any node
+func (p *Provisioner) validateNodeSelectorTerms(terms []v1.NodeSelectorTerm) error {
+	var err error
+	for _, term := range terms {
+		if err = p.validateNodeAffinityRules(term.MatchExpressions); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
 // sendEventOrIgnore sends anonymous nfs-pv provision/delete events
 func sendEventOrIgnore(pvcName, pvName, capacity, stgType, method string) {
 	if !menv.Truthy(menv.OpenEBSEnableAnalytics) {
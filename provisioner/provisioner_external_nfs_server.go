@@ -0,0 +1,171 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"path"
+	"strconv"
+
+	"github.com/openebs/maya/pkg/alertlog"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	nfshook "github.com/openebs/dynamic-nfs-provisioner/pkg/hook"
+	mPV "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/persistentvolume"
+	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	pvController "sigs.k8s.io/sig-storage-lib-external-provisioner/v7/controller"
+)
+
+// ProvisionExternalNFSServer is invoked by the Provisioner to create a NFS
+//
+//	volume as a subdirectory of the existing NFS server export
+func (p *Provisioner) ProvisionExternalNFSServer(ctx context.Context, opts pvController.ProvisionOptions, volumeConfig *VolumeConfig) (*v1.PersistentVolume, error) {
+	pvc := opts.PVC
+	name := opts.PVName
+	casType := "nfs-" + NFSServerTypeExternal
+
+	if pvc.Spec.DataSource != nil {
+		return nil, errors.Errorf("cloning volume %v is not supported for NFS server type %s", name, NFSServerTypeExternal)
+	}
+
+	server, err := volumeConfig.GetExternalNFSServer()
+	if err != nil {
+		klog.Errorf("Failed to get external NFS server. error: %s", err.Error())
+		return nil, err
+	}
+
+	exportPath, err := volumeConfig.GetExternalNFSPath()
+	if err != nil {
+		klog.Errorf("Failed to get external NFS path. error: %s", err.Error())
+		return nil, err
+	}
+
+	archiveOnDelete, err := volumeConfig.IsExternalArchiveOnDelete()
+	if err != nil {
+		klog.Errorf("Failed to get archive on delete config. error: %s", err.Error())
+		return nil, err
+	}
+
+	gid, err := volumeConfig.GetFsGID()
+	if err != nil {
+		klog.Errorf("Failed to get GID for FilePermissions. error: %s", err.Error())
+		return nil, err
+	}
+
+	mode, err := volumeConfig.GetFsMode()
+	if err != nil {
+		klog.Errorf("Failed to get mode for FilePermissions. error: %s", err.Error())
+		return nil, err
+	}
+
+	externalOpts := &ExternalNFSServerOptions{
+		pvName:          name,
+		server:          server,
+		exportPath:      exportPath,
+		permissionsUID:  volumeConfig.GetFsUID(),
+		permissionsGID:  gid,
+		permissionsMode: mode,
+		ctx:             ctx,
+	}
+
+	err = p.createVolumeDirectory(externalOpts)
+	if err != nil {
+		alertlog.Logger.Errorw("",
+			"eventcode", "nfs.pv.provision.failure",
+			"msg", "Failed to provision NFS PV",
+			"rname", opts.PVName,
+			"reason", "Creating volume directory on external NFS server failed",
+			"storagetype", casType,
+		)
+		return nil, err
+	}
+
+	volumePath := path.Join(exportPath, name)
+	klog.Infof("Creating nfs volume %v pointing at %v:%v", name, server, volumePath)
+
+	labels := make(map[string]string)
+	labels[string(mconfig.CASTypeKey)] = casType
+
+	annotations := make(map[string]string)
+	annotations[externalNFSArchiveAnnotation] = strconv.FormatBool(archiveOnDelete)
+
+	pvObj, err := mPV.NewBuilder().
+		WithName(name).
+		WithLabels(labels).
+		WithAnnotations(annotations).
+		WithReclaimPolicy(*opts.StorageClass.ReclaimPolicy).
+		WithAccessModes(pvc.Spec.AccessModes).
+		WithCapacityQty(pvc.Spec.Resources.Requests[v1.ResourceName(v1.ResourceStorage)]).
+		WithMountOptions(opts.StorageClass.MountOptions).
+		WithNFS(server, volumePath, isReadOnlyAccessModes(pvc.Spec.AccessModes)).
+		Build()
+	if err != nil {
+		alertlog.Logger.Errorw("",
+			"eventcode", "nfs.pv.provision.failure",
+			"msg", "Failed to provision NFS PV",
+			"rname", opts.PVName,
+			"reason", "Building volume failed",
+			"storagetype", casType,
+		)
+		return nil, err
+	}
+
+	if p.hook != nil && p.hook.ActionExists(nfshook.ResourceNFSPV, nfshook.EventTypeCreateVolume) {
+		err = p.hook.Action(pvObj, nfshook.ResourceNFSPV, nfshook.EventTypeCreateVolume)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to execute hook on NFS PV=%s", pvObj.Name)
+		}
+	}
+
+	alertlog.Logger.Infow("",
+		"eventcode", "nfs.pv.provision.success",
+		"msg", "Successfully provisioned NFS PV",
+		"rname", opts.PVName,
+		"storagetype", casType,
+	)
+	return pvObj, nil
+}
+
+// DeleteExternalNFSServer is invoked by the PVC controller to perform clean-up
+//
+//	activities before deleteing the PV object. If reclaim policy is
+//	set to not-retain, then this function will remove or archive the
+//	volume directory on the existing NFS server export
+func (p *Provisioner) DeleteExternalNFSServer(ctx context.Context, pv *v1.PersistentVolume) (err error) {
+	defer func() {
+		err = errors.Wrapf(err, "failed to delete volume %v", pv.Name)
+	}()
+
+	if pv.Spec.NFS == nil {
+		return errors.Errorf("NFS source is not set for the PV")
+	}
+
+	externalOpts := &ExternalNFSServerOptions{
+		pvName:     pv.Name,
+		server:     pv.Spec.NFS.Server,
+		exportPath: path.Dir(pv.Spec.NFS.Path),
+		ctx:        ctx,
+	}
+
+	archiveOnDelete, _ := strconv.ParseBool(pv.Annotations[externalNFSArchiveAnnotation])
+	if archiveOnDelete {
+		return p.archiveVolumeDirectory(externalOpts)
+	}
+	return p.removeVolumeDirectory(externalOpts)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	v1 "k8s.io/api/core/v1"
+	pvController "sigs.k8s.io/sig-storage-lib-external-provisioner/v7/controller"
+)
+
+// ProvisionGaneshaNFSServer is invoked by the Provisioner to create a NFS
+//
+//	with userspace NFS-Ganesha server
+func (p *Provisioner) ProvisionGaneshaNFSServer(ctx context.Context, opts pvController.ProvisionOptions, volumeConfig *VolumeConfig) (*v1.PersistentVolume, error) {
+	return p.provisionNFSServer(ctx, opts, volumeConfig, NFSServerTypeGanesha)
+}
+
+// DeleteGaneshaNFSServer is invoked by the PVC controller to perform clean-up
+//
+//	activities before deleteing the PV object. If reclaim policy is
+//	set to not-retain, then this function will delete the NFS-Ganesha
+//	server and the backend PVC
+func (p *Provisioner) DeleteGaneshaNFSServer(ctx context.Context, pv *v1.PersistentVolume) (err error) {
+	defer func() {
+		err = errors.Wrapf(err, "failed to delete volume %v", pv.Name)
+	}()
+
+	//Extract the details to delete NFS Server
+	nfsServerOpts := &KernelNFSServerOptions{
+		pvName:     pv.Name,
+		serverType: NFSServerTypeGanesha,
+		ctx:        ctx,
+	}
+
+	return p.deleteNFSServer(nfsServerOpts)
+}
@@ -18,6 +18,7 @@ package provisioner
 
 import (
 	"context"
+	"path"
 
 	"github.com/openebs/maya/pkg/alertlog"
 	"github.com/pkg/errors"
@@ -34,67 +35,91 @@ import (
 //
 //	with kernel NFS server
 func (p *Provisioner) ProvisionKernalNFSServer(ctx context.Context, opts pvController.ProvisionOptions, volumeConfig *VolumeConfig) (*v1.PersistentVolume, error) {
-	var leaseTime, graceTime int
-	var leaseErr, graceErr error
+	return p.provisionNFSServer(ctx, opts, volumeConfig, NFSServerTypeKernel)
+}
 
+// provisionNFSServer creates the NFS server of given type for the volume
+// and returns the NFS PV pointing to it
+func (p *Provisioner) provisionNFSServer(ctx context.Context, opts pvController.ProvisionOptions, volumeConfig *VolumeConfig, serverType string) (*v1.PersistentVolume, error) {
 	pvc := opts.PVC
 	name := opts.PVName
 	capacity := opts.PVC.Spec.Resources.Requests[v1.ResourceName(v1.ResourceStorage)]
+	casType := "nfs-" + serverType
 
-	leaseTime, leaseErr = volumeConfig.GetNFSServerLeaseTime()
-	graceTime, graceErr = volumeConfig.GetNFServerGraceTime()
-	if leaseErr != nil || graceErr != nil {
-		klog.Errorf("Error parsing lease/grace time, leaseError=%s graceError=%s", leaseErr, graceErr)
-		alertlog.Logger.Errorw("",
-			"eventcode", "nfs.pv.provision.failure",
-			"msg", "Failed to provision NFS PV",
-			"rname", opts.PVName,
-			"reason", "Parsing failed for lease/grace time",
-			"storagetype", "nfs-kernel",
-		)
-	}
-	fsGID, err := volumeConfig.GetFSGroupID()
+	//Extract the details to create a NFS Server
+	nfsServerOpts, err := newKernelNFSServerOptions(ctx, name, capacity.String(), volumeConfig, serverType)
 	if err != nil {
-		klog.Errorf("Error parsing fsgid error: %s", err.Error())
 		return nil, err
 	}
+	nfsServerOpts.provisionerNS = p.namespace
+	nfsServerOpts.pvcName = pvc.Name
+	nfsServerOpts.pvcNamespace = pvc.Namespace
+	nfsServerOpts.pvcUID = string(pvc.UID)
 
-	resources, err := volumeConfig.GetNFSServerResourceRequirements()
+	shared, err := volumeConfig.IsSharedNFSServer()
 	if err != nil {
-		klog.Errorf("Failed to get NFS server resource requirements(requests & limits) error: %s", err.Error())
+		klog.Errorf("Failed to get shared NFS server config. error: %s", err.Error())
 		return nil, err
 	}
 
-	gid, err := volumeConfig.GetFsGID()
-	if err != nil {
-		klog.Errorf("Failed to get GID for FilePermissions. error: %s", err.Error())
-		return nil, err
+	// File permissions of the volume, applied on the volume directory
+	// of the shared NFS server
+	uid, gid, mode := nfsServerOpts.permissionsUID, nfsServerOpts.permissionsGID, nfsServerOpts.permissionsMode
+
+	if shared {
+		nfsServerOpts, err = p.getSharedNFSServerOptions(ctx, volumeConfig.scName, serverType)
+		if err != nil {
+			klog.Errorf("Failed to initialize shared NFS server options for volume %v. error: %s", name, err.Error())
+			return nil, err
+		}
+		// NFS PVC is referred only to record the provisioning
+		// events, it is not used to render the shared NFS server
+		nfsServerOpts.pvcName = pvc.Name
+		nfsServerOpts.pvcNamespace = pvc.Namespace
+		nfsServerOpts.pvcUID = string(pvc.UID)
 	}
 
-	mode, err := volumeConfig.GetFsMode()
-	if err != nil {
-		klog.Errorf("Failed to get mode for FilePermissions. error: %s", err.Error())
-		return nil, err
+	if !shared {
+		// NFS server and its backend volume are placed in the
+		// topology of the first consumer of the volume
+		if opts.SelectedNode != nil {
+			nfsServerOpts.selectedNode = opts.SelectedNode.Name
+		}
+		nfsServerOpts.topology = getNFSServerTopology(opts.SelectedNode, opts.StorageClass.AllowedTopologies)
+		if len(nfsServerOpts.topology) != 0 {
+			err = p.validateNodeSelectorTerms(p.getNodeSelectorTerms(nfsServerOpts))
+			if err != nil {
+				klog.Errorf("NFS server of volume %v can't be placed in the topology of the consumer. error: %s", name, err.Error())
+				return nil, err
+			}
+		}
 	}
 
-	//Extract the details to create a NFS Server
-	nfsServerOpts := &KernelNFSServerOptions{
-		pvName:                name,
-		provisionerNS:         p.namespace,
-		capacity:              capacity.String(),
-		backendStorageClass:   volumeConfig.GetBackendStorageClassFromConfig(),
-		nfsServerCustomConfig: volumeConfig.GetCustomNFSServerConfig(),
-		leaseTime:             leaseTime,
-		graceTime:             graceTime,
-		fsGroup:               fsGID,
-		permissionsUID:        volumeConfig.GetFsUID(),
-		permissionsGID:        gid,
-		permissionsMode:       mode,
-		pvcName:               pvc.Name,
-		pvcNamespace:          pvc.Namespace,
-		pvcUID:                string(pvc.UID),
-		resources:             resources,
-		ctx:                   ctx,
+	readOnly := isReadOnlyAccessModes(pvc.Spec.AccessModes)
+	if readOnly {
+		if err = nfsServerOpts.setReadOnlyExport(); err != nil {
+			klog.Errorf("Failed to export volume %v read-only. error: %s", name, err.Error())
+			return nil, err
+		}
+	}
+
+	if pvc.Spec.DataSource != nil {
+		if shared {
+			return nil, errors.Errorf("cloning volume %v is not supported on the shared NFS server", name)
+		}
+
+		err = p.initCloneSource(nfsServerOpts, pvc, volumeConfig)
+		if err != nil {
+			klog.Errorf("Failed to initialize clone source for volume %v. error: %s", name, err.Error())
+			alertlog.Logger.Errorw("",
+				"eventcode", "nfs.pv.provision.failure",
+				"msg", "Failed to provision NFS PV",
+				"rname", opts.PVName,
+				"reason", "Clone source initialization failed",
+				"storagetype", casType,
+			)
+			return nil, err
+		}
 	}
 
 	nfsService, err := p.getNFSServerAddress(nfsServerOpts)
@@ -105,12 +130,37 @@ func (p *Provisioner) ProvisionKernalNFSServer(ctx context.Context, opts pvContr
 			"msg", "Failed to provision NFS PV",
 			"rname", opts.PVName,
 			"reason", "NFS service initialization failed",
-			"storagetype", "nfs-kernel",
+			"storagetype", casType,
 		)
 		return nil, err
 	}
 
-	klog.Infof("Creating nfs volume %v pointing at %v", name, nfsService)
+	// PV is returned only after the NFS server is serving the volume,
+	// otherwise application pods fail to mount the volume
+	err = p.waitForNFSServerReady(nfsServerOpts, nfsService)
+	if err != nil {
+		klog.Infof("NFS server of volume %v is not ready: %v", name, err)
+		return nil, err
+	}
+
+	exportPath := nfsServerOpts.getExportRoot()
+	if shared {
+		// Volume is a subdirectory of the shared NFS server export
+		err = p.addSharedNFSVolume(nfsServerOpts, name, capacity.Value(), uid, gid, mode)
+		if err != nil {
+			alertlog.Logger.Errorw("",
+				"eventcode", "nfs.pv.provision.failure",
+				"msg", "Failed to provision NFS PV",
+				"rname", opts.PVName,
+				"reason", "Adding volume to shared NFS server failed",
+				"storagetype", casType,
+			)
+			return nil, err
+		}
+		exportPath = path.Join(exportPath, name)
+	}
+
+	klog.Infof("Creating nfs volume %v pointing at %v:%v", name, nfsService, exportPath)
 
 	// TODO initialize the Labels and annotations
 	// Use annotations to specify the context using which the PV was created.
@@ -120,7 +170,10 @@ func (p *Provisioner) ProvisionKernalNFSServer(ctx context.Context, opts pvContr
 	//fstype := casVolume.Spec.FSType
 
 	labels := make(map[string]string)
-	labels[string(mconfig.CASTypeKey)] = "nfs-kernel"
+	labels[string(mconfig.CASTypeKey)] = casType
+	if shared {
+		labels[sharedNFSServerLabelKey] = nfsServerOpts.pvName
+	}
 	//labels[string(v1alpha1.StorageClassKey)] = *className
 
 	//TODO Change the following to a builder pattern
@@ -132,8 +185,32 @@ func (p *Provisioner) ProvisionKernalNFSServer(ctx context.Context, opts pvContr
 		WithReclaimPolicy(*opts.StorageClass.ReclaimPolicy).
 		WithAccessModes(pvc.Spec.AccessModes).
 		WithCapacityQty(pvc.Spec.Resources.Requests[v1.ResourceName(v1.ResourceStorage)]).
-		WithMountOptions(opts.StorageClass.MountOptions).
-		WithNFS(nfsService, "/", false)
+		WithMountOptions(nfsServerOpts.getMountOptions(opts.StorageClass.MountOptions)).
+		WithNFS(nfsService, exportPath, readOnly)
+
+	pvAnnotations := make(map[string]string)
+	// Immutable config of the PVC is recorded, to render the NFS
+	// server with the same config after the PVC config is changed
+	pvAnnotations[provisionedConfigAnnotation], err = getImmutableConfig(pvc.Annotations[string(mconfig.CASConfigKey)])
+	if err != nil {
+		return nil, err
+	}
+	if p.useClusterIP {
+		// ClusterIP is recorded, to request the same
+		// ClusterIP if the NFS server Service is recreated
+		pvAnnotations[nfsServerClusterIPAnnotation] = nfsService
+	}
+	if len(nfsServerOpts.topology) != 0 {
+		// Topology is recorded, to place the NFS server in the
+		// same topology if the NFS server Deployment is recreated
+		pvAnnotations[nfsServerTopologyAnnotation], err = encodeNFSServerTopology(nfsServerOpts.topology)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(pvAnnotations) != 0 {
+		pvObjBuilder = pvObjBuilder.WithAnnotations(pvAnnotations)
+	}
 
 	//Note: The nfs server is launched by the nfs-server-alpine.
 	//When "/" is replaced with "/nfsshare", the mount fails.
@@ -153,7 +230,7 @@ func (p *Provisioner) ProvisionKernalNFSServer(ctx context.Context, opts pvContr
 			"msg", "Failed to provision NFS PV",
 			"rname", opts.PVName,
 			"reason", "Building volume failed",
-			"storagetype", "nfs-kernel",
+			"storagetype", casType,
 		)
 		return nil, err
 	}
@@ -169,7 +246,7 @@ func (p *Provisioner) ProvisionKernalNFSServer(ctx context.Context, opts pvContr
 		"eventcode", "nfs.pv.provision.success",
 		"msg", "Successfully provisioned NFS PV",
 		"rname", opts.PVName,
-		"storagetype", "nfs-kernel",
+		"storagetype", casType,
 	)
 	return pvObj, nil
 }
@@ -185,9 +262,144 @@ func (p *Provisioner) DeleteKernalNFSServer(ctx context.Context, pv *v1.Persiste
 
 	//Extract the details to delete NFS Server
 	nfsServerOpts := &KernelNFSServerOptions{
-		pvName: pv.Name,
-		ctx:    ctx,
+		pvName:     pv.Name,
+		serverType: NFSServerTypeKernel,
+		ctx:        ctx,
 	}
 
 	return p.deleteNFSServer(nfsServerOpts)
 }
+
+// newKernelNFSServerOptions returns the options to create the NFS server
+// of given type for the volume, using the volume configuration
+func newKernelNFSServerOptions(ctx context.Context, name, capacity string, volumeConfig *VolumeConfig, serverType string) (*KernelNFSServerOptions, error) {
+	leaseTime, leaseErr := volumeConfig.GetNFSServerLeaseTime()
+	graceTime, graceErr := volumeConfig.GetNFServerGraceTime()
+	if leaseErr != nil || graceErr != nil {
+		klog.Errorf("Error parsing lease/grace time, leaseError=%s graceError=%s", leaseErr, graceErr)
+		alertlog.Logger.Errorw("",
+			"eventcode", "nfs.pv.provision.failure",
+			"msg", "Failed to provision NFS PV",
+			"rname", name,
+			"reason", "Parsing failed for lease/grace time",
+			"storagetype", "nfs-"+serverType,
+		)
+	}
+	fsGID, err := volumeConfig.GetFSGroupID()
+	if err != nil {
+		klog.Errorf("Error parsing fsgid error: %s", err.Error())
+		return nil, err
+	}
+
+	resources, err := volumeConfig.GetNFSServerResourceRequirements()
+	if err != nil {
+		klog.Errorf("Failed to get NFS server resource requirements(requests & limits) error: %s", err.Error())
+		return nil, err
+	}
+
+	gid, err := volumeConfig.GetFsGID()
+	if err != nil {
+		klog.Errorf("Failed to get GID for FilePermissions. error: %s", err.Error())
+		return nil, err
+	}
+
+	mode, err := volumeConfig.GetFsMode()
+	if err != nil {
+		klog.Errorf("Failed to get mode for FilePermissions. error: %s", err.Error())
+		return nil, err
+	}
+
+	exportOpts, err := volumeConfig.GetExportOptions()
+	if err != nil {
+		klog.Errorf("Failed to get export options. error: %s", err.Error())
+		alertlog.Logger.Errorw("",
+			"eventcode", "nfs.pv.provision.failure",
+			"msg", "Failed to provision NFS PV",
+			"rname", name,
+			"reason", "Invalid export options",
+			"storagetype", "nfs-"+serverType,
+		)
+		return nil, err
+	}
+
+	nfsVersions, err := volumeConfig.GetNFSVersions()
+	if err != nil {
+		klog.Errorf("Failed to get NFS versions. error: %s", err.Error())
+		return nil, err
+	}
+	if serverType != NFSServerTypeKernel &&
+		(len(nfsVersions) != 1 || nfsVersions[0] != NFSVersion4) {
+		return nil, errors.Errorf("%s is not supported for NFS server type %s", NFSVersions, serverType)
+	}
+
+	networkPolicy, err := volumeConfig.GetNetworkPolicyOptions()
+	if err != nil {
+		klog.Errorf("Failed to get NetworkPolicy options. error: %s", err.Error())
+		return nil, err
+	}
+
+	metricsEnabled, err := volumeConfig.IsNFSServerMetricsEnabled()
+	if err != nil {
+		klog.Errorf("Failed to get NFS server metrics config. error: %s", err.Error())
+		return nil, err
+	}
+	if metricsEnabled && serverType != NFSServerTypeKernel {
+		return nil, errors.Errorf("%s is not supported for NFS server type %s", NFSServerMetrics, serverType)
+	}
+
+	nodeAffinity, err := volumeConfig.GetNFSServerNodeAffinity()
+	if err != nil {
+		klog.Errorf("Failed to get NFS server node affinity. error: %s", err.Error())
+		return nil, err
+	}
+
+	tolerations, err := volumeConfig.GetNFSServerTolerations()
+	if err != nil {
+		klog.Errorf("Failed to get NFS server tolerations. error: %s", err.Error())
+		return nil, err
+	}
+
+	priorityClassName, err := volumeConfig.GetNFSServerPriorityClassName()
+	if err != nil {
+		klog.Errorf("Failed to get NFS server priority class. error: %s", err.Error())
+		return nil, err
+	}
+
+	topologySpreadConstraints, err := volumeConfig.GetNFSServerTopologySpreadConstraints()
+	if err != nil {
+		klog.Errorf("Failed to get NFS server topology spread constraints. error: %s", err.Error())
+		return nil, err
+	}
+
+	customServerConfig := volumeConfig.GetCustomNFSServerConfig()
+	if exportOpts != nil {
+		if serverType != NFSServerTypeKernel {
+			return nil, errors.Errorf("%s is not supported for NFS server type %s", ExportOptions, serverType)
+		}
+		customServerConfig = exportOpts.exportsConfig("/nfsshare")
+	}
+
+	return &KernelNFSServerOptions{
+		pvName:                    name,
+		capacity:                  capacity,
+		backendStorageClass:       volumeConfig.GetBackendStorageClassFromConfig(),
+		nfsServerCustomConfig:     customServerConfig,
+		exportOptions:             exportOpts,
+		nfsVersions:               nfsVersions,
+		networkPolicy:             networkPolicy,
+		metricsEnabled:            metricsEnabled,
+		leaseTime:                 leaseTime,
+		graceTime:                 graceTime,
+		fsGroup:                   fsGID,
+		permissionsUID:            volumeConfig.GetFsUID(),
+		permissionsGID:            gid,
+		permissionsMode:           mode,
+		resources:                 resources,
+		serverType:                serverType,
+		nodeAffinity:              nodeAffinity,
+		tolerations:               tolerations,
+		priorityClassName:         priorityClassName,
+		topologySpreadConstraints: topologySpreadConstraints,
+		ctx:                       ctx,
+	}, nil
+}
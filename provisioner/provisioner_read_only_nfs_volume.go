@@ -0,0 +1,178 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"strings"
+
+	"github.com/openebs/maya/pkg/alertlog"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	pvController "sigs.k8s.io/sig-storage-lib-external-provisioner/v7/controller"
+
+	nfshook "github.com/openebs/dynamic-nfs-provisioner/pkg/hook"
+	mPV "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/persistentvolume"
+	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+)
+
+const (
+	// ReadOnlySourceAnnotation is set on the NFS PVC, to expose an
+	// existing NFS PVC of the same namespace read-only, instead of
+	// creating a new volume. Value of the annotation is the name of
+	// the source NFS PVC
+	ReadOnlySourceAnnotation = "nfs.openebs.io/read-only-source"
+
+	// readOnlySourceLabelKey is set on the NFS PV which exposes an
+	// existing NFS PV read-only. Value of the label is the name of
+	// the source NFS PV
+	readOnlySourceLabelKey = "nfs.openebs.io/read-only-source"
+)
+
+// isReadOnlyNFSVolume returns true if the given NFS PV
+// exposes an existing NFS PV read-only
+func isReadOnlyNFSVolume(pv *v1.PersistentVolume) bool {
+	return len(pv.Labels[readOnlySourceLabelKey]) != 0
+}
+
+// ProvisionReadOnlyNFSVolume is invoked by the Provisioner to create a
+// NFS PV, which exposes the NFS server of the source NFS PVC read-only
+func (p *Provisioner) ProvisionReadOnlyNFSVolume(ctx context.Context, opts pvController.ProvisionOptions) (*v1.PersistentVolume, error) {
+	pvc := opts.PVC
+	name := opts.PVName
+	sourceName := strings.TrimSpace(pvc.Annotations[ReadOnlySourceAnnotation])
+
+	sourcePv, err := p.getReadOnlySourcePV(ctx, pvc, sourceName)
+	if err != nil {
+		klog.Errorf("Failed to get read-only source of volume %v. error: %s", name, err.Error())
+		alertlog.Logger.Errorw("",
+			"eventcode", "nfs.pv.provision.failure",
+			"msg", "Failed to provision NFS PV",
+			"rname", opts.PVName,
+			"reason", "Invalid read-only source",
+			"storagetype", "nfs",
+		)
+		return nil, err
+	}
+
+	labels := map[string]string{
+		string(mconfig.CASTypeKey): sourcePv.Labels[string(mconfig.CASTypeKey)],
+		readOnlySourceLabelKey:     sourcePv.Name,
+	}
+
+	klog.Infof("Creating read-only nfs volume %v pointing at %v:%v of volume %v",
+		name, sourcePv.Spec.NFS.Server, sourcePv.Spec.NFS.Path, sourcePv.Name)
+
+	pvObj, err := mPV.NewBuilder().
+		WithName(name).
+		WithLabels(labels).
+		WithReclaimPolicy(*opts.StorageClass.ReclaimPolicy).
+		WithAccessModes(pvc.Spec.AccessModes).
+		WithCapacityQty(sourcePv.Spec.Capacity[v1.ResourceStorage]).
+		WithMountOptions(opts.StorageClass.MountOptions).
+		WithNFS(sourcePv.Spec.NFS.Server, sourcePv.Spec.NFS.Path, true).
+		Build()
+	if err != nil {
+		alertlog.Logger.Errorw("",
+			"eventcode", "nfs.pv.provision.failure",
+			"msg", "Failed to provision NFS PV",
+			"rname", opts.PVName,
+			"reason", "Building volume failed",
+			"storagetype", labels[string(mconfig.CASTypeKey)],
+		)
+		return nil, err
+	}
+
+	if p.hook != nil && p.hook.ActionExists(nfshook.ResourceNFSPV, nfshook.EventTypeCreateVolume) {
+		err = p.hook.Action(pvObj, nfshook.ResourceNFSPV, nfshook.EventTypeCreateVolume)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to execute hook on NFS PV=%s", pvObj.Name)
+		}
+	}
+
+	alertlog.Logger.Infow("",
+		"eventcode", "nfs.pv.provision.success",
+		"msg", "Successfully provisioned NFS PV",
+		"rname", opts.PVName,
+		"storagetype", labels[string(mconfig.CASTypeKey)],
+	)
+	return pvObj, nil
+}
+
+// getReadOnlySourcePV returns the NFS PV of the given source NFS PVC,
+// after verifying that it can be exposed read-only to the given PVC
+func (p *Provisioner) getReadOnlySourcePV(ctx context.Context, pvc *v1.PersistentVolumeClaim, sourceName string) (*v1.PersistentVolume, error) {
+	if !isReadOnlyAccessModes(pvc.Spec.AccessModes) {
+		return nil, errors.Errorf("PVC with %s annotation must request only %s access mode",
+			ReadOnlySourceAnnotation, v1.ReadOnlyMany)
+	}
+
+	sourcePvc, err := p.kubeClient.CoreV1().
+		PersistentVolumeClaims(pvc.Namespace).
+		Get(ctx, sourceName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get source PVC {%s/%s}", pvc.Namespace, sourceName)
+	}
+	if sourcePvc.Status.Phase != v1.ClaimBound {
+		return nil, errors.Errorf("source PVC {%s/%s} is not bound", pvc.Namespace, sourceName)
+	}
+
+	sourcePv, err := p.kubeClient.CoreV1().
+		PersistentVolumes().
+		Get(ctx, sourcePvc.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get PV of source PVC {%s/%s}", pvc.Namespace, sourceName)
+	}
+	if sourcePv.Annotations[annDynamicallyProvisioned] != provisionerName || sourcePv.Spec.NFS == nil {
+		return nil, errors.Errorf("source PVC {%s/%s} is not a NFS PVC", pvc.Namespace, sourceName)
+	}
+	if isReadOnlyNFSVolume(sourcePv) {
+		return nil, errors.Errorf("source PVC {%s/%s} exposes another NFS PVC, use PVC %s instead",
+			pvc.Namespace, sourceName, sourcePv.Labels[readOnlySourceLabelKey])
+	}
+
+	requestSize := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	sourceSize := sourcePv.Spec.Capacity[v1.ResourceStorage]
+	if requestSize.Cmp(sourceSize) > 0 {
+		return nil, errors.Errorf("requested size %s is more than the size %s of source PVC {%s/%s}",
+			requestSize.String(), sourceSize.String(), pvc.Namespace, sourceName)
+	}
+	return sourcePv, nil
+}
+
+// verifyNoReadOnlyNFSVolumes returns error if the given NFS PV is
+// exposed read-only by other NFS PVs, since those would lose access
+// to the NFS server on deletion of the given NFS PV
+func (p *Provisioner) verifyNoReadOnlyNFSVolumes(ctx context.Context, pv *v1.PersistentVolume) error {
+	pvList, err := p.kubeClient.CoreV1().
+		PersistentVolumes().
+		List(ctx, metav1.ListOptions{LabelSelector: readOnlySourceLabelKey + "=" + pv.Name})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list read-only volumes of %s", pv.Name)
+	}
+
+	if len(pvList.Items) != 0 {
+		var names []string
+		for _, readOnlyPv := range pvList.Items {
+			names = append(names, readOnlyPv.Name)
+		}
+		return errors.Errorf("volume is exposed read-only by volumes %s", strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"testing"
+
+	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	pvController "sigs.k8s.io/sig-storage-lib-external-provisioner/v7/controller"
+)
+
+func getFakeReadOnlySourceObjects(pvName, size string) []runtime.Object {
+	sourcePv := getFakeNFSPvObject(pvName, size, provisionerName)
+	sourcePv.Labels = map[string]string{
+		string(mconfig.CASTypeKey): "nfs-kernel",
+	}
+	sourcePv.Spec.NFS = &corev1.NFSVolumeSource{Server: "10.0.0.10", Path: "/"}
+
+	return []runtime.Object{
+		getFakeBoundPvcObject("app", "source-pvc", pvName, size, size),
+		sourcePv,
+	}
+}
+
+func TestProvisionReadOnlyNFSVolume(t *testing.T) {
+	reclaimPolicy := corev1.PersistentVolumeReclaimDelete
+
+	tests := map[string]struct {
+		objs          []runtime.Object
+		accessModes   []corev1.PersistentVolumeAccessMode
+		requestSize   string
+		isErrExpected bool
+	}{
+		"when read-only volume is requested for NFS PVC": {
+			objs:        getFakeReadOnlySourceObjects("source-pv", "5Gi"),
+			accessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany},
+			requestSize: "5Gi",
+		},
+		"when read-write access is requested": {
+			objs:          getFakeReadOnlySourceObjects("source-pv", "5Gi"),
+			accessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany, corev1.ReadWriteMany},
+			requestSize:   "5Gi",
+			isErrExpected: true,
+		},
+		"when requested size is more than the source size": {
+			objs:          getFakeReadOnlySourceObjects("source-pv", "5Gi"),
+			accessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany},
+			requestSize:   "10Gi",
+			isErrExpected: true,
+		},
+		"when source PVC doesn't exist": {
+			accessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany},
+			requestSize:   "5Gi",
+			isErrExpected: true,
+		},
+		"when source PVC is not a NFS PVC": {
+			objs: []runtime.Object{
+				getFakeBoundPvcObject("app", "source-pvc", "source-pv", "5Gi", "5Gi"),
+				getFakeNFSPvObject("source-pv", "5Gi", "other-provisioner"),
+			},
+			accessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany},
+			requestSize:   "5Gi",
+			isErrExpected: true,
+		},
+		"when source PVC is a read-only NFS PVC": {
+			objs: func() []runtime.Object {
+				objs := getFakeReadOnlySourceObjects("source-pv", "5Gi")
+				objs[1].(*corev1.PersistentVolume).Labels[readOnlySourceLabelKey] = "other-pv"
+				return objs
+			}(),
+			accessModes:   []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany},
+			requestSize:   "5Gi",
+			isErrExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			p := &Provisioner{
+				kubeClient: fake.NewSimpleClientset(test.objs...),
+			}
+
+			pvc := getFakeBoundPvcObject("app", "ro-pvc", "", test.requestSize, test.requestSize)
+			pvc.Annotations = map[string]string{ReadOnlySourceAnnotation: "source-pvc"}
+			pvc.Spec.AccessModes = test.accessModes
+
+			pv, err := p.ProvisionReadOnlyNFSVolume(context.TODO(), pvController.ProvisionOptions{
+				PVName:       "ro-pv",
+				PVC:          pvc,
+				StorageClass: &storagev1.StorageClass{ReclaimPolicy: &reclaimPolicy},
+			})
+			if test.isErrExpected {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "source-pv", pv.Labels[readOnlySourceLabelKey])
+			assert.Equal(t, "nfs-kernel", pv.Labels[string(mconfig.CASTypeKey)])
+			assert.Equal(t, &corev1.NFSVolumeSource{Server: "10.0.0.10", Path: "/", ReadOnly: true}, pv.Spec.NFS)
+			assert.Equal(t, test.accessModes, pv.Spec.AccessModes)
+		})
+	}
+}
+
+func TestVerifyNoReadOnlyNFSVolumes(t *testing.T) {
+	readOnlyPv := getFakeNFSPvObject("ro-pv", "5Gi", provisionerName)
+	readOnlyPv.Labels = map[string]string{readOnlySourceLabelKey: "source-pv"}
+
+	tests := map[string]struct {
+		pvName        string
+		isErrExpected bool
+	}{
+		"when NFS PV is exposed by read-only NFS PV": {
+			pvName:        "source-pv",
+			isErrExpected: true,
+		},
+		"when NFS PV is not exposed by read-only NFS PV": {
+			pvName: "other-pv",
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			p := &Provisioner{
+				kubeClient: fake.NewSimpleClientset(readOnlyPv),
+			}
+
+			err := p.verifyNoReadOnlyNFSVolumes(context.TODO(), getFakeNFSPvObject(test.pvName, "5Gi", provisionerName))
+			assert.Equal(t, test.isErrExpected, err != nil, "error: %v", err)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DeleteSharedNFSVolume is invoked by the PVC controller to perform clean-up
+//
+//	activities before deleteing the PV object created on the shared NFS
+//	server. It removes the volume directory from the shared NFS server.
+//	Shared NFS server is kept running for the other volumes.
+func (p *Provisioner) DeleteSharedNFSVolume(ctx context.Context, pv *v1.PersistentVolume) (err error) {
+	defer func() {
+		err = errors.Wrapf(err, "failed to delete volume %v", pv.Name)
+	}()
+
+	serverName := pv.Labels[sharedNFSServerLabelKey]
+	backendPvcName := "nfs-" + serverName
+
+	// Check if the shared NFS server still exists. If it has been
+	// removed, then volume directory has been removed along with it.
+	_, err = p.kubeClient.CoreV1().
+		PersistentVolumeClaims(p.serverNamespace).
+		Get(ctx, backendPvcName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.Infof("Shared NFS server backend PVC {%s/%s} of volume %s doesn't exist", p.serverNamespace, backendPvcName, pv.Name)
+			return nil
+		}
+		return errors.Wrapf(err, "failed to get backend PVC {%s/%s}", p.serverNamespace, backendPvcName)
+	}
+
+	return removeSharedNFSVolume(ctx, p.kubeClient, p.executor, p.serverNamespace, serverName, pv.Name)
+}
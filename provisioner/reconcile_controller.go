@@ -0,0 +1,571 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+This file contains the controller which repairs the NFS server of the
+NFS volumes.
+
+NFS server resources are created only while provisioning the volume, so
+if the backend PVC, Deployment or Service nfs-<pv-name> is deleted, the
+NFS PV is left pointing at nothing. ReconcileController watches the NFS
+PVs and the NFS server resources, and for every bound NFS PV:
+
+- builds the NFS server options from the NFS PVC and its StorageClass,
+  the same way as the volume is provisioned
+- recreates the backend PVC, Deployment and Service, if missing
+- reports each repair through events on the NFS PVC and metrics
+- flags the NFS PV, if the ClusterIP referred by the NFS PV doesn't
+  match any Service in NFS server namespace
+
+Service is recreated with the ClusterIP referred by the NFS PV, so that
+the NFS PV remains usable.
+
+All the NFS PVs are also checked periodically, to repair the resources
+whose deletion was missed by the watch.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	errors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Event reasons for NFS server repair
+	repairSuccessReason = "NFSServerResourceRecreated"
+	repairFailedReason  = "NFSServerResourceRepairFailed"
+
+	// addressMismatchReason is the event reason for the NFS PVs, whose
+	// NFS server address doesn't match any NFS server Service
+	addressMismatchReason = "NFSServerAddressMismatch"
+
+	// reconcileControllerName is used as event source component
+	reconcileControllerName = "nfs-reconcile-controller"
+
+	// Kinds of the NFS server resources, used in events and metrics
+	resourceBackendPVC = "PersistentVolumeClaim"
+	resourceDeployment = "Deployment"
+	resourceService    = "Service"
+)
+
+var (
+	// ReconcileInterval defines the interval to check all the NFS PVs
+	ReconcileInterval = 5 * time.Minute
+)
+
+// ReconcileController recreates the missing NFS server resources of the NFS PVs
+type ReconcileController struct {
+	provisioner *Provisioner
+
+	pvLister     listersv1.PersistentVolumeLister
+	pvcLister    listersv1.PersistentVolumeClaimLister
+	deployLister appslisters.DeploymentLister
+	svcLister    listersv1.ServiceLister
+
+	pvSynced     cache.InformerSynced
+	pvcSynced    cache.InformerSynced
+	deploySynced cache.InformerSynced
+	svcSynced    cache.InformerSynced
+
+	queue    workqueue.RateLimitingInterface
+	recorder record.EventRecorder
+}
+
+// NewReconcileController returns a new ReconcileController. It uses the
+// given informer factory to watch NFS PVs and PVCs, and the server informer
+// factory to watch the Deployments and Services in NFS server namespace.
+func NewReconcileController(p *Provisioner,
+	informerFactory kubeinformers.SharedInformerFactory,
+	serverInformerFactory kubeinformers.SharedInformerFactory) *ReconcileController {
+	pvInformer := informerFactory.Core().V1().PersistentVolumes()
+	pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
+	deployInformer := serverInformerFactory.Apps().V1().Deployments()
+	svcInformer := serverInformerFactory.Core().V1().Services()
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartStructuredLogging(4)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: p.kubeClient.CoreV1().Events("")})
+
+	rc := &ReconcileController{
+		provisioner:  p,
+		pvLister:     pvInformer.Lister(),
+		pvcLister:    pvcInformer.Lister(),
+		deployLister: deployInformer.Lister(),
+		svcLister:    svcInformer.Lister(),
+		pvSynced:     pvInformer.Informer().HasSynced,
+		pvcSynced:    pvcInformer.Informer().HasSynced,
+		deploySynced: deployInformer.Informer().HasSynced,
+		svcSynced:    svcInformer.Informer().HasSynced,
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "nfs-reconcile"),
+		recorder:     eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: reconcileControllerName}),
+	}
+
+	pvInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: rc.enqueuePV,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			rc.enqueuePV(newObj)
+		},
+	})
+
+	// Deletion of NFS server resources triggers the repair of their NFS PVs
+	resourceHandler := cache.ResourceEventHandlerFuncs{
+		DeleteFunc: rc.enqueueResourceOwner,
+	}
+	pvcInformer.Informer().AddEventHandler(resourceHandler)
+	deployInformer.Informer().AddEventHandler(resourceHandler)
+	svcInformer.Informer().AddEventHandler(resourceHandler)
+
+	return rc
+}
+
+// Run starts the workers of ReconcileController and blocks till
+// the given context is cancelled
+func (rc *ReconcileController) Run(ctx context.Context) {
+	defer utilruntime.HandleCrash()
+	defer rc.queue.ShutDown()
+
+	klog.Info("Starting NFS server reconcile controller")
+	defer klog.Info("Shutting down NFS server reconcile controller")
+
+	if !cache.WaitForCacheSync(ctx.Done(), rc.pvSynced, rc.pvcSynced, rc.deploySynced, rc.svcSynced) {
+		klog.Error("Failed to sync caches for NFS server reconcile controller")
+		return
+	}
+
+	go wait.UntilWithContext(ctx, rc.runWorker, time.Second)
+	go wait.UntilWithContext(ctx, rc.enqueueAllPVs, ReconcileInterval)
+
+	<-ctx.Done()
+}
+
+func (rc *ReconcileController) enqueuePV(obj interface{}) {
+	pv, ok := obj.(*corev1.PersistentVolume)
+	if !ok {
+		return
+	}
+
+	if !isReconcilableNFSPV(pv) {
+		return
+	}
+	rc.queue.Add(pv.Name)
+}
+
+// enqueueAllPVs adds all the NFS PVs to the queue
+func (rc *ReconcileController) enqueueAllPVs(ctx context.Context) {
+	pvList, err := rc.pvLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list PVs, err=%v", err)
+		return
+	}
+
+	for _, pv := range pvList {
+		rc.enqueuePV(pv)
+	}
+}
+
+// enqueueResourceOwner adds the NFS PVs of the given NFS server resource
+// to the queue
+func (rc *ReconcileController) enqueueResourceOwner(obj interface{}) {
+	for _, name := range getResourceOwnerPVs(obj, rc.provisioner.serverNamespace, rc.pvLister) {
+		rc.queue.Add(name)
+	}
+}
+
+// getResourceOwnerPVs returns the names of the NFS PVs owning the given
+// NFS server resource. NFS server resources are named as nfs-<owner>,
+// where owner is the NFS PV or the shared NFS server. Resources of the
+// shared NFS server are owned by all the NFS PVs of the shared NFS server.
+func getResourceOwnerPVs(obj interface{}, serverNamespace string, pvLister listersv1.PersistentVolumeLister) []string {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	meta, err := apimeta.Accessor(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return nil
+	}
+
+	if meta.GetNamespace() != serverNamespace {
+		return nil
+	}
+
+	if !strings.HasPrefix(meta.GetName(), "nfs-") {
+		return nil
+	}
+	owner := strings.TrimPrefix(meta.GetName(), "nfs-")
+
+	if !strings.HasPrefix(owner, sharedNFSServerPrefix) {
+		return []string{owner}
+	}
+
+	pvList, err := pvLister.List(labels.SelectorFromSet(labels.Set{sharedNFSServerLabelKey: owner}))
+	if err != nil {
+		klog.Errorf("Failed to list NFS PVs of shared NFS server=%s, err=%v", owner, err)
+		return nil
+	}
+
+	names := make([]string, 0, len(pvList))
+	for _, pv := range pvList {
+		names = append(names, pv.Name)
+	}
+	return names
+}
+
+func (rc *ReconcileController) runWorker(ctx context.Context) {
+	for rc.processNextWorkItem(ctx) {
+	}
+}
+
+func (rc *ReconcileController) processNextWorkItem(ctx context.Context) bool {
+	key, quit := rc.queue.Get()
+	if quit {
+		return false
+	}
+	defer rc.queue.Done(key)
+
+	if err := rc.syncPV(ctx, key.(string)); err != nil {
+		klog.Errorf("Failed to reconcile NFS server of PV %s, err=%v", key, err)
+		rc.queue.AddRateLimited(key)
+		return true
+	}
+
+	rc.queue.Forget(key)
+	return true
+}
+
+// isReconcilableNFSPV returns true if the NFS server of given PV is
+// managed by the provisioner and the PV is bound to a NFS PVC
+func isReconcilableNFSPV(pv *corev1.PersistentVolume) bool {
+	if pv.Annotations[annDynamicallyProvisioned] != provisionerName {
+		return false
+	}
+
+	serverType := GetNFSServerTypeFromPV(pv)
+	if serverType != NFSServerTypeKernel && serverType != NFSServerTypeGanesha {
+		// External NFS server is not managed by the provisioner
+		return false
+	}
+
+	if isReadOnlyNFSVolume(pv) {
+		// NFS server is owned by the source NFS PV
+		return false
+	}
+
+	return pv.DeletionTimestamp == nil &&
+		pv.Status.Phase == corev1.VolumeBound &&
+		pv.Spec.ClaimRef != nil
+}
+
+// syncPV recreates the missing NFS server resources of the given NFS PV
+func (rc *ReconcileController) syncPV(ctx context.Context, name string) error {
+	p := rc.provisioner
+
+	pv, err := rc.pvLister.Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			metrics.NFSServerAddressMismatch.DeleteLabelValues(name)
+			return nil
+		}
+		return err
+	}
+
+	if !isReconcilableNFSPV(pv) || p.pvTracker.Inprogress(pv.Name) {
+		return nil
+	}
+
+	pvc, err := rc.pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(pv.Spec.ClaimRef.Name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// NFS PV will be released, garbage collector
+			// takes care of the NFS server resources
+			return nil
+		}
+		return err
+	}
+	if pvc.UID != pv.Spec.ClaimRef.UID {
+		return nil
+	}
+
+	nfsServerOpts, err := p.getNFSServerOptions(ctx, pv, pvc)
+	if err != nil {
+		rc.recorder.Eventf(pvc, corev1.EventTypeWarning, repairFailedReason,
+			"failed to get NFS server configuration of volume %s: %v", pv.Name, err)
+		return err
+	}
+
+	missing, err := rc.getMissingResources(ctx, nfsServerOpts.pvName)
+	if err != nil {
+		return err
+	}
+
+	var serviceRepaired bool
+	for _, resource := range missing {
+		resourceName := fmt.Sprintf("%s/nfs-%s", p.serverNamespace, nfsServerOpts.pvName)
+		klog.Infof("Recreating missing %s %s of NFS PV %s", resource, resourceName, pv.Name)
+
+		if err = rc.repairResource(nfsServerOpts, resource); err != nil {
+			metrics.NFSServerResourceRepairFailedTotal.WithLabelValues(resource).Inc()
+			rc.recorder.Eventf(pvc, corev1.EventTypeWarning, repairFailedReason,
+				"failed to recreate missing %s %s: %v", resource, resourceName, err)
+			return errors.Wrapf(err, "failed to recreate %s %s", resource, resourceName)
+		}
+
+		metrics.NFSServerResourceRepairTotal.WithLabelValues(resource).Inc()
+		msg := fmt.Sprintf("recreated missing %s %s of NFS volume %s", resource, resourceName, pv.Name)
+		if resource == resourceBackendPVC {
+			msg += ", data of the deleted backend volume is not restored"
+		}
+		rc.recorder.Event(pvc, corev1.EventTypeWarning, repairSuccessReason, msg)
+
+		if resource == resourceService {
+			serviceRepaired = true
+		}
+	}
+
+	if !rc.isProtected(nfsServerOpts) {
+		// NFS server resources created by the older versions
+		// of provisioner don't have the finalizer
+		if err = p.protectNFSServerResources(nfsServerOpts); err != nil {
+			return err
+		}
+	}
+
+	if serviceRepaired {
+		// Service is recreated with the ClusterIP referred by the NFS PV
+		return nil
+	}
+	return rc.checkNFSServerAddress(pv, pvc)
+}
+
+// isProtected returns true if the finalizer and the owner reference of
+// the backend PVC are set on all the NFS server resources of the given
+// NFS server, as per the informer cache
+func (rc *ReconcileController) isProtected(nfsServerOpts *KernelNFSServerOptions) bool {
+	p := rc.provisioner
+	if nfsServerOpts.shared {
+		return true
+	}
+	name := "nfs-" + nfsServerOpts.pvName
+
+	pvcObj, err := rc.pvcLister.PersistentVolumeClaims(p.serverNamespace).Get(name)
+	if err != nil {
+		return false
+	}
+	backendPvcUID := string(pvcObj.UID)
+
+	deployObj, err := rc.deployLister.Deployments(p.serverNamespace).Get(name)
+	if err != nil {
+		return false
+	}
+	svcObj, err := rc.svcLister.Services(p.serverNamespace).Get(name)
+	if err != nil {
+		return false
+	}
+
+	return isNFSServerResourceProtected(pvcObj.ObjectMeta, backendPvcUID) &&
+		isNFSServerResourceProtected(deployObj.ObjectMeta, backendPvcUID) &&
+		isNFSServerResourceProtected(svcObj.ObjectMeta, backendPvcUID)
+}
+
+// checkNFSServerAddress flags the NFS PV, if the ClusterIP referred by the
+// NFS PV doesn't match any Service in NFS server namespace. Such NFS PV
+// can't be mounted, till a Service is created with the ClusterIP.
+func (rc *ReconcileController) checkNFSServerAddress(pv *corev1.PersistentVolume, pvc *corev1.PersistentVolumeClaim) error {
+	p := rc.provisioner
+
+	clusterIP := getNFSServerClusterIP(pv)
+	if len(clusterIP) == 0 {
+		// NFS PV refers to the DNS name of the Service
+		metrics.NFSServerAddressMismatch.DeleteLabelValues(pv.Name)
+		return nil
+	}
+
+	svcList, err := rc.svcLister.Services(p.serverNamespace).List(labels.Everything())
+	if err != nil {
+		return errors.Wrapf(err, "failed to list Services in namespace %s", p.serverNamespace)
+	}
+
+	for _, svc := range svcList {
+		if svc.Spec.ClusterIP == clusterIP {
+			metrics.NFSServerAddressMismatch.WithLabelValues(pv.Name).Set(0)
+			return nil
+		}
+	}
+
+	klog.Warningf("NFS server address %s of PV %s doesn't match any Service in namespace %s",
+		clusterIP, pv.Name, p.serverNamespace)
+	metrics.NFSServerAddressMismatch.WithLabelValues(pv.Name).Set(1)
+	rc.recorder.Eventf(pvc, corev1.EventTypeWarning, addressMismatchReason,
+		"NFS server address %s of volume %s doesn't match any Service in namespace %s",
+		clusterIP, pv.Name, p.serverNamespace)
+	return nil
+}
+
+// getNFSServerOptions returns the options of the NFS server of the given
+// NFS PV, derived from the NFS PVC and its StorageClass. NFS server of
+// the shared NFS PV is derived only from the StorageClass of the NFS PV,
+// so the NFS PVC isn't referred and may be nil for the shared NFS PV.
+func (p *Provisioner) getNFSServerOptions(ctx context.Context, pv *corev1.PersistentVolume, pvc *corev1.PersistentVolumeClaim) (*KernelNFSServerOptions, error) {
+	serverType := strings.TrimPrefix(pv.Labels[string(mconfig.CASTypeKey)], "nfs-")
+
+	var nfsServerOpts *KernelNFSServerOptions
+	var err error
+	if isSharedNFSVolume(pv) {
+		nfsServerOpts, err = p.getSharedNFSServerOptions(ctx, pv.Spec.StorageClassName, serverType)
+	} else {
+		nfsServerOpts, err = p.getVolumeNFSServerOptions(ctx, pv, pvc, serverType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Service is recreated with the ClusterIP referred by the NFS PV
+	nfsServerOpts.clusterIP = getNFSServerClusterIP(pv)
+
+	// Deployment exports the data directory recorded on the backend
+	// PVC. If the backend PVC is missing, it is recreated with the
+	// default data directory.
+	backendPvc, err := p.kubeClient.CoreV1().
+		PersistentVolumeClaims(p.serverNamespace).
+		Get(ctx, "nfs-"+nfsServerOpts.pvName, metav1.GetOptions{})
+	if err == nil {
+		nfsServerOpts.dataDir = backendPvc.Annotations[nfsServerDataDirAnnotation]
+	} else if k8serrors.IsNotFound(err) {
+		nfsServerOpts.dataDir = nfsServerOpts.getDefaultDataDir()
+	} else {
+		return nil, errors.Wrapf(err, "failed to get backend PVC {%s/nfs-%s}", p.serverNamespace, nfsServerOpts.pvName)
+	}
+
+	// Deployment mounts the backend PVC, and Service is
+	// created for the NFS server Deployment
+	nfsServerOpts.backendPvcName = "nfs-" + nfsServerOpts.pvName
+	nfsServerOpts.deploymentName = "nfs-" + nfsServerOpts.pvName
+	return nfsServerOpts, nil
+}
+
+// getVolumeNFSServerOptions returns the options of the NFS server of the
+// given NFS PV, which is not created on the shared NFS server
+func (p *Provisioner) getVolumeNFSServerOptions(ctx context.Context, pv *corev1.PersistentVolume, pvc *corev1.PersistentVolumeClaim, serverType string) (*KernelNFSServerOptions, error) {
+	// NFS server is rendered with the immutable config keys
+	// recorded on the NFS PV, ignoring their changes on the PVC
+	pvc, err := getProvisionedPVC(pv, pvc)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeConfig, err := p.getVolumeConfig(pv.Name, pvc)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := pv.Spec.Capacity[corev1.ResourceStorage]
+	nfsServerOpts, err := newKernelNFSServerOptions(ctx, pv.Name, capacity.String(), volumeConfig, serverType)
+	if err != nil {
+		return nil, err
+	}
+	nfsServerOpts.provisionerNS = p.namespace
+	nfsServerOpts.pvcName = pvc.Name
+	nfsServerOpts.pvcNamespace = pvc.Namespace
+	nfsServerOpts.pvcUID = string(pvc.UID)
+
+	// Deployment is recreated in the topology recorded on the NFS PV
+	nfsServerOpts.topology, err = getNFSServerTopologyFromPV(pv)
+	if err != nil {
+		return nil, err
+	}
+
+	if isReadOnlyAccessModes(pv.Spec.AccessModes) {
+		if err = nfsServerOpts.setReadOnlyExport(); err != nil {
+			return nil, err
+		}
+	}
+	return nfsServerOpts, nil
+}
+
+// getMissingResources returns the kinds of the missing NFS server
+// resources. Resources missing in the informer cache are verified
+// with the API server, since the cache may not be up to date.
+func (rc *ReconcileController) getMissingResources(ctx context.Context, pvName string) ([]string, error) {
+	p := rc.provisioner
+	name := "nfs-" + pvName
+
+	var missing []string
+	for _, resource := range []string{resourceBackendPVC, resourceDeployment, resourceService} {
+		var err error
+		switch resource {
+		case resourceBackendPVC:
+			if _, err = rc.pvcLister.PersistentVolumeClaims(p.serverNamespace).Get(name); k8serrors.IsNotFound(err) {
+				_, err = p.kubeClient.CoreV1().PersistentVolumeClaims(p.serverNamespace).Get(ctx, name, metav1.GetOptions{})
+			}
+		case resourceDeployment:
+			if _, err = rc.deployLister.Deployments(p.serverNamespace).Get(name); k8serrors.IsNotFound(err) {
+				_, err = p.kubeClient.AppsV1().Deployments(p.serverNamespace).Get(ctx, name, metav1.GetOptions{})
+			}
+		case resourceService:
+			if _, err = rc.svcLister.Services(p.serverNamespace).Get(name); k8serrors.IsNotFound(err) {
+				_, err = p.kubeClient.CoreV1().Services(p.serverNamespace).Get(ctx, name, metav1.GetOptions{})
+			}
+		}
+
+		if err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return nil, errors.Wrapf(err, "failed to get %s %s/%s", resource, p.serverNamespace, name)
+			}
+			missing = append(missing, resource)
+		}
+	}
+	return missing, nil
+}
+
+// repairResource creates the given NFS server resource, using the same
+// builders which are used while provisioning the volume
+func (rc *ReconcileController) repairResource(nfsServerOpts *KernelNFSServerOptions, resource string) error {
+	p := rc.provisioner
+
+	switch resource {
+	case resourceBackendPVC:
+		return p.createBackendPVC(nfsServerOpts)
+	case resourceDeployment:
+		return p.createDeployment(nfsServerOpts)
+	case resourceService:
+		return p.createService(nfsServerOpts)
+	}
+	return errors.Errorf("unknown NFS server resource %s", resource)
+}
@@ -0,0 +1,312 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func newFakeReconcileController(serverNs string, objs ...runtime.Object) *ReconcileController {
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	deployIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	svcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *corev1.PersistentVolume:
+			_ = pvIndexer.Add(o)
+		case *corev1.PersistentVolumeClaim:
+			_ = pvcIndexer.Add(o)
+		case *appsv1.Deployment:
+			_ = deployIndexer.Add(o)
+		case *corev1.Service:
+			_ = svcIndexer.Add(o)
+		}
+	}
+
+	p := &Provisioner{
+		kubeClient:      fake.NewSimpleClientset(objs...),
+		serverNamespace: serverNs,
+		namespace:       "openebs",
+		pvTracker:       NewProvisioningTracker(),
+	}
+	p.getVolumeConfig = p.GetVolumeConfig
+
+	return &ReconcileController{
+		provisioner:  p,
+		pvLister:     listersv1.NewPersistentVolumeLister(pvIndexer),
+		pvcLister:    listersv1.NewPersistentVolumeClaimLister(pvcIndexer),
+		deployLister: appslisters.NewDeploymentLister(deployIndexer),
+		svcLister:    listersv1.NewServiceLister(svcIndexer),
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "nfs-reconcile"),
+		recorder:     record.NewFakeRecorder(10),
+	}
+}
+
+func getFakeReconcileObjects(serverNs, pvName string) []runtime.Object {
+	scName := "nfs-sc"
+
+	nfsPvc := getFakeBoundPvcObject("app", "pvc", pvName, "5Gi", "5Gi")
+	nfsPvc.UID = "pvc-uid"
+	nfsPvc.Spec.StorageClassName = &scName
+
+	nfsPv := getFakeNFSPvObject(pvName, "5Gi", provisionerName)
+	nfsPv.Labels = map[string]string{
+		"openebs.io/cas-type": "nfs-kernel",
+	}
+	nfsPv.Spec.ClaimRef = &corev1.ObjectReference{
+		Namespace: nfsPvc.Namespace,
+		Name:      nfsPvc.Name,
+		UID:       nfsPvc.UID,
+	}
+	nfsPv.Status.Phase = corev1.VolumeBound
+
+	name := "nfs-" + pvName
+	return []runtime.Object{
+		nfsPvc,
+		nfsPv,
+		&storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: scName},
+			Provisioner: provisionerName,
+		},
+		getFakeBoundPvcObject(serverNs, name, "backend-"+pvName, "5Gi", "5Gi"),
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: serverNs},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: serverNs},
+		},
+	}
+}
+
+// withoutObject returns the objects other than the object of the given type
+func withoutObject(objs []runtime.Object, kind string) []runtime.Object {
+	var filtered []runtime.Object
+	for _, obj := range objs {
+		switch obj.(type) {
+		case *corev1.Service:
+			if kind == resourceService {
+				continue
+			}
+		case *appsv1.Deployment:
+			if kind == resourceDeployment {
+				continue
+			}
+		case *corev1.PersistentVolumeClaim:
+			if kind == resourceBackendPVC && obj.(*corev1.PersistentVolumeClaim).Namespace == "nfs-ns" {
+				continue
+			}
+		}
+		filtered = append(filtered, obj)
+	}
+	return filtered
+}
+
+func TestReconcileControllerSyncPV(t *testing.T) {
+	os.Setenv(string(NFSServerImageKey), "openebs/nfs-server-alpine:ci")
+	defer os.Unsetenv(string(NFSServerImageKey))
+
+	serverNs := "nfs-ns"
+
+	tests := map[string]struct {
+		objs             []runtime.Object
+		pvName           string
+		expectedRepaired []string
+		expectedMissing  []string
+
+		expectedClusterIP string
+	}{
+		"when all NFS server resources exist": {
+			objs:   getFakeReconcileObjects(serverNs, "pv1"),
+			pvName: "pv1",
+		},
+		"when NFS server Service is missing": {
+			objs:             withoutObject(getFakeReconcileObjects(serverNs, "pv2"), resourceService),
+			pvName:           "pv2",
+			expectedRepaired: []string{resourceService},
+		},
+		"when NFS server Deployment is missing": {
+			objs:             withoutObject(getFakeReconcileObjects(serverNs, "pv3"), resourceDeployment),
+			pvName:           "pv3",
+			expectedRepaired: []string{resourceDeployment},
+		},
+		"when backend PVC is missing": {
+			objs:             withoutObject(getFakeReconcileObjects(serverNs, "pv4"), resourceBackendPVC),
+			pvName:           "pv4",
+			expectedRepaired: []string{resourceBackendPVC},
+		},
+		"when Service referred by ClusterIP is missing": {
+			objs: func() []runtime.Object {
+				objs := withoutObject(getFakeReconcileObjects(serverNs, "pv6"), resourceService)
+				for _, obj := range objs {
+					if pv, ok := obj.(*corev1.PersistentVolume); ok {
+						pv.Annotations[nfsServerClusterIPAnnotation] = "10.0.0.10"
+					}
+				}
+				return objs
+			}(),
+			pvName:            "pv6",
+			expectedRepaired:  []string{resourceService},
+			expectedClusterIP: "10.0.0.10",
+		},
+		"when PV is not provisioned by NFS provisioner": {
+			objs: func() []runtime.Object {
+				objs := withoutObject(getFakeReconcileObjects(serverNs, "pv5"), resourceService)
+				for _, obj := range objs {
+					if pv, ok := obj.(*corev1.PersistentVolume); ok {
+						pv.Annotations[annDynamicallyProvisioned] = "other-provisioner"
+					}
+				}
+				return objs
+			}(),
+			pvName:          "pv5",
+			expectedMissing: []string{resourceService},
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			rc := newFakeReconcileController(serverNs, test.objs...)
+			client := rc.provisioner.kubeClient
+
+			repairCounts := map[string]float64{}
+			for _, resource := range []string{resourceBackendPVC, resourceDeployment, resourceService} {
+				repairCounts[resource] = testutil.ToFloat64(metrics.NFSServerResourceRepairTotal.WithLabelValues(resource))
+			}
+
+			err := rc.syncPV(context.TODO(), test.pvName)
+			assert.NoError(t, err)
+
+			resourceName := "nfs-" + test.pvName
+			_, err = client.CoreV1().PersistentVolumeClaims(serverNs).Get(context.TODO(), resourceName, metav1.GetOptions{})
+			assert.Equal(t, !hasResource(test.expectedMissing, resourceBackendPVC), err == nil)
+			_, err = client.AppsV1().Deployments(serverNs).Get(context.TODO(), resourceName, metav1.GetOptions{})
+			assert.Equal(t, !hasResource(test.expectedMissing, resourceDeployment), err == nil)
+			svc, err := client.CoreV1().Services(serverNs).Get(context.TODO(), resourceName, metav1.GetOptions{})
+			assert.Equal(t, !hasResource(test.expectedMissing, resourceService), err == nil)
+			if err == nil {
+				assert.Equal(t, test.expectedClusterIP, svc.Spec.ClusterIP)
+			}
+
+			for resource, count := range repairCounts {
+				expectedCount := count
+				if hasResource(test.expectedRepaired, resource) {
+					expectedCount++
+				}
+				assert.Equal(t, expectedCount, testutil.ToFloat64(metrics.NFSServerResourceRepairTotal.WithLabelValues(resource)), resource)
+			}
+
+			events := rc.recorder.(*record.FakeRecorder).Events
+			assert.Equal(t, len(test.expectedRepaired), len(events))
+		})
+	}
+}
+
+func TestReconcileControllerCheckNFSServerAddress(t *testing.T) {
+	serverNs := "nfs-ns"
+
+	tests := map[string]struct {
+		pvAnnotations    map[string]string
+		nfsServer        string
+		svcClusterIP     string
+		expectedMismatch float64
+		expectedEvents   int
+	}{
+		"when NFS PV refers to the DNS name of Service": {
+			nfsServer:    "nfs-pv1.nfs-ns.svc.cluster.local",
+			svcClusterIP: "10.0.0.11",
+		},
+		"when recorded ClusterIP matches the Service": {
+			pvAnnotations: map[string]string{nfsServerClusterIPAnnotation: "10.0.0.12"},
+			nfsServer:     "10.0.0.12",
+			svcClusterIP:  "10.0.0.12",
+		},
+		"when recorded ClusterIP doesn't match any Service": {
+			pvAnnotations:    map[string]string{nfsServerClusterIPAnnotation: "10.0.0.13"},
+			nfsServer:        "10.0.0.13",
+			svcClusterIP:     "10.0.0.99",
+			expectedMismatch: 1,
+			expectedEvents:   1,
+		},
+		"when ClusterIP of NFS PV created before recording doesn't match any Service": {
+			nfsServer:        "10.0.0.14",
+			svcClusterIP:     "10.0.0.99",
+			expectedMismatch: 1,
+			expectedEvents:   1,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			objs := getFakeReconcileObjects(serverNs, "pv-address")
+			var pv *corev1.PersistentVolume
+			var pvc *corev1.PersistentVolumeClaim
+			for _, obj := range objs {
+				switch o := obj.(type) {
+				case *corev1.PersistentVolume:
+					pv = o
+					for k, v := range test.pvAnnotations {
+						pv.Annotations[k] = v
+					}
+					pv.Spec.NFS = &corev1.NFSVolumeSource{Server: test.nfsServer, Path: "/"}
+				case *corev1.PersistentVolumeClaim:
+					if o.Namespace != serverNs {
+						pvc = o
+					}
+				case *corev1.Service:
+					o.Spec.ClusterIP = test.svcClusterIP
+				}
+			}
+			rc := newFakeReconcileController(serverNs, objs...)
+
+			assert.NoError(t, rc.checkNFSServerAddress(pv, pvc))
+			assert.Equal(t, test.expectedMismatch, testutil.ToFloat64(metrics.NFSServerAddressMismatch.WithLabelValues(pv.Name)))
+			assert.Equal(t, test.expectedEvents, len(rc.recorder.(*record.FakeRecorder).Events))
+		})
+	}
+}
+
+func hasResource(resources []string, resource string) bool {
+	for _, r := range resources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
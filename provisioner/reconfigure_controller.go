@@ -0,0 +1,566 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+This file contains the controller which applies the changes of the
+cas.openebs.io/config of the NFS PVCs to the running NFS servers.
+
+cas.openebs.io/config of the NFS PVC is read only while provisioning the
+volume. ReconfigureController watches the NFS PVCs, and if the config of
+a bound NFS PVC is changed:
+
+- changes to the immutable keys, like BackendStorageClass, are rejected
+  through an event on the NFS PVC. Immutable keys of the PVC config are
+  recorded on the NFS PV while provisioning the volume, and the NFS server
+  resources are always rendered with the recorded values, so that the
+  controllers recreating or upgrading the NFS server don't pick the
+  changed values
+- if any mutable key is changed, the volume config is read again and the
+  NFS server Deployment is rendered, the same way as the volume is
+  provisioned. Only the fields derived from the mutable keys, i.e. the
+  resources, lease/grace time, export options and file permissions, are
+  applied to the running Deployment.
+
+StorageClass config takes precedence over the PVC config, so changes to
+the keys set in the StorageClass have no effect. Volumes of the shared
+NFS server can't be reconfigured, since the NFS server is shared by
+multiple NFS PVCs.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	cast "github.com/openebs/maya/pkg/castemplate/v1alpha1"
+	"github.com/openebs/maya/pkg/version"
+	errors "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Event reasons for NFS server reconfiguration
+	reconfigureSuccessReason   = "NFSServerReconfigured"
+	reconfigureFailedReason    = "NFSServerReconfigureFailed"
+	configChangeRejectedReason = "VolumeConfigChangeRejected"
+
+	// provisionedConfigAnnotation is set on the NFS PV, to record the
+	// immutable keys of cas.openebs.io/config of the NFS PVC with which
+	// the volume is provisioned
+	provisionedConfigAnnotation = "nfs.openebs.io/provisioned-config"
+)
+
+// mutableConfigKeys are the keys of cas.openebs.io/config,
+// which can be changed after the volume is provisioned
+var mutableConfigKeys = map[string]bool{
+	NFSServerResourceRequests: true,
+	NFSServerResourceLimits:   true,
+	LeaseTime:                 true,
+	GraceTime:                 true,
+	CustomServerConfig:        true,
+	ExportOptions:             true,
+	FilePermissions:           true,
+}
+
+// mutableServerEnvs are the env of the NFS server container,
+// derived from the mutable config keys
+var mutableServerEnvs = []string{
+	"CUSTOM_EXPORTS_CONFIG",
+	"NFS_LEASE_TIME",
+	"NFS_GRACE_TIME",
+	"FILEPERMISSIONS_UID",
+	"FILEPERMISSIONS_GID",
+	"FILEPERMISSIONS_MODE",
+}
+
+// ReconfigureController applies the changes of the mutable config
+// keys of the NFS PVCs to their NFS server Deployments
+type ReconfigureController struct {
+	provisioner *Provisioner
+
+	pvLister     listersv1.PersistentVolumeLister
+	pvcLister    listersv1.PersistentVolumeClaimLister
+	deployLister appslisters.DeploymentLister
+
+	pvSynced     cache.InformerSynced
+	pvcSynced    cache.InformerSynced
+	deploySynced cache.InformerSynced
+
+	queue    workqueue.RateLimitingInterface
+	recorder record.EventRecorder
+}
+
+// NewReconfigureController returns a new ReconfigureController. It uses the
+// given informer factory to watch NFS PVs and PVCs, and the server informer
+// factory to list the Deployments in NFS server namespace.
+func NewReconfigureController(p *Provisioner,
+	informerFactory kubeinformers.SharedInformerFactory,
+	serverInformerFactory kubeinformers.SharedInformerFactory) *ReconfigureController {
+	pvInformer := informerFactory.Core().V1().PersistentVolumes()
+	pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
+	deployInformer := serverInformerFactory.Apps().V1().Deployments()
+
+	rc := &ReconfigureController{
+		provisioner:  p,
+		pvLister:     pvInformer.Lister(),
+		pvcLister:    pvcInformer.Lister(),
+		deployLister: deployInformer.Lister(),
+		pvSynced:     pvInformer.Informer().HasSynced,
+		pvcSynced:    pvcInformer.Informer().HasSynced,
+		deploySynced: deployInformer.Informer().HasSynced,
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "nfs-reconfigure"),
+		recorder:     p.recorder,
+	}
+
+	pvcInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: rc.handlePVCUpdate,
+	})
+
+	return rc
+}
+
+// Run starts the worker of ReconfigureController and blocks till
+// the given context is cancelled
+func (rc *ReconfigureController) Run(ctx context.Context) {
+	defer utilruntime.HandleCrash()
+	defer rc.queue.ShutDown()
+
+	klog.Info("Starting NFS server reconfigure controller")
+	defer klog.Info("Shutting down NFS server reconfigure controller")
+
+	if !cache.WaitForCacheSync(ctx.Done(), rc.pvSynced, rc.pvcSynced, rc.deploySynced) {
+		klog.Error("Failed to sync caches for NFS server reconfigure controller")
+		return
+	}
+
+	go wait.UntilWithContext(ctx, rc.runWorker, time.Second)
+
+	<-ctx.Done()
+}
+
+// handlePVCUpdate rejects the changes to the immutable config keys of the
+// NFS PVC, and adds the NFS PV to the queue if any mutable key is changed
+func (rc *ReconfigureController) handlePVCUpdate(oldObj, newObj interface{}) {
+	oldPvc, ok := oldObj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return
+	}
+	pvc, ok := newObj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return
+	}
+
+	oldConfig := oldPvc.Annotations[string(mconfig.CASConfigKey)]
+	newConfig := pvc.Annotations[string(mconfig.CASConfigKey)]
+	if oldConfig == newConfig || len(pvc.Spec.VolumeName) == 0 {
+		return
+	}
+
+	pv, err := rc.pvLister.Get(pvc.Spec.VolumeName)
+	if err != nil {
+		return
+	}
+	if !isReconcilableNFSPV(pv) || pv.Spec.ClaimRef.UID != pvc.UID {
+		return
+	}
+
+	changedKeys, err := getChangedConfigKeys(oldConfig, newConfig)
+	if err != nil {
+		rc.recorder.Eventf(pvc, corev1.EventTypeWarning, reconfigureFailedReason,
+			"failed to read changed %s of volume %s: %v", mconfig.CASConfigKey, pv.Name, err)
+		return
+	}
+
+	var mutable, immutable []string
+	for _, key := range changedKeys {
+		if mutableConfigKeys[key] {
+			mutable = append(mutable, key)
+		} else {
+			immutable = append(immutable, key)
+		}
+	}
+
+	if len(immutable) != 0 {
+		klog.Warningf("Ignoring changes to immutable config keys %v of PV %s", immutable, pv.Name)
+		rc.recorder.Eventf(pvc, corev1.EventTypeWarning, configChangeRejectedReason,
+			"config keys %s can't be changed after the volume is provisioned, changes are ignored",
+			strings.Join(immutable, ", "))
+	}
+	if len(mutable) == 0 {
+		return
+	}
+
+	if isSharedNFSVolume(pv) {
+		rc.recorder.Eventf(pvc, corev1.EventTypeWarning, configChangeRejectedReason,
+			"config keys %s can't be changed for the volume of shared NFS server %s, changes are ignored",
+			strings.Join(mutable, ", "), pv.Labels[sharedNFSServerLabelKey])
+		return
+	}
+
+	klog.Infof("Config keys %v of PV %s are changed, reconfiguring NFS server", mutable, pv.Name)
+	rc.queue.Add(pv.Name)
+}
+
+func (rc *ReconfigureController) runWorker(ctx context.Context) {
+	for rc.processNextWorkItem(ctx) {
+	}
+}
+
+func (rc *ReconfigureController) processNextWorkItem(ctx context.Context) bool {
+	key, quit := rc.queue.Get()
+	if quit {
+		return false
+	}
+	defer rc.queue.Done(key)
+
+	if err := rc.syncPV(ctx, key.(string)); err != nil {
+		klog.Errorf("Failed to reconfigure NFS server of PV %s, err=%v", key, err)
+		rc.queue.AddRateLimited(key)
+		return true
+	}
+
+	rc.queue.Forget(key)
+	return true
+}
+
+// syncPV applies the mutable config of the given NFS PV
+// to its NFS server Deployment
+func (rc *ReconfigureController) syncPV(ctx context.Context, name string) error {
+	p := rc.provisioner
+
+	pv, err := rc.pvLister.Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if !isReconcilableNFSPV(pv) || isSharedNFSVolume(pv) || p.pvTracker.Inprogress(pv.Name) {
+		return nil
+	}
+
+	pvc, err := rc.pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(pv.Spec.ClaimRef.Name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if pvc.UID != pv.Spec.ClaimRef.UID {
+		return nil
+	}
+
+	nfsServerOpts, err := p.getNFSServerOptions(ctx, pv, pvc)
+	if err != nil {
+		rc.recorder.Eventf(pvc, corev1.EventTypeWarning, reconfigureFailedReason,
+			"failed to get NFS server configuration of volume %s: %v", pv.Name, err)
+		return err
+	}
+
+	deployObj, err := rc.deployLister.Deployments(p.serverNamespace).Get(nfsServerOpts.deploymentName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Reconciler recreates the missing Deployment
+			return nil
+		}
+		return err
+	}
+
+	desired, err := p.buildDeployment(nfsServerOpts)
+	if err != nil {
+		return errors.Wrapf(err, "failed to render NFS server deployment of volume %s", pv.Name)
+	}
+
+	updated, changed := applyMutableConfig(deployObj, desired)
+	if !changed {
+		klog.V(4).Infof("Config of PV %s is already applied to NFS server Deployment", pv.Name)
+		return nil
+	}
+
+	deployName := fmt.Sprintf("%s/%s", deployObj.Namespace, deployObj.Name)
+	_, err = p.kubeClient.AppsV1().
+		Deployments(p.serverNamespace).
+		Update(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		rc.recorder.Eventf(pvc, corev1.EventTypeWarning, reconfigureFailedReason,
+			"failed to update NFS server Deployment %s: %v", deployName, err)
+		return errors.Wrapf(err, "failed to update NFS server deployment %s", deployName)
+	}
+
+	klog.Infof("Applied changed config of PV %s to NFS server Deployment %s", pv.Name, deployName)
+	rc.recorder.Eventf(pvc, corev1.EventTypeNormal, reconfigureSuccessReason,
+		"applied changed config of volume %s to NFS server Deployment %s", pv.Name, deployName)
+	return nil
+}
+
+// applyMutableConfig returns a copy of the given NFS server Deployment,
+// updated with the fields of the rendered Deployment which are derived
+// from the mutable config keys. false is returned if none of the fields
+// is changed.
+func applyMutableConfig(deployObj, desired *appsv1.Deployment) (*appsv1.Deployment, bool) {
+	updated := deployObj.DeepCopy()
+
+	current := getPodContainer(&updated.Spec.Template.Spec, nfsServerContainerName)
+	target := getPodContainer(&desired.Spec.Template.Spec, nfsServerContainerName)
+	if current == nil || target == nil {
+		return nil, false
+	}
+
+	// Deployment rendered with the mutable fields of the running
+	// Deployment has the same spec hash as the running Deployment,
+	// unless the Deployment differs in the other fields too
+	rendered := desired.DeepCopy()
+	copyMutableFields(getPodContainer(&rendered.Spec.Template.Spec, nfsServerContainerName), current)
+
+	if !copyMutableFields(current, target) {
+		return nil, false
+	}
+
+	if err := setDeploymentRevision(rendered); err == nil &&
+		rendered.Annotations[specHashAnnotation] == deployObj.Annotations[specHashAnnotation] {
+		// Deployment matches the rendered spec, after the update
+		if updated.Annotations == nil {
+			updated.Annotations = map[string]string{}
+		}
+		updated.Annotations[provisionerVersionAnnotation] = version.Current()
+		updated.Annotations[specHashAnnotation] = desired.Annotations[specHashAnnotation]
+	}
+	return updated, true
+}
+
+// copyMutableFields copies the fields derived from the mutable config
+// keys from the src container to the dst container, and returns true
+// if any field of the dst container is changed
+func copyMutableFields(dst, src *corev1.Container) bool {
+	changed := false
+
+	if !equality.Semantic.DeepEqual(dst.Resources, src.Resources) {
+		dst.Resources = *src.Resources.DeepCopy()
+		changed = true
+	}
+
+	for _, name := range mutableServerEnvs {
+		value, ok := getContainerEnv(src, name)
+		if !ok {
+			// env is not set if its config key is removed
+			if removeContainerEnv(dst, name) {
+				changed = true
+			}
+			continue
+		}
+		if setContainerEnv(dst, name, value) {
+			changed = true
+		}
+	}
+
+	// Liveness probe of the kernel NFS server waits for the grace time
+	if dst.LivenessProbe != nil && src.LivenessProbe != nil &&
+		dst.LivenessProbe.InitialDelaySeconds != src.LivenessProbe.InitialDelaySeconds {
+		dst.LivenessProbe.InitialDelaySeconds = src.LivenessProbe.InitialDelaySeconds
+		changed = true
+	}
+	return changed
+}
+
+// getPodContainer returns the container of the given name in the pod spec
+func getPodContainer(podSpec *corev1.PodSpec, name string) *corev1.Container {
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == name {
+			return &podSpec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// getContainerEnv returns the value of the given env of the container
+func getContainerEnv(c *corev1.Container, name string) (string, bool) {
+	for _, env := range c.Env {
+		if env.Name == name {
+			return env.Value, true
+		}
+	}
+	return "", false
+}
+
+// setContainerEnv sets the value of the given env of the container,
+// and returns true if the env is changed
+func setContainerEnv(c *corev1.Container, name, value string) bool {
+	for i := range c.Env {
+		if c.Env[i].Name == name {
+			if c.Env[i].Value == value && c.Env[i].ValueFrom == nil {
+				return false
+			}
+			c.Env[i] = corev1.EnvVar{Name: name, Value: value}
+			return true
+		}
+	}
+	c.Env = append(c.Env, corev1.EnvVar{Name: name, Value: value})
+	return true
+}
+
+// removeContainerEnv removes the given env of the container,
+// and returns true if the env is removed
+func removeContainerEnv(c *corev1.Container, name string) bool {
+	for i := range c.Env {
+		if c.Env[i].Name == name {
+			c.Env = append(c.Env[:i], c.Env[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// getChangedConfigKeys returns the sorted names of the config keys added,
+// removed or changed between the given cas.openebs.io/config values
+func getChangedConfigKeys(oldConfigStr, newConfigStr string) ([]string, error) {
+	oldConfig, err := parseCASConfig(oldConfigStr)
+	if err != nil {
+		return nil, err
+	}
+	newConfig, err := parseCASConfig(newConfigStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for name, config := range newConfig {
+		if oldValue, ok := oldConfig[name]; !ok || !reflect.DeepEqual(oldValue, config) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range oldConfig {
+		if _, ok := newConfig[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// parseCASConfig parses the given cas.openebs.io/config, keyed by the config name
+func parseCASConfig(casConfigStr string) (map[string]mconfig.Config, error) {
+	configs := map[string]mconfig.Config{}
+	if len(strings.TrimSpace(casConfigStr)) == 0 {
+		return configs, nil
+	}
+
+	casConfig, err := cast.UnMarshallToConfig(casConfigStr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid config %q", casConfigStr)
+	}
+	for _, config := range casConfig {
+		configs[strings.TrimSpace(config.Name)] = config
+	}
+	return configs, nil
+}
+
+// getImmutableConfig returns the immutable keys of the given
+// cas.openebs.io/config, to be recorded on the NFS PV
+func getImmutableConfig(casConfigStr string) (string, error) {
+	configs, err := parseCASConfig(casConfigStr)
+	if err != nil {
+		return "", err
+	}
+
+	var immutable []mconfig.Config
+	for name, config := range configs {
+		if !mutableConfigKeys[name] {
+			immutable = append(immutable, config)
+		}
+	}
+	return marshalCASConfig(immutable)
+}
+
+// getProvisionedPVC returns the copy of the NFS PVC, whose config has the
+// immutable keys recorded on the NFS PV and the mutable keys of the PVC.
+// NFS PVC is returned as is, if the immutable keys are not recorded on
+// the NFS PV, i.e. the volume is provisioned by the older provisioner.
+func getProvisionedPVC(pv *corev1.PersistentVolume, pvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error) {
+	provisionedConfigStr, ok := pv.Annotations[provisionedConfigAnnotation]
+	if !ok {
+		return pvc, nil
+	}
+
+	provisionedConfig, err := parseCASConfig(provisionedConfigStr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid %s annotation of PV %s", provisionedConfigAnnotation, pv.Name)
+	}
+	pvcConfig, err := parseCASConfig(pvc.Annotations[string(mconfig.CASConfigKey)])
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []mconfig.Config
+	for name, config := range provisionedConfig {
+		if !mutableConfigKeys[name] {
+			configs = append(configs, config)
+		}
+	}
+	for name, config := range pvcConfig {
+		if mutableConfigKeys[name] {
+			configs = append(configs, config)
+		}
+	}
+	casConfigStr, err := marshalCASConfig(configs)
+	if err != nil {
+		return nil, err
+	}
+
+	provisionedPvc := pvc.DeepCopy()
+	if provisionedPvc.Annotations == nil {
+		provisionedPvc.Annotations = map[string]string{}
+	}
+	provisionedPvc.Annotations[string(mconfig.CASConfigKey)] = casConfigStr
+	return provisionedPvc, nil
+}
+
+// marshalCASConfig returns the cas.openebs.io/config of the given
+// configs, sorted by the config name
+func marshalCASConfig(configs []mconfig.Config) (string, error) {
+	if len(configs) == 0 {
+		return "", nil
+	}
+
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Name < configs[j].Name
+	})
+	data, err := yaml.Marshal(configs)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal config")
+	}
+	return string(data), nil
+}
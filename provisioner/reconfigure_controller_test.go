@@ -0,0 +1,460 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func newFakeReconfigureController(serverNs string, objs ...runtime.Object) *ReconfigureController {
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	deployIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *corev1.PersistentVolume:
+			_ = pvIndexer.Add(o)
+		case *corev1.PersistentVolumeClaim:
+			_ = pvcIndexer.Add(o)
+		case *appsv1.Deployment:
+			_ = deployIndexer.Add(o)
+		}
+	}
+
+	p := &Provisioner{
+		kubeClient:      fake.NewSimpleClientset(objs...),
+		serverNamespace: serverNs,
+		namespace:       "openebs",
+		pvTracker:       NewProvisioningTracker(),
+	}
+	p.getVolumeConfig = p.GetVolumeConfig
+
+	return &ReconfigureController{
+		provisioner:  p,
+		pvLister:     listersv1.NewPersistentVolumeLister(pvIndexer),
+		pvcLister:    listersv1.NewPersistentVolumeClaimLister(pvcIndexer),
+		deployLister: appslisters.NewDeploymentLister(deployIndexer),
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "nfs-reconfigure"),
+		recorder:     record.NewFakeRecorder(10),
+	}
+}
+
+// getFakeReconfigureObjects returns the NFS volume objects, whose NFS
+// server Deployment is rendered with the given config of the NFS PVC
+func getFakeReconfigureObjects(t *testing.T, serverNs, pvName, casConfig string) []runtime.Object {
+	objs := getFakeReconcileObjects(serverNs, pvName)
+	for _, obj := range objs {
+		if pvc, ok := obj.(*corev1.PersistentVolumeClaim); ok && pvc.Namespace != serverNs {
+			pvc.Annotations = map[string]string{string(mconfig.CASConfigKey): casConfig}
+		}
+	}
+
+	rc := newFakeReconfigureController(serverNs, objs...)
+	pv, err := rc.pvLister.Get(pvName)
+	assert.NoError(t, err)
+	pvc, err := rc.pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(pv.Spec.ClaimRef.Name)
+	assert.NoError(t, err)
+	nfsServerOpts, err := rc.provisioner.getNFSServerOptions(context.TODO(), pv, pvc)
+	assert.NoError(t, err)
+	desired, err := rc.provisioner.buildDeployment(nfsServerOpts)
+	assert.NoError(t, err)
+	desired.Namespace = serverNs
+
+	for i, obj := range objs {
+		if _, ok := obj.(*appsv1.Deployment); ok {
+			objs[i] = desired
+		}
+	}
+	return objs
+}
+
+func TestReconfigureControllerSyncPV(t *testing.T) {
+	os.Setenv(string(NFSServerImageKey), "openebs/nfs-server-alpine:ci")
+	defer os.Unsetenv(string(NFSServerImageKey))
+
+	serverNs := "nfs-ns"
+	oldConfig := `- name: LeaseTime
+  value: 90
+`
+
+	tests := map[string]struct {
+		newConfig         string
+		expectedChange    bool
+		expectedLeaseTime string
+		expectedCPULimit  string
+	}{
+		"when config is not changed": {
+			newConfig:         oldConfig,
+			expectedLeaseTime: "90",
+		},
+		"when lease time and resource limits are changed": {
+			newConfig: `- name: LeaseTime
+  value: 120
+- name: NFSServerResourceLimits
+  value: |-
+    cpu: 500m
+`,
+			expectedChange:    true,
+			expectedLeaseTime: "120",
+			expectedCPULimit:  "500m",
+		},
+		"when backend StorageClass is changed": {
+			newConfig: oldConfig + `- name: BackendStorageClass
+  value: other-sc
+`,
+			expectedLeaseTime: "90",
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			pvName := "pv-reconfigure"
+			objs := getFakeReconfigureObjects(t, serverNs, pvName, oldConfig)
+			var specHash string
+			for _, obj := range objs {
+				switch o := obj.(type) {
+				case *corev1.PersistentVolumeClaim:
+					if o.Namespace != serverNs {
+						o.Annotations[string(mconfig.CASConfigKey)] = test.newConfig
+					}
+				case *appsv1.Deployment:
+					specHash = o.Annotations[specHashAnnotation]
+				}
+			}
+			rc := newFakeReconfigureController(serverNs, objs...)
+
+			assert.NoError(t, rc.syncPV(context.TODO(), pvName))
+
+			deployObj, err := rc.provisioner.kubeClient.AppsV1().
+				Deployments(serverNs).
+				Get(context.TODO(), "nfs-"+pvName, metav1.GetOptions{})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			container := getPodContainer(&deployObj.Spec.Template.Spec, nfsServerContainerName)
+			leaseTime, _ := getContainerEnv(container, "NFS_LEASE_TIME")
+			assert.Equal(t, test.expectedLeaseTime, leaseTime)
+			if len(test.expectedCPULimit) != 0 {
+				expected := resource.MustParse(test.expectedCPULimit)
+				assert.Equal(t, 0, expected.Cmp(container.Resources.Limits[corev1.ResourceCPU]))
+			}
+
+			events := rc.recorder.(*record.FakeRecorder).Events
+			if test.expectedChange {
+				// Deployment matches the spec rendered with the changed config
+				assert.NotEqual(t, specHash, deployObj.Annotations[specHashAnnotation])
+				if assert.Equal(t, 1, len(events)) {
+					assert.Contains(t, <-events, reconfigureSuccessReason)
+				}
+			} else {
+				assert.Equal(t, specHash, deployObj.Annotations[specHashAnnotation])
+				assert.Equal(t, 0, len(events))
+			}
+		})
+	}
+}
+
+func TestReconfigureControllerHandlePVCUpdate(t *testing.T) {
+	serverNs := "nfs-ns"
+	oldConfig := `- name: LeaseTime
+  value: 90
+`
+
+	tests := map[string]struct {
+		newConfig      string
+		isShared       bool
+		expectedQueued int
+		expectedEvents []string
+	}{
+		"when config is not changed": {
+			newConfig: oldConfig,
+		},
+		"when mutable key is changed": {
+			newConfig: `- name: LeaseTime
+  value: 120
+`,
+			expectedQueued: 1,
+		},
+		"when immutable key is added": {
+			newConfig: oldConfig + `- name: BackendStorageClass
+  value: other-sc
+`,
+			expectedEvents: []string{"Warning " + configChangeRejectedReason + " config keys BackendStorageClass"},
+		},
+		"when mutable and immutable keys are changed": {
+			newConfig: `- name: NFSServerType
+  value: ganesha
+`,
+			expectedQueued: 1,
+			expectedEvents: []string{"Warning " + configChangeRejectedReason + " config keys NFSServerType"},
+		},
+		"when mutable key of shared NFS server volume is changed": {
+			newConfig: `- name: LeaseTime
+  value: 120
+`,
+			isShared:       true,
+			expectedEvents: []string{"Warning " + configChangeRejectedReason + " config keys LeaseTime can't be changed for the volume of shared NFS server"},
+		},
+		"when config is invalid": {
+			newConfig:      "- name: [LeaseTime",
+			expectedEvents: []string{"Warning " + reconfigureFailedReason},
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			pvName := "pv-handle"
+			objs := getFakeReconcileObjects(serverNs, pvName)
+			var oldPvc *corev1.PersistentVolumeClaim
+			for _, obj := range objs {
+				switch o := obj.(type) {
+				case *corev1.PersistentVolumeClaim:
+					if o.Namespace != serverNs {
+						o.Annotations = map[string]string{string(mconfig.CASConfigKey): oldConfig}
+						oldPvc = o
+					}
+				case *corev1.PersistentVolume:
+					if test.isShared {
+						o.Labels[sharedNFSServerLabelKey] = "shared-nfs-sc"
+					}
+				}
+			}
+			rc := newFakeReconfigureController(serverNs, objs...)
+
+			newPvc := oldPvc.DeepCopy()
+			newPvc.Annotations[string(mconfig.CASConfigKey)] = test.newConfig
+			rc.handlePVCUpdate(oldPvc, newPvc)
+
+			assert.Equal(t, test.expectedQueued, rc.queue.Len())
+
+			events := rc.recorder.(*record.FakeRecorder).Events
+			if assert.Equal(t, len(test.expectedEvents), len(events)) {
+				for _, expected := range test.expectedEvents {
+					event := <-events
+					assert.True(t, strings.HasPrefix(event, expected), event)
+				}
+			}
+		})
+	}
+}
+
+func TestGetChangedConfigKeys(t *testing.T) {
+	tests := map[string]struct {
+		oldConfig    string
+		newConfig    string
+		expectedKeys []string
+		isErr        bool
+	}{
+		"when config is added": {
+			newConfig: `- name: LeaseTime
+  value: 90
+`,
+			expectedKeys: []string{"LeaseTime"},
+		},
+		"when config is removed": {
+			oldConfig: `- name: LeaseTime
+  value: 90
+- name: GraceTime
+  value: 90
+`,
+			newConfig: `- name: GraceTime
+  value: 90
+`,
+			expectedKeys: []string{"LeaseTime"},
+		},
+		"when value of config is changed": {
+			oldConfig: `- name: NFSServerResourceLimits
+  value: |-
+    cpu: 500m
+- name: BackendStorageClass
+  value: sc1
+`,
+			newConfig: `- name: BackendStorageClass
+  value: sc2
+- name: NFSServerResourceLimits
+  value: |-
+    cpu: 1
+`,
+			expectedKeys: []string{"BackendStorageClass", "NFSServerResourceLimits"},
+		},
+		"when config is reordered": {
+			oldConfig: `- name: LeaseTime
+  value: 90
+- name: GraceTime
+  value: 90
+`,
+			newConfig: `- name: GraceTime
+  value: 90
+- name: LeaseTime
+  value: 90
+`,
+		},
+		"when config is invalid": {
+			newConfig: "- name: [LeaseTime",
+			isErr:     true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			keys, err := getChangedConfigKeys(test.oldConfig, test.newConfig)
+			assert.Equal(t, test.isErr, err != nil, "%v", err)
+			assert.Equal(t, test.expectedKeys, keys)
+		})
+	}
+}
+
+func TestGetNFSServerOptionsWithProvisionedConfig(t *testing.T) {
+	serverNs := "nfs-ns"
+	provisionedConfig := `- name: BackendStorageClass
+  value: sc1
+- name: LeaseTime
+  value: 90
+- name: NFSVersions
+  value: "4"
+`
+	changedConfig := `- name: BackendStorageClass
+  value: other-sc
+- name: LeaseTime
+  value: 120
+- name: NFSVersions
+  value: "3,4"
+`
+
+	tests := map[string]struct {
+		isRecorded                  bool
+		expectedBackendStorageClass string
+		expectedNFSVersions         []int
+	}{
+		"when immutable config is recorded on PV, recorded config should be used": {
+			isRecorded:                  true,
+			expectedBackendStorageClass: "sc1",
+			expectedNFSVersions:         []int{4},
+		},
+		"when immutable config is not recorded on PV, PVC config should be used": {
+			expectedBackendStorageClass: "other-sc",
+			expectedNFSVersions:         []int{3, 4},
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			pvName := "pv-provisioned"
+			objs := getFakeReconcileObjects(serverNs, pvName)
+			var pv *corev1.PersistentVolume
+			var pvc *corev1.PersistentVolumeClaim
+			for _, obj := range objs {
+				switch o := obj.(type) {
+				case *corev1.PersistentVolumeClaim:
+					if o.Namespace != serverNs {
+						o.Annotations = map[string]string{string(mconfig.CASConfigKey): changedConfig}
+						pvc = o
+					}
+				case *corev1.PersistentVolume:
+					if test.isRecorded {
+						recorded, err := getImmutableConfig(provisionedConfig)
+						assert.NoError(t, err)
+						assert.NotContains(t, recorded, LeaseTime)
+						o.Annotations = map[string]string{provisionedConfigAnnotation: recorded}
+					}
+					pv = o
+				}
+			}
+			rc := newFakeReconfigureController(serverNs, objs...)
+
+			nfsServerOpts, err := rc.provisioner.getNFSServerOptions(context.TODO(), pv, pvc)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, test.expectedBackendStorageClass, nfsServerOpts.backendStorageClass)
+			assert.Equal(t, test.expectedNFSVersions, nfsServerOpts.nfsVersions)
+			// mutable config is always read from the PVC
+			assert.Equal(t, 120, nfsServerOpts.leaseTime)
+			// PVC of the informer cache shouldn't be modified
+			assert.Equal(t, changedConfig, pvc.Annotations[string(mconfig.CASConfigKey)])
+		})
+	}
+}
+
+func TestCopyMutableFields(t *testing.T) {
+	tests := map[string]struct {
+		dstEnv          []corev1.EnvVar
+		srcEnv          []corev1.EnvVar
+		expectedEnv     []corev1.EnvVar
+		expectedChanged bool
+	}{
+		"when env is not changed": {
+			dstEnv:      []corev1.EnvVar{{Name: "NFS_LEASE_TIME", Value: "90"}},
+			srcEnv:      []corev1.EnvVar{{Name: "NFS_LEASE_TIME", Value: "90"}},
+			expectedEnv: []corev1.EnvVar{{Name: "NFS_LEASE_TIME", Value: "90"}},
+		},
+		"when env is changed": {
+			dstEnv:          []corev1.EnvVar{{Name: "NFS_LEASE_TIME", Value: "90"}},
+			srcEnv:          []corev1.EnvVar{{Name: "NFS_LEASE_TIME", Value: "120"}},
+			expectedEnv:     []corev1.EnvVar{{Name: "NFS_LEASE_TIME", Value: "120"}},
+			expectedChanged: true,
+		},
+		"when env is removed, it should be removed from the container": {
+			dstEnv: []corev1.EnvVar{
+				{Name: "SHARED_DIRECTORY", Value: "/nfsshare"},
+				{Name: "CUSTOM_EXPORTS_CONFIG", Value: "/nfsshare *(rw)"},
+			},
+			srcEnv:          []corev1.EnvVar{{Name: "SHARED_DIRECTORY", Value: "/nfsshare"}},
+			expectedEnv:     []corev1.EnvVar{{Name: "SHARED_DIRECTORY", Value: "/nfsshare"}},
+			expectedChanged: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			dst := &corev1.Container{Env: test.dstEnv}
+			src := &corev1.Container{Env: test.srcEnv}
+
+			assert.Equal(t, test.expectedChanged, copyMutableFields(dst, src))
+			assert.Equal(t, test.expectedEnv, dst.Env)
+		})
+	}
+}
@@ -0,0 +1,431 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+This file contains the controller which expands the NFS volumes.
+
+NFS PV is an in-tree NFS volume, so Kubernetes doesn't resize it on
+its own. ResizeController watches the NFS PVCs and, when the requested
+size grows, it performs the following steps:
+
+- expands the backend PVC nfs-<pv-name> which holds the exported data
+- waits till the backend volume filesystem is resized, which happens
+  online since backend volume is mounted by the nfs-server pod
+- updates the NFS PV capacity
+- updates the NFS PVC status capacity and resize conditions
+*/
+
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	errors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// annDynamicallyProvisioned is added on the PV by the provisioner
+	// controller and holds the name of the provisioner
+	annDynamicallyProvisioned = "pv.kubernetes.io/provisioned-by"
+
+	// Event reasons for NFS volume resize
+	resizeStartedReason = "Resizing"
+	resizeFailedReason  = "VolumeResizeFailed"
+	resizeSuccessReason = "VolumeResizeSuccessful"
+
+	// resizeControllerName is used as event source component
+	resizeControllerName = "nfs-resize-controller"
+)
+
+var (
+	// ResizeRecheckInterval defines the interval to recheck the
+	// status of backend PVC resize
+	ResizeRecheckInterval = 10 * time.Second
+)
+
+// ResizeController expands the NFS volumes on NFS PVC resize requests
+type ResizeController struct {
+	kubeClient clientset.Interface
+
+	// serverNamespace in which backend PVCs are created
+	serverNamespace string
+
+	pvcLister listersv1.PersistentVolumeClaimLister
+	pvLister  listersv1.PersistentVolumeLister
+	scLister  storagelisters.StorageClassLister
+
+	pvcSynced cache.InformerSynced
+	pvSynced  cache.InformerSynced
+	scSynced  cache.InformerSynced
+
+	queue    workqueue.RateLimitingInterface
+	recorder record.EventRecorder
+}
+
+// NewResizeController returns a new ResizeController, it uses the given
+// informer factory to watch NFS PVCs, PVs and StorageClasses
+func NewResizeController(kubeClient clientset.Interface,
+	informerFactory kubeinformers.SharedInformerFactory,
+	serverNamespace string) *ResizeController {
+	pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
+	pvInformer := informerFactory.Core().V1().PersistentVolumes()
+	scInformer := informerFactory.Storage().V1().StorageClasses()
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartStructuredLogging(4)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+
+	rc := &ResizeController{
+		kubeClient:      kubeClient,
+		serverNamespace: serverNamespace,
+		pvcLister:       pvcInformer.Lister(),
+		pvLister:        pvInformer.Lister(),
+		scLister:        scInformer.Lister(),
+		pvcSynced:       pvcInformer.Informer().HasSynced,
+		pvSynced:        pvInformer.Informer().HasSynced,
+		scSynced:        scInformer.Informer().HasSynced,
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "nfs-resize"),
+		recorder:        eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: resizeControllerName}),
+	}
+
+	pvcInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: rc.enqueuePVC,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			rc.enqueuePVC(newObj)
+		},
+	})
+
+	return rc
+}
+
+// Run starts the workers of ResizeController and blocks till
+// the given context is cancelled
+func (rc *ResizeController) Run(ctx context.Context) {
+	defer utilruntime.HandleCrash()
+	defer rc.queue.ShutDown()
+
+	klog.Info("Starting NFS volume resize controller")
+	defer klog.Info("Shutting down NFS volume resize controller")
+
+	if !cache.WaitForCacheSync(ctx.Done(), rc.pvcSynced, rc.pvSynced, rc.scSynced) {
+		klog.Error("Failed to sync caches for NFS volume resize controller")
+		return
+	}
+
+	go wait.UntilWithContext(ctx, rc.runWorker, time.Second)
+
+	<-ctx.Done()
+}
+
+func (rc *ResizeController) enqueuePVC(obj interface{}) {
+	pvc, ok := obj.(*corev1.PersistentVolumeClaim)
+	if !ok {
+		return
+	}
+
+	// Only bound PVCs, with increased size request, are of interest
+	if pvc.Status.Phase != corev1.ClaimBound || pvc.Spec.VolumeName == "" {
+		return
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(pvc)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	rc.queue.Add(key)
+}
+
+func (rc *ResizeController) runWorker(ctx context.Context) {
+	for rc.processNextWorkItem(ctx) {
+	}
+}
+
+func (rc *ResizeController) processNextWorkItem(ctx context.Context) bool {
+	key, quit := rc.queue.Get()
+	if quit {
+		return false
+	}
+	defer rc.queue.Done(key)
+
+	requeue, err := rc.syncPVC(ctx, key.(string))
+	if err != nil {
+		klog.Errorf("Failed to resize NFS volume for PVC %s, err=%v", key, err)
+		rc.queue.AddRateLimited(key)
+		return true
+	}
+
+	rc.queue.Forget(key)
+	if requeue {
+		rc.queue.AddAfter(key, ResizeRecheckInterval)
+	}
+	return true
+}
+
+// syncPVC performs the resize steps for the given NFS PVC. It returns true
+// if the PVC needs to be checked again, since backend volume resize is
+// still in progress
+func (rc *ResizeController) syncPVC(ctx context.Context, key string) (bool, error) {
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	pvc, err := rc.pvcLister.PersistentVolumeClaims(ns).Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	pv, err := rc.pvLister.Get(pvc.Spec.VolumeName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if pv.Annotations[annDynamicallyProvisioned] != provisionerName {
+		// PVC is not a NFS PVC
+		return false, nil
+	}
+
+	requestSize := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	pvSize := pv.Spec.Capacity[corev1.ResourceStorage]
+
+	if requestSize.Cmp(pvSize) <= 0 {
+		// Either resize is not requested or NFS PV is already expanded.
+		// Make sure that PVC status also reflects the PV capacity.
+		statusSize := pvc.Status.Capacity[corev1.ResourceStorage]
+		if statusSize.Cmp(pvSize) < 0 {
+			return false, rc.markPVCResizeFinished(ctx, pvc, pvSize)
+		}
+		return false, nil
+	}
+
+	if isSharedNFSVolume(pv) {
+		// Volumes of the shared NFS server don't have a dedicated backend PVC
+		rc.recorder.Event(pvc, corev1.EventTypeWarning, resizeFailedReason,
+			"expansion of the volume created on shared NFS server is not supported")
+		return false, nil
+	}
+
+	if isReadOnlyNFSVolume(pv) {
+		rc.recorder.Event(pvc, corev1.EventTypeWarning, resizeFailedReason,
+			"expansion of the read-only volume is not supported, expand the source volume instead")
+		return false, nil
+	}
+
+	if GetNFSServerTypeFromPV(pv) == NFSServerTypeExternal {
+		// Capacity of the volumes on the external NFS server is not
+		// enforced by the provisioner, only the NFS PV needs to be updated
+		if err = rc.updatePVCapacity(ctx, pv, requestSize); err != nil {
+			return false, err
+		}
+
+		if err = rc.markPVCResizeFinished(ctx, pvc, requestSize); err != nil {
+			return false, err
+		}
+
+		rc.recorder.Eventf(pvc, corev1.EventTypeNormal, resizeSuccessReason,
+			"resize of NFS volume %s to %s is successful", pv.Name, requestSize.String())
+		return false, nil
+	}
+
+	backendPvcName := "nfs-" + pv.Name
+	backendPvc, err := rc.kubeClient.CoreV1().
+		PersistentVolumeClaims(rc.serverNamespace).
+		Get(ctx, backendPvcName, metav1.GetOptions{})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to get backend PVC {%s/%s}", rc.serverNamespace, backendPvcName)
+	}
+
+	if err = rc.validateBackendExpansion(backendPvc); err != nil {
+		// Retrying will not help here, till the backend StorageClass
+		// is updated. PVC will be processed again on next update.
+		rc.recorder.Event(pvc, corev1.EventTypeWarning, resizeFailedReason, err.Error())
+		return false, nil
+	}
+
+	if err = rc.markPVCResizeInProgress(ctx, pvc); err != nil {
+		return false, err
+	}
+
+	backendRequestSize := backendPvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	if backendRequestSize.Cmp(requestSize) < 0 {
+		klog.Infof("Expanding backend PVC {%s/%s} from %s to %s for NFS PV %s",
+			backendPvc.Namespace, backendPvc.Name, backendRequestSize.String(), requestSize.String(), pv.Name)
+
+		backendPvc = backendPvc.DeepCopy()
+		backendPvc.Spec.Resources.Requests[corev1.ResourceStorage] = requestSize
+		_, err = rc.kubeClient.CoreV1().
+			PersistentVolumeClaims(backendPvc.Namespace).
+			Update(ctx, backendPvc, metav1.UpdateOptions{})
+		if err != nil {
+			rc.recorder.Eventf(pvc, corev1.EventTypeWarning, resizeFailedReason,
+				"failed to expand backend PVC %s/%s: %v", backendPvc.Namespace, backendPvc.Name, err)
+			return false, errors.Wrapf(err, "failed to expand backend PVC {%s/%s}", backendPvc.Namespace, backendPvc.Name)
+		}
+
+		rc.recorder.Eventf(pvc, corev1.EventTypeNormal, resizeStartedReason,
+			"expanding backend PVC %s/%s to %s", backendPvc.Namespace, backendPvc.Name, requestSize.String())
+		return true, nil
+	}
+
+	if !isBackendPVCResized(backendPvc, requestSize) {
+		klog.V(4).Infof("Waiting for backend PVC {%s/%s} to be resized to %s",
+			backendPvc.Namespace, backendPvc.Name, requestSize.String())
+		return true, nil
+	}
+
+	if err = rc.updatePVCapacity(ctx, pv, requestSize); err != nil {
+		return false, err
+	}
+
+	if err = rc.markPVCResizeFinished(ctx, pvc, requestSize); err != nil {
+		return false, err
+	}
+
+	rc.recorder.Eventf(pvc, corev1.EventTypeNormal, resizeSuccessReason,
+		"resize of NFS volume %s to %s is successful", pv.Name, requestSize.String())
+	return false, nil
+}
+
+// validateBackendExpansion returns error if backend StorageClass of given
+// backend PVC doesn't allow volume expansion
+func (rc *ResizeController) validateBackendExpansion(backendPvc *corev1.PersistentVolumeClaim) error {
+	if backendPvc.Spec.StorageClassName == nil || *backendPvc.Spec.StorageClassName == "" {
+		return errors.Errorf("backend PVC %s/%s doesn't have a StorageClass, expansion is not supported",
+			backendPvc.Namespace, backendPvc.Name)
+	}
+
+	scName := *backendPvc.Spec.StorageClassName
+	sc, err := rc.scLister.Get(scName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get backend StorageClass %s", scName)
+	}
+
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		return errors.Errorf("backend StorageClass %s doesn't allow volume expansion, "+
+			"set allowVolumeExpansion to true on it to expand NFS volumes", scName)
+	}
+	return nil
+}
+
+// isBackendPVCResized returns true if backend PVC capacity has reached
+// the given size and filesystem resize is completed
+func isBackendPVCResized(backendPvc *corev1.PersistentVolumeClaim, size resource.Quantity) bool {
+	capacity := backendPvc.Status.Capacity[corev1.ResourceStorage]
+	if capacity.Cmp(size) < 0 {
+		return false
+	}
+
+	for _, condition := range backendPvc.Status.Conditions {
+		if condition.Type == corev1.PersistentVolumeClaimFileSystemResizePending &&
+			condition.Status == corev1.ConditionTrue {
+			return false
+		}
+	}
+	return true
+}
+
+// updatePVCapacity sets the capacity of given NFS PV
+func (rc *ResizeController) updatePVCapacity(ctx context.Context, pv *corev1.PersistentVolume, size resource.Quantity) error {
+	pv = pv.DeepCopy()
+	if pv.Spec.Capacity == nil {
+		pv.Spec.Capacity = corev1.ResourceList{}
+	}
+	pv.Spec.Capacity[corev1.ResourceStorage] = size
+
+	_, err := rc.kubeClient.CoreV1().PersistentVolumes().Update(ctx, pv, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update capacity of PV %s", pv.Name)
+	}
+	return nil
+}
+
+// markPVCResizeInProgress adds the Resizing condition on the given PVC
+func (rc *ResizeController) markPVCResizeInProgress(ctx context.Context, pvc *corev1.PersistentVolumeClaim) error {
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Type == corev1.PersistentVolumeClaimResizing {
+			return nil
+		}
+	}
+
+	pvc = pvc.DeepCopy()
+	pvc.Status.Conditions = append(pvc.Status.Conditions, corev1.PersistentVolumeClaimCondition{
+		Type:               corev1.PersistentVolumeClaimResizing,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Message:            fmt.Sprintf("waiting for backend volume nfs-%s to be expanded", pvc.Spec.VolumeName),
+	})
+
+	_, err := rc.kubeClient.CoreV1().
+		PersistentVolumeClaims(pvc.Namespace).
+		UpdateStatus(ctx, pvc, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update status of PVC %s/%s", pvc.Namespace, pvc.Name)
+	}
+	return nil
+}
+
+// markPVCResizeFinished sets the status capacity of given PVC and removes
+// the resize conditions
+func (rc *ResizeController) markPVCResizeFinished(ctx context.Context, pvc *corev1.PersistentVolumeClaim, size resource.Quantity) error {
+	pvc = pvc.DeepCopy()
+	if pvc.Status.Capacity == nil {
+		pvc.Status.Capacity = corev1.ResourceList{}
+	}
+	pvc.Status.Capacity[corev1.ResourceStorage] = size
+
+	var conditions []corev1.PersistentVolumeClaimCondition
+	for _, condition := range pvc.Status.Conditions {
+		if condition.Type == corev1.PersistentVolumeClaimResizing ||
+			condition.Type == corev1.PersistentVolumeClaimFileSystemResizePending {
+			continue
+		}
+		conditions = append(conditions, condition)
+	}
+	pvc.Status.Conditions = conditions
+
+	_, err := rc.kubeClient.CoreV1().
+		PersistentVolumeClaims(pvc.Namespace).
+		UpdateStatus(ctx, pvc, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update status of PVC %s/%s", pvc.Namespace, pvc.Name)
+	}
+	return nil
+}
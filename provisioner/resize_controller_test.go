@@ -0,0 +1,212 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+func getFakeNFSPvObject(name, size, provisioner string) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				annDynamicallyProvisioned: provisioner,
+			},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse(size),
+			},
+		},
+	}
+}
+
+func getFakeBoundPvcObject(ns, name, pvName, requestSize, statusSize string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ns,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			VolumeName: pvName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse(requestSize),
+				},
+			},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Phase: corev1.ClaimBound,
+			Capacity: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse(statusSize),
+			},
+		},
+	}
+}
+
+func getFakeStorageClass(name string, allowExpansion bool) *storagev1.StorageClass {
+	return &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		AllowVolumeExpansion: &allowExpansion,
+	}
+}
+
+func newFakeResizeController(serverNs string, objs ...runtime.Object) *ResizeController {
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	scIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *corev1.PersistentVolumeClaim:
+			// backend PVCs are fetched from API server only
+			if o.Namespace != serverNs {
+				_ = pvcIndexer.Add(o)
+			}
+		case *corev1.PersistentVolume:
+			_ = pvIndexer.Add(o)
+		case *storagev1.StorageClass:
+			_ = scIndexer.Add(o)
+		}
+	}
+
+	return &ResizeController{
+		kubeClient:      fake.NewSimpleClientset(objs...),
+		serverNamespace: serverNs,
+		pvcLister:       listersv1.NewPersistentVolumeClaimLister(pvcIndexer),
+		pvLister:        listersv1.NewPersistentVolumeLister(pvIndexer),
+		scLister:        storagelisters.NewStorageClassLister(scIndexer),
+		recorder:        record.NewFakeRecorder(10),
+	}
+}
+
+func TestResizeControllerSyncPVC(t *testing.T) {
+	serverNs := "nfs-ns"
+
+	tests := map[string]struct {
+		nfsPvc     *corev1.PersistentVolumeClaim
+		nfsPv      *corev1.PersistentVolume
+		backendPvc *corev1.PersistentVolumeClaim
+		backendSC  *storagev1.StorageClass
+
+		expectedRequeue         bool
+		expectedBackendRequest  string
+		expectedPVCapacity      string
+		expectedPVCStatusSize   string
+		expectedResizeCondition bool
+	}{
+		"when backend StorageClass doesn't allow expansion": {
+			nfsPvc:                 getFakeBoundPvcObject("ns1", "pvc1", "pv1", "10Gi", "5Gi"),
+			nfsPv:                  getFakeNFSPvObject("pv1", "5Gi", provisionerName),
+			backendPvc:             getFakeBoundPvcObject(serverNs, "nfs-pv1", "backend-pv1", "5Gi", "5Gi"),
+			backendSC:              getFakeStorageClass("backend-sc", false),
+			expectedBackendRequest: "5Gi",
+			expectedPVCapacity:     "5Gi",
+			expectedPVCStatusSize:  "5Gi",
+		},
+		"when NFS PVC size is increased": {
+			nfsPvc:                  getFakeBoundPvcObject("ns2", "pvc2", "pv2", "10Gi", "5Gi"),
+			nfsPv:                   getFakeNFSPvObject("pv2", "5Gi", provisionerName),
+			backendPvc:              getFakeBoundPvcObject(serverNs, "nfs-pv2", "backend-pv2", "5Gi", "5Gi"),
+			backendSC:               getFakeStorageClass("backend-sc", true),
+			expectedRequeue:         true,
+			expectedBackendRequest:  "10Gi",
+			expectedPVCapacity:      "5Gi",
+			expectedPVCStatusSize:   "5Gi",
+			expectedResizeCondition: true,
+		},
+		"when backend PVC is expanded": {
+			nfsPvc:                 getFakeBoundPvcObject("ns3", "pvc3", "pv3", "10Gi", "5Gi"),
+			nfsPv:                  getFakeNFSPvObject("pv3", "5Gi", provisionerName),
+			backendPvc:             getFakeBoundPvcObject(serverNs, "nfs-pv3", "backend-pv3", "10Gi", "10Gi"),
+			backendSC:              getFakeStorageClass("backend-sc", true),
+			expectedBackendRequest: "10Gi",
+			expectedPVCapacity:     "10Gi",
+			expectedPVCStatusSize:  "10Gi",
+		},
+		"when PV is not provisioned by NFS provisioner": {
+			nfsPvc:                 getFakeBoundPvcObject("ns4", "pvc4", "pv4", "10Gi", "5Gi"),
+			nfsPv:                  getFakeNFSPvObject("pv4", "5Gi", "other-provisioner"),
+			backendPvc:             getFakeBoundPvcObject(serverNs, "nfs-pv4", "backend-pv4", "5Gi", "5Gi"),
+			backendSC:              getFakeStorageClass("backend-sc", true),
+			expectedBackendRequest: "5Gi",
+			expectedPVCapacity:     "5Gi",
+			expectedPVCStatusSize:  "5Gi",
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			scName := test.backendSC.Name
+			test.backendPvc.Spec.StorageClassName = &scName
+
+			rc := newFakeResizeController(serverNs, test.nfsPvc, test.nfsPv, test.backendPvc, test.backendSC)
+			key := test.nfsPvc.Namespace + "/" + test.nfsPvc.Name
+
+			requeue, err := rc.syncPVC(context.TODO(), key)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedRequeue, requeue)
+
+			backendPvc, err := rc.kubeClient.CoreV1().
+				PersistentVolumeClaims(serverNs).
+				Get(context.TODO(), test.backendPvc.Name, metav1.GetOptions{})
+			assert.NoError(t, err)
+			backendRequest := backendPvc.Spec.Resources.Requests[corev1.ResourceStorage]
+			assert.Equal(t, test.expectedBackendRequest, backendRequest.String())
+
+			pv, err := rc.kubeClient.CoreV1().
+				PersistentVolumes().
+				Get(context.TODO(), test.nfsPv.Name, metav1.GetOptions{})
+			assert.NoError(t, err)
+			pvCapacity := pv.Spec.Capacity[corev1.ResourceStorage]
+			assert.Equal(t, test.expectedPVCapacity, pvCapacity.String())
+
+			pvc, err := rc.kubeClient.CoreV1().
+				PersistentVolumeClaims(test.nfsPvc.Namespace).
+				Get(context.TODO(), test.nfsPvc.Name, metav1.GetOptions{})
+			assert.NoError(t, err)
+			statusSize := pvc.Status.Capacity[corev1.ResourceStorage]
+			assert.Equal(t, test.expectedPVCStatusSize, statusSize.String())
+
+			var hasResizeCondition bool
+			for _, condition := range pvc.Status.Conditions {
+				if condition.Type == corev1.PersistentVolumeClaimResizing {
+					hasResizeCondition = true
+				}
+			}
+			assert.Equal(t, test.expectedResizeCondition, hasResizeCondition)
+		})
+	}
+}
@@ -23,8 +23,10 @@ import (
 	nfshook "github.com/openebs/dynamic-nfs-provisioner/pkg/hook"
 	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/dynamic"
 	clientset "k8s.io/client-go/kubernetes"
 	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
 )
 
 // Provisioner struct has the configuration and utilities required
@@ -34,6 +36,9 @@ type Provisioner struct {
 
 	kubeClient clientset.Interface
 
+	// dynamicClient manages the VolumeSnapshots of the backend volumes
+	dynamicClient dynamic.Interface
+
 	// namespace in which provisioner is running
 	namespace string
 
@@ -64,8 +69,22 @@ type Provisioner struct {
 	// backendPvcTimeout defines timeout for backend PVC Bound check
 	backendPvcTimeout time.Duration
 
+	// serverReadyTimeout defines timeout for NFS server readiness check
+	serverReadyTimeout time.Duration
+
+	// prober checks if the NFS server is serving the volume
+	prober nfsServerProber
+
 	// hooks which needs to be executed on provisioning events
 	hook *nfshook.Hook
+
+	// executor executes the commands in NFS server pods, to manage
+	// the volumes of shared NFS server
+	executor commandExecutor
+
+	// recorder records the events of the NFS server creation
+	// on the NFS PVC
+	recorder record.EventRecorder
 }
 
 // VolumeConfig struct contains the merged configuration of the PVC
@@ -0,0 +1,756 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+This file contains the controller which upgrades the NFS server
+Deployments of the NFS volumes.
+
+NFS server Deployment is created only while provisioning the volume, so
+the new images, env defaults or resource settings of the provisioner
+never reach the existing volumes. Each NFS server Deployment is stamped
+with the provisioner version and the hash of its rendered spec.
+UpgradeController watches the NFS PVs and the NFS server Deployments,
+and for every bound NFS PV:
+
+- renders the NFS server Deployment from the NFS PVC and its StorageClass,
+  the same way as the volume is provisioned
+- flags the NFS PV, if the spec hash of the Deployment doesn't match the
+  rendered spec
+- rolls out the rendered spec as per the upgrade policy, unless the
+  volume has opted out of the upgrade
+
+Shared NFS server serves all the NFS PVs of its StorageClass, so it is
+upgraded once for the shared NFS server instead of each NFS PV. Shared
+NFS server Deployment is rendered only from the StorageClass config,
+and the drift and upgrade events are reported on the Deployment.
+
+Upgrade policy is one of:
+- manual: Deployment is upgraded once it is annotated with
+  nfs.openebs.io/upgrade-approved=true
+- automatic: Deployment is upgraded as soon as the drift is detected
+- maintenance-window: Deployment is upgraded within the configured
+  maintenance window
+
+NFS servers are upgraded one at a time, and the upgrade waits for the
+rollout of the Deployment to complete, since the NFS volume is not
+available while the NFS server pod is recreated.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/helper"
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+	"github.com/openebs/maya/pkg/version"
+	errors "github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+// UpgradePolicy defines when the NFS server Deployments, which differ
+// from the rendered spec, are upgraded
+type UpgradePolicy string
+
+const (
+	// UpgradePolicyManual upgrades the NFS server Deployments
+	// approved by the annotation nfs.openebs.io/upgrade-approved
+	UpgradePolicyManual UpgradePolicy = "manual"
+
+	// UpgradePolicyAutomatic upgrades the NFS server Deployments
+	// as soon as the drift is detected
+	UpgradePolicyAutomatic UpgradePolicy = "automatic"
+
+	// UpgradePolicyMaintenanceWindow upgrades the NFS server
+	// Deployments within the maintenance window
+	UpgradePolicyMaintenanceWindow UpgradePolicy = "maintenance-window"
+)
+
+const (
+	// provisionerVersionAnnotation records the version of the
+	// provisioner which rendered the NFS server Deployment
+	provisionerVersionAnnotation = "nfs.openebs.io/provisioner-version"
+
+	// specHashAnnotation records the hash of the rendered
+	// spec of the NFS server Deployment
+	specHashAnnotation = "nfs.openebs.io/spec-hash"
+
+	// upgradeApprovedAnnotation approves the upgrade of the NFS
+	// server Deployment. It is removed once the Deployment is upgraded.
+	upgradeApprovedAnnotation = "nfs.openebs.io/upgrade-approved"
+
+	// upgradeDisabledAnnotation opts out the NFS volume from the
+	// upgrade, if set on the NFS PVC or the NFS server Deployment
+	upgradeDisabledAnnotation = "nfs.openebs.io/upgrade-disabled"
+
+	// sharedNFSServerQueueKeyPrefix is the prefix of the queue key of
+	// the shared NFS server, followed by the shared NFS server name.
+	// NFS PV names can't have '/', so the keys don't conflict.
+	sharedNFSServerQueueKeyPrefix = "shared-nfs-server/"
+
+	// Event reasons for NFS server upgrade
+	specDriftReason      = "NFSServerSpecDrift"
+	upgradeSuccessReason = "NFSServerUpgraded"
+	upgradeFailedReason  = "NFSServerUpgradeFailed"
+)
+
+var (
+	// UpgradeInterval defines the interval to check all the NFS PVs
+	UpgradeInterval = 5 * time.Minute
+
+	// UpgradeRolloutTimeout defines the timeout for the rollout
+	// of the upgraded NFS server Deployment
+	UpgradeRolloutTimeout = 5 * time.Minute
+)
+
+// UpgradeController upgrades the NFS server Deployments of the NFS PVs,
+// which differ from the spec rendered by the provisioner
+type UpgradeController struct {
+	provisioner *Provisioner
+
+	policy UpgradePolicy
+	window *MaintenanceWindow
+
+	pvLister     listersv1.PersistentVolumeLister
+	pvcLister    listersv1.PersistentVolumeClaimLister
+	deployLister appslisters.DeploymentLister
+
+	pvSynced     cache.InformerSynced
+	pvcSynced    cache.InformerSynced
+	deploySynced cache.InformerSynced
+
+	queue    workqueue.RateLimitingInterface
+	recorder record.EventRecorder
+
+	// reportedDrift holds the rendered spec hash of the NFS PVs and the
+	// shared NFS servers whose drift is reported, keyed by the queue key,
+	// so that the drift is reported once for each change. It is accessed
+	// only by the single worker.
+	reportedDrift map[string]string
+
+	// now returns the current time, to check the maintenance window
+	now func() time.Time
+}
+
+// NewUpgradeController returns a new UpgradeController with the given
+// upgrade policy. Maintenance window is required only for maintenance-window
+// policy. It uses the given informer factory to watch NFS PVs and PVCs, and
+// the server informer factory to watch the Deployments in NFS server namespace.
+func NewUpgradeController(p *Provisioner,
+	policy UpgradePolicy,
+	window *MaintenanceWindow,
+	informerFactory kubeinformers.SharedInformerFactory,
+	serverInformerFactory kubeinformers.SharedInformerFactory) *UpgradeController {
+	pvInformer := informerFactory.Core().V1().PersistentVolumes()
+	pvcInformer := informerFactory.Core().V1().PersistentVolumeClaims()
+	deployInformer := serverInformerFactory.Apps().V1().Deployments()
+
+	uc := &UpgradeController{
+		provisioner:   p,
+		policy:        policy,
+		window:        window,
+		pvLister:      pvInformer.Lister(),
+		pvcLister:     pvcInformer.Lister(),
+		deployLister:  deployInformer.Lister(),
+		pvSynced:      pvInformer.Informer().HasSynced,
+		pvcSynced:     pvcInformer.Informer().HasSynced,
+		deploySynced:  deployInformer.Informer().HasSynced,
+		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "nfs-upgrade"),
+		recorder:      p.recorder,
+		reportedDrift: map[string]string{},
+		now:           time.Now,
+	}
+
+	pvInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: uc.enqueuePV,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			uc.enqueuePV(newObj)
+		},
+	})
+
+	// Changes to the NFS server Deployment, like the approval of the
+	// upgrade, trigger the upgrade check of their NFS PVs
+	deployInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			for _, name := range getResourceOwnerPVs(newObj, p.serverNamespace, uc.pvLister) {
+				if pv, err := uc.pvLister.Get(name); err == nil {
+					uc.enqueuePV(pv)
+				}
+			}
+		},
+	})
+
+	return uc
+}
+
+// Run starts the worker of UpgradeController and blocks till
+// the given context is cancelled
+func (uc *UpgradeController) Run(ctx context.Context) {
+	defer utilruntime.HandleCrash()
+	defer uc.queue.ShutDown()
+
+	klog.Infof("Starting NFS server upgrade controller with policy=%s", uc.policy)
+	defer klog.Info("Shutting down NFS server upgrade controller")
+
+	if !cache.WaitForCacheSync(ctx.Done(), uc.pvSynced, uc.pvcSynced, uc.deploySynced) {
+		klog.Error("Failed to sync caches for NFS server upgrade controller")
+		return
+	}
+
+	// Single worker upgrades the NFS servers one at a time
+	go wait.UntilWithContext(ctx, uc.runWorker, time.Second)
+	go wait.UntilWithContext(ctx, uc.enqueueAllPVs, UpgradeInterval)
+
+	<-ctx.Done()
+}
+
+func (uc *UpgradeController) enqueuePV(obj interface{}) {
+	pv, ok := obj.(*corev1.PersistentVolume)
+	if !ok {
+		return
+	}
+
+	if !isReconcilableNFSPV(pv) {
+		return
+	}
+	if isSharedNFSVolume(pv) {
+		uc.queue.Add(sharedNFSServerQueueKeyPrefix + pv.Labels[sharedNFSServerLabelKey])
+		return
+	}
+	uc.queue.Add(pv.Name)
+}
+
+// enqueueAllPVs adds all the NFS PVs to the queue
+func (uc *UpgradeController) enqueueAllPVs(ctx context.Context) {
+	pvList, err := uc.pvLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list PVs, err=%v", err)
+		return
+	}
+
+	for _, pv := range pvList {
+		uc.enqueuePV(pv)
+	}
+}
+
+func (uc *UpgradeController) runWorker(ctx context.Context) {
+	for uc.processNextWorkItem(ctx) {
+	}
+}
+
+func (uc *UpgradeController) processNextWorkItem(ctx context.Context) bool {
+	key, quit := uc.queue.Get()
+	if quit {
+		return false
+	}
+	defer uc.queue.Done(key)
+
+	var err error
+	if serverName := strings.TrimPrefix(key.(string), sharedNFSServerQueueKeyPrefix); serverName != key.(string) {
+		err = uc.syncSharedNFSServer(ctx, serverName)
+	} else {
+		err = uc.syncPV(ctx, key.(string))
+	}
+	if err != nil {
+		klog.Errorf("Failed to upgrade NFS server of %s, err=%v", key, err)
+		uc.queue.AddRateLimited(key)
+		return true
+	}
+
+	uc.queue.Forget(key)
+	return true
+}
+
+// syncPV upgrades the NFS server Deployment of the given NFS PV, if it
+// differs from the rendered spec and the upgrade is allowed. NFS PVs of
+// the shared NFS server are upgraded by syncSharedNFSServer.
+func (uc *UpgradeController) syncPV(ctx context.Context, name string) error {
+	p := uc.provisioner
+
+	pv, err := uc.pvLister.Get(name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			metrics.NFSServerSpecDrift.DeleteLabelValues(name)
+			delete(uc.reportedDrift, name)
+			return nil
+		}
+		return err
+	}
+
+	if !isReconcilableNFSPV(pv) || isSharedNFSVolume(pv) || p.pvTracker.Inprogress(pv.Name) {
+		return nil
+	}
+
+	pvc, err := uc.pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(pv.Spec.ClaimRef.Name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if pvc.UID != pv.Spec.ClaimRef.UID {
+		return nil
+	}
+
+	nfsServerOpts, err := p.getNFSServerOptions(ctx, pv, pvc)
+	if err != nil {
+		uc.recorder.Eventf(pvc, corev1.EventTypeWarning, upgradeFailedReason,
+			"failed to get NFS server configuration of volume %s: %v", pv.Name, err)
+		return err
+	}
+
+	return uc.syncDeployment(ctx, nfsServerTarget{
+		key:         pv.Name,
+		description: "volume " + pv.Name,
+		eventObj:    pvc,
+		annotations: pvc.Annotations,
+	}, nfsServerOpts)
+}
+
+// syncSharedNFSServer upgrades the Deployment of the given shared NFS
+// server, if it differs from the rendered spec and the upgrade is allowed.
+// Shared NFS server is rendered from the StorageClass of its NFS PVs.
+func (uc *UpgradeController) syncSharedNFSServer(ctx context.Context, serverName string) error {
+	p := uc.provisioner
+	key := sharedNFSServerQueueKeyPrefix + serverName
+
+	pvList, err := uc.pvLister.List(labels.SelectorFromSet(labels.Set{sharedNFSServerLabelKey: serverName}))
+	if err != nil {
+		return err
+	}
+	var pv *corev1.PersistentVolume
+	for _, item := range pvList {
+		if isReconcilableNFSPV(item) {
+			pv = item
+			break
+		}
+	}
+	if pv == nil {
+		// Garbage collector removes the unused shared NFS server
+		metrics.NFSServerSpecDrift.DeleteLabelValues(serverName)
+		delete(uc.reportedDrift, key)
+		return nil
+	}
+
+	// Shared NFS server can't be rendered once its StorageClass is deleted
+	_, err = p.kubeClient.StorageV1().StorageClasses().Get(ctx, pv.Spec.StorageClassName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.V(4).Infof("StorageClass %s of shared NFS server %s doesn't exist, skipping upgrade",
+				pv.Spec.StorageClassName, serverName)
+			return nil
+		}
+		return err
+	}
+
+	nfsServerOpts, err := p.getNFSServerOptions(ctx, pv, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get configuration of shared NFS server %s", serverName)
+	}
+
+	return uc.syncDeployment(ctx, nfsServerTarget{
+		key:         key,
+		metricLabel: serverName,
+		description: "shared NFS server " + serverName,
+	}, nfsServerOpts)
+}
+
+// nfsServerTarget refers the NFS PV or the shared NFS server, whose
+// NFS server Deployment is upgraded
+type nfsServerTarget struct {
+	// key is the queue key of the NFS PV or the shared NFS server
+	key string
+
+	// metricLabel is the value of the PV label of the drift metric.
+	// Queue key is used if it is empty.
+	metricLabel string
+
+	// description refers the target in the logs and events
+	description string
+
+	// eventObj is the object on which the events are recorded.
+	// Events are recorded on the NFS server Deployment if it is nil.
+	eventObj runtime.Object
+
+	// annotations are checked for the opt-out of the upgrade,
+	// along with the NFS server Deployment annotations
+	annotations map[string]string
+}
+
+func (t nfsServerTarget) getMetricLabel() string {
+	if len(t.metricLabel) != 0 {
+		return t.metricLabel
+	}
+	return t.key
+}
+
+// syncDeployment upgrades the NFS server Deployment of the given target,
+// rendered with the given NFS server options, if it differs from the
+// rendered spec and the upgrade is allowed
+func (uc *UpgradeController) syncDeployment(ctx context.Context, target nfsServerTarget, nfsServerOpts *KernelNFSServerOptions) error {
+	p := uc.provisioner
+
+	deployObj, err := uc.deployLister.Deployments(p.serverNamespace).Get(nfsServerOpts.deploymentName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Reconciler recreates the missing Deployment
+			return nil
+		}
+		return err
+	}
+
+	desired, err := p.buildDeployment(nfsServerOpts)
+	if err != nil {
+		return errors.Wrapf(err, "failed to render NFS server deployment of %s", target.description)
+	}
+
+	// Drift in the informer cache is verified with the API server,
+	// since the cache may not be up to date with the last upgrade
+	desiredHash := desired.Annotations[specHashAnnotation]
+	if deployObj.Annotations[specHashAnnotation] != desiredHash {
+		deployObj, err = p.kubeClient.AppsV1().
+			Deployments(p.serverNamespace).
+			Get(ctx, nfsServerOpts.deploymentName, metav1.GetOptions{})
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	metricLabel := target.getMetricLabel()
+	if deployObj.Annotations[specHashAnnotation] == desiredHash {
+		metrics.NFSServerSpecDrift.WithLabelValues(metricLabel).Set(0)
+		delete(uc.reportedDrift, target.key)
+		return nil
+	}
+	metrics.NFSServerSpecDrift.WithLabelValues(metricLabel).Set(1)
+
+	eventObj := target.eventObj
+	if eventObj == nil {
+		eventObj = deployObj
+	}
+
+	if reason := uc.getUpgradePendingReason(target.annotations, deployObj); len(reason) != 0 {
+		uc.reportDrift(target, eventObj, deployObj, desiredHash, reason)
+		return nil
+	}
+
+	deployName := fmt.Sprintf("%s/%s", deployObj.Namespace, deployObj.Name)
+	klog.Infof("Upgrading NFS server Deployment %s of %s to provisioner version %s",
+		deployName, target.description, desired.Annotations[provisionerVersionAnnotation])
+
+	if err = uc.upgradeDeployment(ctx, deployObj, desired); err != nil {
+		metrics.NFSServerUpgradeFailedTotal.Inc()
+		uc.recorder.Eventf(eventObj, corev1.EventTypeWarning, upgradeFailedReason,
+			"failed to upgrade NFS server Deployment %s: %v", deployName, err)
+		return errors.Wrapf(err, "failed to upgrade NFS server deployment %s", deployName)
+	}
+
+	metrics.NFSServerUpgradeTotal.Inc()
+	metrics.NFSServerSpecDrift.WithLabelValues(metricLabel).Set(0)
+	delete(uc.reportedDrift, target.key)
+	uc.recorder.Eventf(eventObj, corev1.EventTypeNormal, upgradeSuccessReason,
+		"upgraded NFS server Deployment %s of %s to provisioner version %s",
+		deployName, target.description, desired.Annotations[provisionerVersionAnnotation])
+	return nil
+}
+
+// getUpgradePendingReason returns the reason to hold the upgrade of the
+// NFS server Deployment. Empty string is returned if the upgrade is allowed.
+// Given annotations, i.e. of the NFS PVC, can opt out of the upgrade.
+func (uc *UpgradeController) getUpgradePendingReason(annotations map[string]string, deployObj *appsv1.Deployment) string {
+	if isAnnotationTrue(annotations, upgradeDisabledAnnotation) ||
+		isAnnotationTrue(deployObj.Annotations, upgradeDisabledAnnotation) {
+		return fmt.Sprintf("upgrade is disabled by annotation %s", upgradeDisabledAnnotation)
+	}
+
+	if isAnnotationTrue(deployObj.Annotations, upgradeApprovedAnnotation) {
+		return ""
+	}
+
+	switch uc.policy {
+	case UpgradePolicyAutomatic:
+		return ""
+	case UpgradePolicyMaintenanceWindow:
+		if uc.window != nil && uc.window.Contains(uc.now()) {
+			return ""
+		}
+		return fmt.Sprintf("upgrade is scheduled in maintenance window %s", uc.window)
+	}
+	return fmt.Sprintf("upgrade is pending approval, annotate the Deployment with %s=true", upgradeApprovedAnnotation)
+}
+
+// reportDrift reports the drift of the NFS server Deployment through an
+// event on the given object, once for each change of the rendered spec
+func (uc *UpgradeController) reportDrift(target nfsServerTarget, eventObj runtime.Object,
+	deployObj *appsv1.Deployment, desiredHash, reason string) {
+	if uc.reportedDrift[target.key] == desiredHash {
+		return
+	}
+	uc.reportedDrift[target.key] = desiredHash
+
+	klog.Infof("NFS server Deployment %s/%s of %s differs from the rendered spec, %s",
+		deployObj.Namespace, deployObj.Name, target.description, reason)
+	uc.recorder.Eventf(eventObj, corev1.EventTypeNormal, specDriftReason,
+		"NFS server Deployment %s/%s of %s differs from the spec rendered by provisioner version %s, %s",
+		deployObj.Namespace, deployObj.Name, target.description, version.Current(), reason)
+}
+
+// upgradeDeployment updates the pod template of the given NFS server
+// Deployment with the rendered Deployment, and waits for the rollout.
+// Replicas of the Deployment are retained.
+func (uc *UpgradeController) upgradeDeployment(ctx context.Context, deployObj, desired *appsv1.Deployment) error {
+	p := uc.provisioner
+
+	// Selector of the Deployment is immutable
+	selector, err := metav1.LabelSelectorAsSelector(deployObj.Spec.Selector)
+	if err != nil || !selector.Matches(labels.Set(desired.Spec.Template.Labels)) {
+		return errors.Errorf("selector doesn't match the rendered pod labels, Deployment must be recreated")
+	}
+
+	updated := deployObj.DeepCopy()
+	updated.Spec.Template = desired.Spec.Template
+	updated.Spec.Strategy = desired.Spec.Strategy
+
+	if updated.Labels == nil {
+		updated.Labels = map[string]string{}
+	}
+	for k, v := range desired.Labels {
+		updated.Labels[k] = v
+	}
+	// Annotations and finalizers of the rendered Deployment include
+	// the provisioner version, spec hash and those added by the hook
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	for k, v := range desired.Annotations {
+		updated.Annotations[k] = v
+	}
+	delete(updated.Annotations, upgradeApprovedAnnotation)
+	helper.AddFinalizers(&updated.ObjectMeta, desired.Finalizers)
+
+	_, err = p.kubeClient.AppsV1().
+		Deployments(updated.Namespace).
+		Update(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+
+	return uc.waitForRollout(ctx, updated.Namespace, updated.Name)
+}
+
+// waitForRollout waits for all the replicas of the given Deployment
+// to be updated and available
+func (uc *UpgradeController) waitForRollout(ctx context.Context, namespace, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, UpgradeRolloutTimeout)
+	defer cancel()
+
+	err := wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		deployObj, err := uc.provisioner.kubeClient.AppsV1().
+			Deployments(namespace).
+			Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return isDeploymentRolledOut(deployObj), nil
+	}, ctx.Done())
+	if err == wait.ErrWaitTimeout {
+		return errors.Errorf("timed out waiting for the rollout of Deployment %s/%s", namespace, name)
+	}
+	return err
+}
+
+// isDeploymentRolledOut returns true if the controller has observed the
+// latest spec of the given Deployment, and all the replicas are updated
+// and available
+func isDeploymentRolledOut(deployObj *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployObj.Spec.Replicas != nil {
+		replicas = *deployObj.Spec.Replicas
+	}
+
+	status := deployObj.Status
+	return status.ObservedGeneration >= deployObj.Generation &&
+		status.Replicas == replicas &&
+		status.UpdatedReplicas == replicas &&
+		status.AvailableReplicas == replicas
+}
+
+// setDeploymentRevision stamps the given NFS server Deployment with the
+// provisioner version and the hash of its spec
+func setDeploymentRevision(deployObj *appsv1.Deployment) error {
+	data, err := json.Marshal(deployObj.Spec)
+	if err != nil {
+		return errors.Wrapf(err, "failed to compute hash of Deployment %s", deployObj.Name)
+	}
+	hash := sha256.Sum256(data)
+
+	if deployObj.Annotations == nil {
+		deployObj.Annotations = map[string]string{}
+	}
+	deployObj.Annotations[provisionerVersionAnnotation] = version.Current()
+	deployObj.Annotations[specHashAnnotation] = hex.EncodeToString(hash[:8])
+	return nil
+}
+
+// isAnnotationTrue returns true if the given annotation is set to a
+// true boolean value
+func isAnnotationTrue(annotations map[string]string, key string) bool {
+	value, err := strconv.ParseBool(strings.TrimSpace(annotations[key]))
+	return err == nil && value
+}
+
+// ParseUpgradePolicy parses the given upgrade policy
+func ParseUpgradePolicy(policy string) (UpgradePolicy, error) {
+	switch UpgradePolicy(strings.ToLower(strings.TrimSpace(policy))) {
+	case UpgradePolicyManual:
+		return UpgradePolicyManual, nil
+	case UpgradePolicyAutomatic:
+		return UpgradePolicyAutomatic, nil
+	case UpgradePolicyMaintenanceWindow:
+		return UpgradePolicyMaintenanceWindow, nil
+	}
+	return "", errors.Errorf("unsupported upgrade policy %q, supported policies are %s, %s and %s",
+		policy, UpgradePolicyManual, UpgradePolicyAutomatic, UpgradePolicyMaintenanceWindow)
+}
+
+// MaintenanceWindow is the daily time window, in UTC, to upgrade the
+// NFS server Deployments, optionally limited to some days of the week
+type MaintenanceWindow struct {
+	// days are the days of the week, on which the window starts.
+	// Window starts on every day, if empty.
+	days map[time.Weekday]bool
+
+	// start and end are the offsets of the window from the midnight.
+	// Window ends on the next day, if end is before start.
+	start time.Duration
+	end   time.Duration
+
+	// spec is the window as configured
+	spec string
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseMaintenanceWindow parses the maintenance window of format
+// "[<days>] <start>-<end>", where days are comma separated days of the
+// week, and start and end are in HH:MM format, e.g. "Sat,Sun 02:00-04:00"
+func ParseMaintenanceWindow(spec string) (*MaintenanceWindow, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, errors.Errorf("invalid maintenance window %q, expected format is \"[<days>] <start>-<end>\"", spec)
+	}
+
+	window := &MaintenanceWindow{
+		days: map[time.Weekday]bool{},
+		spec: strings.Join(fields, " "),
+	}
+	if len(fields) == 2 {
+		for _, day := range strings.Split(fields[0], ",") {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				return nil, errors.Errorf("invalid day %q in maintenance window %q", day, spec)
+			}
+			window.days[weekday] = true
+		}
+	}
+
+	times := strings.Split(fields[len(fields)-1], "-")
+	if len(times) != 2 {
+		return nil, errors.Errorf("invalid time range %q in maintenance window %q", fields[len(fields)-1], spec)
+	}
+	var err error
+	if window.start, err = parseTimeOfDay(times[0]); err != nil {
+		return nil, errors.Wrapf(err, "invalid maintenance window %q", spec)
+	}
+	if window.end, err = parseTimeOfDay(times[1]); err != nil {
+		return nil, errors.Wrapf(err, "invalid maintenance window %q", spec)
+	}
+	if window.start == window.end {
+		return nil, errors.Errorf("empty maintenance window %q", spec)
+	}
+	return window, nil
+}
+
+// parseTimeOfDay parses the time in HH:MM format to the offset from the midnight
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.Errorf("invalid time %q, expected format is HH:MM", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains returns true if the given time is within the maintenance window
+func (w *MaintenanceWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := t.Sub(midnight)
+
+	startDay := t.Weekday()
+	switch {
+	case w.start < w.end:
+		if offset < w.start || offset >= w.end {
+			return false
+		}
+	case offset >= w.start:
+	case offset < w.end:
+		// Window started on the previous day
+		startDay = (startDay + 6) % 7
+	default:
+		return false
+	}
+	return len(w.days) == 0 || w.days[startDay]
+}
+
+// String returns the maintenance window as configured
+func (w *MaintenanceWindow) String() string {
+	if w == nil {
+		return "<none>"
+	}
+	return w.spec
+}
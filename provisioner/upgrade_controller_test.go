@@ -0,0 +1,465 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	nfshook "github.com/openebs/dynamic-nfs-provisioner/pkg/hook"
+	"github.com/openebs/dynamic-nfs-provisioner/pkg/metrics"
+	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func newFakeUpgradeController(serverNs string, policy UpgradePolicy, window *MaintenanceWindow, objs ...runtime.Object) *UpgradeController {
+	pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	deployIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *corev1.PersistentVolume:
+			_ = pvIndexer.Add(o)
+		case *corev1.PersistentVolumeClaim:
+			_ = pvcIndexer.Add(o)
+		case *appsv1.Deployment:
+			_ = deployIndexer.Add(o)
+		}
+	}
+
+	p := &Provisioner{
+		kubeClient:      fake.NewSimpleClientset(objs...),
+		serverNamespace: serverNs,
+		namespace:       "openebs",
+		pvTracker:       NewProvisioningTracker(),
+	}
+	p.getVolumeConfig = p.GetVolumeConfig
+
+	return &UpgradeController{
+		provisioner:   p,
+		policy:        policy,
+		window:        window,
+		pvLister:      listersv1.NewPersistentVolumeLister(pvIndexer),
+		pvcLister:     listersv1.NewPersistentVolumeClaimLister(pvcIndexer),
+		deployLister:  appslisters.NewDeploymentLister(deployIndexer),
+		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "nfs-upgrade"),
+		recorder:      record.NewFakeRecorder(10),
+		reportedDrift: map[string]string{},
+		now:           time.Now,
+	}
+}
+
+// getFakeUpgradeObjects returns the NFS volume objects, whose NFS server
+// Deployment is rolled out and stamped with the given spec hash
+func getFakeUpgradeObjects(serverNs, pvName, specHash string) []runtime.Object {
+	objs := getFakeReconcileObjects(serverNs, pvName)
+	for _, obj := range objs {
+		if deployObj, ok := obj.(*appsv1.Deployment); ok {
+			deployObj.Annotations = map[string]string{specHashAnnotation: specHash}
+			deployObj.Spec.Selector = &metav1.LabelSelector{
+				MatchLabels: map[string]string{nfsServerLabelKey: deployObj.Name},
+			}
+			deployObj.Status = appsv1.DeploymentStatus{
+				Replicas:          1,
+				UpdatedReplicas:   1,
+				AvailableReplicas: 1,
+			}
+		}
+	}
+	return objs
+}
+
+// getRenderedSpecHash returns the spec hash of the NFS server Deployment
+// rendered for the given NFS PV
+func getRenderedSpecHash(t *testing.T, serverNs, pvName string, objs ...runtime.Object) string {
+	uc := newFakeUpgradeController(serverNs, UpgradePolicyManual, nil, objs...)
+	pv, err := uc.pvLister.Get(pvName)
+	assert.NoError(t, err)
+	pvc, err := uc.pvcLister.PersistentVolumeClaims(pv.Spec.ClaimRef.Namespace).Get(pv.Spec.ClaimRef.Name)
+	assert.NoError(t, err)
+
+	nfsServerOpts, err := uc.provisioner.getNFSServerOptions(context.TODO(), pv, pvc)
+	assert.NoError(t, err)
+	desired, err := uc.provisioner.buildDeployment(nfsServerOpts)
+	assert.NoError(t, err)
+	return desired.Annotations[specHashAnnotation]
+}
+
+// getFakeDeploymentHook returns the hook which adds an annotation and
+// a finalizer to the NFS server Deployment on volume creation
+func getFakeDeploymentHook(t *testing.T) *nfshook.Hook {
+	hook, err := nfshook.ParseHooks([]byte(`
+version: 1.0.0
+hooks:
+  addOrUpdateEntriesOnCreateVolumeEvent:
+    name: createHook
+    nfsDeployment:
+      annotations:
+        example.io/track: "true"
+      finalizers:
+      - test.io/tracking-protection
+`))
+	assert.NoError(t, err)
+	return hook
+}
+
+func TestUpgradeControllerSyncPV(t *testing.T) {
+	os.Setenv(string(NFSServerImageKey), "openebs/nfs-server-alpine:ci")
+	defer os.Unsetenv(string(NFSServerImageKey))
+
+	serverNs := "nfs-ns"
+	inWindow, _ := ParseMaintenanceWindow("00:00-23:59")
+	outOfWindow, _ := ParseMaintenanceWindow("Mon 02:00-02:01")
+
+	tests := map[string]struct {
+		policy              UpgradePolicy
+		window              *MaintenanceWindow
+		pvcAnnotations      map[string]string
+		deployAnnotations   map[string]string
+		isUpToDate          bool
+		expectedUpgrade     bool
+		expectedDrift       float64
+		expectedEventReason string
+		isHookConfigured    bool
+	}{
+		"when Deployment matches the rendered spec": {
+			policy:     UpgradePolicyAutomatic,
+			isUpToDate: true,
+		},
+		"when policy is automatic": {
+			policy:              UpgradePolicyAutomatic,
+			expectedUpgrade:     true,
+			expectedEventReason: upgradeSuccessReason,
+		},
+		"when policy is automatic and hook is configured": {
+			policy:              UpgradePolicyAutomatic,
+			isHookConfigured:    true,
+			expectedUpgrade:     true,
+			expectedEventReason: upgradeSuccessReason,
+		},
+		"when policy is manual and upgrade is not approved": {
+			policy:              UpgradePolicyManual,
+			expectedDrift:       1,
+			expectedEventReason: specDriftReason,
+		},
+		"when policy is manual and upgrade is approved": {
+			policy:              UpgradePolicyManual,
+			deployAnnotations:   map[string]string{upgradeApprovedAnnotation: "true"},
+			expectedUpgrade:     true,
+			expectedEventReason: upgradeSuccessReason,
+		},
+		"when policy is automatic and NFS PVC opted out": {
+			policy:              UpgradePolicyAutomatic,
+			pvcAnnotations:      map[string]string{upgradeDisabledAnnotation: "true"},
+			expectedDrift:       1,
+			expectedEventReason: specDriftReason,
+		},
+		"when policy is automatic and Deployment opted out": {
+			policy:              UpgradePolicyAutomatic,
+			deployAnnotations:   map[string]string{upgradeDisabledAnnotation: "true"},
+			expectedDrift:       1,
+			expectedEventReason: specDriftReason,
+		},
+		"when maintenance window is open": {
+			policy:              UpgradePolicyMaintenanceWindow,
+			window:              inWindow,
+			expectedUpgrade:     true,
+			expectedEventReason: upgradeSuccessReason,
+		},
+		"when maintenance window is closed": {
+			policy:              UpgradePolicyMaintenanceWindow,
+			window:              outOfWindow,
+			expectedDrift:       1,
+			expectedEventReason: specDriftReason,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			pvName := "pv-upgrade"
+			objs := getFakeUpgradeObjects(serverNs, pvName, "stale-hash")
+			for _, obj := range objs {
+				switch o := obj.(type) {
+				case *corev1.PersistentVolumeClaim:
+					if o.Namespace != serverNs {
+						o.Annotations = test.pvcAnnotations
+					}
+				case *appsv1.Deployment:
+					for k, v := range test.deployAnnotations {
+						o.Annotations[k] = v
+					}
+					if test.isUpToDate {
+						o.Annotations[specHashAnnotation] = getRenderedSpecHash(t, serverNs, pvName, objs...)
+					}
+				}
+			}
+
+			uc := newFakeUpgradeController(serverNs, test.policy, test.window, objs...)
+			if test.isHookConfigured {
+				uc.provisioner.hook = getFakeDeploymentHook(t)
+			}
+			// Maintenance window "Mon 02:00-02:01" is closed on Tuesday
+			uc.now = func() time.Time { return time.Date(2021, time.June, 1, 2, 0, 30, 0, time.UTC) }
+
+			upgradeCount := testutil.ToFloat64(metrics.NFSServerUpgradeTotal)
+
+			assert.NoError(t, uc.syncPV(context.TODO(), pvName))
+			// drift is reported once for each change of the rendered spec
+			assert.NoError(t, uc.syncPV(context.TODO(), pvName))
+
+			deployObj, err := uc.provisioner.kubeClient.AppsV1().
+				Deployments(serverNs).
+				Get(context.TODO(), "nfs-"+pvName, metav1.GetOptions{})
+			assert.NoError(t, err)
+
+			if test.expectedUpgrade {
+				assert.NotEqual(t, "stale-hash", deployObj.Annotations[specHashAnnotation])
+				assert.NotContains(t, deployObj.Annotations, upgradeApprovedAnnotation)
+				assert.Equal(t, "openebs/nfs-server-alpine:ci", deployObj.Spec.Template.Spec.Containers[0].Image)
+				assert.Equal(t, upgradeCount+1, testutil.ToFloat64(metrics.NFSServerUpgradeTotal))
+				if test.isHookConfigured {
+					// upgraded Deployment is rendered with the hook, same as on create
+					assert.Equal(t, "true", deployObj.Annotations["example.io/track"])
+					assert.Contains(t, deployObj.Finalizers, "test.io/tracking-protection")
+				}
+			} else {
+				assert.Empty(t, deployObj.Spec.Template.Spec.Containers)
+				assert.Equal(t, upgradeCount, testutil.ToFloat64(metrics.NFSServerUpgradeTotal))
+			}
+			assert.Equal(t, test.expectedDrift, testutil.ToFloat64(metrics.NFSServerSpecDrift.WithLabelValues(pvName)))
+
+			events := uc.recorder.(*record.FakeRecorder).Events
+			if len(test.expectedEventReason) == 0 {
+				assert.Equal(t, 0, len(events))
+				return
+			}
+			if assert.Equal(t, 1, len(events)) {
+				assert.Contains(t, <-events, test.expectedEventReason)
+			}
+		})
+	}
+}
+
+func TestUpgradeControllerSyncSharedNFSServer(t *testing.T) {
+	os.Setenv(string(NFSServerImageKey), "openebs/nfs-server-alpine:ci")
+	defer os.Unsetenv(string(NFSServerImageKey))
+
+	serverNs := "nfs-ns"
+	scName := "nfs-sc"
+	serverName := getSharedNFSServerName(scName)
+
+	objs := []runtime.Object{
+		&storagev1.StorageClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: scName,
+				Annotations: map[string]string{
+					string(mconfig.CASConfigKey): "- name: SharedNFSServer\n  value: \"true\"\n- name: SharedBackendCapacity\n  value: 10Gi\n",
+				},
+			},
+			Provisioner: provisionerName,
+		},
+		getFakeBoundPvcObject(serverNs, "nfs-"+serverName, "backend-"+serverName, "10Gi", "10Gi"),
+	}
+
+	// NFS PVCs of the shared NFS server have different config
+	pvcConfigs := map[string]string{
+		"pv1": "- name: NFSServerResourceLimits\n  value: |-\n    cpu: 500m\n",
+		"pv2": "- name: LeaseTime\n  value: 120\n",
+	}
+	for pvName, casConfig := range pvcConfigs {
+		nfsPvc := getFakeBoundPvcObject("app", "pvc-"+pvName, pvName, "5Gi", "5Gi")
+		nfsPvc.UID = types.UID("uid-" + pvName)
+		nfsPvc.Spec.StorageClassName = &scName
+		nfsPvc.Annotations = map[string]string{string(mconfig.CASConfigKey): casConfig}
+
+		nfsPv := getFakeNFSPvObject(pvName, "5Gi", provisionerName)
+		nfsPv.Labels = map[string]string{
+			"openebs.io/cas-type":   "nfs-kernel",
+			sharedNFSServerLabelKey: serverName,
+		}
+		nfsPv.Spec.StorageClassName = scName
+		nfsPv.Spec.ClaimRef = &corev1.ObjectReference{
+			Namespace: nfsPvc.Namespace,
+			Name:      nfsPvc.Name,
+			UID:       nfsPvc.UID,
+		}
+		nfsPv.Status.Phase = corev1.VolumeBound
+		objs = append(objs, nfsPvc, nfsPv)
+	}
+
+	// Shared NFS server is rendered with the same spec for all the NFS PVs
+	specHash := getRenderedSpecHash(t, serverNs, "pv1", objs...)
+	assert.NotEmpty(t, specHash)
+	assert.Equal(t, specHash, getRenderedSpecHash(t, serverNs, "pv2", objs...))
+
+	objs = append(objs, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "nfs-" + serverName,
+			Namespace:   serverNs,
+			Annotations: map[string]string{specHashAnnotation: specHash},
+		},
+	})
+	uc := newFakeUpgradeController(serverNs, UpgradePolicyAutomatic, nil, objs...)
+
+	// NFS PVs of the shared NFS server are queued as the shared NFS server
+	for _, pvName := range []string{"pv1", "pv2"} {
+		pv, err := uc.pvLister.Get(pvName)
+		assert.NoError(t, err)
+		uc.enqueuePV(pv)
+	}
+	assert.Equal(t, 1, uc.queue.Len())
+
+	upgradeCount := testutil.ToFloat64(metrics.NFSServerUpgradeTotal)
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, uc.syncSharedNFSServer(context.TODO(), serverName))
+		assert.NoError(t, uc.syncPV(context.TODO(), "pv1"))
+		assert.NoError(t, uc.syncPV(context.TODO(), "pv2"))
+	}
+
+	deployObj, err := uc.provisioner.kubeClient.AppsV1().
+		Deployments(serverNs).
+		Get(context.TODO(), "nfs-"+serverName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, specHash, deployObj.Annotations[specHashAnnotation])
+	assert.Empty(t, deployObj.Spec.Template.Spec.Containers)
+	assert.Equal(t, upgradeCount, testutil.ToFloat64(metrics.NFSServerUpgradeTotal))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.NFSServerSpecDrift.WithLabelValues(serverName)))
+	assert.Equal(t, 0, len(uc.recorder.(*record.FakeRecorder).Events))
+}
+
+func TestSetDeploymentRevision(t *testing.T) {
+	deployObj := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "nfs-pv1"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "nfs-server", Image: "nfs-server:0.1"}},
+				},
+			},
+		},
+	}
+
+	assert.NoError(t, setDeploymentRevision(deployObj))
+	hash := deployObj.Annotations[specHashAnnotation]
+	assert.NotEmpty(t, hash)
+	assert.Contains(t, deployObj.Annotations, provisionerVersionAnnotation)
+
+	assert.NoError(t, setDeploymentRevision(deployObj))
+	assert.Equal(t, hash, deployObj.Annotations[specHashAnnotation])
+
+	deployObj.Spec.Template.Spec.Containers[0].Image = "nfs-server:0.2"
+	assert.NoError(t, setDeploymentRevision(deployObj))
+	assert.NotEqual(t, hash, deployObj.Annotations[specHashAnnotation])
+}
+
+func TestParseMaintenanceWindow(t *testing.T) {
+	// 2021-06-05 is a Saturday
+	saturday := func(hour, min int) time.Time {
+		return time.Date(2021, time.June, 5, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := map[string]struct {
+		spec          string
+		isErr         bool
+		time          time.Time
+		expectedMatch bool
+	}{
+		"when window is daily and time is within the window": {
+			spec:          "02:00-04:00",
+			time:          saturday(3, 0),
+			expectedMatch: true,
+		},
+		"when window is daily and time is at the end of the window": {
+			spec: "02:00-04:00",
+			time: saturday(4, 0),
+		},
+		"when window is on the day of the time": {
+			spec:          "Sat,Sun 02:00-04:00",
+			time:          saturday(2, 0),
+			expectedMatch: true,
+		},
+		"when window is on other days": {
+			spec: "mon,tue 02:00-04:00",
+			time: saturday(3, 0),
+		},
+		"when window started on the previous day": {
+			spec:          "Fri 22:00-02:00",
+			time:          saturday(1, 0),
+			expectedMatch: true,
+		},
+		"when window starts on the day of the time after midnight": {
+			spec: "Sat 22:00-02:00",
+			time: saturday(1, 0),
+		},
+		"when time is converted to UTC": {
+			spec:          "02:00-04:00",
+			time:          saturday(3, 0).In(time.FixedZone("IST", 5*3600+1800)),
+			expectedMatch: true,
+		},
+		"when day is invalid": {
+			spec:  "Someday 02:00-04:00",
+			isErr: true,
+		},
+		"when time range is invalid": {
+			spec:  "02:00",
+			isErr: true,
+		},
+		"when time is invalid": {
+			spec:  "02:00-25:00",
+			isErr: true,
+		},
+		"when window is empty": {
+			spec:  "02:00-02:00",
+			isErr: true,
+		},
+		"when window is not set": {
+			spec:  "",
+			isErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			window, err := ParseMaintenanceWindow(test.spec)
+			assert.Equal(t, test.isErr, err != nil, "%v", err)
+			if err != nil {
+				return
+			}
+			assert.Equal(t, test.expectedMatch, window.Contains(test.time))
+		})
+	}
+}
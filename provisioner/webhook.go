@@ -0,0 +1,236 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	"github.com/pkg/errors"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// WebhookValidatePath is the path on which the webhook server
+	// serves the validation requests
+	WebhookValidatePath = "/validate"
+)
+
+// WebhookConfig defines the configuration of the webhook server
+// which validates the cas.openebs.io/config of the NFS StorageClasses
+// and the NFS PVCs
+type WebhookConfig struct {
+	// ListenAddress is the address of the webhook server.
+	// Webhook server is disabled if it is empty
+	ListenAddress string
+
+	// CertFile and KeyFile are the paths of the TLS certificate
+	// and the TLS key of the webhook server
+	CertFile string
+	KeyFile  string
+}
+
+// webhookHandler validates the AdmissionReview requests
+// of StorageClasses and PersistentVolumeClaims
+type webhookHandler struct {
+	p *Provisioner
+}
+
+// startWebhookServer runs the webhook server till the given
+// context is cancelled
+func startWebhookServer(ctx context.Context, p *Provisioner, cfg WebhookConfig) {
+	mux := http.NewServeMux()
+	mux.Handle(WebhookValidatePath, &webhookHandler{p: p})
+
+	server := &http.Server{
+		Addr:    cfg.ListenAddress,
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Failed to shutdown webhook server, err=%v", err)
+		}
+	}()
+
+	klog.Infof("Starting webhook server at address [%s]", cfg.ListenAddress)
+	err := server.ListenAndServeTLS(cfg.CertFile, cfg.KeyFile)
+	if err != nil && err != http.ErrServerClosed {
+		klog.Errorf("Failed to start webhook server at [%s]: %v", cfg.ListenAddress, err)
+	}
+}
+
+// ServeHTTP decodes the AdmissionReview request and responds
+// with the result of the validation
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	review := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, "invalid AdmissionReview request", http.StatusBadRequest)
+		return
+	}
+
+	response := &admissionv1.AdmissionResponse{
+		UID:     review.Request.UID,
+		Allowed: true,
+	}
+	if err := h.validate(r.Context(), review.Request); err != nil {
+		klog.Infof("Rejecting %s of %s %s/%s: %v", review.Request.Operation,
+			review.Request.Kind.Kind, review.Request.Namespace, review.Request.Name, err)
+		response.Allowed = false
+		response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		}
+	}
+
+	review.Response = response
+	review.Request = nil
+	out, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// validate returns error if the cas.openebs.io/config of the
+// StorageClass or the PersistentVolumeClaim is invalid
+func (h *webhookHandler) validate(ctx context.Context, req *admissionv1.AdmissionRequest) error {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return nil
+	}
+
+	switch req.Kind.Kind {
+	case "StorageClass":
+		sc := &storagev1.StorageClass{}
+		if err := json.Unmarshal(req.Object.Raw, sc); err != nil {
+			return errors.Wrapf(err, "failed to decode StorageClass")
+		}
+		if req.Operation == admissionv1.Update {
+			oldSc := &storagev1.StorageClass{}
+			if err := json.Unmarshal(req.OldObject.Raw, oldSc); err == nil &&
+				oldSc.Annotations[string(mconfig.CASConfigKey)] == sc.Annotations[string(mconfig.CASConfigKey)] {
+				return nil
+			}
+		}
+		return h.validateStorageClass(sc)
+	case "PersistentVolumeClaim":
+		pvc := &v1.PersistentVolumeClaim{}
+		if err := json.Unmarshal(req.Object.Raw, pvc); err != nil {
+			return errors.Wrapf(err, "failed to decode PersistentVolumeClaim")
+		}
+		if req.Operation == admissionv1.Update {
+			oldPvc := &v1.PersistentVolumeClaim{}
+			if err := json.Unmarshal(req.OldObject.Raw, oldPvc); err == nil &&
+				oldPvc.Annotations[string(mconfig.CASConfigKey)] == pvc.Annotations[string(mconfig.CASConfigKey)] {
+				return nil
+			}
+		}
+		return h.validatePersistentVolumeClaim(ctx, pvc)
+	}
+	return nil
+}
+
+// validateStorageClass validates the cas.openebs.io/config
+// of the given NFS StorageClass
+func (h *webhookHandler) validateStorageClass(sc *storagev1.StorageClass) error {
+	if sc.Provisioner != provisionerName {
+		return nil
+	}
+
+	casConfigStr := sc.Annotations[string(mconfig.CASConfigKey)]
+	if err := validateCASConfigKeys(casConfigStr); err != nil {
+		return errors.Wrapf(err, "invalid %s of StorageClass %s", mconfig.CASConfigKey, sc.Name)
+	}
+
+	volumeConfig, err := h.p.GetVolumeConfigFromParameters(sc.Name, sc.Annotations)
+	if err != nil {
+		return errors.Wrapf(err, "invalid %s of StorageClass %s", mconfig.CASConfigKey, sc.Name)
+	}
+	if err := volumeConfig.Validate(); err != nil {
+		return errors.Wrapf(err, "invalid %s of StorageClass %s", mconfig.CASConfigKey, sc.Name)
+	}
+	return nil
+}
+
+// validatePersistentVolumeClaim validates the cas.openebs.io/config of
+// the given NFS PVC, merged with the config of its StorageClass
+func (h *webhookHandler) validatePersistentVolumeClaim(ctx context.Context, pvc *v1.PersistentVolumeClaim) error {
+	casConfigStr := pvc.Annotations[string(mconfig.CASConfigKey)]
+	if len(casConfigStr) == 0 {
+		return nil
+	}
+
+	scName := GetStorageClassNameFromPVC(pvc)
+	if scName == nil || len(*scName) == 0 {
+		return nil
+	}
+	sc, err := h.p.kubeClient.StorageV1().StorageClasses().Get(ctx, *scName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// StorageClass may be created after the PVC
+			return nil
+		}
+		return errors.Wrapf(err, "failed to get StorageClass %s", *scName)
+	}
+	if sc.Provisioner != provisionerName {
+		return nil
+	}
+
+	if err := validateCASConfigKeys(casConfigStr); err != nil {
+		return errors.Wrapf(err, "invalid %s of PVC %s/%s", mconfig.CASConfigKey, pvc.Namespace, pvc.Name)
+	}
+	if err := validatePVCConfigKeys(casConfigStr); err != nil {
+		return errors.Wrapf(err, "invalid %s of PVC %s/%s", mconfig.CASConfigKey, pvc.Namespace, pvc.Name)
+	}
+
+	volumeConfig, err := h.p.GetVolumeConfig(pvc.Name, pvc)
+	if err != nil {
+		return errors.Wrapf(err, "invalid %s of PVC %s/%s", mconfig.CASConfigKey, pvc.Namespace, pvc.Name)
+	}
+	if err := volumeConfig.Validate(); err != nil {
+		return errors.Wrapf(err, "invalid %s of PVC %s/%s", mconfig.CASConfigKey, pvc.Namespace, pvc.Name)
+	}
+	return nil
+}
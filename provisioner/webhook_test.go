@@ -0,0 +1,182 @@
+/*
+Copyright 2021 The OpenEBS Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioner
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mconfig "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func getFakeNFSStorageClass(name, casConfig string) *storagev1.StorageClass {
+	sc := getFakeStorageClass(name, false)
+	sc.Provisioner = provisionerName
+	if len(casConfig) != 0 {
+		sc.Annotations = map[string]string{string(mconfig.CASConfigKey): casConfig}
+	}
+	return sc
+}
+
+func getFakeAdmissionReview(t *testing.T, kind string, operation admissionv1.Operation, obj, oldObj interface{}) []byte {
+	req := &admissionv1.AdmissionRequest{
+		UID:       "test-uid",
+		Kind:      metav1.GroupVersionKind{Kind: kind},
+		Operation: operation,
+	}
+	raw, err := json.Marshal(obj)
+	assert.NoError(t, err)
+	req.Object = runtime.RawExtension{Raw: raw}
+	if oldObj != nil {
+		raw, err = json.Marshal(oldObj)
+		assert.NoError(t, err)
+		req.OldObject = runtime.RawExtension{Raw: raw}
+	}
+
+	body, err := json.Marshal(&admissionv1.AdmissionReview{Request: req})
+	assert.NoError(t, err)
+	return body
+}
+
+func TestWebhookHandler(t *testing.T) {
+	validConfig := `- name: LeaseTime
+  value: "30"`
+	invalidConfig := `- name: LeaseTime
+  value: "5000"`
+	unknownKeyConfig := `- name: GraceTme
+  value: "30"`
+
+	pvcWithConfig := func(scName, casConfig string) *corev1.PersistentVolumeClaim {
+		pvc := getFakePVCObject("app", "test-pvc", scName, "uid")
+		pvc.Annotations = map[string]string{string(mconfig.CASConfigKey): casConfig}
+		return pvc
+	}
+
+	tests := map[string]struct {
+		objs            []runtime.Object
+		kind            string
+		operation       admissionv1.Operation
+		obj             interface{}
+		oldObj          interface{}
+		allowedExpected bool
+	}{
+		"when NFS StorageClass has valid config": {
+			kind:            "StorageClass",
+			operation:       admissionv1.Create,
+			obj:             getFakeNFSStorageClass("nfs-sc", validConfig),
+			allowedExpected: true,
+		},
+		"when NFS StorageClass has invalid config": {
+			kind:      "StorageClass",
+			operation: admissionv1.Create,
+			obj:       getFakeNFSStorageClass("nfs-sc", invalidConfig),
+		},
+		"when NFS StorageClass has unknown config key": {
+			kind:      "StorageClass",
+			operation: admissionv1.Create,
+			obj:       getFakeNFSStorageClass("nfs-sc", unknownKeyConfig),
+		},
+		"when StorageClass of other provisioner has invalid config": {
+			kind:      "StorageClass",
+			operation: admissionv1.Create,
+			obj: func() *storagev1.StorageClass {
+				sc := getFakeNFSStorageClass("other-sc", invalidConfig)
+				sc.Provisioner = "other-provisioner"
+				return sc
+			}(),
+			allowedExpected: true,
+		},
+		"when config of NFS StorageClass is not updated": {
+			kind:            "StorageClass",
+			operation:       admissionv1.Update,
+			obj:             getFakeNFSStorageClass("nfs-sc", invalidConfig),
+			oldObj:          getFakeNFSStorageClass("nfs-sc", invalidConfig),
+			allowedExpected: true,
+		},
+		"when NFS PVC has valid config": {
+			objs:            []runtime.Object{getFakeNFSStorageClass("nfs-sc", "")},
+			kind:            "PersistentVolumeClaim",
+			operation:       admissionv1.Create,
+			obj:             pvcWithConfig("nfs-sc", validConfig),
+			allowedExpected: true,
+		},
+		"when NFS PVC has invalid config": {
+			objs:      []runtime.Object{getFakeNFSStorageClass("nfs-sc", "")},
+			kind:      "PersistentVolumeClaim",
+			operation: admissionv1.Create,
+			obj:       pvcWithConfig("nfs-sc", invalidConfig),
+		},
+		"when invalid config of NFS PVC is overridden by StorageClass": {
+			objs:            []runtime.Object{getFakeNFSStorageClass("nfs-sc", validConfig)},
+			kind:            "PersistentVolumeClaim",
+			operation:       admissionv1.Create,
+			obj:             pvcWithConfig("nfs-sc", invalidConfig),
+			allowedExpected: true,
+		},
+		"when NFS PVC has StorageClass only config": {
+			objs:      []runtime.Object{getFakeNFSStorageClass("nfs-sc", "")},
+			kind:      "PersistentVolumeClaim",
+			operation: admissionv1.Create,
+			obj: pvcWithConfig("nfs-sc", `- name: NFSServerPriorityClassName
+  value: nfs-critical`),
+		},
+		"when StorageClass of PVC doesn't exist": {
+			kind:            "PersistentVolumeClaim",
+			operation:       admissionv1.Create,
+			obj:             pvcWithConfig("nfs-sc", invalidConfig),
+			allowedExpected: true,
+		},
+	}
+
+	for name, test := range tests {
+		name := name
+		test := test
+		t.Run(name, func(t *testing.T) {
+			handler := &webhookHandler{
+				p: &Provisioner{
+					kubeClient: fake.NewSimpleClientset(test.objs...),
+				},
+			}
+
+			body := getFakeAdmissionReview(t, test.kind, test.operation, test.obj, test.oldObj)
+			req := httptest.NewRequest(http.MethodPost, WebhookValidatePath, bytes.NewReader(body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if !assert.Equal(t, http.StatusOK, rec.Code) {
+				return
+			}
+
+			review := &admissionv1.AdmissionReview{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), review))
+			if !assert.NotNil(t, review.Response) {
+				return
+			}
+			assert.Equal(t, "test-uid", string(review.Response.UID))
+			assert.Equal(t, test.allowedExpected, review.Response.Allowed, "response: %+v", review.Response.Result)
+		})
+	}
+}
@@ -167,6 +167,10 @@ func (k *KubeClient) listDeploymentPods(deploy *appsv1.Deployment) (*corev1.PodL
 	return k.listPods(deploy.Namespace, labelSelector)
 }
 
+func (k *KubeClient) deletePod(podNamespace, podName string) error {
+	return k.CoreV1().Pods(podNamespace).Delete(context.TODO(), podName, metav1.DeleteOptions{})
+}
+
 func (k *KubeClient) createNamespace(namespace string) error {
 	_, err := k.CoreV1().Namespaces().Get(context.TODO(), namespace, metav1.GetOptions{})
 	if err != nil {
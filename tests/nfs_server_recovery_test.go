@@ -0,0 +1,332 @@
+/*
+Copyright 2021 The OpenEBS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tests
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	mayav1alpha1 "github.com/openebs/maya/pkg/apis/openebs.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	deploy "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/apps/v1/deployment"
+	container "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/container"
+	pvc "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/persistentvolumeclaim"
+	pts "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/podtemplatespec"
+	volume "github.com/openebs/dynamic-nfs-provisioner/pkg/kubernetes/api/core/v1/volume"
+	provisioner "github.com/openebs/dynamic-nfs-provisioner/provisioner"
+)
+
+var _ = Describe("TEST NFS SERVER STATE RECOVERY", func() {
+	var (
+		// application values
+		deployName           = "busybox-nfs-lock"
+		label                = "demo=nfs-lock-deployment"
+		applicationNamespace = "nfs-tests-ns"
+		labelselector        = map[string]string{
+			"demo": "nfs-lock-deployment",
+		}
+		lockFile = "/mnt/store1/lockfile"
+
+		// pvc values
+		accessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+		capacity    = "2Gi"
+		pvcName     = "pvc-nfs-lock"
+
+		// nfs provisioner values
+		openebsNamespace = "openebs"
+		nfsServerLabel   = "openebs.io/nfs-server"
+		scName           = "nfs-server-recovery-sc"
+		backendSCName    = "openebs-hostpath"
+		scNfsServerType  = "kernel"
+		scGraceTime      = "15"
+		scLeaseTime      = "15"
+		recoveryDir      = "/var/lib/nfs/v4recovery"
+
+		nfsServerPodName string
+	)
+
+	When("create storageclass with nfs configuration", func() {
+		It("should create storageclass", func() {
+			By("creating storageclass")
+
+			casObj := []mayav1alpha1.Config{
+				{
+					Name:  provisioner.KeyPVNFSServerType,
+					Value: scNfsServerType,
+				},
+				{
+					Name:  provisioner.LeaseTime,
+					Value: scLeaseTime,
+				},
+				{
+					Name:  provisioner.GraceTime,
+					Value: scGraceTime,
+				},
+				{
+					Name:  provisioner.KeyPVBackendStorageClass,
+					Value: backendSCName,
+				},
+			}
+
+			casObjStr, err := yaml.Marshal(casObj)
+			Expect(err).To(BeNil(), "while marshaling cas object")
+
+			err = Client.createStorageClass(&storagev1.StorageClass{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: scName,
+					Annotations: map[string]string{
+						string(mayav1alpha1.CASTypeKey):   "nfsrwx",
+						string(mayav1alpha1.CASConfigKey): string(casObjStr),
+					},
+				},
+				Provisioner: "openebs.io/nfsrwx",
+			})
+			Expect(err).To(BeNil(), "while creating SC{%s}", scName)
+		})
+	})
+
+	When(fmt.Sprintf("pvc with storageclass %s is created", scName), func() {
+		It("should create a pvc ", func() {
+			By("building a pvc")
+			pvcObj, err := pvc.NewBuilder().
+				WithName(pvcName).
+				WithNamespace(applicationNamespace).
+				WithStorageClass(scName).
+				WithAccessModes(accessModes).
+				WithCapacity(capacity).Build()
+			Expect(err).ShouldNot(
+				HaveOccurred(),
+				"while building pvc {%s} in namespace {%s}",
+				pvcName,
+				applicationNamespace,
+			)
+
+			By("creating above pvc")
+			err = Client.createPVC(pvcObj)
+			Expect(err).To(BeNil(), "while creating pvc {%s} in namespace {%s}", pvcName, applicationNamespace)
+
+			pvcPhase, err := Client.waitForPVCBound(applicationNamespace, pvcName)
+			Expect(err).To(BeNil(), "while waiting for pvc %s/%s bound phase", applicationNamespace, pvcName)
+			Expect(pvcPhase).To(Equal(corev1.ClaimBound), "pvc %s/%s should be in bound phase", applicationNamespace, pvcName)
+		})
+	})
+
+	When("deployment holding a lock on the volume is created", func() {
+		It("should create a deployment and a running pod", func() {
+			By("building a deployment")
+			// The container exits if the lock is lost, since the writes
+			// on the locked file fail after the lock is lost
+			deployObj, err := deploy.NewBuilder().
+				WithName(deployName).
+				WithNamespace(applicationNamespace).
+				WithLabelsNew(labelselector).
+				WithSelectorMatchLabelsNew(labelselector).
+				WithPodTemplateSpecBuilder(
+					pts.NewBuilder().
+						WithLabelsNew(labelselector).
+						WithContainerBuildersNew(
+							container.NewBuilder().
+								WithName("busybox").
+								WithImage("busybox").
+								WithCommandNew(
+									[]string{
+										"sh",
+										"-c",
+										fmt.Sprintf("exec 3>>%s; flock 3 || exit 1; "+
+											"while true; do date >&3 || exit 1; sleep 1; done", lockFile),
+									},
+								).
+								WithVolumeMountsNew(
+									[]corev1.VolumeMount{
+										{
+											Name:      "demo-vol1",
+											MountPath: "/mnt/store1",
+										},
+									},
+								),
+						).
+						WithVolumeBuilders(
+							volume.NewBuilder().
+								WithName("demo-vol1").
+								WithPVCSource(pvcName),
+						),
+				).
+				Build()
+			Expect(err).ShouldNot(
+				HaveOccurred(),
+				"while building deployment {%s} in namespace {%s}",
+				deployName,
+				applicationNamespace,
+			)
+
+			By("creating above deployment")
+			err = Client.createDeployment(deployObj)
+			Expect(err).To(
+				BeNil(),
+				"while creating deployment {%s} in namespace {%s}",
+				deployName,
+				applicationNamespace,
+			)
+
+			By("verifying pod count as 1")
+			err = Client.waitForPods(applicationNamespace, label, corev1.PodRunning, 1)
+			Expect(err).To(BeNil(), "while verifying pod count")
+
+			By("verifying the lock is held")
+			Expect(isLockHeld(applicationNamespace, label, lockFile)).To(BeTrue(), "while verifying the lock on %s", lockFile)
+		})
+	})
+
+	When("verifying nfs-server recovery records", func() {
+		It("should have client records in the recovery directory", func() {
+			By("fetching nfs-server pod")
+			pvcObj, err := Client.getPVC(applicationNamespace, pvcName)
+			Expect(err).ShouldNot(
+				HaveOccurred(),
+				"while fetching pvc {%s} in namespace {%s}",
+				pvcName,
+				applicationNamespace,
+			)
+
+			nfsDeployment := fmt.Sprintf("nfs-%s", pvcObj.Spec.VolumeName)
+			podList, err := Client.listPods(openebsNamespace, fmt.Sprintf("%s=%s", nfsServerLabel, nfsDeployment))
+			Expect(err).To(BeNil(), "while fetching nfs-server pod")
+			Expect(len(podList.Items)).To(Equal(1), "while fetching nfs-server pod")
+			nfsServerPodName = podList.Items[0].Name
+
+			stdOut, stdErr, err := Client.Exec([]string{"/bin/sh", "-c", "ls -A " + recoveryDir},
+				nfsServerPodName,
+				"nfs-server",
+				openebsNamespace,
+			)
+			Expect(err).To(BeNil(), "while listing directory=%s err={%s}", recoveryDir, stdErr)
+			Expect(strings.TrimSpace(stdOut)).NotTo(BeEmpty(), "while verifying recovery records")
+		})
+	})
+
+	When("nfs-server pod is deleted", func() {
+		It("should retain the lock after nfs-server restarts", func() {
+			By("deleting nfs-server pod")
+			err := Client.deletePod(openebsNamespace, nfsServerPodName)
+			Expect(err).To(BeNil(), "while deleting nfs-server pod %s/%s", openebsNamespace, nfsServerPodName)
+
+			By("waiting for new nfs-server pod to come into running state")
+			pvcObj, err := Client.getPVC(applicationNamespace, pvcName)
+			Expect(err).To(BeNil(), "while fetching pvc {%s} in namespace {%s}", pvcName, applicationNamespace)
+
+			nfsServerSelector := fmt.Sprintf("%s=nfs-%s", nfsServerLabel, pvcObj.Spec.VolumeName)
+			var nfsServerRestarted bool
+			maxRetryCount := 30
+			for maxRetryCount != 0 {
+				podList, err := Client.listPods(openebsNamespace, nfsServerSelector)
+				Expect(err).To(BeNil(), "while fetching nfs-server pod")
+				if len(podList.Items) == 1 &&
+					podList.Items[0].Name != nfsServerPodName &&
+					podList.Items[0].Status.Phase == corev1.PodRunning {
+					nfsServerRestarted = true
+					break
+				}
+
+				time.Sleep(5 * time.Second)
+				maxRetryCount--
+			}
+			Expect(nfsServerRestarted).Should(BeTrue(), "while waiting for nfs-server pod restart")
+
+			By("waiting for the grace period to end")
+			graceTime, err := strconv.Atoi(scGraceTime)
+			Expect(err).To(BeNil(), "while parsing grace time")
+			time.Sleep(time.Duration(2*graceTime) * time.Second)
+
+			By("verifying the lock holder is still running")
+			podList, err := Client.listPods(applicationNamespace, label)
+			Expect(err).To(BeNil(), "while fetching application pod")
+			Expect(len(podList.Items)).To(Equal(1), "while fetching application pod")
+			Expect(podList.Items[0].Status.Phase).To(Equal(corev1.PodRunning), "while verifying application pod phase")
+			for _, status := range podList.Items[0].Status.ContainerStatuses {
+				Expect(status.RestartCount).To(BeZero(), "lock holder exited after nfs-server restart")
+			}
+
+			By("verifying the lock is held")
+			Expect(isLockHeld(applicationNamespace, label, lockFile)).To(BeTrue(), "while verifying the lock on %s", lockFile)
+		})
+	})
+
+	When("lock holder deployment is deleted", func() {
+		It("should not have any deployment or running pod", func() {
+			By("deleting lock holder deployment")
+			err := Client.deleteDeployment(applicationNamespace, deployName)
+			Expect(err).To(
+				BeNil(),
+				"while deleting deployment {%s} in namespace {%s}",
+				deployName,
+				applicationNamespace,
+			)
+
+			By("verifying pod count as 0")
+			err = Client.waitForPods(applicationNamespace, label, corev1.PodRunning, 0)
+			Expect(err).To(BeNil(), "while verifying pod count")
+		})
+	})
+
+	When(fmt.Sprintf("pvc with storageclass %s is deleted ", scName), func() {
+		It("should delete the pvc", func() {
+			By("deleting above pvc")
+			err := Client.deletePVC(applicationNamespace, pvcName)
+			Expect(err).To(
+				BeNil(),
+				"while deleting pvc {%s} in namespace {%s}",
+				pvcName,
+				applicationNamespace,
+			)
+		})
+	})
+
+	When(fmt.Sprintf("StorageClass %s is deleted", scName), func() {
+		It("should delete the SC", func() {
+			By("deleting SC")
+			err := Client.deleteStorageClass(scName)
+			Expect(err).To(
+				BeNil(),
+				"while deleting sc {%s}",
+				scName,
+			)
+		})
+	})
+})
+
+// isLockHeld returns true if the given file can't be locked by
+// the application pod, since it is locked by another process
+func isLockHeld(namespace, labelSelector, file string) bool {
+	podList, err := Client.listPods(namespace, labelSelector)
+	Expect(err).To(BeNil(), "while fetching application pod")
+	Expect(len(podList.Items)).To(Equal(1), "while fetching application pod")
+
+	_, _, err = Client.Exec([]string{"/bin/sh", "-c", "flock -n " + file + " true"},
+		podList.Items[0].Name,
+		"busybox",
+		namespace,
+	)
+	return err != nil
+}